@@ -0,0 +1,23 @@
+package api
+
+// ServiceCephCluster represents the client configuration for a Ceph cluster.
+//
+// Keyrings maps client entity names (such as "client.admin") to their secret key. MSMode sets the
+// messenger v2 connection mode ("crc", "secure", "prefer-crc" or "prefer-secure"), with the Ceph
+// default used when not set.
+type ServiceCephCluster struct {
+	FSID     string            `json:"fsid"               yaml:"fsid"`
+	Monitors []string          `json:"monitors"           yaml:"monitors"`
+	Keyrings map[string]string `json:"keyrings,omitempty" yaml:"keyrings,omitempty"`
+	MSMode   string            `json:"ms_mode"            yaml:"ms_mode"`
+}
+
+// ServiceCeph represents the state and configuration of the Ceph client service.
+type ServiceCeph struct {
+	State struct{} `json:"state" yaml:"state"`
+
+	Config struct {
+		Enabled  bool                          `json:"enabled"  yaml:"enabled"`
+		Clusters map[string]ServiceCephCluster `json:"clusters" yaml:"clusters"`
+	} `json:"config" yaml:"config"`
+}
@@ -1,10 +1,19 @@
 package api
 
 // ServiceISCSITarget represents a single ISCSI target.
+//
+// Interface optionally binds the session to a specific network interface. When CHAPUsername and
+// CHAPPassword are set, CHAP authentication is used for both discovery and login, with mutual CHAP
+// if CHAPUsernameIn and CHAPPasswordIn are also set.
 type ServiceISCSITarget struct {
-	Target  string `json:"target"  yaml:"target"`
-	Address string `json:"address" yaml:"address"`
-	Port    int    `json:"port"    yaml:"port"`
+	Target         string `json:"target"           yaml:"target"`
+	Address        string `json:"address"          yaml:"address"`
+	Port           int    `json:"port"             yaml:"port"`
+	Interface      string `json:"interface"        yaml:"interface"`
+	CHAPUsername   string `json:"chap_username"    yaml:"chap_username"`
+	CHAPPassword   string `json:"chap_password"    yaml:"chap_password"`
+	CHAPUsernameIn string `json:"chap_username_in" yaml:"chap_username_in"`
+	CHAPPasswordIn string `json:"chap_password_in" yaml:"chap_password_in"`
 }
 
 // ServiceISCSI represents the state and configuration of the ISCSI service.
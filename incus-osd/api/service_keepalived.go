@@ -0,0 +1,24 @@
+package api
+
+// ServiceKeepalived represents the state and configuration of the keepalived (VRRP) service.
+type ServiceKeepalived struct {
+	State struct{} `json:"state" yaml:"state"`
+
+	Config struct {
+		Enabled        bool                             `json:"enabled"                   yaml:"enabled"`
+		VirtualRouters []ServiceKeepalivedVirtualRouter `json:"virtual_routers,omitempty" yaml:"virtual_routers,omitempty"`
+	} `json:"config" yaml:"config"`
+}
+
+// ServiceKeepalivedVirtualRouter represents a VRRP instance sharing floating addresses between hosts.
+// The host with the highest Priority holds the addresses. UnicastPeers can be set on networks
+// not allowing multicast VRRP advertisements.
+type ServiceKeepalivedVirtualRouter struct {
+	Name                  string   `json:"name"                    yaml:"name"`
+	Interface             string   `json:"interface"               yaml:"interface"`
+	VirtualRouterID       int      `json:"virtual_router_id"       yaml:"virtual_router_id"`
+	Priority              int      `json:"priority"                yaml:"priority"`
+	AdvertisementInterval int      `json:"advertisement_interval"  yaml:"advertisement_interval"`
+	Addresses             []string `json:"addresses,omitempty"     yaml:"addresses,omitempty"`
+	UnicastPeers          []string `json:"unicast_peers,omitempty" yaml:"unicast_peers,omitempty"`
+}
@@ -0,0 +1,26 @@
+package api
+
+// ServiceMultipathMap represents an assembled multipath device.
+type ServiceMultipathMap struct {
+	Name  string `json:"name"  yaml:"name"`
+	WWID  string `json:"wwid"  yaml:"wwid"`
+	Paths int    `json:"paths" yaml:"paths"`
+}
+
+// ServiceMultipath represents the state and configuration of the multipath service.
+//
+// PathSelector (such as "service-time 0") and PathGroupingPolicy (such as "multibus") default to the
+// multipath-tools defaults when not set. Blacklist holds the WWIDs of devices to never use as paths.
+type ServiceMultipath struct {
+	State struct {
+		Maps []ServiceMultipathMap `json:"maps" yaml:"maps"`
+	} `json:"state" yaml:"state"`
+
+	Config struct {
+		Enabled            bool     `json:"enabled"              yaml:"enabled"`
+		FriendlyNames      bool     `json:"friendly_names"       yaml:"friendly_names"`
+		PathSelector       string   `json:"path_selector"        yaml:"path_selector"`
+		PathGroupingPolicy string   `json:"path_grouping_policy" yaml:"path_grouping_policy"`
+		Blacklist          []string `json:"blacklist"            yaml:"blacklist"`
+	} `json:"config" yaml:"config"`
+}
@@ -1,13 +1,21 @@
 package api
 
 // ServiceNVMETarget represents a single NVME target.
+//
+// Transport is either "tcp" or "rdma". HostInterface optionally forces the connection through a
+// specific network interface (TCP only).
 type ServiceNVMETarget struct {
-	Transport string `json:"transport" yaml:"transport"`
-	Address   string `json:"address"   yaml:"address"`
-	Port      int    `json:"port"      yaml:"port"`
+	Transport     string `json:"transport"      yaml:"transport"`
+	Address       string `json:"address"        yaml:"address"`
+	Port          int    `json:"port"           yaml:"port"`
+	HostInterface string `json:"host_interface" yaml:"host_interface"`
 }
 
 // ServiceNVME represents the state and configuration of the NVME service.
+//
+// HostNQN overrides the generated host NQN. ReconnectDelay and ControllerLossTimeout (in seconds,
+// -1 to retry forever) control how lost connections are re-established, using the nvme-cli
+// defaults when not set.
 type ServiceNVME struct {
 	State struct {
 		HostID  string `json:"host_id"  yaml:"host_id"`
@@ -15,7 +23,10 @@ type ServiceNVME struct {
 	} `json:"state" yaml:"state"`
 
 	Config struct {
-		Enabled bool                `json:"enabled" yaml:"enabled"`
-		Targets []ServiceNVMETarget `json:"targets" yaml:"targets"`
+		Enabled               bool                `json:"enabled"                 yaml:"enabled"`
+		Targets               []ServiceNVMETarget `json:"targets"                 yaml:"targets"`
+		HostNQN               string              `json:"host_nqn"                yaml:"host_nqn"`
+		ReconnectDelay        int                 `json:"reconnect_delay"         yaml:"reconnect_delay"`
+		ControllerLossTimeout int                 `json:"controller_loss_timeout" yaml:"controller_loss_timeout"`
 	} `json:"config" yaml:"config"`
 }
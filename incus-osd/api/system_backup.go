@@ -0,0 +1,9 @@
+package api
+
+// SystemBackupPost is used to create an encrypted backup of the system state. If no target is
+// provided, the backup is returned directly, otherwise it's uploaded (HTTP PUT) to the target URL or
+// written to the target path.
+type SystemBackupPost struct {
+	Passphrase string `json:"passphrase" yaml:"passphrase"`
+	Target     string `json:"target"     yaml:"target"`
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"time"
+)
+
+// SystemDecommission is returned when requesting a decommission of the system. The token must be
+// passed back before it expires to confirm the operation.
+type SystemDecommission struct {
+	Token   string    `json:"token"   yaml:"token"`
+	Expires time.Time `json:"expires" yaml:"expires"`
+}
+
+// SystemDecommissionPost is used to request, then confirm, a decommission of the system.
+type SystemDecommissionPost struct {
+	Token string `json:"token" yaml:"token"`
+}
@@ -1,12 +1,74 @@
 package api
 
+import (
+	"time"
+)
+
 // SystemEncryption defines a struct to hold information about the system's encryption state.
 type SystemEncryption struct {
 	Config struct {
-		RecoveryKeys []string `json:"recovery_keys" yaml:"recovery_keys"`
+		RecoveryKeys []string              `json:"recovery_keys"  yaml:"recovery_keys"`
+		Tang         *SystemEncryptionTang `json:"tang,omitempty" yaml:"tang,omitempty"`
 	} `json:"config" yaml:"config"`
 
 	State struct {
-		RecoveryKeysRetrieved bool `json:"recovery_keys_retrieved" yaml:"recovery_keys_retrieved"`
+		RecoveryKeysRetrieved bool                          `json:"recovery_keys_retrieved" yaml:"recovery_keys_retrieved"`
+		Audit                 []SystemEncryptionAuditEntry  `json:"audit,omitempty"         yaml:"audit,omitempty"`
+		Reencryption          *SystemEncryptionReencryption `json:"reencryption,omitempty"  yaml:"reencryption,omitempty"`
 	} `json:"state" yaml:"state"`
 }
+
+// SystemEncryptionTang defines a network-bound unlock policy for the root volume. The volume can
+// be unlocked once Threshold of the Tang servers (plus the TPM, when IncludeTPM is set) are
+// reachable. The TPM-sealed key slot is always kept so the system remains manageable.
+type SystemEncryptionTang struct {
+	Servers    []SystemEncryptionTangServer `json:"servers"     yaml:"servers"`
+	Threshold  int                          `json:"threshold"   yaml:"threshold"`
+	IncludeTPM bool                         `json:"include_tpm" yaml:"include_tpm"`
+}
+
+// SystemEncryptionTangServer represents a Tang server, identified by the thumbprint of its signing key.
+type SystemEncryptionTangServer struct {
+	URL        string `json:"url"        yaml:"url"`
+	Thumbprint string `json:"thumbprint" yaml:"thumbprint"`
+}
+
+// SystemEncryptionKeySlot represents a LUKS key slot of an encrypted volume. Type is one of "tpm2",
+// "recovery", "password", "clevis" or "other".
+type SystemEncryptionKeySlot struct {
+	Volume string `json:"volume" yaml:"volume"`
+	Slot   int    `json:"slot"   yaml:"slot"`
+	Type   string `json:"type"   yaml:"type"`
+}
+
+// SystemEncryptionKeySlotPost is used to add a key slot to an encrypted volume. A recovery key is
+// generated for the "recovery" type, while the "password" type uses the provided passphrase.
+type SystemEncryptionKeySlotPost struct {
+	Volume     string `json:"volume"     yaml:"volume"`
+	Type       string `json:"type"       yaml:"type"`
+	Passphrase string `json:"passphrase" yaml:"passphrase"`
+}
+
+// SystemEncryptionReencryption reports the progress of the re-encryption of a volume with a new
+// volume key. TemporarySlot is the key slot used to authorize the operation, removed on completion.
+type SystemEncryptionReencryption struct {
+	Volume        string  `json:"volume"         yaml:"volume"`
+	Running       bool    `json:"running"        yaml:"running"`
+	Progress      float64 `json:"progress"       yaml:"progress"`
+	Error         string  `json:"error"          yaml:"error"`
+	TemporarySlot int     `json:"temporary_slot" yaml:"temporary_slot"`
+}
+
+// SystemEncryptionReencryptionPost is used to start the re-encryption of a volume.
+type SystemEncryptionReencryptionPost struct {
+	Volume string `json:"volume" yaml:"volume"`
+}
+
+// SystemEncryptionAuditEntry records a change to the encryption keys.
+type SystemEncryptionAuditEntry struct {
+	Time   time.Time `json:"time"   yaml:"time"`
+	Action string    `json:"action" yaml:"action"`
+	Volume string    `json:"volume" yaml:"volume"`
+	Slot   int       `json:"slot"   yaml:"slot"`
+	Type   string    `json:"type"   yaml:"type"`
+}
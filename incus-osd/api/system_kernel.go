@@ -0,0 +1,22 @@
+package api
+
+// SystemKernel defines a struct to hold the kernel command line configuration.
+type SystemKernel struct {
+	Config SystemKernelConfig `json:"config" yaml:"config"`
+
+	State struct {
+		// Configured parameters found on the running kernel's command line.
+		Active []string `json:"active" yaml:"active"`
+
+		// Whether the parameters changed since the system booted.
+		RebootRequired bool `json:"reboot_required" yaml:"reboot_required"`
+	} `json:"state" yaml:"state"`
+}
+
+// SystemKernelConfig holds the extra kernel command line parameters, such as "intel_iommu=on" or
+// "isolcpus=2-7". They're provided to the kernel through a systemd-stub command line addon, kept
+// across OS updates and taking effect on the next boot. With Secure Boot enforcing, the firmware
+// only loads the addon if it's trusted, see the active parameters for those in effect.
+type SystemKernelConfig struct {
+	Parameters []string `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
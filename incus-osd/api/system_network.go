@@ -4,10 +4,102 @@ package api
 type SystemNetwork struct {
 	Config *SystemNetworkConfig `json:"config" yaml:"config"`
 
-	State struct{} `json:"state" yaml:"state"`
+	State struct {
+		Interfaces map[string]SystemNetworkInterfaceState `json:"interfaces,omitempty" yaml:"interfaces,omitempty"`
+		Warning    string                                 `json:"warning"              yaml:"warning"`
+
+		DuplicateAddresses map[string][]string `json:"duplicate_addresses,omitempty" yaml:"duplicate_addresses,omitempty"`
+	} `json:"state" yaml:"state"`
+}
+
+// SystemNetworkInterfaceState holds the runtime state of a network interface.
+type SystemNetworkInterfaceState struct {
+	WakeOnLANSupported []string `json:"wake_on_lan_supported,omitempty" yaml:"wake_on_lan_supported,omitempty"`
+}
+
+// SystemNetworkDeviceState holds the operational state of a network device.
+type SystemNetworkDeviceState struct {
+	Description      string                       `json:"description"                 yaml:"description"`
+	AlternativeNames []string                     `json:"alternative_names,omitempty" yaml:"alternative_names,omitempty"`
+	Hwaddr           string                       `json:"hwaddr"                      yaml:"hwaddr"`
+	MTU              int                          `json:"mtu"                         yaml:"mtu"`
+	Carrier          bool                         `json:"carrier"                     yaml:"carrier"`
+	Speed            int                          `json:"speed"                       yaml:"speed"`
+	Duplex           string                       `json:"duplex"                      yaml:"duplex"`
+	OperationalState string                       `json:"operational_state"           yaml:"operational_state"`
+	SetupState       string                       `json:"setup_state"                 yaml:"setup_state"`
+	OnlineState      string                       `json:"online_state"                yaml:"online_state"`
+	Addresses        []SystemNetworkAddressState  `json:"addresses,omitempty"         yaml:"addresses,omitempty"`
+	Routes           []SystemNetworkRouteState    `json:"routes,omitempty"            yaml:"routes,omitempty"`
+	Nameservers      []string                     `json:"nameservers,omitempty"       yaml:"nameservers,omitempty"`
+	DHCPLease        *SystemNetworkDHCPLeaseState `json:"dhcp_lease,omitempty"        yaml:"dhcp_lease,omitempty"`
+}
+
+// SystemNetworkAddressState holds an address assigned to a network device. Lifetimes are in seconds, with -1 meaning forever.
+type SystemNetworkAddressState struct {
+	Address           string `json:"address"            yaml:"address"`
+	Scope             string `json:"scope"              yaml:"scope"`
+	ValidLifetime     int    `json:"valid_lifetime"     yaml:"valid_lifetime"`
+	PreferredLifetime int    `json:"preferred_lifetime" yaml:"preferred_lifetime"`
+}
+
+// SystemNetworkRouteState holds a route using a network device.
+type SystemNetworkRouteState struct {
+	To       string `json:"to"       yaml:"to"`
+	Via      string `json:"via"      yaml:"via"`
+	Metric   int    `json:"metric"   yaml:"metric"`
+	Protocol string `json:"protocol" yaml:"protocol"`
+}
+
+// SystemNetworkDHCPLeaseState holds the details of a DHCPv4 lease. The lifetime is in seconds.
+type SystemNetworkDHCPLeaseState struct {
+	Address  string `json:"address"  yaml:"address"`
+	Server   string `json:"server"   yaml:"server"`
+	Router   string `json:"router"   yaml:"router"`
+	Lifetime int    `json:"lifetime" yaml:"lifetime"`
+}
+
+// SystemNetworkMTUCheck holds the result of sending non-fragmented packets of the device's MTU toward a target.
+type SystemNetworkMTUCheck struct {
+	Target  string `json:"target"  yaml:"target"`
+	Device  string `json:"device"  yaml:"device"`
+	MTU     int    `json:"mtu"     yaml:"mtu"`
+	Success bool   `json:"success" yaml:"success"`
+	Error   string `json:"error"   yaml:"error"`
+}
+
+// SystemNetworkConfigError describes a problem with a single field of a network configuration.
+// Field is the path to the offending value, for example "interfaces[0].addresses[1]".
+type SystemNetworkConfigError struct {
+	Field   string `json:"field"   yaml:"field"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// SystemNetworkLLDPNeighbor holds information about a neighbor discovered using LLDP.
+type SystemNetworkLLDPNeighbor struct {
+	ChassisID         string `json:"chassis_id"         yaml:"chassis_id"`
+	PortID            string `json:"port_id"            yaml:"port_id"`
+	PortDescription   string `json:"port_description"   yaml:"port_description"`
+	SystemName        string `json:"system_name"        yaml:"system_name"`
+	SystemDescription string `json:"system_description" yaml:"system_description"`
+	VLAN              int    `json:"vlan"               yaml:"vlan"`
 }
 
 // SystemNetworkConfig represents the user modifiable network configuration.
+// RoutingTables maps routing table names to their number, allowing routes and rules to refer to them by name.
+//
+// IsolateUplinks makes replies leave through the uplink they arrived on, for hosts with multiple uplinks each
+// having their own default gateway. Each uplink gets a routing table, starting at 10000, holding its default
+// routes and used for traffic from its static addresses.
+//
+// When WatchdogTimeout is set, the previous configuration is restored if all uplinks stay offline for that
+// many seconds after the configuration is applied.
+//
+// DuplicateAddressDetection can be set to "warn" or "fail" to detect static addresses already in use on the
+// network segment, either reporting them in the network state or failing to apply the configuration.
+//
+// Secrets (keys and passwords) are returned as RedactedSecret. Sending back RedactedSecret, or
+// leaving a secret empty, keeps the current value.
 type SystemNetworkConfig struct {
 	DNS   *SystemNetworkDNS   `json:"dns"   yaml:"dns"`
 	NTP   *SystemNetworkNTP   `json:"ntp"   yaml:"ntp"`
@@ -16,69 +108,440 @@ type SystemNetworkConfig struct {
 	Interfaces []SystemNetworkInterface `json:"interfaces,omitempty" yaml:"interfaces,omitempty"`
 	Bonds      []SystemNetworkBond      `json:"bonds,omitempty"      yaml:"bonds,omitempty"`
 	VLANs      []SystemNetworkVLAN      `json:"vlans,omitempty"      yaml:"vlans,omitempty"`
+	VXLANs     []SystemNetworkVXLAN     `json:"vxlans,omitempty"     yaml:"vxlans,omitempty"`
+	Tunnels    []SystemNetworkTunnel    `json:"tunnels,omitempty"    yaml:"tunnels,omitempty"`
+	VRFs       []SystemNetworkVRF       `json:"vrfs,omitempty"       yaml:"vrfs,omitempty"`
+	PPPoE      []SystemNetworkPPPoE     `json:"pppoe,omitempty"      yaml:"pppoe,omitempty"`
+	Dummies    []SystemNetworkDummy     `json:"dummies,omitempty"    yaml:"dummies,omitempty"`
+
+	CLAT *SystemNetworkCLAT `json:"clat,omitempty" yaml:"clat,omitempty"`
+
+	Probes []SystemNetworkProbe `json:"probes,omitempty" yaml:"probes,omitempty"`
+
+	RoutingTables  map[string]int `json:"routing_tables,omitempty" yaml:"routing_tables,omitempty"`
+	IsolateUplinks bool           `json:"isolate_uplinks"          yaml:"isolate_uplinks"`
+
+	WatchdogTimeout           int    `json:"watchdog_timeout"            yaml:"watchdog_timeout"`
+	DuplicateAddressDetection string `json:"duplicate_address_detection" yaml:"duplicate_address_detection"`
 }
 
-// SystemNetworkInterface contains information about a network interface.
+// SystemNetworkInterface contains information about a network interface. By default a bridge
+// is created on top of the interface, Mode can be set to "macvlan" or "ipvlan" to instead
+// create a single device of that kind for the host, in which case vlans can't use it as parent.
+// Mode "direct" configures the interface itself, renamed to Name, without any device on top of it.
+// Setting KernelName skips the renaming of the interface, which is then matched by its existing name.
+// A Description that is also a valid interface name, such as "storage-A", is additionally set as an
+// alternative name of the interface and must then be unique.
 type SystemNetworkInterface struct {
-	Name      string               `json:"name"                yaml:"name"`
-	MTU       int                  `json:"mtu"                 yaml:"mtu"`
-	VLAN      int                  `json:"vlan"                yaml:"vlan"`
-	VLANTags  []int                `json:"vlan_tags,omitempty" yaml:"vlan_tags,omitempty"`
-	Addresses []string             `json:"addresses,omitempty" yaml:"addresses,omitempty"`
-	Routes    []SystemNetworkRoute `json:"routes,omitempty"    yaml:"routes,omitempty"`
-	Hwaddr    string               `json:"hwaddr"              yaml:"hwaddr"`
-	Roles     []string             `json:"roles,omitempty"     yaml:"roles,omitempty"`
-	LLDP      bool                 `json:"lldp"                yaml:"lldp"`
+	Name                  string                         `json:"name"                          yaml:"name"`
+	Description           string                         `json:"description"                   yaml:"description"`
+	Mode                  string                         `json:"mode"                          yaml:"mode"`
+	MTU                   int                            `json:"mtu"                           yaml:"mtu"`
+	VLAN                  int                            `json:"vlan"                          yaml:"vlan"`
+	VLANTags              []int                          `json:"vlan_tags,omitempty"           yaml:"vlan_tags,omitempty"`
+	Addresses             []string                       `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
+	DHCP                  *SystemNetworkDHCP             `json:"dhcp,omitempty"                yaml:"dhcp,omitempty"`
+	IPv6Token             string                         `json:"ipv6_token"                    yaml:"ipv6_token"`
+	AddressGenerationMode string                         `json:"address_generation_mode"       yaml:"address_generation_mode"`
+	DisableIPv6           bool                           `json:"disable_ipv6"                  yaml:"disable_ipv6"`
+	PrefixDelegation      *SystemNetworkPrefixDelegation `json:"prefix_delegation,omitempty"   yaml:"prefix_delegation,omitempty"`
+	DHCPServer            *SystemNetworkDHCPServer       `json:"dhcp_server,omitempty"         yaml:"dhcp_server,omitempty"`
+	MulticastDNS          *bool                          `json:"multicast_dns,omitempty"       yaml:"multicast_dns,omitempty"`
+	LLMNR                 *bool                          `json:"llmnr,omitempty"               yaml:"llmnr,omitempty"`
+	ProxyARP              bool                           `json:"proxy_arp"                     yaml:"proxy_arp"`
+	ProxyNDP              bool                           `json:"proxy_ndp"                     yaml:"proxy_ndp"`
+	ProxyNDPAddresses     []string                       `json:"proxy_ndp_addresses,omitempty" yaml:"proxy_ndp_addresses,omitempty"`
+	Online                *SystemNetworkOnline           `json:"online,omitempty"              yaml:"online,omitempty"`
+	Routes                []SystemNetworkRoute           `json:"routes,omitempty"              yaml:"routes,omitempty"`
+	Rules                 []SystemNetworkRule            `json:"rules,omitempty"               yaml:"rules,omitempty"`
+	Neighbors             []SystemNetworkNeighbor        `json:"neighbors,omitempty"           yaml:"neighbors,omitempty"`
+	Hwaddr                string                         `json:"hwaddr"                        yaml:"hwaddr"`
+	OverrideHwaddr        string                         `json:"override_hwaddr"               yaml:"override_hwaddr"`
+	KernelName            string                         `json:"kernel_name"                   yaml:"kernel_name"`
+	Match                 *SystemNetworkInterfaceMatch   `json:"match,omitempty"               yaml:"match,omitempty"`
+	Roles                 []string                       `json:"roles,omitempty"               yaml:"roles,omitempty"`
+	LLDP                  bool                           `json:"lldp"                          yaml:"lldp"`
+	WakeOnLAN             string                         `json:"wake_on_lan"                   yaml:"wake_on_lan"`
+	Tuning                *SystemNetworkInterfaceTuning  `json:"tuning,omitempty"              yaml:"tuning,omitempty"`
+	Bridge                *SystemNetworkBridge           `json:"bridge,omitempty"              yaml:"bridge,omitempty"`
+	SRIOV                 *SystemNetworkSRIOV            `json:"sriov,omitempty"               yaml:"sriov,omitempty"`
+	QoS                   *SystemNetworkQoS              `json:"qos,omitempty"                 yaml:"qos,omitempty"`
+	IEEE8021X             *SystemNetworkIEEE8021X        `json:"ieee8021x,omitempty"           yaml:"ieee8021x,omitempty"`
+}
+
+// RedactedSecret replaces the secrets in the network configuration returned by the API.
+const RedactedSecret = "[redacted]"
+
+// SystemNetworkIEEE8021X defines the 802.1X port authentication settings of an interface.
+// Certificates and keys are provided as PEM encoded strings.
+type SystemNetworkIEEE8021X struct {
+	EAPMethod         string `json:"eap_method"          yaml:"eap_method"`
+	Identity          string `json:"identity"            yaml:"identity"`
+	AnonymousIdentity string `json:"anonymous_identity"  yaml:"anonymous_identity"`
+	Password          string `json:"password"            yaml:"password"`
+	Phase2            string `json:"phase2"              yaml:"phase2"`
+	CACert            string `json:"ca_cert"             yaml:"ca_cert"`
+	ClientCert        string `json:"client_cert"         yaml:"client_cert"`
+	ClientKey         string `json:"client_key"          yaml:"client_key"`
+	ClientKeyPassword string `json:"client_key_password" yaml:"client_key_password"`
+}
+
+// SystemNetworkInterfaceMatch defines alternative selectors used to find an interface instead of
+// its permanent MAC address. The configured Hwaddr is still used to name the interface and its bridge.
+type SystemNetworkInterfaceMatch struct {
+	Path       string   `json:"path"                 yaml:"path"`
+	Driver     string   `json:"driver"               yaml:"driver"`
+	Properties []string `json:"properties,omitempty" yaml:"properties,omitempty"`
+}
+
+// SystemNetworkInterfaceTuning defines the offload, ring buffer and queue settings of an interface.
+// Unset values keep the driver defaults.
+type SystemNetworkInterfaceTuning struct {
+	GRO              *bool `json:"gro,omitempty"     yaml:"gro,omitempty"`
+	GSO              *bool `json:"gso,omitempty"     yaml:"gso,omitempty"`
+	TSO              *bool `json:"tso,omitempty"     yaml:"tso,omitempty"`
+	LRO              *bool `json:"lro,omitempty"     yaml:"lro,omitempty"`
+	RXRingSize       int   `json:"rx_ring_size"      yaml:"rx_ring_size"`
+	TXRingSize       int   `json:"tx_ring_size"      yaml:"tx_ring_size"`
+	RXChannels       int   `json:"rx_channels"       yaml:"rx_channels"`
+	TXChannels       int   `json:"tx_channels"       yaml:"tx_channels"`
+	CombinedChannels int   `json:"combined_channels" yaml:"combined_channels"`
+}
+
+// SystemNetworkBridge defines the spanning tree and multicast settings of the bridge created on top of
+// an interface or bond. Timespans use the systemd format ("15s", ...) and unset values keep the kernel defaults.
+type SystemNetworkBridge struct {
+	STP             *bool  `json:"stp,omitempty"      yaml:"stp,omitempty"`
+	ForwardDelaySec string `json:"forward_delay_sec"  yaml:"forward_delay_sec"`
+	HelloTimeSec    string `json:"hello_time_sec"     yaml:"hello_time_sec"`
+	MaxAgeSec       string `json:"max_age_sec"        yaml:"max_age_sec"`
+	Priority        *int   `json:"priority,omitempty" yaml:"priority,omitempty"`
+
+	MulticastSnooping    *bool `json:"multicast_snooping,omitempty" yaml:"multicast_snooping,omitempty"`
+	MulticastQuerier     *bool `json:"multicast_querier,omitempty"  yaml:"multicast_querier,omitempty"`
+	MulticastIGMPVersion int   `json:"multicast_igmp_version"       yaml:"multicast_igmp_version"`
+}
+
+// SystemNetworkQoS defines the egress queueing discipline of a device. Qdisc can be "cake" (default),
+// "fq_codel" or "tbf". Bandwidth limits the egress rate (e.g. "500M" or "10G") and is required by "tbf",
+// while "fq_codel" doesn't support it.
+type SystemNetworkQoS struct {
+	Qdisc     string `json:"qdisc"     yaml:"qdisc"`
+	Bandwidth string `json:"bandwidth" yaml:"bandwidth"`
+}
+
+// SystemNetworkSRIOV defines the SR-IOV virtual functions to create on an interface.
+type SystemNetworkSRIOV struct {
+	NumVFs int                    `json:"num_vfs"       yaml:"num_vfs"`
+	VFs    []SystemNetworkSRIOVVF `json:"vfs,omitempty" yaml:"vfs,omitempty"`
+}
+
+// SystemNetworkSRIOVVF defines the configuration of a single SR-IOV virtual function.
+type SystemNetworkSRIOVVF struct {
+	VF         int    `json:"vf"                    yaml:"vf"`
+	Hwaddr     string `json:"hwaddr"                yaml:"hwaddr"`
+	VLAN       int    `json:"vlan"                  yaml:"vlan"`
+	SpoofCheck *bool  `json:"spoof_check,omitempty" yaml:"spoof_check,omitempty"`
+	Trust      *bool  `json:"trust,omitempty"       yaml:"trust,omitempty"`
 }
 
 // SystemNetworkBond contains information about a network bond.
 type SystemNetworkBond struct {
-	Name      string               `json:"name"                yaml:"name"`
-	Mode      string               `json:"mode"                yaml:"mode"`
-	MTU       int                  `json:"mtu"                 yaml:"mtu"`
-	VLAN      int                  `json:"vlan"                yaml:"vlan"`
-	VLANTags  []int                `json:"vlan_tags,omitempty" yaml:"vlan_tags,omitempty"`
-	Addresses []string             `json:"addresses,omitempty" yaml:"addresses,omitempty"`
-	Routes    []SystemNetworkRoute `json:"routes,omitempty"    yaml:"routes,omitempty"`
-	Hwaddr    string               `json:"hwaddr"              yaml:"hwaddr"`
-	Members   []string             `json:"members,omitempty"   yaml:"members,omitempty"`
-	Roles     []string             `json:"roles,omitempty"     yaml:"roles,omitempty"`
-	LLDP      bool                 `json:"lldp"                yaml:"lldp"`
+	Name                  string                         `json:"name"                          yaml:"name"`
+	Description           string                         `json:"description"                   yaml:"description"`
+	Mode                  string                         `json:"mode"                          yaml:"mode"`
+	MTU                   int                            `json:"mtu"                           yaml:"mtu"`
+	VLAN                  int                            `json:"vlan"                          yaml:"vlan"`
+	VLANTags              []int                          `json:"vlan_tags,omitempty"           yaml:"vlan_tags,omitempty"`
+	Addresses             []string                       `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
+	DHCP                  *SystemNetworkDHCP             `json:"dhcp,omitempty"                yaml:"dhcp,omitempty"`
+	IPv6Token             string                         `json:"ipv6_token"                    yaml:"ipv6_token"`
+	AddressGenerationMode string                         `json:"address_generation_mode"       yaml:"address_generation_mode"`
+	DisableIPv6           bool                           `json:"disable_ipv6"                  yaml:"disable_ipv6"`
+	PrefixDelegation      *SystemNetworkPrefixDelegation `json:"prefix_delegation,omitempty"   yaml:"prefix_delegation,omitempty"`
+	DHCPServer            *SystemNetworkDHCPServer       `json:"dhcp_server,omitempty"         yaml:"dhcp_server,omitempty"`
+	MulticastDNS          *bool                          `json:"multicast_dns,omitempty"       yaml:"multicast_dns,omitempty"`
+	LLMNR                 *bool                          `json:"llmnr,omitempty"               yaml:"llmnr,omitempty"`
+	ProxyARP              bool                           `json:"proxy_arp"                     yaml:"proxy_arp"`
+	ProxyNDP              bool                           `json:"proxy_ndp"                     yaml:"proxy_ndp"`
+	ProxyNDPAddresses     []string                       `json:"proxy_ndp_addresses,omitempty" yaml:"proxy_ndp_addresses,omitempty"`
+	Online                *SystemNetworkOnline           `json:"online,omitempty"              yaml:"online,omitempty"`
+	Routes                []SystemNetworkRoute           `json:"routes,omitempty"              yaml:"routes,omitempty"`
+	Rules                 []SystemNetworkRule            `json:"rules,omitempty"               yaml:"rules,omitempty"`
+	Neighbors             []SystemNetworkNeighbor        `json:"neighbors,omitempty"           yaml:"neighbors,omitempty"`
+	Hwaddr                string                         `json:"hwaddr"                        yaml:"hwaddr"`
+	Members               []string                       `json:"members,omitempty"             yaml:"members,omitempty"`
+	Roles                 []string                       `json:"roles,omitempty"               yaml:"roles,omitempty"`
+	LLDP                  bool                           `json:"lldp"                          yaml:"lldp"`
+	Bridge                *SystemNetworkBridge           `json:"bridge,omitempty"              yaml:"bridge,omitempty"`
+	QoS                   *SystemNetworkQoS              `json:"qos,omitempty"                 yaml:"qos,omitempty"`
+
+	// Bond tuning options, timespans use the systemd format ("100ms", "1s", ...).
+	MIIMonitorSec      string   `json:"mii_monitor_sec"          yaml:"mii_monitor_sec"`
+	LACPTransmitRate   string   `json:"lacp_transmit_rate"       yaml:"lacp_transmit_rate"`
+	TransmitHashPolicy string   `json:"transmit_hash_policy"     yaml:"transmit_hash_policy"`
+	MinLinks           int      `json:"min_links"                yaml:"min_links"`
+	ARPIntervalSec     string   `json:"arp_interval_sec"         yaml:"arp_interval_sec"`
+	ARPIPTargets       []string `json:"arp_ip_targets,omitempty" yaml:"arp_ip_targets,omitempty"`
+	UpDelaySec         string   `json:"up_delay_sec"             yaml:"up_delay_sec"`
+	DownDelaySec       string   `json:"down_delay_sec"           yaml:"down_delay_sec"`
+
+	// Active-backup options, PrimaryMember is the MAC address of the preferred member.
+	PrimaryMember     string `json:"primary_member"       yaml:"primary_member"`
+	PrimaryReselect   string `json:"primary_reselect"     yaml:"primary_reselect"`
+	FailOverMACPolicy string `json:"fail_over_mac_policy" yaml:"fail_over_mac_policy"`
 }
 
-// SystemNetworkVLAN contains information about a network vlan.
+// SystemNetworkVLAN contains information about a network vlan. The parent may be an interface,
+// a bond or another vlan (QinQ), with Protocol selecting between "802.1q" (default) and "802.1ad".
 type SystemNetworkVLAN struct {
+	Name                  string                         `json:"name"                          yaml:"name"`
+	Description           string                         `json:"description"                   yaml:"description"`
+	Parent                string                         `json:"parent"                        yaml:"parent"`
+	ID                    int                            `json:"id"                            yaml:"id"`
+	Protocol              string                         `json:"protocol"                      yaml:"protocol"`
+	MTU                   int                            `json:"mtu"                           yaml:"mtu"`
+	Addresses             []string                       `json:"addresses,omitempty"           yaml:"addresses,omitempty"`
+	DHCP                  *SystemNetworkDHCP             `json:"dhcp,omitempty"                yaml:"dhcp,omitempty"`
+	IPv6Token             string                         `json:"ipv6_token"                    yaml:"ipv6_token"`
+	AddressGenerationMode string                         `json:"address_generation_mode"       yaml:"address_generation_mode"`
+	DisableIPv6           bool                           `json:"disable_ipv6"                  yaml:"disable_ipv6"`
+	PrefixDelegation      *SystemNetworkPrefixDelegation `json:"prefix_delegation,omitempty"   yaml:"prefix_delegation,omitempty"`
+	DHCPServer            *SystemNetworkDHCPServer       `json:"dhcp_server,omitempty"         yaml:"dhcp_server,omitempty"`
+	MulticastDNS          *bool                          `json:"multicast_dns,omitempty"       yaml:"multicast_dns,omitempty"`
+	LLMNR                 *bool                          `json:"llmnr,omitempty"               yaml:"llmnr,omitempty"`
+	ProxyARP              bool                           `json:"proxy_arp"                     yaml:"proxy_arp"`
+	ProxyNDP              bool                           `json:"proxy_ndp"                     yaml:"proxy_ndp"`
+	ProxyNDPAddresses     []string                       `json:"proxy_ndp_addresses,omitempty" yaml:"proxy_ndp_addresses,omitempty"`
+	Online                *SystemNetworkOnline           `json:"online,omitempty"              yaml:"online,omitempty"`
+	Routes                []SystemNetworkRoute           `json:"routes,omitempty"              yaml:"routes,omitempty"`
+	Rules                 []SystemNetworkRule            `json:"rules,omitempty"               yaml:"rules,omitempty"`
+	Neighbors             []SystemNetworkNeighbor        `json:"neighbors,omitempty"           yaml:"neighbors,omitempty"`
+	Roles                 []string                       `json:"roles,omitempty"               yaml:"roles,omitempty"`
+}
+
+// SystemNetworkVXLAN contains information about a VXLAN tunnel.
+type SystemNetworkVXLAN struct {
+	Name            string               `json:"name"                yaml:"name"`
+	Parent          string               `json:"parent"              yaml:"parent"`
+	VNI             int                  `json:"vni"                 yaml:"vni"`
+	Local           string               `json:"local"               yaml:"local"`
+	Remote          string               `json:"remote"              yaml:"remote"`
+	DestinationPort int                  `json:"destination_port"    yaml:"destination_port"`
+	TTL             int                  `json:"ttl"                 yaml:"ttl"`
+	MTU             int                  `json:"mtu"                 yaml:"mtu"`
+	Addresses       []string             `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	DHCP            *SystemNetworkDHCP   `json:"dhcp,omitempty"      yaml:"dhcp,omitempty"`
+	Routes          []SystemNetworkRoute `json:"routes,omitempty"    yaml:"routes,omitempty"`
+	Roles           []string             `json:"roles,omitempty"     yaml:"roles,omitempty"`
+}
+
+// SystemNetworkTunnel contains information about a network tunnel. Supported kinds are
+// "wireguard", "gre", "gretap" and "geneve". Layer 2 tunnels (gretap and geneve) can be
+// attached to one of the managed bridges instead of being addressed directly.
+type SystemNetworkTunnel struct {
+	Name            string                        `json:"name"                yaml:"name"`
+	Kind            string                        `json:"kind"                yaml:"kind"`
+	Parent          string                        `json:"parent"              yaml:"parent"`
+	Bridge          string                        `json:"bridge"              yaml:"bridge"`
+	Local           string                        `json:"local"               yaml:"local"`
+	Remote          string                        `json:"remote"              yaml:"remote"`
+	Key             string                        `json:"key"                 yaml:"key"`
+	VNI             int                           `json:"vni"                 yaml:"vni"`
+	DestinationPort int                           `json:"destination_port"    yaml:"destination_port"`
+	TTL             int                           `json:"ttl"                 yaml:"ttl"`
+	MTU             int                           `json:"mtu"                 yaml:"mtu"`
+	Addresses       []string                      `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	DHCP            *SystemNetworkDHCP            `json:"dhcp,omitempty"      yaml:"dhcp,omitempty"`
+	Routes          []SystemNetworkRoute          `json:"routes,omitempty"    yaml:"routes,omitempty"`
+	Roles           []string                      `json:"roles,omitempty"     yaml:"roles,omitempty"`
+	WireGuard       *SystemNetworkTunnelWireGuard `json:"wireguard,omitempty" yaml:"wireguard,omitempty"`
+}
+
+// SystemNetworkTunnelWireGuard contains the WireGuard specific tunnel configuration.
+// The private key is only persisted in the state file on the encrypted root volume.
+type SystemNetworkTunnelWireGuard struct {
+	PrivateKey string                             `json:"private_key"     yaml:"private_key"`
+	ListenPort int                                `json:"listen_port"     yaml:"listen_port"`
+	Peers      []SystemNetworkTunnelWireGuardPeer `json:"peers,omitempty" yaml:"peers,omitempty"`
+}
+
+// SystemNetworkTunnelWireGuardPeer defines a WireGuard peer.
+type SystemNetworkTunnelWireGuardPeer struct {
+	PublicKey           string   `json:"public_key"            yaml:"public_key"`
+	PresharedKey        string   `json:"preshared_key"         yaml:"preshared_key"`
+	Endpoint            string   `json:"endpoint"              yaml:"endpoint"`
+	AllowedIPs          []string `json:"allowed_ips,omitempty" yaml:"allowed_ips,omitempty"`
+	PersistentKeepalive int      `json:"persistent_keepalive"  yaml:"persistent_keepalive"`
+}
+
+// SystemNetworkVRF contains information about a VRF (Virtual Routing and Forwarding) domain.
+// Members are the names of the interfaces, bonds or vlans to enslave to the VRF.
+//
+// A single VRF may be flagged as the management VRF, in which case the outgoing update and provider
+// traffic goes through it, while local services remain reachable from it.
+type SystemNetworkVRF struct {
+	Name       string   `json:"name"              yaml:"name"`
+	Table      int      `json:"table"             yaml:"table"`
+	Members    []string `json:"members,omitempty" yaml:"members,omitempty"`
+	Management bool     `json:"management"        yaml:"management"`
+}
+
+// SystemNetworkDummy contains information about a dummy device, carrying static service addresses such as
+// anycast addresses or cluster VIPs. The device is only waited on when Online is set.
+type SystemNetworkDummy struct {
 	Name      string               `json:"name"                yaml:"name"`
-	Parent    string               `json:"parent"              yaml:"parent"`
-	ID        int                  `json:"id"                  yaml:"id"`
-	MTU       int                  `json:"mtu"                 yaml:"mtu"`
 	Addresses []string             `json:"addresses,omitempty" yaml:"addresses,omitempty"`
-	Routes    []SystemNetworkRoute `json:"routes,omitempty"    yaml:"routes,omitempty"`
+	Online    *SystemNetworkOnline `json:"online,omitempty"    yaml:"online,omitempty"`
 	Roles     []string             `json:"roles,omitempty"     yaml:"roles,omitempty"`
 }
 
-// SystemNetworkRoute defines a route.
+// SystemNetworkCLAT configures a 464XLAT customer-side translator (CLAT), providing IPv4 connectivity
+// over an IPv6-only uplink through the network's NAT64 gateway. The uplink Interface and the NAT64
+// PLATPrefix are detected automatically unless provided, the latter through DNS64 (RFC 7050).
+// DNS64Servers, if set, are used as the system's global nameservers. The translator stays inactive
+// while native IPv4 connectivity is available.
+type SystemNetworkCLAT struct {
+	Interface    string   `json:"interface"               yaml:"interface"`
+	PLATPrefix   string   `json:"plat_prefix"             yaml:"plat_prefix"`
+	DNS64Servers []string `json:"dns64_servers,omitempty" yaml:"dns64_servers,omitempty"`
+}
+
+// SystemNetworkPPPoE contains information about a PPPoE uplink established over the parent device.
+// The addresses, default route and nameservers are provided by the peer.
+type SystemNetworkPPPoE struct {
+	Name     string `json:"name"     yaml:"name"`
+	Parent   string `json:"parent"   yaml:"parent"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	MTU      int    `json:"mtu"      yaml:"mtu"`
+}
+
+// SystemNetworkDHCP defines the DHCP client options of a device. Unset values keep the systemd-networkd defaults.
+// RouteMetric sets the metric of the routes learned through DHCP, defaulting to 100, so the preferred default
+// route can be selected on hosts with multiple uplinks.
+type SystemNetworkDHCP struct {
+	SendHostname          *bool  `json:"send_hostname,omitempty"   yaml:"send_hostname,omitempty"`
+	UseHostname           *bool  `json:"use_hostname,omitempty"    yaml:"use_hostname,omitempty"`
+	VendorClassIdentifier string `json:"vendor_class_identifier"   yaml:"vendor_class_identifier"`
+	ClientIdentifier      string `json:"client_identifier"         yaml:"client_identifier"`
+	RequestOptions        []int  `json:"request_options,omitempty" yaml:"request_options,omitempty"`
+	UseDNS                *bool  `json:"use_dns,omitempty"         yaml:"use_dns,omitempty"`
+	UseNTP                *bool  `json:"use_ntp,omitempty"         yaml:"use_ntp,omitempty"`
+	UseRoutes             *bool  `json:"use_routes,omitempty"      yaml:"use_routes,omitempty"`
+	RouteMetric           int    `json:"route_metric"              yaml:"route_metric"`
+}
+
+// SystemNetworkOnline controls when a device is considered online. OperationalState is the minimum
+// operational state required (e.g. "degraded" or "routable") and AddressFamily the address family
+// required ("ipv4", "ipv6", "both" or "any"). When Ignore is set, the device isn't waited on at all.
+//
+// CarrierTimeout is an additional number of seconds to wait for the device, for example when the
+// switch port is delayed by spanning tree. IgnoreCarrierLoss keeps the device configuration through
+// carrier loss, either always ("true") or for a systemd timespan ("5s", ...).
+type SystemNetworkOnline struct {
+	Ignore            bool   `json:"ignore"              yaml:"ignore"`
+	OperationalState  string `json:"operational_state"   yaml:"operational_state"`
+	AddressFamily     string `json:"address_family"      yaml:"address_family"`
+	CarrierTimeout    int    `json:"carrier_timeout"     yaml:"carrier_timeout"`
+	IgnoreCarrierLoss string `json:"ignore_carrier_loss" yaml:"ignore_carrier_loss"`
+}
+
+// SystemNetworkPrefixDelegation configures a device to get an IPv6 subnet from a prefix delegated
+// through DHCPv6 on the uplink device, and to announce it using router advertisements.
+type SystemNetworkPrefixDelegation struct {
+	Uplink   string `json:"uplink"    yaml:"uplink"`
+	SubnetID string `json:"subnet_id" yaml:"subnet_id"`
+}
+
+// SystemNetworkDHCPServer configures a small DHCP server on a device, allowing a directly connected
+// system to reach the host for recovery. The device must have a static IPv4 address.
+type SystemNetworkDHCPServer struct {
+	PoolOffset int  `json:"pool_offset" yaml:"pool_offset"`
+	PoolSize   int  `json:"pool_size"   yaml:"pool_size"`
+	EmitRouter bool `json:"emit_router" yaml:"emit_router"`
+	SendRA     bool `json:"send_ra"     yaml:"send_ra"`
+}
+
+// SystemNetworkRoute defines a route. Type can be used to create "blackhole", "unreachable"
+// or "prohibit" routes, in which case Via should be left empty.
 type SystemNetworkRoute struct {
-	To  string `json:"to"  yaml:"to"`
-	Via string `json:"via" yaml:"via"`
+	To     string `json:"to"     yaml:"to"`
+	Via    string `json:"via"    yaml:"via"`
+	Metric int    `json:"metric" yaml:"metric"`
+	Table  string `json:"table"  yaml:"table"`
+	Scope  string `json:"scope"  yaml:"scope"`
+	Source string `json:"source" yaml:"source"`
+	OnLink bool   `json:"onlink" yaml:"onlink"`
+	MTU    int    `json:"mtu"    yaml:"mtu"`
+	Type   string `json:"type"   yaml:"type"`
+}
+
+// SystemNetworkRule defines a routing policy rule.
+type SystemNetworkRule struct {
+	From     string `json:"from"     yaml:"from"`
+	To       string `json:"to"       yaml:"to"`
+	Table    string `json:"table"    yaml:"table"`
+	Priority int    `json:"priority" yaml:"priority"`
+	FwMark   string `json:"fwmark"   yaml:"fwmark"`
 }
 
-// SystemNetworkDNS defines DNS configuration options.
+// SystemNetworkNeighbor defines a static neighbor (ARP/NDP) entry.
+type SystemNetworkNeighbor struct {
+	Address string `json:"address" yaml:"address"`
+	Hwaddr  string `json:"hwaddr"  yaml:"hwaddr"`
+}
+
+// SystemNetworkDNS defines DNS configuration options. DNSOverTLS and DNSSEC accept the
+// systemd-resolved values ("yes", "no", "opportunistic" or "allow-downgrade"), while
+// ServerNames maps a nameserver to the hostname used to validate its TLS certificate.
+// MulticastDNS and LLMNR can also be overridden for individual devices.
+//
+// When no Hostname is configured and UseDHCPHostname is set, the hostname provided by the DHCP
+// server is used. This can also be controlled for individual devices through their DHCP options.
 type SystemNetworkDNS struct {
-	Hostname      string   `json:"hostname"                 yaml:"hostname"`
-	Domain        string   `json:"domain"                   yaml:"domain"`
-	SearchDomains []string `json:"search_domains,omitempty" yaml:"search_domains,omitempty"`
-	Nameservers   []string `json:"nameservers,omitempty"    yaml:"nameservers,omitempty"`
+	Hostname        string            `json:"hostname"                 yaml:"hostname"`
+	Domain          string            `json:"domain"                   yaml:"domain"`
+	SearchDomains   []string          `json:"search_domains,omitempty" yaml:"search_domains,omitempty"`
+	Nameservers     []string          `json:"nameservers,omitempty"    yaml:"nameservers,omitempty"`
+	ServerNames     map[string]string `json:"server_names,omitempty"   yaml:"server_names,omitempty"`
+	DNSOverTLS      string            `json:"dns_over_tls"             yaml:"dns_over_tls"`
+	DNSSEC          string            `json:"dnssec"                   yaml:"dnssec"`
+	MulticastDNS    *bool             `json:"multicast_dns,omitempty"  yaml:"multicast_dns,omitempty"`
+	LLMNR           *bool             `json:"llmnr,omitempty"          yaml:"llmnr,omitempty"`
+	UseDHCPHostname bool              `json:"use_dhcp_hostname"        yaml:"use_dhcp_hostname"`
 }
 
-// SystemNetworkNTP defines static timeservers to use.
+// SystemNetworkNTP defines static timeservers to use. Backend selects between "timesyncd" (default)
+// and "chrony", the latter being required to authenticate the timeservers using NTS. The chrony
+// backend only uses the configured timeservers, systemd-timesyncd being masked while it's selected.
+//
+// When no Timeservers are configured, UseDHCP makes the timeservers provided by DHCP (option 42)
+// the only time source, rather than also falling back to the default public timeservers. This is
+// only supported by the "timesyncd" backend.
 type SystemNetworkNTP struct {
 	Timeservers []string `json:"timeservers,omitempty" yaml:"timeservers,omitempty"`
+	Backend     string   `json:"backend"               yaml:"backend"`
+	NTS         bool     `json:"nts"                   yaml:"nts"`
+	UseDHCP     bool     `json:"use_dhcp"              yaml:"use_dhcp"`
+}
+
+// SystemNetworkProbe defines a connectivity check which must succeed before the network is considered online.
+// Type can be "icmp" (Target is a host), "tcp" (Target is a host:port) or "https" (Target is a URL).
+type SystemNetworkProbe struct {
+	Type   string `json:"type"   yaml:"type"`
+	Target string `json:"target" yaml:"target"`
 }
 
 // SystemNetworkProxy defines proxy configuration.
+// NoProxy is a comma-separated list of hosts, domains or CIDR subnets to reach directly.
+// PACURL is exposed to applications through the auto_proxy environment variable.
+// If set, Username and Password are used to authenticate against the HTTP and HTTPS proxies.
 type SystemNetworkProxy struct {
 	HTTPProxy  string `json:"http_proxy"  yaml:"http_proxy"`
 	HTTPSProxy string `json:"https_proxy" yaml:"https_proxy"`
 	NoProxy    string `json:"no_proxy"    yaml:"no_proxy"`
+	PACURL     string `json:"pac_url"     yaml:"pac_url"`
+	Username   string `json:"username"    yaml:"username"`
+	Password   string `json:"password"    yaml:"password"`
 }
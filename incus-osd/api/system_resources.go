@@ -0,0 +1,29 @@
+package api
+
+// SystemResources defines a struct to hold the system's memory resources configuration.
+type SystemResources struct {
+	Config SystemResourcesConfig `json:"config" yaml:"config"`
+
+	State struct {
+		Swap []SystemResourcesSwapDevice `json:"swap,omitempty" yaml:"swap,omitempty"`
+	} `json:"state" yaml:"state"`
+}
+
+// SystemResourcesConfig holds the swap configuration. Sizes are in MiB, with zero disabling the
+// device. The swap zvol is created in the encrypted "local" ZFS pool. Swappiness is left to the
+// kernel default when not set.
+type SystemResourcesConfig struct {
+	ZramSize      int    `json:"zram_size"            yaml:"zram_size"`
+	ZramAlgorithm string `json:"zram_algorithm"       yaml:"zram_algorithm"`
+	SwapZvolSize  int    `json:"swap_zvol_size"       yaml:"swap_zvol_size"`
+	Swappiness    *int   `json:"swappiness,omitempty" yaml:"swappiness,omitempty"`
+}
+
+// SystemResourcesSwapDevice holds the state of an active swap device, with sizes in bytes.
+type SystemResourcesSwapDevice struct {
+	Device   string `json:"device"   yaml:"device"`
+	Type     string `json:"type"     yaml:"type"`
+	Size     int64  `json:"size"     yaml:"size"`
+	Used     int64  `json:"used"     yaml:"used"`
+	Priority int    `json:"priority" yaml:"priority"`
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"time"
+)
+
+// SystemSecurity defines a struct to hold information about the system's security configuration.
+type SystemSecurity struct {
+	Config struct {
+		Firewall SystemSecurityFirewall `json:"firewall" yaml:"firewall"`
+	} `json:"config" yaml:"config"`
+
+	State struct {
+		// Secure Boot key updates, from staging to activation.
+		SecureBootUpdates []SystemSecuritySecureBootUpdate `json:"secureboot_updates,omitempty" yaml:"secureboot_updates,omitempty"`
+	} `json:"state" yaml:"state"`
+}
+
+// SystemSecurityFirewall defines the host firewall configuration. When enabled, only the listed
+// sources may reach each of the restricted services, other traffic isn't affected.
+type SystemSecurityFirewall struct {
+	Enabled  bool                            `json:"enabled"            yaml:"enabled"`
+	Services []SystemSecurityFirewallService `json:"services,omitempty" yaml:"services,omitempty"`
+}
+
+// SystemSecurityFirewallService restricts the sources (addresses or subnets) allowed to reach a local service.
+// Name can be one of the well-known "incus" or "ssh" services, otherwise Protocol and Port must be set.
+type SystemSecurityFirewallService struct {
+	Name     string   `json:"name"              yaml:"name"`
+	Protocol string   `json:"protocol"          yaml:"protocol"`
+	Port     int      `json:"port"              yaml:"port"`
+	Sources  []string `json:"sources,omitempty" yaml:"sources,omitempty"`
+}
+
+// SystemSecuritySecureBoot reports the Secure Boot state, the certificates enrolled in the PK, KEK
+// and db variables and the key updates. The dbx revocations shipped with OS updates are applied
+// before installing them, as long as they don't revoke the boot loader or the OS images.
+//
+// Keys are rotated in two steps: updates appending the new certificates are enrolled first, letting
+// the system boot images signed with either the old or new keys. Once running an image signed with
+// the new keys, the updates replacing the variables, dropping the old certificates, are activated.
+type SystemSecuritySecureBoot struct {
+	Enabled   bool `json:"enabled"    yaml:"enabled"`
+	SetupMode bool `json:"setup_mode" yaml:"setup_mode"`
+
+	// Number of forbidden signatures (hashes and certificates) in dbx.
+	Revocations int `json:"revocations" yaml:"revocations"`
+
+	Keys    map[string][]SystemSecuritySecureBootCertificate `json:"keys"    yaml:"keys"`
+	Updates []SystemSecuritySecureBootUpdate                 `json:"updates" yaml:"updates"`
+}
+
+// SystemSecuritySecureBootCertificate describes a Secure Boot certificate.
+type SystemSecuritySecureBootCertificate struct {
+	Subject     string    `json:"subject"     yaml:"subject"`
+	Fingerprint string    `json:"fingerprint" yaml:"fingerprint"`
+	NotAfter    time.Time `json:"not_after"   yaml:"not_after"`
+}
+
+// SystemSecuritySecureBootUpdate is a signed update of a Secure Boot variable ("PK", "KEK" or "db"),
+// either appending to it or replacing it. Status is one of "staged", "enrolled" (append updates),
+// "activated" (replacement updates) or "failed".
+type SystemSecuritySecureBootUpdate struct {
+	ID           string                                `json:"id"              yaml:"id"`
+	Variable     string                                `json:"variable"        yaml:"variable"`
+	Append       bool                                  `json:"append"          yaml:"append"`
+	Certificates []SystemSecuritySecureBootCertificate `json:"certificates"    yaml:"certificates"`
+	Status       string                                `json:"status"          yaml:"status"`
+	Error        string                                `json:"error,omitempty" yaml:"error,omitempty"`
+	StagedAt     time.Time                             `json:"staged_at"       yaml:"staged_at"`
+}
+
+// SystemSecuritySecureBootUpdatePost is used to stage a Secure Boot variable update. Data is the
+// signed update (EFI_VARIABLE_AUTHENTICATION_2 followed by the signature lists), as produced by
+// "sign-efi-sig-list", signed by the current KEK (for db) or PK (for KEK and PK).
+type SystemSecuritySecureBootUpdatePost struct {
+	Variable string `json:"variable" yaml:"variable"`
+	Append   bool   `json:"append"   yaml:"append"`
+	Data     []byte `json:"data"     yaml:"data"`
+}
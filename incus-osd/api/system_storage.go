@@ -0,0 +1,179 @@
+package api
+
+import (
+	"time"
+)
+
+// SystemStorage defines a struct to hold information about the system's local storage.
+type SystemStorage struct {
+	Config SystemStorageConfig `json:"config" yaml:"config"`
+	State  SystemStorageState  `json:"state"  yaml:"state"`
+}
+
+// SystemStorageConfig holds the storage maintenance configuration.
+type SystemStorageConfig struct {
+	Scrub SystemStorageScrub `json:"scrub" yaml:"scrub"`
+	Trim  SystemStorageTrim  `json:"trim"  yaml:"trim"`
+}
+
+// SystemStorageScrub defines when the ZFS pools are periodically scrubbed. A scrub is started
+// every IntervalDays days (30 if not set), and only between WindowStart and WindowEnd ("HH:MM"
+// in local time) if a window is provided.
+type SystemStorageScrub struct {
+	Enabled      bool   `json:"enabled"       yaml:"enabled"`
+	IntervalDays int    `json:"interval_days" yaml:"interval_days"`
+	WindowStart  string `json:"window_start"  yaml:"window_start"`
+	WindowEnd    string `json:"window_end"    yaml:"window_end"`
+}
+
+// SystemStorageTrim defines when the ZFS pools and other filesystems are periodically trimmed.
+// A TRIM is started every IntervalDays days (7 if not set), and only between WindowStart and
+// WindowEnd ("HH:MM" in local time) if a window is provided. Rate optionally limits the ZFS TRIM
+// rate per device, in bytes per second with an optional K, M, G or T suffix.
+type SystemStorageTrim struct {
+	Enabled      bool   `json:"enabled"       yaml:"enabled"`
+	IntervalDays int    `json:"interval_days" yaml:"interval_days"`
+	WindowStart  string `json:"window_start"  yaml:"window_start"`
+	WindowEnd    string `json:"window_end"    yaml:"window_end"`
+	Rate         string `json:"rate"          yaml:"rate"`
+}
+
+// SystemStorageState holds the drives and ZFS pools available on the system.
+type SystemStorageState struct {
+	Drives []SystemStorageDrive `json:"drives,omitempty" yaml:"drives,omitempty"`
+	Pools  []SystemStoragePool  `json:"pools,omitempty"  yaml:"pools,omitempty"`
+
+	Scrubs map[string]SystemStorageScrubState `json:"scrubs,omitempty" yaml:"scrubs,omitempty"`
+	Trims  map[string]SystemStorageTrimState  `json:"trims,omitempty"  yaml:"trims,omitempty"`
+}
+
+// SystemStorageScrubState holds the outcome of the last scheduled scrub of a ZFS pool.
+type SystemStorageScrubState struct {
+	LastStart time.Time `json:"last_start" yaml:"last_start"`
+	Running   bool      `json:"running"    yaml:"running"`
+	Result    string    `json:"result"     yaml:"result"`
+}
+
+// SystemStorageTrimState holds the outcome of the last scheduled TRIM of a ZFS pool or of the
+// other filesystems (listed as "filesystems").
+type SystemStorageTrimState struct {
+	LastStart time.Time `json:"last_start" yaml:"last_start"`
+	Result    string    `json:"result"     yaml:"result"`
+}
+
+// SystemStorageDrive holds information about a block device.
+type SystemStorageDrive struct {
+	ID        string `json:"id"        yaml:"id"`
+	Device    string `json:"device"    yaml:"device"`
+	Model     string `json:"model"     yaml:"model"`
+	Serial    string `json:"serial"    yaml:"serial"`
+	Size      int64  `json:"size"      yaml:"size"`
+	Removable bool   `json:"removable" yaml:"removable"`
+	InUse     bool   `json:"in_use"    yaml:"in_use"`
+
+	// Firmware revision, bus ("sata", "sas", "nvme", "usb", ...) and whether the drive is a spinning disk.
+	Firmware   string `json:"firmware"   yaml:"firmware"`
+	Bus        string `json:"bus"        yaml:"bus"`
+	Rotational bool   `json:"rotational" yaml:"rotational"`
+
+	// Current temperature in degrees Celsius, as reported by SMART.
+	Temperature int `json:"temperature" yaml:"temperature"`
+
+	// ZFS pools using the drive or one of its partitions.
+	Pools []string `json:"pools,omitempty" yaml:"pools,omitempty"`
+
+	SMART *SystemStorageDriveSMART `json:"smart,omitempty" yaml:"smart,omitempty"`
+}
+
+// SystemStorageDriveSMART holds the SMART health information of a drive.
+type SystemStorageDriveSMART struct {
+	Enabled      bool  `json:"enabled"        yaml:"enabled"`
+	Passed       bool  `json:"passed"         yaml:"passed"`
+	Temperature  int   `json:"temperature"    yaml:"temperature"`
+	PowerOnHours int64 `json:"power_on_hours" yaml:"power_on_hours"`
+
+	// ATA SMART attributes.
+	Attributes []SystemStorageDriveSMARTAttribute `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+
+	// NVMe SMART/health information log.
+	NVMe *SystemStorageDriveSMARTNVMe `json:"nvme,omitempty" yaml:"nvme,omitempty"`
+
+	// Pre-failure conditions detected on the drive.
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// SystemStorageDriveSMARTAttribute holds a single ATA SMART attribute.
+type SystemStorageDriveSMARTAttribute struct {
+	ID         int    `json:"id"          yaml:"id"`
+	Name       string `json:"name"        yaml:"name"`
+	Value      int    `json:"value"       yaml:"value"`
+	Worst      int    `json:"worst"       yaml:"worst"`
+	Threshold  int    `json:"threshold"   yaml:"threshold"`
+	Raw        int64  `json:"raw"         yaml:"raw"`
+	PreFailure bool   `json:"pre_failure" yaml:"pre_failure"`
+	WhenFailed string `json:"when_failed" yaml:"when_failed"`
+}
+
+// SystemStorageDriveSMARTNVMe holds the NVMe SMART/health information log.
+type SystemStorageDriveSMARTNVMe struct {
+	CriticalWarning         int   `json:"critical_warning"          yaml:"critical_warning"`
+	AvailableSpare          int   `json:"available_spare"           yaml:"available_spare"`
+	AvailableSpareThreshold int   `json:"available_spare_threshold" yaml:"available_spare_threshold"`
+	PercentageUsed          int   `json:"percentage_used"           yaml:"percentage_used"`
+	MediaErrors             int64 `json:"media_errors"              yaml:"media_errors"`
+	UnsafeShutdowns         int64 `json:"unsafe_shutdowns"          yaml:"unsafe_shutdowns"`
+}
+
+// SystemStoragePool holds information about a ZFS pool.
+type SystemStoragePool struct {
+	Name     string   `json:"name"              yaml:"name"`
+	Topology string   `json:"topology"          yaml:"topology"`
+	Devices  []string `json:"devices,omitempty" yaml:"devices,omitempty"`
+	State    string   `json:"state"             yaml:"state"`
+
+	DeviceStates map[string]string `json:"device_states,omitempty" yaml:"device_states,omitempty"`
+
+	// Details reported by "zpool status" about the pool's health, the last or current
+	// scrub or resilver and any data errors.
+	Status string `json:"status" yaml:"status"`
+	Scan   string `json:"scan"   yaml:"scan"`
+	Errors string `json:"errors" yaml:"errors"`
+
+	// Usage, in bytes and percent.
+	Size          int64 `json:"size"          yaml:"size"`
+	Allocated     int64 `json:"allocated"     yaml:"allocated"`
+	Free          int64 `json:"free"          yaml:"free"`
+	Capacity      int   `json:"capacity"      yaml:"capacity"`
+	Fragmentation int   `json:"fragmentation" yaml:"fragmentation"`
+
+	Datasets []SystemStoragePoolDataset `json:"datasets,omitempty" yaml:"datasets,omitempty"`
+
+	// Base64 encoded encryption key, only returned when retrieving a single pool.
+	EncryptionKey string `json:"encryption_key,omitempty" yaml:"encryption_key,omitempty"`
+}
+
+// SystemStoragePoolDataset holds the usage of a ZFS dataset, in bytes.
+type SystemStoragePoolDataset struct {
+	Name       string `json:"name"       yaml:"name"`
+	Used       int64  `json:"used"       yaml:"used"`
+	Available  int64  `json:"available"  yaml:"available"`
+	Referenced int64  `json:"referenced" yaml:"referenced"`
+}
+
+// SystemStoragePoolPost is used to create or import an additional encrypted ZFS pool.
+type SystemStoragePoolPost struct {
+	Name     string   `json:"name"              yaml:"name"`
+	Topology string   `json:"topology"          yaml:"topology"`
+	Devices  []string `json:"devices,omitempty" yaml:"devices,omitempty"`
+
+	// Import an existing pool rather than creating a new one.
+	Import bool `json:"import" yaml:"import"`
+
+	// Base64 encoded encryption key of the pool to import.
+	EncryptionKey string `json:"encryption_key,omitempty" yaml:"encryption_key,omitempty"`
+}
+
+// SystemStorageDriveLocatePut is used to turn the locate (identify) LED of a drive on or off.
+type SystemStorageDriveLocatePut struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"time"
+)
+
+// SystemUpdate defines a struct to hold the OS and application update configuration.
+type SystemUpdate struct {
+	Config SystemUpdateConfig `json:"config" yaml:"config"`
+
+	State struct {
+		// Release which failed to boot, the system having rolled back to the running release.
+		FailedRelease string `json:"failed_release" yaml:"failed_release"`
+
+		// Incus evacuation performed ahead of the reboot into an OS update, until restored.
+		Evacuation *SystemUpdateEvacuation `json:"evacuation,omitempty" yaml:"evacuation,omitempty"`
+
+		// Newer OS release available or awaiting a reboot, if any.
+		PendingRelease *SystemUpdateRelease `json:"pending_release,omitempty" yaml:"pending_release,omitempty"`
+
+		// Progress of the update currently being applied, if any.
+		Progress *SystemUpdateProgress `json:"progress,omitempty" yaml:"progress,omitempty"`
+	} `json:"state" yaml:"state"`
+}
+
+// SystemUpdateEvacuation records how Incus was evacuated, either "cluster" for a cluster member
+// evacuation or "stop" with the "project/name" of the instances which were stopped.
+type SystemUpdateEvacuation struct {
+	Mode      string   `json:"mode"                yaml:"mode"`
+	Instances []string `json:"instances,omitempty" yaml:"instances,omitempty"`
+}
+
+// SystemUpdateRelease describes an OS release along with its published notes. Severity is one of
+// "low", "medium", "high" or "critical", empty if not published. RebootRequired tells whether
+// applying the release requires a reboot, which is always the case for OS images. Staged is set once
+// the release is installed, the system then only waiting for the reboot.
+type SystemUpdateRelease struct {
+	Version        string `json:"version"         yaml:"version"`
+	Severity       string `json:"severity"        yaml:"severity"`
+	Changelog      string `json:"changelog"       yaml:"changelog"`
+	RebootRequired bool   `json:"reboot_required" yaml:"reboot_required"`
+	Staged         bool   `json:"staged"          yaml:"staged"`
+}
+
+// SystemUpdateProgress reports the progress of an OS or application update. Phase is one of
+// "download", "verify" (when update signing certificates are configured) or "install".
+// ETA is the estimated number of seconds left in the phase, -1 if unknown.
+type SystemUpdateProgress struct {
+	Phase     string    `json:"phase"      yaml:"phase"`
+	Component string    `json:"component"  yaml:"component"`
+	Release   string    `json:"release"    yaml:"release"`
+	StartedAt time.Time `json:"started_at" yaml:"started_at"`
+
+	BytesDone  int64   `json:"bytes_done"  yaml:"bytes_done"`
+	BytesTotal int64   `json:"bytes_total" yaml:"bytes_total"`
+	Percentage float64 `json:"percentage"  yaml:"percentage"`
+	ETA        int     `json:"eta"         yaml:"eta"`
+}
+
+// SystemUpdateConfig holds the update configuration. Channel is one of "stable" (default),
+// "candidate" or "testing", allowing a subset of systems to receive releases ahead of the others.
+//
+// When maintenance windows are configured, periodic update checks only download and apply updates
+// during a window, and the system then reboots into the new release during a window.
+//
+// PinnedVersion restricts updates to that release, whatever the channel. Releases older than the
+// running one aren't installed. A hold blocks automatic updates until released, updates requested
+// through the API are still applied.
+//
+// DownloadRateLimit caps the bandwidth used to download updates, in bytes per second (0 for no limit).
+//
+// Evacuate moves the Incus instances away before rebooting into an OS update: a clustered server is
+// evacuated, a standalone one has its running instances cleanly stopped. They're restored once the
+// system is back up.
+//
+// RemovableMedia enables looking for an update bundle ("incus-os-update.tar" or "incus-os-update.tar.gz")
+// on inserted removable media, the update being applied once found. Bundles uploaded through the API are
+// always accepted.
+type SystemUpdateConfig struct {
+	Channel string `json:"channel" yaml:"channel"`
+
+	PinnedVersion string `json:"pinned_version" yaml:"pinned_version"`
+	Hold          bool   `json:"hold"           yaml:"hold"`
+	HoldReason    string `json:"hold_reason"    yaml:"hold_reason"`
+
+	DownloadRateLimit int64 `json:"download_rate_limit" yaml:"download_rate_limit"`
+	Evacuate          bool  `json:"evacuate"            yaml:"evacuate"`
+	RemovableMedia    bool  `json:"removable_media"     yaml:"removable_media"`
+
+	ClusterReboot SystemUpdateClusterReboot `json:"cluster_reboot" yaml:"cluster_reboot"`
+
+	PeerCache SystemUpdatePeerCache `json:"peer_cache" yaml:"peer_cache"`
+
+	Applications SystemUpdateApplications `json:"applications" yaml:"applications"`
+
+	Signing SystemUpdateSigning `json:"signing" yaml:"signing"`
+
+	Rollout SystemUpdateRollout `json:"rollout" yaml:"rollout"`
+
+	MaintenanceWindows []SystemUpdateMaintenanceWindow `json:"maintenance_windows,omitempty" yaml:"maintenance_windows,omitempty"`
+}
+
+// SystemUpdateMaintenanceWindow defines a period during which updates may be applied, between Start
+// and End ("HH:MM") on the listed days ("monday" to "sunday", every day if empty). Timezone is an
+// IANA time zone name, the system's time zone being used if not set.
+type SystemUpdateMaintenanceWindow struct {
+	Days     []string `json:"days,omitempty" yaml:"days,omitempty"`
+	Start    string   `json:"start"          yaml:"start"`
+	End      string   `json:"end"            yaml:"end"`
+	Timezone string   `json:"timezone"       yaml:"timezone"`
+}
+
+// SystemUpdateSigning lists additional PEM encoded certificates trusted to sign OS updates, for
+// systems running re-signed builds. When set, every OS update is verified before being installed,
+// against those certificates and, unless Exclusive, the ones enrolled in the Secure Boot db.
+//
+// The signing configuration can only be set through the seed.
+type SystemUpdateSigning struct {
+	Certificates []string `json:"certificates,omitempty" yaml:"certificates,omitempty"`
+	Exclusive    bool     `json:"exclusive"              yaml:"exclusive"`
+}
+
+// SystemUpdateClusterReboot serializes the reboots into OS updates across the members of the local
+// Incus cluster. When enabled, a member only reboots into an update once fewer than MaxConcurrent
+// (1 by default) other members are rebooting or not online, and clears its claim once back online,
+// letting the next one go. Reboots requested through the API aren't coordinated.
+type SystemUpdateClusterReboot struct {
+	Enabled       bool `json:"enabled"        yaml:"enabled"`
+	MaxConcurrent int  `json:"max_concurrent" yaml:"max_concurrent"`
+}
+
+// SystemUpdateRollout staggers the rollout of new releases across a fleet. Canary systems take a
+// release as soon as it's published, the others once it's been published for Delay (a duration such
+// as "48h", no delay if empty).
+//
+// Cohort is either "canary" or "main" to explicitly place the system, otherwise CanaryPercentage
+// percent of the systems are canaries, selected from their machine ID. Updates requested through the
+// API aren't delayed.
+type SystemUpdateRollout struct {
+	Cohort           string `json:"cohort"            yaml:"cohort"`
+	CanaryPercentage int    `json:"canary_percentage" yaml:"canary_percentage"`
+	Delay            string `json:"delay"             yaml:"delay"`
+}
+
+// SystemUpdateApplications configures the application updates. Unless Independent is set, the
+// applications are updated along with the OS.
+//
+// Independent applications are checked for updates every Frequency (a duration, "6h" by default),
+// from Channel (the OS channel if empty) and during their own MaintenanceWindows (any time if none),
+// letting application fixes land without waiting for an OS reboot window. Holds and pinned
+// releases apply to both.
+type SystemUpdateApplications struct {
+	Independent bool   `json:"independent" yaml:"independent"`
+	Channel     string `json:"channel"     yaml:"channel"`
+	Frequency   string `json:"frequency"   yaml:"frequency"`
+
+	MaintenanceWindows []SystemUpdateMaintenanceWindow `json:"maintenance_windows,omitempty" yaml:"maintenance_windows,omitempty"`
+}
+
+// SystemUpdatePeerCache configures the sharing of downloaded OS updates between the members of an
+// Incus cluster. When enabled, the system serves its downloaded OS images on Port (8444 by default),
+// over TLS using the Incus cluster certificate, and fetches OS updates from its peers before falling
+// back to the update provider. Files fetched from peers are only kept if the release signatures
+// check out against the Secure Boot db and update signing certificates. Applications are always
+// downloaded from the update provider.
+//
+// Peers lists the "host" or "host:port" addresses of the other members, those of the local Incus
+// cluster being used if empty.
+type SystemUpdatePeerCache struct {
+	Enabled bool     `json:"enabled"         yaml:"enabled"`
+	Port    int      `json:"port"            yaml:"port"`
+	Peers   []string `json:"peers,omitempty" yaml:"peers,omitempty"`
+}
+
+// SystemUpdateSlots reports the health of the A/B OS image slots, along with the boot entries and
+// the releases which can be rolled back to.
+type SystemUpdateSlots struct {
+	Slots           []SystemUpdateSlot      `json:"slots"            yaml:"slots"`
+	BootEntries     []SystemUpdateBootEntry `json:"boot_entries"     yaml:"boot_entries"`
+	RollbackTargets []string                `json:"rollback_targets" yaml:"rollback_targets"`
+}
+
+// SystemUpdateSlot describes one of the A/B partitions holding an OS image, Version being empty for
+// an unused slot. Active is set on the slot of the running release and NextBoot on the one of the
+// release booted by default on the next boot.
+type SystemUpdateSlot struct {
+	Name      string `json:"name"      yaml:"name"`
+	Partition string `json:"partition" yaml:"partition"`
+	Version   string `json:"version"   yaml:"version"`
+	Active    bool   `json:"active"    yaml:"active"`
+	NextBoot  bool   `json:"next_boot" yaml:"next_boot"`
+}
+
+// SystemUpdateBootEntry describes the boot entry of a release. Status is "good" once the release
+// booted successfully, "pending" while its boot attempts are being counted or "bad" once it ran out
+// of them, systemd-boot then falling back to another release.
+type SystemUpdateBootEntry struct {
+	Version   string `json:"version"    yaml:"version"`
+	Status    string `json:"status"     yaml:"status"`
+	TriesLeft int    `json:"tries_left" yaml:"tries_left"`
+	TriesDone int    `json:"tries_done" yaml:"tries_done"`
+}
+
+// SystemUpdateRollbackPost is used to roll back to the previous OS image.
+type SystemUpdateRollbackPost struct {
+	Reboot bool `json:"reboot" yaml:"reboot"`
+}
@@ -3,25 +3,37 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"golang.org/x/sys/unix"
 
 	"github.com/lxc/incus-os/incus-osd/internal/applications"
+	"github.com/lxc/incus-os/incus-osd/internal/backup"
+	"github.com/lxc/incus-os/incus-osd/internal/bundle"
+	"github.com/lxc/incus-os/incus-osd/internal/firewall"
 	"github.com/lxc/incus-os/incus-osd/internal/install"
 	"github.com/lxc/incus-os/incus-osd/internal/keyring"
+	"github.com/lxc/incus-os/incus-osd/internal/peercache"
 	"github.com/lxc/incus-os/incus-osd/internal/providers"
 	"github.com/lxc/incus-os/incus-osd/internal/rest"
+	"github.com/lxc/incus-os/incus-osd/internal/schedule"
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
 	"github.com/lxc/incus-os/incus-osd/internal/seed"
 	"github.com/lxc/incus-os/incus-osd/internal/services"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 	"github.com/lxc/incus-os/incus-osd/internal/tui"
 	"github.com/lxc/incus-os/incus-osd/internal/zfs"
@@ -130,6 +142,12 @@ func run(ctx context.Context, s *state.State, t *tui.TUI) error {
 	// Done with all initialization.
 	slog.Info("System is ready", "release", s.OS.RunningRelease)
 
+	// Allow the boot entry to be marked as good.
+	err = systemd.MarkBootReady()
+	if err != nil {
+		return err
+	}
+
 	return server.Serve(ctx)
 }
 
@@ -192,6 +210,20 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 
 	s.OS.RunningRelease = runningRelease
 
+	// Detect a rollback from an OS update which failed to boot.
+	if s.OS.NextRelease != "" && s.OS.NextRelease != runningRelease && systemd.IsReleaseBootFailed(s.OS.NextRelease) {
+		slog.Error("OS update failed to boot, rolled back to the previous release", "failed", s.OS.NextRelease, "release", runningRelease)
+
+		s.System.Update.State.FailedRelease = s.OS.NextRelease
+		s.OS.NextRelease = ""
+	}
+
+	// Forget about a pending release which is now running or failed to boot.
+	pending := s.System.Update.State.PendingRelease
+	if pending != nil && (pending.Version == runningRelease || pending.Version == s.System.Update.State.FailedRelease) {
+		s.System.Update.State.PendingRelease = nil
+	}
+
 	// Check kernel keyring.
 	slog.Debug("Getting trusted system keys")
 	keys, err := keyring.GetKeys(ctx, keyring.PlatformKeyring)
@@ -228,6 +260,9 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 
 	slog.Info("System is starting up", "mode", mode, "release", s.OS.RunningRelease)
 
+	// Applications get installed during the initial update check, so record whether this is the first start.
+	firstStart := len(s.Applications) == 0
+
 	// If there's no network configuration in the state, attempt to fetch from the seed info.
 	if s.System.Network.Config == nil {
 		s.System.Network.Config, err = seed.GetNetwork(ctx, seed.SeedPartitionPath)
@@ -236,9 +271,44 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		}
 	}
 
+	// Don't bring up a network configuration change which wasn't confirmed before the restart.
+	if s.System.NetworkUnconfirmed {
+		if s.System.NetworkLastKnownGood != nil {
+			slog.Warn("Network configuration change wasn't confirmed, restoring the last known good one")
+
+			s.System.Network.Config = s.System.NetworkLastKnownGood
+			s.System.Network.State.Warning = "Network configuration was reverted as it wasn't confirmed"
+		}
+
+		s.System.NetworkUnconfirmed = false
+	}
+
 	// Perform network configuration.
 	slog.Info("Bringing up the network")
 	err = systemd.ApplyNetworkConfiguration(ctx, s.System.Network.Config, 30*time.Second)
+	if err != nil {
+		// If enabled, fallback to the last known good network configuration.
+		lastKnownGood := s.System.NetworkLastKnownGood
+		if s.System.Network.Config == nil || s.System.Network.Config.WatchdogTimeout <= 0 || lastKnownGood == nil {
+			return err
+		}
+
+		slog.Warn("Failed to apply the network configuration, restoring the last known good one", "err", err)
+
+		s.System.Network.Config = lastKnownGood
+		s.System.Network.State.Warning = "Network configuration was reverted as it failed to apply"
+
+		err = systemd.ApplyNetworkConfiguration(ctx, s.System.Network.Config, 30*time.Second)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Watch for the uplinks to come online.
+	systemd.StartNetworkWatchdog(s, nil)
+
+	// Apply the host firewall rules.
+	err = firewall.ApplyFirewall(ctx, s.System.Security.Config.Firewall)
 	if err != nil {
 		return err
 	}
@@ -255,7 +325,39 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		return errors.New("currently unsupported operating mode")
 	}
 
-	p, err := providers.Load(ctx, provider, nil)
+	// On first start, select the update channel and maintenance windows from the seed.
+	if firstStart {
+		updateSeed, err := seed.GetUpdate(ctx, seed.SeedPartitionPath)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if updateSeed != nil {
+			s.System.Update.Config.Channel = updateSeed.Channel
+			s.System.Update.Config.DownloadRateLimit = max(updateSeed.DownloadRateLimit, 0)
+
+			if updateSeed.Signing != nil {
+				_, err := secureboot.ParseCertificates(updateSeed.Signing.Certificates)
+				if err != nil {
+					return fmt.Errorf("invalid update signing certificates: %w", err)
+				}
+
+				s.System.Update.Config.Signing = *updateSeed.Signing
+			}
+
+			if updateSeed.Rollout != nil {
+				err := schedule.ValidateRollout(*updateSeed.Rollout)
+				if err != nil {
+					return fmt.Errorf("invalid update rollout policy: %w", err)
+				}
+
+				s.System.Update.Config.Rollout = *updateSeed.Rollout
+			}
+			s.System.Update.Config.MaintenanceWindows = updateSeed.MaintenanceWindows
+		}
+	}
+
+	p, err := providers.Load(ctx, provider, providerConfig(s))
 	if err != nil {
 		return err
 	}
@@ -265,13 +367,93 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		updateChecker(ctx, s, t, p, true, false)
 	}
 
-	// Ensure  the "local" ZFS pool is available.
+	// Ensure  the "local" ZFS pool is available, using the layout selected at install time, if any.
 	slog.Info("Bringing up the local storage")
-	err = zfs.ImportOrCreateLocalPool(ctx)
+
+	localPool, err := seed.GetLocalPool(ctx, seed.SeedPartitionPath)
+	if err != nil && !seed.IsMissing(err) {
+		return err
+	}
+
+	if localPool == nil {
+		localPool = &seed.InstallSeedLocalPool{}
+	}
+
+	err = zfs.ImportOrCreateLocalPool(ctx, localPool.Topology, localPool.Devices)
 	if err != nil {
 		return err
 	}
 
+	// On first start, restore the system state from a backup if provided in the seed.
+	if firstStart {
+		restoreSeed, err := seed.GetRestore(ctx, seed.SeedPartitionPath)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if restoreSeed != nil {
+			slog.Info("Restoring the system state from backup", "source", restoreSeed.Source)
+
+			data, err := backup.Fetch(ctx, restoreSeed.Source)
+			if err != nil {
+				return err
+			}
+
+			err = backup.Restore(ctx, s, data, restoreSeed.Passphrase)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Resume any interrupted re-encryption of a system volume.
+	go systemd.ResumeReencryption(ctx, s)
+
+	// Grow the local storage if the boot disk was enlarged.
+	_, err = storage.GrowLocalData(ctx)
+	if err != nil {
+		slog.Error("Failed to grow local storage", "err", err.Error())
+	}
+
+	// Bring up any additional ZFS pool.
+	err = zfs.ImportPools(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Any kernel parameter change took effect with this boot.
+	s.System.Kernel.State.RebootRequired = false
+
+	// Apply the swap configuration.
+	err = systemd.ApplyResources(ctx, s.System.Resources.Config)
+	if err != nil {
+		slog.Error("Failed to apply resources configuration", "err", err.Error())
+	}
+
+	// On first start, apply any services configuration from the seed.
+	if firstStart {
+		srvSeed, err := seed.GetServices(ctx, seed.SeedPartitionPath)
+		if err != nil && !seed.IsMissing(err) {
+			return err
+		}
+
+		if srvSeed != nil && srvSeed.Ceph != nil {
+			s.Services.Ceph.Config = srvSeed.Ceph.Config
+		}
+
+		if srvSeed != nil && srvSeed.ISCSI != nil {
+			s.Services.ISCSI.Config = srvSeed.ISCSI.Config
+		}
+
+		if srvSeed != nil && srvSeed.Multipath != nil {
+			s.Services.Multipath.Config = srvSeed.Multipath.Config
+		}
+
+		if srvSeed != nil && srvSeed.NVME != nil {
+			s.Services.NVME.Config = srvSeed.NVME.Config
+		}
+	}
+
 	// Run services startup actions.
 	for _, srvName := range services.ValidNames {
 		srv, err := services.Load(ctx, s, srvName)
@@ -321,15 +503,40 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 		}
 	}
 
+	// Restore Incus after an evacuation ahead of the OS update, then let the next cluster member reboot.
+	_, hasIncus := s.Applications["incus"]
+	if s.System.Update.State.Evacuation != nil || hasIncus {
+		go func() {
+			if s.System.Update.State.Evacuation != nil && !restoreEvacuation(ctx, s) {
+				return
+			}
+
+			if hasIncus {
+				releaseClusterReboot(ctx)
+			}
+		}()
+	}
+
+	// Monitor the health of the drives.
+	go storage.MonitorSMART(ctx)
+
+	// Run scheduled storage maintenance.
+	go zfs.MaintenanceScheduler(ctx, s)
+
 	// Run periodic update checks if we have a working provider.
 	if p != nil {
 		go updateChecker(ctx, s, t, p, false, false)
+		go appUpdateChecker(ctx, s, t, p)
 	}
 
-	// Set up handler for shutdown tasks.
-	s.TriggerReboot = make(chan error, 1)
-	s.TriggerShutdown = make(chan error, 1)
-	s.TriggerUpdate = make(chan bool, 1)
+	// Share the downloaded updates with the other cluster members.
+	go peercache.Run(ctx, s)
+
+	// Watch for update bundles on removable media, after removing those left over from a previous run.
+	_ = os.RemoveAll(bundle.ExtractPath)
+
+	go bundle.WatchRemovableMedia(ctx, s)
+
 	chSignal := make(chan os.Signal, 1)
 	signal.Notify(chSignal, unix.SIGTERM)
 	go func() {
@@ -347,6 +554,26 @@ func startup(ctx context.Context, s *state.State, t *tui.TUI) error {
 			updateChecker(ctx, s, t, p, false, true)
 
 			goto waitSignal
+		case path := <-s.TriggerBundleUpdate:
+			// Apply the update from the extracted bundle through the local provider.
+			bundleConfig := providerConfig(s)
+			bundleConfig["path"] = path
+
+			bundleProvider, err := providers.Load(ctx, "local", bundleConfig)
+			if err != nil {
+				slog.Error("Failed to load update bundle", "err", err.Error())
+			} else {
+				updateChecker(ctx, s, t, bundleProvider, false, true)
+			}
+
+			_ = os.RemoveAll(path)
+
+			goto waitSignal
+		}
+
+		// Evacuate Incus before rebooting into an OS update.
+		if action == "reboot" {
+			evacuateForUpdate(ctx, s)
 		}
 
 		err := shutdown(ctx, s, t)
@@ -375,9 +602,48 @@ func updateChecker(ctx context.Context, s *state.State, t *tui.TUI, p providers.
 			t.DisplayModal("Incus OS Update", persistentModalMessage, 0, 0)
 		}
 
-		// Sleep at the top of each loop, except if we're performing a startup check.
+		// Sleep at the top of each loop, except if we're performing a startup check. With maintenance
+		// windows configured, wait for the next window.
 		if !isStartupCheck && !isUserRequested {
 			time.Sleep(6 * time.Hour)
+
+			for len(s.System.Update.Config.MaintenanceWindows) > 0 && !schedule.InWindow(s.System.Update.Config.MaintenanceWindows, time.Now()) {
+				time.Sleep(15 * time.Minute)
+			}
+		}
+
+		// Automatic updates are blocked while on hold, except for the initial application install.
+		if s.System.Update.Config.Hold && !isUserRequested && len(s.Applications) > 0 {
+			slog.Info("Automatic updates are on hold", "reason", s.System.Update.Config.HoldReason)
+
+			// Still report what the next OS release holds.
+			update, err := p.GetOSUpdate(ctx)
+			if err == nil {
+				recordPendingRelease(s, update)
+			}
+
+			if isStartupCheck {
+				break
+			}
+
+			continue
+		}
+
+		// Switch to the configured update channel, pinned release or download settings.
+		newProvider, err := reloadProvider(ctx, p, providerConfig(s))
+		if err != nil {
+			slog.Error("Failed to switch update channel", "err", err.Error())
+
+			if isStartupCheck || isUserRequested {
+				break
+			}
+
+			continue
+		}
+
+		if newProvider != p {
+			slog.Info("Switched update channel", "channel", newProvider.Channel(), "version", newProvider.PinnedVersion())
+			p = newProvider
 		}
 
 		// If user requested, clear cache.
@@ -424,7 +690,7 @@ func updateChecker(ctx context.Context, s *state.State, t *tui.TUI, p providers.
 		}
 
 		// Check for the latest OS update.
-		newInstalledOSVersion, err := checkDoOSUpdate(ctx, s, t, p, isStartupCheck)
+		newInstalledOSVersion, err := checkDoOSUpdate(ctx, s, t, p, isStartupCheck, isUserRequested)
 		if err != nil {
 			slog.Error("Failed to check for OS updates", "err", err.Error(), "provider", p.Type())
 			persistentModalMessage = "[red]Error[white] Failed to check for OS updates: " + err.Error() + " (provider: " + p.Type() + ")"
@@ -441,30 +707,25 @@ func updateChecker(ctx context.Context, s *state.State, t *tui.TUI, p providers.
 			t.DisplayModal("Incus OS Update", persistentModalMessage, 0, 0)
 		}
 
-		// Check for application updates.
-		appsUpdated := map[string]string{}
-		for _, appName := range toInstall {
-			newAppVersion, err := checkDoAppUpdate(ctx, s, t, p, appName, isStartupCheck)
+		// Check for application updates, unless they're checked on their own schedule.
+		if !s.System.Update.Config.Applications.Independent || isStartupCheck || isUserRequested {
+			appProvider, err := loadAppProvider(ctx, s, p)
 			if err != nil {
-				slog.Error("Failed to check for application updates", "err", err.Error(), "provider", p.Type())
-				persistentModalMessage = "[red]Error[white] Failed to check for application updates: " + err.Error() + " (provider: " + p.Type() + ")"
+				slog.Error("Failed to switch application update channel", "err", err.Error())
 
-				break
+				if isStartupCheck || isUserRequested {
+					break
+				}
+
+				continue
 			}
 
-			if newAppVersion != "" {
-				appsUpdated[appName] = newAppVersion
+			modalMessage, err := checkDoAppUpdates(ctx, s, t, appProvider, toInstall, isStartupCheck, isUserRequested)
+			if modalMessage != "" {
+				persistentModalMessage = modalMessage
 			}
-		}
 
-		// Apply the system extensions.
-		if len(appsUpdated) > 0 {
-			slog.Debug("Refreshing system extensions")
-			err = systemd.RefreshExtensions(ctx)
 			if err != nil {
-				slog.Error("Failed to refresh system extensions", "err", err.Error())
-				persistentModalMessage = "[red]Error[white] Failed to refresh system extensions: " + err.Error()
-
 				if isStartupCheck || isUserRequested {
 					break
 				}
@@ -473,34 +734,34 @@ func updateChecker(ctx context.Context, s *state.State, t *tui.TUI, p providers.
 			}
 		}
 
-		// Notify the applications that they need to update/restart.
-		for appName, appVersion := range appsUpdated {
-			// Get the application.
-			app, err := applications.Load(ctx, appName)
-			if err != nil {
-				slog.Error("Failed to load application", "err", err.Error())
-				persistentModalMessage = "[red]Error[white] Failed to load application: " + err.Error()
+		if isStartupCheck || isUserRequested {
+			// If running a one-time update, we're done.
+			break
+		}
 
+		// With maintenance windows configured, reboot into a pending OS update during the window.
+		windows := s.System.Update.Config.MaintenanceWindows
+		if len(windows) > 0 && s.OS.NextRelease != "" && s.OS.NextRelease != s.OS.RunningRelease && schedule.InWindow(windows, time.Now()) {
+			// Wait for our turn to reboot within the Incus cluster.
+			if !acquireClusterReboot(ctx, s) {
 				continue
 			}
 
-			// Reload the application.
-			if !isStartupCheck {
-				slog.Info("Reloading application", "name", appName, "version", appVersion)
+			if !schedule.InWindow(windows, time.Now()) {
+				slog.Info("Maintenance window closed while waiting to reboot into the OS update", "release", s.OS.NextRelease)
 
-				err = app.Update(ctx, appVersion)
+				err := applications.ReleaseClusterReboot(ctx)
 				if err != nil {
-					slog.Error("Failed to update application", "err", err.Error())
-					persistentModalMessage = "[red]Error[white] Failed to update application: " + err.Error()
-
-					continue
+					slog.Warn("Failed to release the cluster reboot slot", "err", err.Error())
 				}
+
+				continue
 			}
-		}
 
-		if isStartupCheck || isUserRequested {
-			// If running a one-time update, we're done.
-			break
+			slog.Info("Rebooting into the OS update during the maintenance window", "release", s.OS.NextRelease)
+			s.RequestReboot()
+
+			return
 		}
 	}
 
@@ -510,7 +771,164 @@ func updateChecker(ctx context.Context, s *state.State, t *tui.TUI, p providers.
 	}
 }
 
-func checkDoOSUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, isStartupCheck bool) (string, error) {
+// providerConfig returns the provider configuration for the update channel and pinned release.
+func providerConfig(s *state.State) map[string]string {
+	// The signing certificates are used to verify the files fetched from peers.
+	signing, _ := json.Marshal(s.System.Update.Config.Signing)
+
+	return map[string]string{
+		"channel":    s.System.Update.Config.Channel,
+		"version":    s.System.Update.Config.PinnedVersion,
+		"rate_limit": strconv.FormatInt(s.System.Update.Config.DownloadRateLimit, 10),
+
+		"peer_cache":      strconv.FormatBool(s.System.Update.Config.PeerCache.Enabled),
+		"peer_cache_port": strconv.Itoa(s.System.Update.Config.PeerCache.Port),
+		"peers":           strings.Join(s.System.Update.Config.PeerCache.Peers, ","),
+		"signing":         string(signing),
+	}
+}
+
+// appProviderConfig returns the provider configuration for the application updates.
+func appProviderConfig(s *state.State) map[string]string {
+	config := providerConfig(s)
+
+	if s.System.Update.Config.Applications.Independent && s.System.Update.Config.Applications.Channel != "" {
+		config["channel"] = s.System.Update.Config.Applications.Channel
+	}
+
+	return config
+}
+
+// loadAppProvider returns the provider for the application updates, the OS one if the
+// configuration matches.
+func loadAppProvider(ctx context.Context, s *state.State, p providers.Provider) (providers.Provider, error) {
+	return reloadProvider(ctx, p, appProviderConfig(s))
+}
+
+// reloadProvider returns a provider using the given settings, the current one if they're unchanged.
+// Settings specific to the provider, such as the path of an extracted update bundle, are kept.
+func reloadProvider(ctx context.Context, p providers.Provider, config map[string]string) (providers.Provider, error) {
+	newConfig := map[string]string{}
+	maps.Copy(newConfig, p.Config())
+	maps.Copy(newConfig, config)
+
+	if maps.Equal(p.Config(), newConfig) {
+		return p, nil
+	}
+
+	return providers.Load(ctx, p.Type(), newConfig)
+}
+
+// appUpdateChecker periodically checks for application updates on their own schedule, when
+// configured to be independent from the OS updates.
+func appUpdateChecker(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider) {
+	for {
+		time.Sleep(schedule.Frequency(s.System.Update.Config.Applications.Frequency, 6*time.Hour))
+
+		// Application updates are otherwise handled along with the OS ones.
+		if !s.System.Update.Config.Applications.Independent {
+			continue
+		}
+
+		for len(s.System.Update.Config.Applications.MaintenanceWindows) > 0 && !schedule.InWindow(s.System.Update.Config.Applications.MaintenanceWindows, time.Now()) {
+			time.Sleep(15 * time.Minute)
+		}
+
+		if s.System.Update.Config.Hold {
+			slog.Info("Automatic application updates are on hold", "reason", s.System.Update.Config.HoldReason)
+
+			continue
+		}
+
+		appProvider, err := loadAppProvider(ctx, s, p)
+		if err != nil {
+			slog.Error("Failed to switch application update channel", "err", err.Error())
+
+			continue
+		}
+
+		// Keep the provider around to benefit from its cache.
+		p = appProvider
+
+		toInstall := []string{}
+		for name := range s.Applications {
+			toInstall = append(toInstall, name)
+		}
+
+		modalMessage, _ := checkDoAppUpdates(ctx, s, t, appProvider, toInstall, false, false)
+		if modalMessage != "" {
+			t.DisplayModal("Incus OS Update", modalMessage, 0, 0)
+		}
+	}
+}
+
+// appUpdateLock serializes the application updates from the OS and application update checkers.
+var appUpdateLock sync.Mutex
+
+// checkDoAppUpdates updates the applications, refreshing the system extensions and notifying the
+// updated applications. Returns the message to display about any failure.
+func checkDoAppUpdates(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, toInstall []string, isStartupCheck bool, isUserRequested bool) (string, error) {
+	appUpdateLock.Lock()
+	defer appUpdateLock.Unlock()
+
+	persistentModalMessage := ""
+
+	appsUpdated := map[string]string{}
+	for _, appName := range toInstall {
+		newAppVersion, err := checkDoAppUpdate(ctx, s, t, p, appName, isStartupCheck, isUserRequested)
+		if err != nil {
+			slog.Error("Failed to check for application updates", "err", err.Error(), "provider", p.Type())
+			persistentModalMessage = "[red]Error[white] Failed to check for application updates: " + err.Error() + " (provider: " + p.Type() + ")"
+
+			break
+		}
+
+		if newAppVersion != "" {
+			appsUpdated[appName] = newAppVersion
+		}
+	}
+
+	// Apply the system extensions.
+	if len(appsUpdated) > 0 {
+		slog.Debug("Refreshing system extensions")
+
+		err := systemd.RefreshExtensions(ctx)
+		if err != nil {
+			slog.Error("Failed to refresh system extensions", "err", err.Error())
+
+			return "[red]Error[white] Failed to refresh system extensions: " + err.Error(), err
+		}
+	}
+
+	// Notify the applications that they need to update/restart.
+	for appName, appVersion := range appsUpdated {
+		// Get the application.
+		app, err := applications.Load(ctx, appName)
+		if err != nil {
+			slog.Error("Failed to load application", "err", err.Error())
+			persistentModalMessage = "[red]Error[white] Failed to load application: " + err.Error()
+
+			continue
+		}
+
+		// Reload the application.
+		if !isStartupCheck {
+			slog.Info("Reloading application", "name", appName, "version", appVersion)
+
+			err = app.Update(ctx, appVersion)
+			if err != nil {
+				slog.Error("Failed to update application", "err", err.Error())
+				persistentModalMessage = "[red]Error[white] Failed to update application: " + err.Error()
+
+				continue
+			}
+		}
+	}
+
+	return persistentModalMessage, nil
+}
+
+func checkDoOSUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, isStartupCheck bool, isUserRequested bool) (string, error) {
 	slog.Debug("Checking for OS updates")
 
 	update, err := p.GetOSUpdate(ctx)
@@ -524,16 +942,53 @@ func checkDoOSUpdate(ctx context.Context, s *state.State, t *tui.TUI, p provider
 		return "", err
 	}
 
+	// Don't retry a release which failed to boot.
+	if update.Version() == s.System.Update.State.FailedRelease {
+		slog.Warn("Skipping OS update which previously failed to boot", "release", update.Version())
+
+		return "", nil
+	}
+
 	// Apply the update.
 	if update.Version() != s.OS.RunningRelease && update.Version() != s.OS.NextRelease {
 		if !update.IsNewerThan(s.OS.RunningRelease) {
 			return "", errors.New("local Incus OS version (" + s.OS.RunningRelease + ") is newer than available update (" + update.Version() + "); skipping")
 		}
 
+		recordPendingRelease(s, update)
+
+		// Wait for the release to be rolled out to this system.
+		if !rolloutAllowed(s, update.PublishedAt(), isUserRequested) {
+			slog.Info("Delaying OS update per the rollout policy", "release", update.Version())
+
+			return "", nil
+		}
+
 		// Download the update into place.
 		slog.Info("Downloading OS update", "release", update.Version())
 		t.DisplayModal("Incus OS Update", "Downloading Incus OS update version "+update.Version(), 0, 0)
-		err := update.Download(ctx, systemd.SystemUpdatesPath)
+
+		defer func() { s.System.Update.State.Progress = nil }()
+
+		err := update.Download(ctx, systemd.SystemUpdatesPath, updateProgress(s, t, "os", update.Version(), "Downloading Incus OS update version "+update.Version()))
+		if err != nil {
+			return "", err
+		}
+
+		// Verify the update against the trusted signing certificates.
+		signing := s.System.Update.Config.Signing
+		if len(signing.Certificates) > 0 {
+			slog.Info("Verifying OS update", "release", update.Version())
+			setUpdatePhase(s, "verify", "os", update.Version())
+
+			err = secureboot.VerifyUpdate(ctx, systemd.SystemUpdatesPath, update.Version(), signing.Certificates, signing.Exclusive)
+			if err != nil {
+				return "", fmt.Errorf("failed to verify OS update: %w", err)
+			}
+		}
+
+		// Apply the Secure Boot revocations shipped with the update.
+		err = applyDBXUpdate(ctx, s, update.Version())
 		if err != nil {
 			return "", err
 		}
@@ -541,6 +996,8 @@ func checkDoOSUpdate(ctx context.Context, s *state.State, t *tui.TUI, p provider
 		// Apply the update and reboot if first time through loop, otherwise wait for user to reboot system.
 		slog.Info("Applying OS update", "release", update.Version())
 		t.DisplayModal("Incus OS Update", "Applying Incus OS update version "+update.Version(), 0, 0)
+		setUpdatePhase(s, "install", "os", update.Version())
+
 		err = systemd.ApplySystemUpdate(ctx, update.Version(), isStartupCheck)
 		if err != nil {
 			return "", err
@@ -548,6 +1005,7 @@ func checkDoOSUpdate(ctx context.Context, s *state.State, t *tui.TUI, p provider
 
 		// Record the release.
 		s.OS.NextRelease = update.Version()
+		s.System.Update.State.PendingRelease.Staged = true
 
 		t.RemoveModal()
 
@@ -559,7 +1017,7 @@ func checkDoOSUpdate(ctx context.Context, s *state.State, t *tui.TUI, p provider
 	return "", nil
 }
 
-func checkDoAppUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, appName string, isStartupCheck bool) (string, error) {
+func checkDoAppUpdate(ctx context.Context, s *state.State, t *tui.TUI, p providers.Provider, appName string, isStartupCheck bool, isUserRequested bool) (string, error) {
 	slog.Debug("Checking for application updates")
 
 	app, err := p.GetApplication(ctx, appName)
@@ -579,10 +1037,20 @@ func checkDoAppUpdate(ctx context.Context, s *state.State, t *tui.TUI, p provide
 			return "", errors.New("local application " + app.Name() + " version (" + s.Applications[app.Name()].Version + ") is newer than available update (" + app.Version() + "); skipping")
 		}
 
+		// Wait for the release to be rolled out to this system, unless installing the application.
+		if s.Applications[app.Name()].Version != "" && !rolloutAllowed(s, app.PublishedAt(), isUserRequested) {
+			slog.Info("Delaying application update per the rollout policy", "application", app.Name(), "release", app.Version())
+
+			return "", nil
+		}
+
 		// Download the application.
 		slog.Info("Downloading application", "application", app.Name(), "release", app.Version())
 		t.DisplayModal("Incus OS Update", "Downloading application "+app.Name()+" update "+app.Version(), 0, 0)
-		err = app.Download(ctx, systemd.SystemExtensionsPath)
+
+		defer func() { s.System.Update.State.Progress = nil }()
+
+		err = app.Download(ctx, systemd.SystemExtensionsPath, updateProgress(s, t, app.Name(), app.Version(), "Downloading application "+app.Name()+" update "+app.Version()))
 		if err != nil {
 			return "", err
 		}
@@ -603,3 +1071,213 @@ func checkDoAppUpdate(ctx context.Context, s *state.State, t *tui.TUI, p provide
 
 	return "", nil
 }
+
+// rolloutAllowed returns whether a release published at the provided time may be applied under the
+// rollout policy. Updates requested through the API and the initial install aren't delayed.
+func rolloutAllowed(s *state.State, published time.Time, isUserRequested bool) bool {
+	if isUserRequested || len(s.Applications) == 0 {
+		return true
+	}
+
+	machineID, err := os.ReadFile("/etc/machine-id")
+	if err != nil {
+		slog.Warn("Failed to get the machine ID", "err", err.Error())
+	}
+
+	return !schedule.RolloutTime(s.System.Update.Config.Rollout, strings.TrimSpace(string(machineID)), published).After(time.Now())
+}
+
+// recordPendingRelease records the newer OS release and its notes, which are kept until it's running.
+func recordPendingRelease(s *state.State, update providers.OSUpdate) {
+	if !update.IsNewerThan(s.OS.RunningRelease) || update.Version() == s.System.Update.State.FailedRelease {
+		return
+	}
+
+	notes := update.ReleaseNotes()
+
+	s.System.Update.State.PendingRelease = &api.SystemUpdateRelease{
+		Version:        update.Version(),
+		Severity:       notes.Severity,
+		Changelog:      notes.Changelog,
+		RebootRequired: true,
+		Staged:         update.Version() == s.OS.NextRelease,
+	}
+}
+
+// setUpdatePhase records the start of a new update phase.
+func setUpdatePhase(s *state.State, phase string, component string, release string) {
+	s.System.Update.State.Progress = &api.SystemUpdateProgress{
+		Phase:     phase,
+		Component: component,
+		Release:   release,
+		StartedAt: time.Now(),
+		ETA:       -1,
+	}
+}
+
+// updateProgress returns a function recording the download progress of an update into the state,
+// the console and the logs, the latter two being refreshed at most every few seconds.
+func updateProgress(s *state.State, t *tui.TUI, component string, release string, msg string) providers.ProgressFunc {
+	setUpdatePhase(s, "download", component, release)
+	progress := s.System.Update.State.Progress
+
+	lastReport := time.Time{}
+
+	return func(done int64, total int64) {
+		percentage, eta := providers.ProgressStats(done, total, time.Since(progress.StartedAt))
+
+		progress.BytesDone = done
+		progress.BytesTotal = total
+		progress.Percentage = percentage
+		progress.ETA = eta
+
+		if time.Since(lastReport) < 5*time.Second && done < total {
+			return
+		}
+
+		lastReport = time.Now()
+
+		slog.Debug("Downloading update", "component", component, "release", release, "done", done, "total", total, "eta", eta)
+		t.DisplayModal("Incus OS Update", msg, done, total)
+	}
+}
+
+// acquireClusterReboot waits for a slot to reboot within the Incus cluster, if configured to
+// coordinate reboots. Returns whether the system may reboot.
+func acquireClusterReboot(ctx context.Context, s *state.State) bool {
+	_, ok := s.Applications["incus"]
+	if !s.System.Update.Config.ClusterReboot.Enabled || !ok {
+		return true
+	}
+
+	err := applications.AcquireClusterReboot(ctx, s.System.Update.Config.ClusterReboot.MaxConcurrent)
+	if err != nil {
+		slog.Error("Failed to coordinate the reboot with the Incus cluster", "err", err.Error())
+
+		return false
+	}
+
+	return true
+}
+
+// evacuateForUpdate evacuates Incus, if configured to, when rebooting into an OS update.
+func evacuateForUpdate(ctx context.Context, s *state.State) {
+	if !s.System.Update.Config.Evacuate || s.OS.NextRelease == "" || s.OS.NextRelease == s.OS.RunningRelease {
+		return
+	}
+
+	_, ok := s.Applications["incus"]
+	if !ok {
+		return
+	}
+
+	slog.Info("Evacuating Incus ahead of the OS update", "release", s.OS.NextRelease)
+
+	evacuation, err := applications.EvacuateIncus(ctx)
+	if err != nil {
+		slog.Error("Failed to evacuate Incus", "err", err.Error())
+	}
+
+	if evacuation != nil {
+		s.System.Update.State.Evacuation = evacuation
+		_ = s.Save(ctx)
+	}
+}
+
+// restoreEvacuation restores Incus after an evacuation, retrying while Incus is starting up. Returns
+// whether it succeeded.
+func restoreEvacuation(ctx context.Context, s *state.State) bool {
+	for range 10 {
+		slog.Info("Restoring Incus after the OS update", "mode", s.System.Update.State.Evacuation.Mode)
+
+		err := applications.RestoreIncus(ctx, s.System.Update.State.Evacuation)
+		if err == nil {
+			s.System.Update.State.Evacuation = nil
+			_ = s.Save(ctx)
+
+			return true
+		}
+
+		slog.Warn("Failed to restore Incus", "err", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(30 * time.Second):
+		}
+	}
+
+	slog.Error("Giving up on restoring Incus, manual intervention is required")
+
+	return false
+}
+
+// releaseClusterReboot clears the cluster reboot claim once the member is back online, retrying while
+// Incus is starting up.
+func releaseClusterReboot(ctx context.Context) {
+	for range 10 {
+		err := applications.ReleaseClusterReboot(ctx)
+		if err == nil {
+			return
+		}
+
+		slog.Debug("Failed to release the cluster reboot slot", "err", err.Error())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
+
+	slog.Warn("Giving up on releasing the cluster reboot slot, it will expire on its own")
+}
+
+// applyDBXUpdate applies the dbx (forbidden signatures) update shipped with an OS update, if any.
+// Only an update revoking its own image is fatal, the dbx update being skipped on other failures.
+func applyDBXUpdate(ctx context.Context, s *state.State, version string) error {
+	dbxUpdate := filepath.Join(systemd.SystemUpdatesPath, "IncusOS_"+version+".dbx.auth")
+
+	_, err := os.Stat(dbxUpdate)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	newUKI := filepath.Join(systemd.SystemUpdatesPath, "IncusOS_"+version+".efi")
+
+	err = secureboot.CheckRevoked(ctx, dbxUpdate, []string{newUKI})
+	if errors.Is(err, secureboot.ErrRevoked) {
+		return fmt.Errorf("OS update revoked by its own dbx update: %w", err)
+	}
+
+	// Don't risk revoking the running release if its image can't be found.
+	_, err = systemd.BootEntry(s.OS.RunningRelease)
+	if err != nil {
+		slog.Error("Skipping dbx update", "err", err.Error())
+
+		return nil
+	}
+
+	// Protect every installed release, including those which may be rolled back to.
+	ukis, err := systemd.BootEntries()
+	if err != nil {
+		slog.Error("Skipping dbx update", "err", err.Error())
+
+		return nil
+	}
+
+	applied, err := secureboot.ApplyDBXUpdate(ctx, dbxUpdate, secureboot.BootComponents(append(ukis, newUKI)...))
+	if err != nil {
+		slog.Error("Skipping dbx update", "release", version, "err", err.Error())
+
+		return nil
+	}
+
+	if applied {
+		slog.Info("Applied dbx update", "release", version)
+	}
+
+	return nil
+}
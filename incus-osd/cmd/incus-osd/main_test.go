@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestReloadProviderKeepsBundlePath(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{}
+	s.System.Update.Config.Channel = "stable"
+
+	// Load the provider of an extracted update bundle, as done when one is uploaded.
+	bundleConfig := providerConfig(s)
+	bundleConfig["path"] = t.TempDir()
+
+	p, err := providers.Load(context.Background(), "local", bundleConfig)
+	require.NoError(t, err)
+
+	// An unchanged configuration keeps the provider.
+	newProvider, err := reloadProvider(context.Background(), p, providerConfig(s))
+	require.NoError(t, err)
+	require.Same(t, p, newProvider)
+
+	newProvider, err = loadAppProvider(context.Background(), s, p)
+	require.NoError(t, err)
+	require.Same(t, p, newProvider)
+
+	// A changed configuration still reads the update from the bundle.
+	s.System.Update.Config.Channel = "testing"
+
+	newProvider, err = reloadProvider(context.Background(), p, providerConfig(s))
+	require.NoError(t, err)
+	require.NotSame(t, p, newProvider)
+	require.Equal(t, "testing", newProvider.Channel())
+	require.Equal(t, bundleConfig["path"], newProvider.Config()["path"])
+}
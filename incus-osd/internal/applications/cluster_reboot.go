@@ -0,0 +1,209 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	incusclient "github.com/lxc/incus/v6/client"
+	incusapi "github.com/lxc/incus/v6/shared/api"
+)
+
+// rebootMarkerKey is the cluster member configuration key recording when the member claimed a slot
+// to reboot into an OS update.
+const rebootMarkerKey = "user.incus-os.reboot"
+
+// rebootMarkerExpiry is how long a claim is honored, so a member failing to come back doesn't block
+// the other members forever.
+const rebootMarkerExpiry = 2 * time.Hour
+
+// rebootRetryDelay is how long to wait before trying again to claim a reboot slot.
+const rebootRetryDelay = time.Minute
+
+// rebootSettleDelay lets the concurrent claims land before checking which ones win.
+const rebootSettleDelay = 10 * time.Second
+
+// AcquireClusterReboot waits for the local Incus cluster to be healthy enough for this member to
+// reboot, claiming one of the maxConcurrent reboot slots. A slot is taken by every other member which
+// isn't online, such as one being evacuated or rebooting, and by every recent claim. Concurrent claims
+// are ordered by time then member name, the later ones backing off. Returns immediately if Incus
+// isn't clustered.
+func AcquireClusterReboot(ctx context.Context, maxConcurrent int) error {
+	c, err := incusclient.ConnectIncusUnix("", nil)
+	if err != nil {
+		return err
+	}
+
+	server, _, err := c.GetServer()
+	if err != nil {
+		return err
+	}
+
+	if !server.Environment.ServerClustered {
+		return nil
+	}
+
+	self := server.Environment.ServerName
+
+	for {
+		members, err := c.GetClusterMembers()
+		if err != nil {
+			return err
+		}
+
+		if rebootSlotAvailable(members, self, maxConcurrent, time.Now(), false) {
+			err = setRebootMarker(c, self, time.Now().UTC().Format(time.RFC3339))
+			if err != nil {
+				return err
+			}
+
+			err = sleepContext(ctx, rebootSettleDelay)
+			if err != nil {
+				return errors.Join(err, setRebootMarker(c, self, ""))
+			}
+
+			members, err = c.GetClusterMembers()
+			if err != nil {
+				return errors.Join(err, setRebootMarker(c, self, ""))
+			}
+
+			if rebootSlotAvailable(members, self, maxConcurrent, time.Now(), true) {
+				return nil
+			}
+
+			// Another member won the slot.
+			err = setRebootMarker(c, self, "")
+			if err != nil {
+				return err
+			}
+		}
+
+		slog.Info("Waiting for the Incus cluster to be ready for the reboot", "member", self)
+
+		err = sleepContext(ctx, rebootRetryDelay)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ReleaseClusterReboot clears the reboot claim of this member once it's back online, letting the
+// next member reboot. Returns immediately if Incus isn't clustered or nothing was claimed.
+func ReleaseClusterReboot(_ context.Context) error {
+	c, err := incusclient.ConnectIncusUnix("", nil)
+	if err != nil {
+		return err
+	}
+
+	server, _, err := c.GetServer()
+	if err != nil {
+		return err
+	}
+
+	if !server.Environment.ServerClustered {
+		return nil
+	}
+
+	member, _, err := c.GetClusterMember(server.Environment.ServerName)
+	if err != nil {
+		return err
+	}
+
+	if member.Config[rebootMarkerKey] == "" {
+		return nil
+	}
+
+	if member.Status != "Online" {
+		return errors.New("cluster member " + member.ServerName + " isn't online yet (" + strings.ToLower(member.Status) + ")")
+	}
+
+	return setRebootMarker(c, member.ServerName, "")
+}
+
+// rebootSlotAvailable returns whether the member may reboot. Once claimed, the member's own claim
+// competes with the other ones, otherwise it's ignored, being left over from an earlier attempt.
+func rebootSlotAvailable(members []incusapi.ClusterMember, self string, maxConcurrent int, now time.Time, claimed bool) bool {
+	type claim struct {
+		name string
+		at   time.Time
+	}
+
+	busy := 0
+	claims := []claim{}
+
+	for _, member := range members {
+		at, err := time.Parse(time.RFC3339, member.Config[rebootMarkerKey])
+		hasClaim := err == nil && now.Sub(at) < rebootMarkerExpiry
+
+		if member.ServerName == self {
+			if claimed && hasClaim {
+				claims = append(claims, claim{name: self, at: at})
+			}
+
+			continue
+		}
+
+		if hasClaim {
+			claims = append(claims, claim{name: member.ServerName, at: at})
+		} else if member.Status != "Online" {
+			busy++
+		}
+	}
+
+	if !claimed {
+		return busy+len(claims) < max(maxConcurrent, 1)
+	}
+
+	// Rank the claims, earliest first.
+	slices.SortFunc(claims, func(a claim, b claim) int {
+		if !a.at.Equal(b.at) {
+			return a.at.Compare(b.at)
+		}
+
+		return strings.Compare(a.name, b.name)
+	})
+
+	rank := slices.IndexFunc(claims, func(c claim) bool { return c.name == self })
+	if rank < 0 {
+		return false
+	}
+
+	return busy+rank < max(maxConcurrent, 1)
+}
+
+// setRebootMarker sets, or clears if empty, the reboot claim of the cluster member.
+func setRebootMarker(c incusclient.InstanceServer, name string, value string) error {
+	member, etag, err := c.GetClusterMember(name)
+	if err != nil {
+		return err
+	}
+
+	put := member.Writable()
+	put.Config = maps.Clone(put.Config)
+
+	if put.Config == nil {
+		put.Config = map[string]string{}
+	}
+
+	if value == "" {
+		delete(put.Config, rebootMarkerKey)
+	} else {
+		put.Config[rebootMarkerKey] = value
+	}
+
+	return c.UpdateClusterMember(name, put, etag)
+}
+
+// sleepContext waits for the duration, returning early with an error if the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
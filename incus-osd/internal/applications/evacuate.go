@@ -0,0 +1,128 @@
+package applications
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	incusclient "github.com/lxc/incus/v6/client"
+	incusapi "github.com/lxc/incus/v6/shared/api"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// EvacuateIncus moves the instances away from the local Incus server ahead of a reboot. Cluster
+// members are evacuated, letting Incus live-migrate or restart the instances on other members,
+// while standalone servers have their running instances cleanly stopped. The returned evacuation
+// lists what needs restoring, including when an error is returned part way.
+func EvacuateIncus(_ context.Context) (*api.SystemUpdateEvacuation, error) {
+	c, err := incusclient.ConnectIncusUnix("", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	server, _, err := c.GetServer()
+	if err != nil {
+		return nil, err
+	}
+
+	// Evacuate the cluster member.
+	if server.Environment.ServerClustered {
+		op, err := c.UpdateClusterMemberState(server.Environment.ServerName, incusapi.ClusterMemberStatePost{Action: "evacuate"})
+		if err != nil {
+			return nil, err
+		}
+
+		evacuation := &api.SystemUpdateEvacuation{Mode: "cluster"}
+
+		return evacuation, op.Wait()
+	}
+
+	// Stop the running instances.
+	instances, err := c.GetInstancesAllProjects(incusapi.InstanceTypeAny)
+	if err != nil {
+		return nil, err
+	}
+
+	evacuation := &api.SystemUpdateEvacuation{Mode: "stop"}
+
+	for _, inst := range instances {
+		if inst.StatusCode != incusapi.Running {
+			continue
+		}
+
+		err = setInstanceState(c.UseProject(inst.Project), inst.Name, "stop")
+		if err != nil {
+			return evacuation, err
+		}
+
+		evacuation.Instances = append(evacuation.Instances, inst.Project+"/"+inst.Name)
+	}
+
+	return evacuation, nil
+}
+
+// RestoreIncus reverts an evacuation performed by EvacuateIncus.
+func RestoreIncus(_ context.Context, evacuation *api.SystemUpdateEvacuation) error {
+	c, err := incusclient.ConnectIncusUnix("", nil)
+	if err != nil {
+		return err
+	}
+
+	// Restore the cluster member.
+	if evacuation.Mode == "cluster" {
+		server, _, err := c.GetServer()
+		if err != nil {
+			return err
+		}
+
+		op, err := c.UpdateClusterMemberState(server.Environment.ServerName, incusapi.ClusterMemberStatePost{Action: "restore"})
+		if err != nil {
+			return err
+		}
+
+		return op.Wait()
+	}
+
+	// Start the stopped instances.
+	errs := []error{}
+
+	for _, instance := range evacuation.Instances {
+		project, name, ok := strings.Cut(instance, "/")
+		if !ok {
+			continue
+		}
+
+		// Skip instances which were already started, for example through autostart.
+		inst, _, err := c.UseProject(project).GetInstance(name)
+		if err != nil || inst.StatusCode == incusapi.Running {
+			continue
+		}
+
+		err = setInstanceState(c.UseProject(project), name, "start")
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// setInstanceState starts or cleanly stops an instance, forcing the stop after a timeout.
+func setInstanceState(c incusclient.InstanceServer, name string, action string) error {
+	op, err := c.UpdateInstanceState(name, incusapi.InstanceStatePut{Action: action, Timeout: 60}, "")
+	if err == nil {
+		err = op.Wait()
+	}
+
+	if err == nil || action != "stop" {
+		return err
+	}
+
+	op, err = c.UpdateInstanceState(name, incusapi.InstanceStatePut{Action: action, Force: true}, "")
+	if err != nil {
+		return err
+	}
+
+	return op.Wait()
+}
@@ -0,0 +1,375 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/internal/firewall"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+var (
+	// incusOSPath holds the daemon state and the ZFS pool keys.
+	incusOSPath = "/var/lib/incus-os"
+
+	// incusPath is the Incus data directory.
+	incusPath = "/var/lib/incus"
+)
+
+// incusFiles lists the Incus database and certificates, relative to incusPath.
+var incusFiles = []string{"database", "server.crt", "server.key", "cluster.crt", "cluster.key"}
+
+const stateFile = "state.json"
+
+// Create returns an encrypted archive of the system state, the keys of the ZFS pools and, if Incus
+// is stopped, the Incus database and certificates.
+func Create(ctx context.Context, s *state.State, passphrase string) ([]byte, error) {
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	body, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	err = addFile(tw, stateFile, body, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the ZFS pool keys.
+	entries, err := os.ReadDir(incusOSPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || entry.Name() == stateFile {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(incusOSPath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		err = addFile(tw, "incus-os/"+entry.Name(), content, 0o600)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The Incus database is only consistent while Incus is stopped.
+	if !systemd.IsActive(ctx, "incus.service") {
+		for _, name := range incusFiles {
+			err := addTree(tw, name)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = gz.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return encrypt(buf.Bytes(), passphrase)
+}
+
+// Restore restores an encrypted archive made by Create. The configuration which isn't tied to the
+// hardware is merged into the current state, the keys of the ZFS pools not already on this system are
+// put back in place and the Incus database and certificates, if present, are restored. The firewall
+// rules, applied before the restore at startup, are enforced again. Incus must be stopped.
+func Restore(ctx context.Context, s *state.State, data []byte, passphrase string) error {
+	data, err := decrypt(data, passphrase)
+	if err != nil {
+		return err
+	}
+
+	backupState, hasIncus, err := extract(ctx, data, incusOSPath)
+	if err != nil {
+		return err
+	}
+
+	mergeState(s, backupState, hasIncus)
+
+	// Enforce the restored firewall rules.
+	err = firewall.ApplyFirewall(ctx, s.System.Security.Config.Firewall)
+	if err != nil {
+		return err
+	}
+
+	// Bind the root volume to the Tang servers again.
+	if s.System.Encryption.Config.Tang != nil {
+		err := systemd.ApplyTang(ctx, s, s.System.Encryption.Config.Tang)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Save(ctx)
+}
+
+// extract extracts a decrypted backup archive, restoring the ZFS pool keys into osPath and the Incus
+// data. Returns the backed up state and whether the Incus data was restored.
+//
+// The keys of the pools of this system are never replaced, their pools being unusable otherwise:
+// the "local" pool is created anew on each system, and other pools already imported here keep
+// their key file.
+func extract(ctx context.Context, data []byte, osPath string) (*state.State, bool, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+
+	var backupState *state.State
+
+	hasIncus := false
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return nil, false, err
+		}
+
+		switch {
+		case hdr.Name == stateFile:
+			backupState = &state.State{}
+
+			err = json.NewDecoder(tr).Decode(backupState)
+			if err != nil {
+				return nil, false, err
+			}
+
+		case strings.HasPrefix(hdr.Name, "incus-os/"):
+			name := strings.TrimPrefix(hdr.Name, "incus-os/")
+			if !filepath.IsLocal(name) || name == stateFile {
+				return nil, false, fmt.Errorf("invalid backup entry %q", hdr.Name)
+			}
+
+			// Keep the keys of the pools of this system.
+			_, err = os.Stat(filepath.Join(osPath, name))
+			if name == "zpool.local.key" || (isPoolKeyfile(name) && err == nil) {
+				continue
+			}
+
+			err = writeFile(tr, filepath.Join(osPath, name), 0o600)
+			if err != nil {
+				return nil, false, err
+			}
+
+		case strings.HasPrefix(hdr.Name, "incus/"):
+			name := strings.TrimPrefix(hdr.Name, "incus/")
+			if !filepath.IsLocal(name) {
+				return nil, false, fmt.Errorf("invalid backup entry %q", hdr.Name)
+			}
+
+			if !hasIncus && systemd.IsActive(ctx, "incus.service") {
+				return nil, false, errors.New("incus must be stopped to restore its data")
+			}
+
+			hasIncus = true
+
+			if hdr.Typeflag == tar.TypeDir {
+				err = os.MkdirAll(filepath.Join(incusPath, name), hdr.FileInfo().Mode().Perm())
+			} else {
+				err = writeFile(tr, filepath.Join(incusPath, name), hdr.FileInfo().Mode().Perm())
+			}
+
+			if err != nil {
+				return nil, false, err
+			}
+		}
+	}
+
+	if backupState == nil {
+		return nil, false, errors.New("backup doesn't hold a system state")
+	}
+
+	return backupState, hasIncus, nil
+}
+
+// Fetch retrieves a backup from a HTTP(S) URL or a local file.
+func Fetch(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source) //nolint:gosec
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch backup: %s", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// Upload stores a backup to a HTTP(S) URL (using PUT) or to a local file.
+func Upload(ctx context.Context, target string, data []byte) error {
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		if !filepath.IsAbs(target) {
+			return fmt.Errorf("invalid backup target %q", target)
+		}
+
+		return os.WriteFile(target, data, 0o600)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("failed to upload backup: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// mergeState merges the configuration from a backup into the current state. The encryption keys,
+// OS release and network configuration belong to the current hardware and are kept, as are the
+// Secure Boot key updates whose files are staged locally. Applications are installed from the seed,
+// so are only marked as initialized when their data was restored.
+func mergeState(s *state.State, backup *state.State, hasIncus bool) {
+	s.Services = backup.Services
+	s.System.Security.Config = backup.System.Security.Config
+	s.System.Storage.Config = backup.System.Storage.Config
+	s.System.Resources.Config = backup.System.Resources.Config
+	s.System.Encryption.Config.Tang = backup.System.Encryption.Config.Tang
+
+	app, ok := s.Applications["incus"]
+	if hasIncus && ok {
+		app.Initialized = backup.Applications["incus"].Initialized
+		s.Applications["incus"] = app
+	}
+}
+
+// addFile adds a single file to the archive.
+func addFile(tw *tar.Writer, name string, content []byte, mode int64) error {
+	err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     mode,
+		Size:     int64(len(content)),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(content)
+
+	return err
+}
+
+// addTree adds a file or directory from the Incus data directory to the archive, if it exists.
+func addTree(tw *tar.Writer, name string) error {
+	return filepath.WalkDir(filepath.Join(incusPath, name), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+
+			return err
+		}
+
+		rel, err := filepath.Rel(incusPath, path)
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return tw.WriteHeader(&tar.Header{
+				Name:     "incus/" + rel + "/",
+				Mode:     int64(info.Mode().Perm()),
+				Typeflag: tar.TypeDir,
+			})
+		}
+
+		// Skip sockets and other special files.
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		content, err := os.ReadFile(path) //nolint:gosec
+		if err != nil {
+			return err
+		}
+
+		return addFile(tw, "incus/"+rel, content, int64(info.Mode().Perm()))
+	})
+}
+
+// writeFile writes a file from the archive, creating its parent directory if needed.
+func writeFile(r io.Reader, path string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = io.Copy(f, r) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// isPoolKeyfile returns whether a file is the encryption key of a ZFS pool.
+func isPoolKeyfile(name string) bool {
+	return strings.HasPrefix(name, "zpool.") && strings.HasSuffix(name, ".key")
+}
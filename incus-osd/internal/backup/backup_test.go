@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+
+	data, err := encrypt([]byte("hello"), "secret")
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "hello")
+
+	plain, err := decrypt(data, "secret")
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(plain))
+
+	_, err = decrypt(data, "wrong")
+	require.Error(t, err)
+
+	_, err = decrypt([]byte("garbage"), "secret")
+	require.Error(t, err)
+
+	_, err = encrypt([]byte("hello"), "")
+	require.Error(t, err)
+}
+
+func TestMergeState(t *testing.T) {
+	t.Parallel()
+
+	s := &state.State{Applications: map[string]state.Application{"incus": {Version: "2"}}}
+	s.System.Encryption.Config.RecoveryKeys = []string{"current"}
+	s.OS.RunningRelease = "2"
+
+	backup := &state.State{Applications: map[string]state.Application{"incus": {Version: "1", Initialized: true}}}
+	backup.System.Encryption.Config.RecoveryKeys = []string{"old"}
+	backup.System.Encryption.Config.Tang = &api.SystemEncryptionTang{Threshold: 1}
+	backup.System.Security.Config.Firewall.Enabled = true
+	backup.System.Security.State.SecureBootUpdates = []api.SystemSecuritySecureBootUpdate{{ID: "old"}}
+	backup.OS.RunningRelease = "1"
+
+	mergeState(s, backup, false)
+	require.Equal(t, []string{"current"}, s.System.Encryption.Config.RecoveryKeys)
+	require.Equal(t, "2", s.OS.RunningRelease)
+	require.NotNil(t, s.System.Encryption.Config.Tang)
+	require.True(t, s.System.Security.Config.Firewall.Enabled)
+	require.Empty(t, s.System.Security.State.SecureBootUpdates)
+	require.False(t, s.Applications["incus"].Initialized)
+
+	mergeState(s, backup, true)
+	require.True(t, s.Applications["incus"].Initialized)
+	require.Equal(t, "2", s.Applications["incus"].Version)
+}
+
+func TestExtractKeepsPoolKeys(t *testing.T) {
+	t.Parallel()
+
+	// A host which already created its "local" pool and imported the "tank" one.
+	osPath := t.TempDir()
+
+	for name, content := range map[string]string{"zpool.local.key": "new local", "zpool.tank.key": "new tank"} {
+		err := os.WriteFile(filepath.Join(osPath, name), []byte(content), 0o600)
+		require.NoError(t, err)
+	}
+
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range map[string]string{
+		stateFile:                  "{}",
+		"incus-os/zpool.local.key": "old local",
+		"incus-os/zpool.tank.key":  "old tank",
+		"incus-os/zpool.data.key":  "old data",
+	} {
+		require.NoError(t, addFile(tw, name, []byte(content), 0o600))
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	backupState, hasIncus, err := extract(context.Background(), buf.Bytes(), osPath)
+	require.NoError(t, err)
+	require.NotNil(t, backupState)
+	require.False(t, hasIncus)
+
+	for name, content := range map[string]string{"zpool.local.key": "new local", "zpool.tank.key": "new tank", "zpool.data.key": "old data"} {
+		data, err := os.ReadFile(filepath.Join(osPath, name))
+		require.NoError(t, err)
+		require.Equal(t, content, string(data), name)
+	}
+}
@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// magic identifies an encrypted backup and its format version.
+var magic = []byte("INCUSOS-BACKUP1\n")
+
+const saltSize = 16
+
+// deriveKey derives the AES-256 key from the passphrase.
+func deriveKey(passphrase string, salt []byte) []byte {
+	return argon2.IDKey([]byte(passphrase), salt, 3, 64*1024, 4, 32)
+}
+
+// encrypt seals the data with a key derived from the passphrase. The output holds the magic, the
+// salt, the nonce, then the encrypted data.
+func encrypt(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("a passphrase is required")
+	}
+
+	salt := make([]byte, saltSize)
+
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := append([]byte{}, magic...)
+	ret = append(ret, salt...)
+	ret = append(ret, nonce...)
+
+	return gcm.Seal(ret, nonce, data, magic), nil
+}
+
+// decrypt opens data sealed by encrypt.
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, errors.New("not a system backup")
+	}
+
+	data = data[len(magic):]
+	if len(data) < saltSize {
+		return nil, errors.New("truncated backup")
+	}
+
+	gcm, err := newGCM(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("truncated backup")
+	}
+
+	ret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], magic)
+	if err != nil {
+		return nil, errors.New("failed to decrypt backup, invalid passphrase or corrupted data")
+	}
+
+	return ret, nil
+}
+
+// newGCM returns the AES-GCM cipher for the passphrase and salt.
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(deriveKey(passphrase, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
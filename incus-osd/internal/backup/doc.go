@@ -0,0 +1,3 @@
+// Package backup creates and restores encrypted archives of the system state, allowing a system
+// to be recovered onto new hardware.
+package backup
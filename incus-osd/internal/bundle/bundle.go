@@ -0,0 +1,136 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractPath is where update bundles are extracted to, each in its own directory, for use by the local provider.
+var ExtractPath = "/var/cache/incus-os/bundle"
+
+// NewExtractPath returns a new empty directory to extract an update bundle into, so extracting a
+// bundle never affects another one an update is being applied from.
+func NewExtractPath() (string, error) {
+	err := os.MkdirAll(ExtractPath, 0o700)
+	if err != nil {
+		return "", err
+	}
+
+	return os.MkdirTemp(ExtractPath, "bundle-")
+}
+
+// Extract extracts an update bundle, a tarball (optionally gzip compressed) holding a "RELEASE" file
+// with the release version and an optional "CHANGELOG" along with the OS update files and application
+// images, in the layout used by the local provider. Returns the release version.
+func Extract(r io.Reader, target string) (string, error) {
+	err := os.RemoveAll(target)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(target, 0o700)
+	if err != nil {
+		return "", err
+	}
+
+	br := bufio.NewReader(r)
+
+	// Detect gzip compression.
+	var reader io.Reader = br
+
+	magic, err := br.Peek(2)
+	if err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return "", err
+		}
+
+		defer gz.Close()
+
+		reader = gz
+	}
+
+	tr := tar.NewReader(reader)
+
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return "", err
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		name := strings.TrimPrefix(hdr.Name, "./")
+		if !isBundleFile(name) || hdr.Typeflag != tar.TypeReg {
+			return "", fmt.Errorf("unexpected bundle entry %q", hdr.Name)
+		}
+
+		err = writeFile(tr, filepath.Join(target, name))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return ReadVersion(target)
+}
+
+// ReadVersion returns the release version of an extracted update bundle.
+func ReadVersion(path string) (string, error) {
+	body, err := os.ReadFile(filepath.Join(path, "RELEASE")) //nolint:gosec
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errors.New("update bundle doesn't have a RELEASE file")
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(string(body)), nil
+}
+
+// isBundleFile returns whether the name is valid for a file of an update bundle. Bundles are flat.
+func isBundleFile(name string) bool {
+	if name == "" || strings.Contains(name, "/") || !filepath.IsLocal(name) {
+		return false
+	}
+
+	return name == "RELEASE" || name == "CHANGELOG" || strings.HasPrefix(name, "IncusOS_") || strings.HasSuffix(name, ".raw")
+}
+
+// writeFile writes a file from the bundle.
+func writeFile(r io.Reader, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	// Copy in chunks to avoid excessive memory consumption.
+	for {
+		_, err := io.CopyN(f, r, 4*1024*1024)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	return f.Close()
+}
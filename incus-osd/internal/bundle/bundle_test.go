@@ -0,0 +1,117 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func makeBundle(t *testing.T, files map[string]string, compress bool) []byte {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+
+	var tw *tar.Writer
+
+	var gz *gzip.Writer
+
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+
+	if gz != nil {
+		require.NoError(t, gz.Close())
+	}
+
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	files := map[string]string{
+		"RELEASE":                      "202506011200\n",
+		"IncusOS_202506011200.efi":     "efi",
+		"./incus.raw":                  "incus",
+		"IncusOS_202506011200.usr.raw": "usr",
+	}
+
+	for _, compress := range []bool{false, true} {
+		target := filepath.Join(t.TempDir(), "bundle")
+
+		version, err := Extract(bytes.NewReader(makeBundle(t, files, compress)), target)
+		require.NoError(t, err)
+		require.Equal(t, "202506011200", version)
+
+		content, err := os.ReadFile(filepath.Join(target, "incus.raw"))
+		require.NoError(t, err)
+		require.Equal(t, "incus", string(content))
+	}
+}
+
+func TestNewExtractPath(t *testing.T) { //nolint:paralleltest
+	ExtractPath = filepath.Join(t.TempDir(), "bundle")
+
+	first, err := NewExtractPath()
+	require.NoError(t, err)
+
+	second, err := NewExtractPath()
+	require.NoError(t, err)
+
+	// Each bundle gets its own directory, so extracting one leaves the other alone.
+	require.NotEqual(t, first, second)
+	require.Equal(t, ExtractPath, filepath.Dir(first))
+
+	_, err = Extract(bytes.NewReader(makeBundle(t, map[string]string{"RELEASE": "202506011200\n", "incus.raw": "incus"}, false)), first)
+	require.NoError(t, err)
+
+	_, err = Extract(bytes.NewReader(makeBundle(t, map[string]string{"RELEASE": "202506021200\n"}, false)), second)
+	require.NoError(t, err)
+
+	version, err := ReadVersion(first)
+	require.NoError(t, err)
+	require.Equal(t, "202506011200", version)
+}
+
+func TestExtractInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := Extract(bytes.NewReader(makeBundle(t, map[string]string{"../escape.raw": "x"}, false)), filepath.Join(t.TempDir(), "bundle"))
+	require.Error(t, err)
+
+	_, err = Extract(bytes.NewReader(makeBundle(t, map[string]string{"sub/incus.raw": "x"}, false)), filepath.Join(t.TempDir(), "bundle"))
+	require.Error(t, err)
+
+	_, err = Extract(bytes.NewReader(makeBundle(t, map[string]string{"incus.raw": "x"}, false)), filepath.Join(t.TempDir(), "bundle"))
+	require.ErrorContains(t, err, "RELEASE")
+}
+
+func TestSelectMedia(t *testing.T) {
+	t.Parallel()
+
+	devices := []lsblkMedia{
+		{KName: "/dev/sda1", FSType: "ext4"},
+		{KName: "/dev/sdb", Removable: true, Hotplug: true},
+		{KName: "/dev/sdb1", Removable: true, Hotplug: true, FSType: "vfat"},
+		{KName: "/dev/sdc1", Hotplug: true, FSType: "crypto_LUKS"},
+		{KName: "/dev/sr0", Removable: true, FSType: "iso9660"},
+	}
+
+	require.Equal(t, map[string]string{"/dev/sdb1": "vfat", "/dev/sr0": "iso9660"}, selectMedia(devices))
+}
@@ -0,0 +1,3 @@
+// Package bundle handles offline update bundles, allowing OS and application updates to be applied
+// from local media on systems without access to a remote provider.
+package bundle
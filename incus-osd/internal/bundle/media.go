@@ -0,0 +1,164 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// mediaBundleNames lists the names of the update bundles looked for on removable media.
+var mediaBundleNames = []string{"incus-os-update.tar", "incus-os-update.tar.gz"}
+
+// mediaFilesystems lists the filesystems of removable media which are checked for a bundle.
+var mediaFilesystems = []string{"exfat", "ext4", "iso9660", "udf", "vfat"}
+
+// mediaMountPath is where removable media get temporarily mounted.
+var mediaMountPath = "/run/incus-os/media"
+
+type lsblkMedia struct {
+	KName     string `json:"kname"`
+	Removable bool   `json:"rm"`
+	Hotplug   bool   `json:"hotplug"`
+	FSType    string `json:"fstype"`
+}
+
+// WatchRemovableMedia periodically looks for an update bundle on newly inserted removable media, if
+// enabled in the update configuration. When found, the bundle is extracted and an update from it is triggered.
+func WatchRemovableMedia(ctx context.Context, s *state.State) {
+	seen := map[string]bool{}
+
+	for {
+		// Anyone with physical access can insert media, so only look for bundles when asked to.
+		if s.System.Update.Config.RemovableMedia {
+			err := checkRemovableMedia(ctx, s, seen)
+			if err != nil {
+				slog.Error("Failed to check removable media for an update bundle", "err", err.Error())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+// checkRemovableMedia checks the removable media not seen yet for an update bundle.
+func checkRemovableMedia(ctx context.Context, s *state.State, seen map[string]bool) error {
+	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-J", "-l", "-p", "-o", "KNAME,RM,HOTPLUG,FSTYPE")
+	if err != nil {
+		return err
+	}
+
+	devices := struct {
+		Blockdevices []lsblkMedia `json:"blockdevices"`
+	}{}
+
+	err = json.Unmarshal([]byte(output), &devices)
+	if err != nil {
+		return err
+	}
+
+	media := selectMedia(devices.Blockdevices)
+
+	// Forget about removed media, so they get checked again once inserted back.
+	for device := range seen {
+		_, ok := media[device]
+		if !ok {
+			delete(seen, device)
+		}
+	}
+
+	for device, fsType := range media {
+		if seen[device] {
+			continue
+		}
+
+		seen[device] = true
+
+		path, version, err := extractFromMedia(device, fsType)
+		if err != nil {
+			slog.Warn("Failed to read update bundle from removable media", "device", device, "err", err.Error())
+
+			continue
+		}
+
+		if version == "" {
+			continue
+		}
+
+		slog.Info("Found update bundle on removable media", "device", device, "release", version)
+		s.TriggerBundleUpdate <- path
+	}
+
+	return nil
+}
+
+// selectMedia returns the removable devices holding a supported filesystem, along with the filesystem.
+func selectMedia(devices []lsblkMedia) map[string]string {
+	ret := map[string]string{}
+
+	for _, dev := range devices {
+		if (dev.Removable || dev.Hotplug) && slices.Contains(mediaFilesystems, dev.FSType) {
+			ret[dev.KName] = dev.FSType
+		}
+	}
+
+	return ret
+}
+
+// extractFromMedia mounts the device and extracts the update bundle found on it, if any. Returns the
+// path of the extracted bundle and its release version, or an empty version if there's none.
+func extractFromMedia(device string, fsType string) (string, string, error) {
+	err := os.MkdirAll(mediaMountPath, 0o700)
+	if err != nil {
+		return "", "", err
+	}
+
+	err = unix.Mount(device, mediaMountPath, fsType, unix.MS_RDONLY|unix.MS_NOEXEC|unix.MS_NOSUID|unix.MS_NODEV, "")
+	if err != nil {
+		return "", "", err
+	}
+
+	defer func() { _ = unix.Unmount(mediaMountPath, 0) }()
+
+	for _, name := range mediaBundleNames {
+		f, err := os.Open(filepath.Join(mediaMountPath, name)) //nolint:gosec
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return "", "", err
+		}
+
+		defer f.Close()
+
+		path, err := NewExtractPath()
+		if err != nil {
+			return "", "", err
+		}
+
+		version, err := Extract(f, path)
+		if err != nil {
+			_ = os.RemoveAll(path)
+
+			return "", "", err
+		}
+
+		return path, version, nil
+	}
+
+	return "", "", nil
+}
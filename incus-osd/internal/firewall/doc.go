@@ -0,0 +1,2 @@
+// Package firewall is used to manage the host firewall.
+package firewall
@@ -0,0 +1,115 @@
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// The nftables table holding all the host firewall rules.
+var tableName = "incus_os"
+
+// Well-known services which can be restricted without specifying their protocol and port.
+var wellKnownServices = map[string]struct {
+	protocol string
+	port     int
+}{
+	"incus": {"tcp", 8443},
+	"ssh":   {"tcp", 22},
+}
+
+// ApplyFirewall replaces the host firewall rules with those generated from the provided configuration.
+func ApplyFirewall(ctx context.Context, cfg api.SystemSecurityFirewall) error {
+	ruleset, err := generateRuleset(cfg)
+	if err != nil {
+		return err
+	}
+
+	return subprocess.RunCommandWithFds(ctx, strings.NewReader(ruleset), nil, "nft", "-f", "-")
+}
+
+// generateRuleset returns the nftables ruleset for the provided configuration. The table is always
+// created then deleted first so the ruleset atomically replaces any existing rules.
+func generateRuleset(cfg api.SystemSecurityFirewall) (string, error) {
+	ret := fmt.Sprintf("table inet %s\ndelete table inet %s\n", tableName, tableName)
+
+	if !cfg.Enabled {
+		return ret, nil
+	}
+
+	ret += fmt.Sprintf("table inet %s {\n", tableName)
+	ret += "\tchain input {\n"
+	ret += "\t\ttype filter hook input priority filter; policy accept;\n"
+	ret += "\t\tiif lo accept\n"
+	ret += "\t\tct state established,related accept\n"
+
+	for _, service := range cfg.Services {
+		protocol, port, err := getServicePort(service)
+		if err != nil {
+			return "", err
+		}
+
+		ipv4Sources := []string{}
+		ipv6Sources := []string{}
+
+		for _, source := range service.Sources {
+			ip, _, err := net.ParseCIDR(source)
+			if err != nil {
+				ip = net.ParseIP(source)
+			}
+
+			if ip == nil {
+				return "", fmt.Errorf("invalid firewall source %q for service %q", source, service.Name)
+			}
+
+			if ip.To4() != nil {
+				ipv4Sources = append(ipv4Sources, source)
+			} else {
+				ipv6Sources = append(ipv6Sources, source)
+			}
+		}
+
+		if len(ipv4Sources) > 0 {
+			ret += fmt.Sprintf("\t\t%s dport %d ip saddr { %s } accept\n", protocol, port, strings.Join(ipv4Sources, ", "))
+		}
+
+		if len(ipv6Sources) > 0 {
+			ret += fmt.Sprintf("\t\t%s dport %d ip6 saddr { %s } accept\n", protocol, port, strings.Join(ipv6Sources, ", "))
+		}
+
+		ret += fmt.Sprintf("\t\t%s dport %d drop\n", protocol, port)
+	}
+
+	ret += "\t}\n"
+	ret += "}\n"
+
+	return ret, nil
+}
+
+// getServicePort returns the protocol and port of a restricted service.
+func getServicePort(service api.SystemSecurityFirewallService) (string, int, error) {
+	if service.Protocol == "" && service.Port == 0 {
+		wellKnown, ok := wellKnownServices[service.Name]
+		if !ok {
+			return "", 0, fmt.Errorf("unknown firewall service %q", service.Name)
+		}
+
+		return wellKnown.protocol, wellKnown.port, nil
+	}
+
+	if !slices.Contains([]string{"tcp", "udp"}, service.Protocol) {
+		return "", 0, fmt.Errorf("invalid protocol %q for firewall service %q", service.Protocol, service.Name)
+	}
+
+	if service.Port <= 0 || service.Port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %d for firewall service %q", service.Port, service.Name)
+	}
+
+	return service.Protocol, service.Port, nil
+}
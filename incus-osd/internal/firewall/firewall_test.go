@@ -0,0 +1,45 @@
+package firewall
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestRulesetGeneration(t *testing.T) {
+	t.Parallel()
+
+	// A disabled firewall only removes existing rules.
+	ruleset, err := generateRuleset(api.SystemSecurityFirewall{})
+	require.NoError(t, err)
+	require.Equal(t, "table inet incus_os\ndelete table inet incus_os\n", ruleset)
+
+	cfg := api.SystemSecurityFirewall{
+		Enabled: true,
+		Services: []api.SystemSecurityFirewallService{
+			{
+				Name:    "incus",
+				Sources: []string{"10.0.0.0/8", "fd00::/8", "192.168.1.10"},
+			},
+			{
+				Name:     "bgp",
+				Protocol: "tcp",
+				Port:     179,
+				Sources:  []string{"10.0.0.1"},
+			},
+		},
+	}
+
+	ruleset, err = generateRuleset(cfg)
+	require.NoError(t, err)
+	require.Equal(t, "table inet incus_os\ndelete table inet incus_os\ntable inet incus_os {\n\tchain input {\n\t\ttype filter hook input priority filter; policy accept;\n\t\tiif lo accept\n\t\tct state established,related accept\n\t\ttcp dport 8443 ip saddr { 10.0.0.0/8, 192.168.1.10 } accept\n\t\ttcp dport 8443 ip6 saddr { fd00::/8 } accept\n\t\ttcp dport 8443 drop\n\t\ttcp dport 179 ip saddr { 10.0.0.1 } accept\n\t\ttcp dport 179 drop\n\t}\n}\n", ruleset)
+
+	// Invalid configurations.
+	_, err = generateRuleset(api.SystemSecurityFirewall{Enabled: true, Services: []api.SystemSecurityFirewallService{{Name: "unknown"}}})
+	require.Error(t, err)
+
+	_, err = generateRuleset(api.SystemSecurityFirewall{Enabled: true, Services: []api.SystemSecurityFirewallService{{Name: "ssh", Sources: []string{"not-an-address"}}}})
+	require.Error(t, err)
+}
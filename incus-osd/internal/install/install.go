@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -72,7 +73,11 @@ func CheckSystemRequirements(ctx context.Context) error {
 			return err
 		}
 
-		_, err = getTargetDevice(targets, config.Target, source)
+		target, err := getTargetDevice(targets, config.Target, source)
+		if err == nil {
+			_, err = getLocalPoolDevices(targets, config.LocalPool, source, target)
+		}
+
 		if err != nil {
 			devices := []string{}
 			for _, t := range targets {
@@ -140,10 +145,26 @@ func (i *Install) DoInstall(ctx context.Context) error {
 		return err
 	}
 
+	poolDevices, err := getLocalPoolDevices(targets, i.config.LocalPool, sourceDevice, targetDevice)
+	if err != nil {
+		i.tui.DisplayModal("Incus OS Install", "[red]Error: "+err.Error(), 0, 0)
+
+		return err
+	}
+
 	slog.Info("Installing incus-osd", "source", sourceDevice, "target", targetDevice)
 	i.tui.DisplayModal("Incus OS Install", fmt.Sprintf("Installing incus-osd from %s to %s.", sourceDevice, targetDevice), 0, 0)
 
-	err = i.performInstall(ctx, sourceDevice, targetDevice, sourceIsReadonly)
+	if len(poolDevices) > 0 {
+		poolDeviceNames := []string{}
+		for _, device := range poolDevices {
+			poolDeviceNames = append(poolDeviceNames, device.KName)
+		}
+
+		slog.Info("Using additional devices for the local storage pool", "topology", i.config.LocalPool.Topology, "devices", poolDeviceNames)
+	}
+
+	err = i.performInstall(ctx, sourceDevice, targetDevice, poolDevices, sourceIsReadonly)
 	if err != nil {
 		i.tui.DisplayModal("Incus OS Install", "[red]Error: "+err.Error(), 0, 0)
 
@@ -302,28 +323,95 @@ func getTargetDevice(potentialTargets []blockdevices, seedTarget *seed.InstallSe
 	return "", errors.New("unable to determine target device")
 }
 
-// performInstall performs the steps to install incus-osd from the given target to the source device.
-func (i *Install) performInstall(ctx context.Context, sourceDevice string, targetDevice string, sourceIsReadonly bool) error {
-	// Verify the target device doesn't already have a partition table, or that `ForceInstall` is set to true.
-	output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-v", targetDevice)
-	if err != nil {
-		return err
+// getLocalPoolDevices determines the additional devices to include in the "local" ZFS pool. Each selector
+// picks the first matching device which isn't the source, the target or already selected.
+func getLocalPoolDevices(potentialTargets []blockdevices, localPool *seed.InstallSeedLocalPool, sourceDevice string, targetDevice string) ([]blockdevices, error) {
+	if localPool == nil {
+		return nil, nil
 	}
 
-	if !strings.Contains(output, "Creating new GPT entries in memory") && !i.config.ForceInstall {
-		return fmt.Errorf("a partition table already exists on device '%s', and `ForceInstall` from install configuration isn't true", targetDevice)
+	// Minimum number of additional devices required by each topology.
+	minDevices := map[string]int{
+		"mirror": 1,
+		"raidz1": 2,
+		"raidz2": 3,
 	}
 
-	// If ForceInstall is true, zap any existing GPT table on the target device.
-	if i.config.ForceInstall {
-		_, err := subprocess.RunCommandContext(ctx, "sgdisk", "-Z", targetDevice)
+	numDevices, ok := minDevices[localPool.Topology]
+	if !ok {
+		return nil, fmt.Errorf("unsupported local pool topology '%s'", localPool.Topology)
+	}
+
+	if len(localPool.Devices) < numDevices {
+		return nil, fmt.Errorf("local pool topology '%s' requires at least %d additional devices", localPool.Topology, numDevices)
+	}
+
+	ret := []blockdevices{}
+
+	for _, id := range localPool.Devices {
+		if id == "" {
+			return nil, errors.New("local pool device selector can't be empty")
+		}
+
+		found := false
+
+		for _, device := range potentialTargets {
+			if device.KName == sourceDevice || device.KName == targetDevice || device.ID == "" {
+				continue
+			}
+
+			if slices.ContainsFunc(ret, func(d blockdevices) bool { return d.KName == device.KName }) {
+				continue
+			}
+
+			if strings.Contains(device.ID, id) {
+				ret = append(ret, device)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unable to determine local pool device matching '%s'", id)
+		}
+	}
+
+	return ret, nil
+}
+
+// performInstall performs the steps to install incus-osd from the given target to the source device.
+func (i *Install) performInstall(ctx context.Context, sourceDevice string, targetDevice string, poolDevices []blockdevices, sourceIsReadonly bool) error {
+	// Verify the target device and any additional local pool devices don't already have a partition table,
+	// or that `ForceInstall` is set to true.
+	devices := []string{targetDevice}
+	for _, device := range poolDevices {
+		devices = append(devices, device.KName)
+	}
+
+	for _, device := range devices {
+		output, err := subprocess.RunCommandContext(ctx, "sgdisk", "-v", device)
 		if err != nil {
 			return err
 		}
+
+		if !strings.Contains(output, "Creating new GPT entries in memory") && !i.config.ForceInstall {
+			return fmt.Errorf("a partition table already exists on device '%s', and `ForceInstall` from install configuration isn't true", device)
+		}
+	}
+
+	// If ForceInstall is true, zap any existing GPT table on the target and additional local pool devices.
+	if i.config.ForceInstall {
+		for _, device := range devices {
+			_, err := subprocess.RunCommandContext(ctx, "sgdisk", "-Z", device)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Turn off swap and unmount /boot.
-	_, err = subprocess.RunCommandContext(ctx, "swapoff", "-a")
+	_, err := subprocess.RunCommandContext(ctx, "swapoff", "-a")
 	if err != nil {
 		return err
 	}
@@ -447,15 +535,24 @@ func (i *Install) performInstall(ctx context.Context, sourceDevice string, targe
 		}
 	}
 
-	// Remove the install configuration file, if present, from the target seed partition.
+	// Remove the install configuration file, if present, from the target seed partition. Any local pool
+	// layout is also removed, as it only applies to the devices of the system it was recorded on.
 	targetSeedPartition := fmt.Sprintf("%s%s2", targetDevice, targetPartitionPrefix)
-	for _, filename := range []string{"install.json", "install.yaml"} {
+	for _, filename := range []string{"install.json", "install.yaml", "local-pool.json", "local-pool.yaml"} {
 		_, err = subprocess.RunCommandContext(ctx, "tar", "-f", targetSeedPartition, "--delete", filename)
 		if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("tar: %s: Not found in archive", filename)) {
 			return err
 		}
 	}
 
+	// Record the layout of the "local" ZFS pool, which gets created on first boot.
+	if len(poolDevices) > 0 {
+		err = writeLocalPoolSeed(ctx, targetSeedPartition, i.config.LocalPool.Topology, poolDevices)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Finally, run `bootctl install`.
 	err = os.MkdirAll("/boot", 0o755)
 	if err != nil {
@@ -477,6 +574,39 @@ func (i *Install) performInstall(ctx context.Context, sourceDevice string, targe
 	return err
 }
 
+// writeLocalPoolSeed adds the layout of the "local" ZFS pool to the target seed partition. Devices are
+// recorded using their stable /dev/disk/by-id/ path.
+func writeLocalPoolSeed(ctx context.Context, targetSeedPartition string, topology string, poolDevices []blockdevices) error {
+	localPool := seed.InstallSeedLocalPool{
+		Topology: topology,
+	}
+
+	for _, device := range poolDevices {
+		localPool.Devices = append(localPool.Devices, filepath.Join("/dev/disk/by-id", device.ID))
+	}
+
+	content, err := json.Marshal(localPool)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "incus-osd-seed-")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	err = os.WriteFile(filepath.Join(tmpDir, "local-pool.json"), content, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "tar", "-f", targetSeedPartition, "-C", tmpDir, "-r", "local-pool.json")
+
+	return err
+}
+
 // Copy partition definitions to target device. We can't just do a `sgdisk -R target source`
 // because the install media may have a different sector size than the target device (for example,
 // if the installer is running from a CDROM).
@@ -0,0 +1,3 @@
+// Package peercache shares the downloaded OS updates with the other members of an Incus cluster,
+// cutting the external traffic when many systems update from behind the same uplink.
+package peercache
@@ -0,0 +1,126 @@
+package peercache
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// Run serves the downloaded updates to the peers while the peer cache is enabled, following
+// configuration changes until the context is cancelled.
+func Run(ctx context.Context, s *state.State) {
+	var server *http.Server
+
+	listening := 0
+
+	for {
+		port := 0
+		if s.System.Update.Config.PeerCache.Enabled {
+			port = s.System.Update.Config.PeerCache.Port
+			if port <= 0 {
+				port = providers.DefaultPeerCachePort
+			}
+		}
+
+		if port != listening {
+			if server != nil {
+				_ = server.Close()
+				server = nil
+			}
+
+			listening = 0
+
+			if port != 0 {
+				listener, err := listenTLS(port)
+				if err != nil {
+					slog.Error("Failed to start the update peer cache", "port", port, "err", err.Error())
+				} else {
+					slog.Info("Serving updates to peers", "port", port)
+
+					server = &http.Server{
+						Handler:           newHandler(s, systemd.SystemUpdatesPath),
+						ReadHeaderTimeout: 10 * time.Second,
+					}
+
+					listening = port
+
+					go func() {
+						err := server.Serve(listener)
+						if err != nil && !errors.Is(err, http.ErrServerClosed) {
+							slog.Error("Update peer cache failed", "err", err.Error())
+						}
+					}()
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if server != nil {
+				_ = server.Close()
+			}
+
+			return
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+// listenTLS listens on the port, securing the connections with the Incus cluster certificate which
+// the peers expect.
+func listenTLS(port int) (net.Listener, error) {
+	cert, err := tls.LoadX509KeyPair(providers.IncusClusterCertificatePath, providers.IncusClusterKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		return nil, err
+	}
+
+	return tls.NewListener(listener, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS13,
+	}), nil
+}
+
+// newHandler returns the handler serving the update files. Only the OS files of fully downloaded
+// releases are served, those of the running and next releases, as only their signatures can be
+// checked by the peers.
+func newHandler(s *state.State, updatesPath string) http.Handler {
+	router := http.NewServeMux()
+
+	router.HandleFunc("GET /1.0/updates/{version}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		version := r.PathValue("version")
+		name := r.PathValue("name")
+
+		if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		// OS update files.
+		if strings.HasPrefix(name, "IncusOS_"+version+".") && (version == s.OS.RunningRelease || version == s.OS.NextRelease) {
+			http.ServeFile(w, r, filepath.Join(updatesPath, name))
+
+			return
+		}
+
+		http.NotFound(w, r)
+	})
+
+	return router
+}
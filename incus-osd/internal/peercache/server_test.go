@@ -0,0 +1,63 @@
+package peercache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	updatesPath := t.TempDir()
+
+	for _, name := range []string{"IncusOS_202501010000.efi", "IncusOS_202502010000.efi"} {
+		err := os.WriteFile(filepath.Join(updatesPath, name), []byte(name), 0o600)
+		require.NoError(t, err)
+	}
+
+	s := &state.State{
+		Applications: map[string]state.Application{"incus": {Version: "202501010000"}},
+		OS:           state.OS{RunningRelease: "202501010000"},
+	}
+
+	handler := newHandler(s, updatesPath)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/1.0/updates/202501010000/IncusOS_202501010000.efi", http.StatusOK, "IncusOS_202501010000.efi"},
+
+		// Application images, which peers can't verify.
+		{"/1.0/updates/202501010000/incus.raw", http.StatusNotFound, ""},
+
+		// Release which isn't fully downloaded yet.
+		{"/1.0/updates/202502010000/IncusOS_202502010000.efi", http.StatusNotFound, ""},
+
+		// Mismatched versions.
+		{"/1.0/updates/202501010000/IncusOS_202502010000.efi", http.StatusNotFound, ""},
+
+		// Other files.
+		{"/1.0/updates/202501010000/..%2Fstate.txt", http.StatusNotFound, ""},
+		{"/1.0/updates/202501010000/missing.raw", http.StatusNotFound, ""},
+	}
+
+	for _, tc := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		require.Equal(t, tc.status, rec.Code, tc.path)
+
+		if tc.body != "" {
+			require.Equal(t, tc.body, rec.Body.String(), tc.path)
+		}
+	}
+}
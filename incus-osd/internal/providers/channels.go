@@ -0,0 +1,57 @@
+package providers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Channels lists the update channels, from the most to the least conservative.
+var Channels = []string{"stable", "candidate", "testing"}
+
+// ValidateChannel checks that the update channel is valid. An empty channel is the "stable" one.
+func ValidateChannel(channel string) error {
+	if channel != "" && !slices.Contains(Channels, channel) {
+		return fmt.Errorf("invalid update channel %q", channel)
+	}
+
+	return nil
+}
+
+// ValidateVersion checks that a pinned release version is valid (YYYYMMDDhhmm).
+func ValidateVersion(version string) error {
+	if version == "" {
+		return nil
+	}
+
+	_, err := strconv.ParseUint(version, 10, 64)
+	if err != nil || len(version) != 12 {
+		return fmt.Errorf("invalid release version %q", version)
+	}
+
+	return nil
+}
+
+// configChannel returns the update channel from the provider configuration.
+func configChannel(config map[string]string) string {
+	if config["channel"] == "" {
+		return "stable"
+	}
+
+	return config["channel"]
+}
+
+// releaseInChannel returns whether a Github release is published to the channel. Full releases are
+// published to all channels, pre-releases tagged with a "-candidate" suffix to the "candidate" and
+// "testing" channels and other pre-releases to the "testing" channel only.
+func releaseInChannel(channel string, prerelease bool, tag string) bool {
+	switch channel {
+	case "testing":
+		return true
+	case "candidate":
+		return !prerelease || strings.HasSuffix(tag, "-candidate")
+	default:
+		return !prerelease
+	}
+}
@@ -0,0 +1,72 @@
+package providers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// deltaAssetName returns the name of the delta turning the file of the previous release into the
+// file of the new release. Deltas are created with "zstd --patch-from".
+func deltaAssetName(fileName string, previousVersion string) string {
+	return fileName + ".from-" + previousVersion + ".zst"
+}
+
+// isDeltaAsset returns whether the asset is a delta.
+func isDeltaAsset(name string) bool {
+	return strings.Contains(name, ".from-") && strings.HasSuffix(name, ".zst")
+}
+
+// fileKind returns the kind of an OS update file, independently of its version and UUID, such as
+// "usr-x86-64.raw" for "IncusOS_202506011200.usr-x86-64.<uuid>.raw" or "efi".
+func fileKind(name string) string {
+	fields := strings.Split(name, ".")
+	if len(fields) < 2 {
+		return ""
+	}
+
+	if len(fields) == 2 {
+		return fields[1]
+	}
+
+	return fields[1] + "." + fields[len(fields)-1]
+}
+
+// previousRelease returns the version of the OS update files found in the directory, along with the
+// files indexed by kind.
+func previousRelease(path string) (string, map[string]string) {
+	version := ""
+	files := map[string]string{}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", files
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "IncusOS_") || !entry.Type().IsRegular() {
+			continue
+		}
+
+		fileVersion, _, ok := strings.Cut(strings.TrimPrefix(name, "IncusOS_"), ".")
+		if !ok {
+			continue
+		}
+
+		version = fileVersion
+		files[fileKind(name)] = filepath.Join(path, name)
+	}
+
+	return version, files
+}
+
+// applyDelta rebuilds a file from the matching file of the previous release and a delta.
+func applyDelta(ctx context.Context, base string, delta string, target string) error {
+	_, err := subprocess.RunCommandContext(ctx, "zstd", "-d", "-q", "-f", "--long=31", "--patch-from="+base, delta, "-o", target)
+
+	return err
+}
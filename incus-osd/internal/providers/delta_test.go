@@ -0,0 +1,48 @@
+package providers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileKind(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "usr-x86-64.raw", fileKind("IncusOS_202506011200.usr-x86-64.8a7b3c.raw"))
+	require.Equal(t, "usr-x86-64-verity.raw", fileKind("IncusOS_202506011200.usr-x86-64-verity.8a7b3c.raw"))
+	require.Equal(t, "efi", fileKind("IncusOS_202506011200.efi"))
+	require.Empty(t, fileKind("RELEASE"))
+}
+
+func TestPreviousRelease(t *testing.T) {
+	t.Parallel()
+
+	path := t.TempDir()
+
+	for _, name := range []string{"IncusOS_202506011200.efi", "IncusOS_202506011200.usr-x86-64.8a7b3c.raw", "incus.raw"} {
+		require.NoError(t, os.WriteFile(filepath.Join(path, name), nil, 0o600))
+	}
+
+	version, files := previousRelease(path)
+	require.Equal(t, "202506011200", version)
+	require.Equal(t, map[string]string{
+		"efi":            filepath.Join(path, "IncusOS_202506011200.efi"),
+		"usr-x86-64.raw": filepath.Join(path, "IncusOS_202506011200.usr-x86-64.8a7b3c.raw"),
+	}, files)
+
+	version, files = previousRelease(filepath.Join(path, "missing"))
+	require.Empty(t, version)
+	require.Empty(t, files)
+}
+
+func TestDeltaAssetName(t *testing.T) {
+	t.Parallel()
+
+	name := deltaAssetName("IncusOS_202506021200.usr-x86-64.9c8d.raw", "202506011200")
+	require.Equal(t, "IncusOS_202506021200.usr-x86-64.9c8d.raw.from-202506011200.zst", name)
+	require.True(t, isDeltaAsset(name))
+	require.False(t, isDeltaAsset("IncusOS_202506021200.usr-x86-64.9c8d.raw.gz"))
+}
@@ -0,0 +1,216 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DownloadCachePath is where partially downloaded files are kept, allowing interrupted downloads to resume.
+var DownloadCachePath = "/var/cache/incus-os/downloads"
+
+// downloadAttempts is the number of times an interrupted download is resumed before giving up.
+var downloadAttempts = 5
+
+// downloadRetryDelay is the delay before resuming an interrupted download.
+var downloadRetryDelay = 10 * time.Second
+
+// ValidateRateLimit checks that a download rate limit (bytes per second, 0 for unlimited) is valid.
+func ValidateRateLimit(limit string) error {
+	if limit == "" {
+		return nil
+	}
+
+	_, err := strconv.ParseUint(limit, 10, 63)
+	if err != nil {
+		return fmt.Errorf("invalid download rate limit %q", limit)
+	}
+
+	return nil
+}
+
+// configRateLimit returns the download rate limit, in bytes per second, from the provider configuration.
+func configRateLimit(config map[string]string) int64 {
+	limit, err := strconv.ParseInt(config["rate_limit"], 10, 64)
+	if err != nil || limit < 0 {
+		return 0
+	}
+
+	return limit
+}
+
+// fetchURL downloads the URL into the partial file, resuming from its current size using HTTP range
+// requests, both when a previous download was interrupted and when the connection drops midway.
+func fetchURL(ctx context.Context, client *http.Client, url string, partial string, tr *transfer, limit int64) error {
+	base := tr.done
+
+	var err error
+
+	for attempt := range downloadAttempts {
+		if attempt > 0 {
+			slog.Warn("Resuming interrupted download", "url", url, "err", err.Error())
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(downloadRetryDelay):
+			}
+		}
+
+		err = fetchURLOnce(ctx, client, url, partial, tr, base, limit)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+func fetchURLOnce(ctx context.Context, client *http.Client, url string, partial string, tr *transfer, base int64, limit int64) error {
+	// Open the partial file.
+	// #nosec G304
+	fd, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	offset, err := fd.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	// Request the missing part of the file.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusOK:
+		// The server doesn't support range requests, start over.
+		offset = 0
+
+		err = fd.Truncate(0)
+		if err != nil {
+			return err
+		}
+
+		_, err = fd.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The file was already fully downloaded.
+		if offset > 0 {
+			return nil
+		}
+
+		fallthrough
+	default:
+		return fmt.Errorf("failed to download %q: %s", url, resp.Status)
+	}
+
+	tr.done = base + offset
+
+	// Read in chunks to avoid excessive memory consumption.
+	body := tr.wrap(newRateLimitedReader(ctx, resp.Body, limit))
+
+	for {
+		_, err = io.CopyN(fd, body, 4*1024*1024)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rateLimitedReader throttles reads to an average number of bytes per second.
+type rateLimitedReader struct {
+	ctx    context.Context //nolint:containedctx
+	reader io.Reader
+	limit  int64
+
+	start time.Time
+	done  int64
+}
+
+// newRateLimitedReader returns a reader limited to the rate in bytes per second, or the reader
+// itself if the limit is zero.
+func newRateLimitedReader(ctx context.Context, r io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return r
+	}
+
+	return &rateLimitedReader{ctx: ctx, reader: r, limit: limit}
+}
+
+// Read reads from the underlying reader, waiting as needed to stay under the rate limit.
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+
+	// Don't read more than a second worth of data at once.
+	if int64(len(p)) > r.limit {
+		p = p[:r.limit]
+	}
+
+	n, err := r.reader.Read(p)
+	r.done += int64(n)
+
+	// Wait until the data read fits within the rate limit.
+	wait := time.Duration(float64(r.done)/float64(r.limit)*float64(time.Second)) - time.Since(r.start)
+	if wait > 0 {
+		select {
+		case <-r.ctx.Done():
+			return n, r.ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	return n, err
+}
+
+// pruneDownloadCache removes partial downloads which haven't been resumed for a week.
+func pruneDownloadCache() {
+	entries, err := os.ReadDir(DownloadCachePath)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < 7*24*time.Hour {
+			continue
+		}
+
+		_ = os.Remove(filepath.Join(DownloadCachePath, entry.Name()))
+	}
+}
@@ -0,0 +1,97 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchURLResume(t *testing.T) {
+	t.Parallel()
+
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "asset", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	partial := filepath.Join(t.TempDir(), "asset.partial")
+
+	// Simulate an interrupted download.
+	err := os.WriteFile(partial, content[:4000], 0o600)
+	require.NoError(t, err)
+
+	tr := newTransfer(int64(len(content)), nil)
+
+	err = fetchURL(context.Background(), server.Client(), server.URL, partial, tr, 0)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(partial)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+	require.Equal(t, int64(len(content)), tr.done)
+
+	// Resuming a complete download is a no-op.
+	err = fetchURL(context.Background(), server.Client(), server.URL, partial, newTransfer(0, nil), 0)
+	require.NoError(t, err)
+
+	data, err = os.ReadFile(partial)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+}
+
+func TestFetchURLNoRange(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("complete content")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	partial := filepath.Join(t.TempDir(), "asset.partial")
+
+	err := os.WriteFile(partial, []byte("stale"), 0o600)
+	require.NoError(t, err)
+
+	err = fetchURL(context.Background(), server.Client(), server.URL, partial, newTransfer(0, nil), 0)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(partial)
+	require.NoError(t, err)
+	require.Equal(t, content, data)
+}
+
+func TestRateLimitedReader(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+
+	n, err := io.Copy(io.Discard, newRateLimitedReader(context.Background(), bytes.NewReader(make([]byte, 3000)), 10000))
+	require.NoError(t, err)
+	require.Equal(t, int64(3000), n)
+	require.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+
+	// No limit.
+	r := bytes.NewReader(nil)
+	require.Equal(t, io.Reader(r), newRateLimitedReader(context.Background(), r, 0))
+}
+
+func TestValidateRateLimit(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateRateLimit(""))
+	require.NoError(t, ValidateRateLimit("1048576"))
+	require.Error(t, ValidateRateLimit("-1"))
+	require.Error(t, ValidateRateLimit("fast"))
+}
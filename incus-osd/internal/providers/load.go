@@ -12,6 +12,21 @@ func Load(ctx context.Context, name string, config map[string]string) (Provider,
 		return nil, fmt.Errorf("unknown provider %q", name)
 	}
 
+	err := ValidateChannel(config["channel"])
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateVersion(config["version"])
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateRateLimit(config["rate_limit"])
+	if err != nil {
+		return nil, err
+	}
+
 	var p Provider
 
 	switch name {
@@ -28,7 +43,7 @@ func Load(ctx context.Context, name string, config map[string]string) (Provider,
 		}
 	}
 
-	err := p.load(ctx)
+	err = p.load(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"slices"
+	"strings"
+)
+
+// Severities lists the release severities, from the least to the most urgent.
+var Severities = []string{"low", "medium", "high", "critical"}
+
+// ReleaseNotes holds the metadata published along with a release.
+type ReleaseNotes struct {
+	// Severity is one of Severities, empty if not published.
+	Severity string
+
+	// Changelog is the free-form description of the changes.
+	Changelog string
+}
+
+// parseReleaseNotes extracts the release notes from a release description. The severity is
+// published as a "Severity: <level>" line, which is removed from the changelog.
+func parseReleaseNotes(body string) ReleaseNotes {
+	notes := ReleaseNotes{}
+	lines := []string{}
+
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if found && notes.Severity == "" && strings.EqualFold(strings.TrimSpace(key), "severity") {
+			severity := strings.ToLower(strings.TrimSpace(value))
+			if slices.Contains(Severities, severity) {
+				notes.Severity = severity
+
+				continue
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	notes.Changelog = strings.TrimSpace(strings.Join(lines, "\n"))
+
+	return notes
+}
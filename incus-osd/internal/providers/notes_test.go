@@ -0,0 +1,21 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReleaseNotes(t *testing.T) {
+	t.Parallel()
+
+	notes := parseReleaseNotes("Severity: High\r\n\r\n- Fix a kernel crash\r\n- Update Incus\r\n")
+	require.Equal(t, "high", notes.Severity)
+	require.Equal(t, "- Fix a kernel crash\n- Update Incus", notes.Changelog)
+
+	notes = parseReleaseNotes("Severity: unknown\n- Update Incus")
+	require.Empty(t, notes.Severity)
+	require.Equal(t, "Severity: unknown\n- Update Incus", notes.Changelog)
+
+	require.Equal(t, ReleaseNotes{}, parseReleaseNotes(""))
+}
@@ -0,0 +1,244 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
+)
+
+// DefaultPeerCachePort is the port on which downloaded updates are shared with peers by default.
+const DefaultPeerCachePort = 8444
+
+// IncusSocketPath is the Incus API socket, used to discover the other cluster members.
+var IncusSocketPath = "/var/lib/incus/unix.socket"
+
+// IncusClusterCertificatePath and IncusClusterKeyPath are the Incus cluster certificate and key,
+// shared by the cluster members and used to secure the peer cache.
+var (
+	IncusClusterCertificatePath = "/var/lib/incus/cluster.crt"
+	IncusClusterKeyPath         = "/var/lib/incus/cluster.key"
+)
+
+// loadPeerClient returns the client for fetching updates from the peers, which must present the
+// Incus cluster certificate.
+func loadPeerClient() (*http.Client, error) {
+	// #nosec G304
+	content, err := os.ReadFile(IncusClusterCertificatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("invalid Incus cluster certificate")
+	}
+
+	return newPeerClient(block.Bytes), nil
+}
+
+// newPeerClient returns a client only trusting peers presenting the provided (DER encoded)
+// certificate. It fails fast on unreachable peers, without limiting the duration of the transfer.
+func newPeerClient(certificate []byte) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+			ResponseHeaderTimeout: 10 * time.Second,
+			TLSClientConfig: &tls.Config{
+				MinVersion: tls.VersionTLS13,
+
+				// The cluster certificate is pinned rather than validated against the peer address.
+				InsecureSkipVerify: true, //nolint:gosec
+				VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+					if len(rawCerts) == 0 || !bytes.Equal(rawCerts[0], certificate) {
+						return errors.New("peer doesn't present the cluster certificate")
+					}
+
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// PeerCacheURL returns the path under which a peer serves a file of a release.
+func PeerCacheURL(version string, name string) string {
+	return "/1.0/updates/" + url.PathEscape(version) + "/" + url.PathEscape(name)
+}
+
+// configPeerPort returns the peer cache port from the provider configuration.
+func configPeerPort(config map[string]string) int {
+	port, err := strconv.Atoi(config["peer_cache_port"])
+	if err != nil || port <= 0 {
+		return DefaultPeerCachePort
+	}
+
+	return port
+}
+
+// configPeers returns the addresses of the peers to fetch updates from, the configured ones or
+// else the members of the local Incus cluster.
+func configPeers(ctx context.Context, config map[string]string) []string {
+	if config["peer_cache"] != "true" {
+		return nil
+	}
+
+	port := configPeerPort(config)
+
+	peers := []string{}
+	for _, peer := range strings.Split(config["peers"], ",") {
+		if peer != "" {
+			peers = append(peers, peerAddress(peer, port))
+		}
+	}
+
+	if len(peers) > 0 {
+		return peers
+	}
+
+	hosts, err := discoverIncusPeers(ctx)
+	if err != nil {
+		slog.Debug("Failed to discover update cache peers", "err", err.Error())
+
+		return nil
+	}
+
+	for _, host := range hosts {
+		peers = append(peers, peerAddress(host, port))
+	}
+
+	return peers
+}
+
+// peerAddress returns a "host:port" peer address, using the default port if none is specified.
+func peerAddress(peer string, port int) string {
+	_, _, err := net.SplitHostPort(peer)
+	if err == nil {
+		return peer
+	}
+
+	return net.JoinHostPort(strings.Trim(peer, "[]"), strconv.Itoa(port))
+}
+
+// discoverIncusPeers returns the hosts of the other members of the local Incus cluster.
+func discoverIncusPeers(ctx context.Context) ([]string, error) {
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", IncusSocketPath)
+			},
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://incus/1.0/cluster/members?recursion=1", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	var members struct {
+		Metadata []struct {
+			ServerName string `json:"server_name"`
+			URL        string `json:"url"`
+		} `json:"metadata"`
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&members)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+
+	hosts := []string{}
+
+	for _, member := range members.Metadata {
+		// Skip ourselves.
+		if member.ServerName == hostname {
+			continue
+		}
+
+		u, err := url.Parse(member.URL)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+
+		hosts = append(hosts, u.Hostname())
+	}
+
+	return hosts, nil
+}
+
+// fetchFromPeers attempts to fetch a file of a release from the peers, replacing the provided
+// download size in the transfer. Returns whether it succeeded.
+func fetchFromPeers(ctx context.Context, client *http.Client, peers []string, version string, name string, target string, size int64, tr *transfer) bool {
+	for _, peer := range peers {
+		base := tr.done
+		partial := target + ".partial"
+
+		err := fetchURLOnce(ctx, client, "https://"+peer+PeerCacheURL(version, name), partial, tr, base, 0)
+		if err == nil {
+			err = os.Rename(partial, target)
+		}
+
+		if err != nil {
+			_ = os.Remove(partial)
+			tr.done = base
+
+			slog.Debug("Failed to fetch update from peer", "peer", peer, "file", name, "err", err.Error())
+
+			continue
+		}
+
+		// Peers serve uncompressed files.
+		tr.total += tr.done - base - size
+
+		slog.Info("Fetched update from peer", "peer", peer, "file", filepath.Base(target))
+
+		return true
+	}
+
+	return false
+}
+
+// configSigning returns the trusted update signing certificates from the provider configuration.
+func configSigning(config map[string]string) ([]string, bool) {
+	signing := struct {
+		Certificates []string `json:"certificates"`
+		Exclusive    bool     `json:"exclusive"`
+	}{}
+
+	_ = json.Unmarshal([]byte(config["signing"]), &signing)
+
+	return signing.Certificates, signing.Exclusive
+}
+
+// verifyPeerUpdate checks the signatures of the OS update files of a release, before using files
+// fetched from the peers. The /usr image itself is then checked by dm-verity against the signed
+// root hash when booting.
+func verifyPeerUpdate(ctx context.Context, config map[string]string, path string, version string) error {
+	certificates, exclusive := configSigning(config)
+
+	return secureboot.VerifyUpdate(ctx, path, version, certificates, exclusive)
+}
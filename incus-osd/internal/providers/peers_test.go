@@ -0,0 +1,73 @@
+package providers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerAddress(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "10.0.0.1:8444", peerAddress("10.0.0.1", 8444))
+	require.Equal(t, "10.0.0.1:9000", peerAddress("10.0.0.1:9000", 8444))
+	require.Equal(t, "[fd00::1]:8444", peerAddress("fd00::1", 8444))
+	require.Equal(t, "[fd00::1]:8444", peerAddress("[fd00::1]", 8444))
+	require.Equal(t, "node1:8444", peerAddress("node1", 8444))
+}
+
+func TestConfigPeers(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, configPeers(context.Background(), map[string]string{"peers": "10.0.0.1"}))
+	require.Equal(t, []string{"10.0.0.1:9000", "10.0.0.2:9001"}, configPeers(context.Background(), map[string]string{
+		"peer_cache":      "true",
+		"peer_cache_port": "9000",
+		"peers":           "10.0.0.1,10.0.0.2:9001",
+	}))
+}
+
+func TestFetchFromPeers(t *testing.T) {
+	t.Parallel()
+
+	name := "IncusOS_202501010000.efi"
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != PeerCacheURL("202501010000", name) {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		_, _ = w.Write([]byte("uncompressed image"))
+	}))
+	defer server.Close()
+
+	missing := httptest.NewTLSServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	client := newPeerClient(server.Certificate().Raw)
+	peers := []string{strings.TrimPrefix(missing.URL, "https://"), strings.TrimPrefix(server.URL, "https://")}
+	target := filepath.Join(t.TempDir(), name)
+
+	tr := newTransfer(10, nil)
+	require.True(t, fetchFromPeers(context.Background(), client, peers, "202501010000", name, target, 10, tr))
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	require.Equal(t, "uncompressed image", string(data))
+	require.Equal(t, tr.total, tr.done)
+
+	require.False(t, fetchFromPeers(context.Background(), client, peers[:1], "202501010000", name, target, 10, newTransfer(10, nil)))
+	require.NoFileExists(t, target+".partial")
+
+	// Peers not presenting the cluster certificate aren't trusted.
+	require.False(t, fetchFromPeers(context.Background(), newPeerClient([]byte("other")), peers[1:], "202501010000", name, target+".other", 10, newTransfer(10, nil)))
+	require.NoFileExists(t, target+".other")
+}
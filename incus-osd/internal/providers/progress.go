@@ -0,0 +1,63 @@
+package providers
+
+import (
+	"io"
+	"time"
+)
+
+// ProgressFunc is called during a download with the bytes transferred so far and the total.
+type ProgressFunc func(done int64, total int64)
+
+// transfer tracks the progress of a download spanning multiple files.
+type transfer struct {
+	done     int64
+	total    int64
+	progress ProgressFunc
+}
+
+// newTransfer returns a transfer of the provided total size.
+func newTransfer(total int64, progress ProgressFunc) *transfer {
+	return &transfer{total: total, progress: progress}
+}
+
+// wrap returns a reader reporting the progress of the transfer.
+func (t *transfer) wrap(r io.Reader) io.Reader {
+	return &progressReader{reader: r, transfer: t}
+}
+
+type progressReader struct {
+	reader   io.Reader
+	transfer *transfer
+}
+
+// Read reads from the underlying reader and reports the progress.
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+
+	r.transfer.done += int64(n)
+	if r.transfer.progress != nil {
+		r.transfer.progress(r.transfer.done, r.transfer.total)
+	}
+
+	return n, err
+}
+
+// ProgressStats returns the completion percentage and the estimated time left, in seconds, of a
+// transfer. The ETA is -1 when it can't be estimated yet.
+func ProgressStats(done int64, total int64, elapsed time.Duration) (float64, int) {
+	if total <= 0 {
+		return 0, -1
+	}
+
+	percentage := float64(done) * 100 / float64(total)
+	if done <= 0 || elapsed <= 0 {
+		return percentage, -1
+	}
+
+	rate := float64(done) / elapsed.Seconds()
+	if done >= total {
+		return 100, 0
+	}
+
+	return percentage, int(float64(total-done) / rate)
+}
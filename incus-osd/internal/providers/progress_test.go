@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressStats(t *testing.T) {
+	t.Parallel()
+
+	percentage, eta := ProgressStats(25, 100, 10*time.Second)
+	require.InDelta(t, 25.0, percentage, 0.001)
+	require.Equal(t, 30, eta)
+
+	percentage, eta = ProgressStats(100, 100, 10*time.Second)
+	require.InDelta(t, 100.0, percentage, 0.001)
+	require.Equal(t, 0, eta)
+
+	_, eta = ProgressStats(0, 100, 0)
+	require.Equal(t, -1, eta)
+
+	_, eta = ProgressStats(10, 0, time.Second)
+	require.Equal(t, -1, eta)
+}
+
+func TestTransfer(t *testing.T) {
+	t.Parallel()
+
+	reports := []int64{}
+	tr := newTransfer(8, func(done int64, total int64) {
+		require.Equal(t, int64(8), total)
+		reports = append(reports, done)
+	})
+
+	_, err := io.Copy(io.Discard, tr.wrap(bytes.NewReader([]byte("abcd"))))
+	require.NoError(t, err)
+
+	_, err = io.Copy(io.Discard, tr.wrap(bytes.NewReader([]byte("efgh"))))
+	require.NoError(t, err)
+
+	require.Equal(t, int64(8), reports[len(reports)-1])
+}
@@ -5,9 +5,11 @@ import (
 	"context"
 	"errors"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +27,8 @@ type github struct {
 
 	releaseLastCheck time.Time
 	releaseVersion   string
+	releaseNotes     ReleaseNotes
+	releasePublished time.Time
 	releaseAssets    []*ghapi.ReleaseAsset
 	releaseMu        sync.Mutex
 }
@@ -40,6 +44,18 @@ func (*github) Type() string {
 	return "github"
 }
 
+func (p *github) Channel() string {
+	return configChannel(p.config)
+}
+
+func (p *github) PinnedVersion() string {
+	return p.config["version"]
+}
+
+func (p *github) Config() map[string]string {
+	return p.config
+}
+
 func (p *github) GetOSUpdate(ctx context.Context) (OSUpdate, error) {
 	// Get latest release.
 	err := p.checkRelease(ctx)
@@ -64,9 +80,11 @@ func (p *github) GetOSUpdate(ctx context.Context) (OSUpdate, error) {
 
 	// Prepare the OS update struct.
 	update := githubOSUpdate{
-		provider: p,
-		assets:   p.releaseAssets,
-		version:  p.releaseVersion,
+		provider:  p,
+		assets:    p.releaseAssets,
+		version:   p.releaseVersion,
+		notes:     p.releaseNotes,
+		published: p.releasePublished,
 	}
 
 	return &update, nil
@@ -96,10 +114,11 @@ func (p *github) GetApplication(ctx context.Context, name string) (Application,
 
 	// Prepare the application struct.
 	app := githubApplication{
-		provider: p,
-		name:     name,
-		assets:   p.releaseAssets,
-		version:  p.releaseVersion,
+		provider:  p,
+		name:      name,
+		assets:    p.releaseAssets,
+		version:   p.releaseVersion,
+		published: p.releasePublished,
 	}
 
 	return &app, nil
@@ -135,10 +154,52 @@ func (p *github) checkRelease(ctx context.Context) error {
 		return nil
 	}
 
-	// Get the latest release.
-	release, _, err := p.gh.Repositories.GetLatestRelease(ctx, p.organization, p.repository)
-	if err != nil {
-		return p.checkLimit(err)
+	var release *ghapi.RepositoryRelease
+
+	if p.PinnedVersion() != "" {
+		// Look for the pinned release, whatever the update channel.
+		opts := &ghapi.ListOptions{PerPage: 100}
+
+		for release == nil {
+			releases, resp, err := p.gh.Repositories.ListReleases(ctx, p.organization, p.repository, opts)
+			if err != nil {
+				return p.checkLimit(err)
+			}
+
+			for _, entry := range releases {
+				if !entry.GetDraft() && entry.GetName() == p.PinnedVersion() {
+					release = entry
+
+					break
+				}
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+
+			opts.Page = resp.NextPage
+		}
+	} else {
+		// Get the latest release published to the update channel.
+		releases, _, err := p.gh.Repositories.ListReleases(ctx, p.organization, p.repository, nil)
+		if err != nil {
+			return p.checkLimit(err)
+		}
+
+		for _, entry := range releases {
+			if entry.GetDraft() || !releaseInChannel(p.Channel(), entry.GetPrerelease(), entry.GetTagName()) {
+				continue
+			}
+
+			if release == nil || datetimeComparison(entry.GetName(), release.GetName()) {
+				release = entry
+			}
+		}
+	}
+
+	if release == nil {
+		return ErrNoUpdateAvailable
 	}
 
 	// Get the list of files for the release.
@@ -150,27 +211,52 @@ func (p *github) checkRelease(ctx context.Context) error {
 	// Record the release.
 	p.releaseLastCheck = time.Now()
 	p.releaseVersion = release.GetName()
+	p.releaseNotes = parseReleaseNotes(release.GetBody())
+	p.releasePublished = release.GetPublishedAt().Time
 	p.releaseAssets = assets
 
 	return nil
 }
 
-func (p *github) downloadAsset(ctx context.Context, assetID int64, target string) error {
-	// Get a reader for the release asset.
-	rc, _, err := p.gh.Repositories.DownloadReleaseAsset(ctx, p.organization, p.repository, assetID, http.DefaultClient)
+func (p *github) downloadAsset(ctx context.Context, asset *ghapi.ReleaseAsset, target string, compressed bool, tr *transfer) error {
+	err := os.MkdirAll(DownloadCachePath, 0o700)
 	if err != nil {
-		return p.checkLimit(err)
+		return err
+	}
+
+	pruneDownloadCache()
+
+	// Download the release asset, resuming any previously interrupted download.
+	partial := filepath.Join(DownloadCachePath, strconv.FormatInt(asset.GetID(), 10)+"-"+asset.GetName()+".partial")
+
+	err = fetchURL(ctx, http.DefaultClient, asset.GetBrowserDownloadURL(), partial, tr, configRateLimit(p.config))
+	if err != nil {
+		return err
 	}
 
-	defer rc.Close()
+	defer os.Remove(partial)
 
-	// Setup a gzip reader to decompress during streaming.
-	body, err := gzip.NewReader(rc)
+	// #nosec G304
+	src, err := os.Open(partial)
 	if err != nil {
 		return err
 	}
 
-	defer body.Close()
+	defer src.Close()
+
+	var body io.Reader = src
+
+	// Setup a gzip reader to decompress the file.
+	if compressed {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+
+		defer gz.Close()
+
+		body = gz
+	}
 
 	// Create the target path.
 	// #nosec G304
@@ -181,7 +267,7 @@ func (p *github) downloadAsset(ctx context.Context, assetID int64, target string
 
 	defer fd.Close()
 
-	// Read from the decompressor in chunks to avoid excessive memory consumption.
+	// Copy in chunks to avoid excessive memory consumption.
 	for {
 		_, err = io.CopyN(fd, body, 4*1024*1024)
 		if err != nil {
@@ -203,6 +289,8 @@ type githubApplication struct {
 	assets  []*ghapi.ReleaseAsset
 	name    string
 	version string
+
+	published time.Time
 }
 
 func (a *githubApplication) Name() string {
@@ -217,13 +305,20 @@ func (a *githubApplication) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(a.version, otherVersion)
 }
 
-func (a *githubApplication) Download(ctx context.Context, target string) error {
+func (a *githubApplication) PublishedAt() time.Time {
+	return a.published
+}
+
+func (a *githubApplication) Download(ctx context.Context, target string, progress ProgressFunc) error {
 	// Create the target path.
 	err := os.MkdirAll(target, 0o700)
 	if err != nil {
 		return err
 	}
 
+	selected := []*ghapi.ReleaseAsset{}
+	total := int64(0)
+
 	for _, asset := range a.assets {
 		appName := strings.TrimSuffix(asset.GetName(), ".raw.gz")
 
@@ -232,8 +327,17 @@ func (a *githubApplication) Download(ctx context.Context, target string) error {
 			continue
 		}
 
+		selected = append(selected, asset)
+		total += int64(asset.GetSize())
+	}
+
+	tr := newTransfer(total, progress)
+
+	for _, asset := range selected {
+		fileName := strings.TrimSuffix(asset.GetName(), ".gz")
+
 		// Download the application.
-		err = a.provider.downloadAsset(ctx, asset.GetID(), filepath.Join(target, strings.TrimSuffix(asset.GetName(), ".gz")))
+		err = a.provider.downloadAsset(ctx, asset, filepath.Join(target, fileName), true, tr)
 		if err != nil {
 			return err
 		}
@@ -248,29 +352,54 @@ type githubOSUpdate struct {
 
 	assets  []*ghapi.ReleaseAsset
 	version string
+	notes   ReleaseNotes
+
+	published time.Time
 }
 
 func (o *githubOSUpdate) Version() string {
 	return o.version
 }
 
+func (o *githubOSUpdate) PublishedAt() time.Time {
+	return o.published
+}
+
+func (o *githubOSUpdate) ReleaseNotes() ReleaseNotes {
+	return o.notes
+}
+
 func (o *githubOSUpdate) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(o.version, otherVersion)
 }
 
-func (o *githubOSUpdate) Download(ctx context.Context, target string) error {
-	// Clear the target path.
-	err := os.RemoveAll(target)
+func (o *githubOSUpdate) Download(ctx context.Context, target string, progress ProgressFunc) error {
+	// Keep the files of the previous release aside, to apply deltas against.
+	previousPath := strings.TrimSuffix(target, "/") + ".previous"
+
+	err := os.RemoveAll(previousPath)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(target, previousPath)
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
+	defer func() { _ = os.RemoveAll(previousPath) }()
+
+	previousVersion, previousFiles := previousRelease(previousPath)
+
 	// Create the target path.
 	err = os.MkdirAll(target, 0o700)
 	if err != nil {
 		return err
 	}
 
+	selected := []*ghapi.ReleaseAsset{}
+	total := int64(0)
+
 	for _, asset := range o.assets {
 		// Only select OS files.
 		if !strings.HasPrefix(asset.GetName(), "IncusOS_") {
@@ -283,13 +412,80 @@ func (o *githubOSUpdate) Download(ctx context.Context, target string) error {
 			continue
 		}
 
-		// Skip the full image.
-		if fields[1] == "img.gz" || fields[1] == "iso.gz" {
+		// Skip the full image and the deltas.
+		if fields[1] == "img.gz" || fields[1] == "iso.gz" || isDeltaAsset(asset.GetName()) {
+			continue
+		}
+
+		selected = append(selected, asset)
+		total += int64(asset.GetSize())
+	}
+
+	tr := newTransfer(total, progress)
+
+	peers := configPeers(ctx, o.provider.config)
+	peerClient := &http.Client{}
+
+	if len(peers) > 0 {
+		peerClient, err = loadPeerClient()
+		if err != nil {
+			slog.Debug("Not fetching the update from peers", "err", err.Error())
+
+			peers = nil
+		}
+	}
+
+	fromPeers := []*ghapi.ReleaseAsset{}
+
+	for _, asset := range selected {
+		fileName := strings.TrimSuffix(asset.GetName(), ".gz")
+
+		// Prefer fetching the file from a peer.
+		if fetchFromPeers(ctx, peerClient, peers, o.version, fileName, filepath.Join(target, fileName), int64(asset.GetSize()), tr) {
+			fromPeers = append(fromPeers, asset)
+
+			continue
+		}
+
+		// Then a delta from the previous release, falling back to the full file.
+		if o.downloadDelta(ctx, fileName, previousVersion, previousFiles, target, tr) {
+			// Account for the full file not being downloaded.
+			tr.total -= int64(asset.GetSize())
+
 			continue
 		}
 
 		// Download the actual update.
-		err = o.provider.downloadAsset(ctx, asset.GetID(), filepath.Join(target, strings.TrimSuffix(asset.GetName(), ".gz")))
+		err = o.provider.downloadAsset(ctx, asset, filepath.Join(target, fileName), true, tr)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Only keep the files fetched from peers if the release is properly signed, downloading them
+	// from the provider otherwise.
+	if len(fromPeers) == 0 {
+		return nil
+	}
+
+	err = verifyPeerUpdate(ctx, o.provider.config, target, o.version)
+	if err == nil {
+		return nil
+	}
+
+	slog.Warn("Discarding the update files fetched from peers", "release", o.version, "err", err.Error())
+
+	for _, asset := range fromPeers {
+		fileName := strings.TrimSuffix(asset.GetName(), ".gz")
+
+		err = os.Remove(filepath.Join(target, fileName))
+		if err != nil {
+			return err
+		}
+
+		tr.total += int64(asset.GetSize())
+
+		err = o.provider.downloadAsset(ctx, asset, filepath.Join(target, fileName), true, tr)
 		if err != nil {
 			return err
 		}
@@ -297,3 +493,44 @@ func (o *githubOSUpdate) Download(ctx context.Context, target string) error {
 
 	return nil
 }
+
+// downloadDelta attempts to rebuild a file of the update from the matching file of the previous
+// release and a delta. Returns whether it succeeded.
+func (o *githubOSUpdate) downloadDelta(ctx context.Context, fileName string, previousVersion string, previousFiles map[string]string, target string, tr *transfer) bool {
+	base := previousFiles[fileKind(fileName)]
+	if previousVersion == "" || previousVersion == o.version || base == "" {
+		return false
+	}
+
+	deltaName := deltaAssetName(fileName, previousVersion)
+
+	for _, asset := range o.assets {
+		if asset.GetName() != deltaName {
+			continue
+		}
+
+		delta := filepath.Join(filepath.Dir(base), deltaName)
+
+		// Account for the delta being downloaded.
+		tr.total += int64(asset.GetSize())
+
+		err := o.provider.downloadAsset(ctx, asset, delta, false, tr)
+		if err == nil {
+			err = applyDelta(ctx, base, delta, filepath.Join(target, fileName))
+		}
+
+		_ = os.Remove(delta)
+
+		if err != nil {
+			slog.Warn("Failed to apply OS update delta, downloading the full file", "file", fileName, "err", err.Error())
+
+			return false
+		}
+
+		slog.Debug("Applied OS update delta", "file", fileName, "from", previousVersion)
+
+		return true
+	}
+
+	return false
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // The Local provider.
@@ -17,6 +18,9 @@ type local struct {
 
 	releaseAssets  []string
 	releaseVersion string
+	releaseNotes   ReleaseNotes
+
+	releasePublished time.Time
 }
 
 func (*local) ClearCache(_ context.Context) error {
@@ -28,6 +32,18 @@ func (*local) Type() string {
 	return "local"
 }
 
+func (p *local) Channel() string {
+	return configChannel(p.config)
+}
+
+func (p *local) PinnedVersion() string {
+	return p.config["version"]
+}
+
+func (p *local) Config() map[string]string {
+	return p.config
+}
+
 func (p *local) GetOSUpdate(ctx context.Context) (OSUpdate, error) {
 	// Get latest release.
 	err := p.checkRelease(ctx)
@@ -52,9 +68,11 @@ func (p *local) GetOSUpdate(ctx context.Context) (OSUpdate, error) {
 
 	// Prepare the OS update struct.
 	update := localOSUpdate{
-		provider: p,
-		assets:   p.releaseAssets,
-		version:  p.releaseVersion,
+		provider:  p,
+		assets:    p.releaseAssets,
+		version:   p.releaseVersion,
+		notes:     p.releaseNotes,
+		published: p.releasePublished,
 	}
 
 	return &update, nil
@@ -84,19 +102,31 @@ func (p *local) GetApplication(ctx context.Context, name string) (Application, e
 
 	// Prepare the application struct.
 	app := localApplication{
-		provider: p,
-		name:     name,
-		assets:   p.releaseAssets,
-		version:  p.releaseVersion,
+		provider:  p,
+		name:      name,
+		assets:    p.releaseAssets,
+		version:   p.releaseVersion,
+		published: p.releasePublished,
 	}
 
 	return &app, nil
 }
 
 func (p *local) load(_ context.Context) error {
-	// Use a hardcoded path for now.
+	// Use the provided path, such as an extracted update bundle.
+	if p.config["path"] != "" {
+		p.path = p.config["path"]
+
+		return nil
+	}
+
+	// Use a hardcoded path for now, with a sub-directory for each channel other than "stable".
 	p.path = "/root/updates/"
 
+	if p.Channel() != "stable" {
+		p.path = filepath.Join(p.path, p.Channel()) + "/"
+	}
+
 	return nil
 }
 
@@ -119,6 +149,28 @@ func (p *local) checkRelease(_ context.Context) error {
 
 	p.releaseVersion = strings.TrimSpace(string(body))
 
+	// The release is considered published when its RELEASE file was written.
+	fi, err := os.Stat(filepath.Join(p.path, "RELEASE"))
+	if err != nil {
+		return err
+	}
+
+	p.releasePublished = fi.ModTime()
+
+	// Only offer the pinned release.
+	if p.PinnedVersion() != "" && p.releaseVersion != p.PinnedVersion() {
+		return ErrNoUpdateAvailable
+	}
+
+	// Parse the optional release notes.
+	// #nosec G304
+	body, err = os.ReadFile(filepath.Join(p.path, "CHANGELOG"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	p.releaseNotes = parseReleaseNotes(string(body))
+
 	// Build asset list.
 	assets := []string{}
 
@@ -136,7 +188,7 @@ func (p *local) checkRelease(_ context.Context) error {
 	return nil
 }
 
-func (p *local) copyAsset(_ context.Context, name string, target string) error {
+func (p *local) copyAsset(_ context.Context, name string, target string, tr *transfer) error {
 	// Open the source.
 	// #nosec G304
 	src, err := os.Open(filepath.Join(p.path, name))
@@ -156,8 +208,10 @@ func (p *local) copyAsset(_ context.Context, name string, target string) error {
 	defer dst.Close()
 
 	// Copy the content.
+	body := tr.wrap(src)
+
 	for {
-		_, err := io.CopyN(dst, src, 4*1024*1024)
+		_, err := io.CopyN(dst, body, 4*1024*1024)
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				break
@@ -177,6 +231,8 @@ type localApplication struct {
 	assets  []string
 	name    string
 	version string
+
+	published time.Time
 }
 
 func (a *localApplication) Name() string {
@@ -191,13 +247,19 @@ func (a *localApplication) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(a.version, otherVersion)
 }
 
-func (a *localApplication) Download(ctx context.Context, target string) error {
+func (a *localApplication) PublishedAt() time.Time {
+	return a.published
+}
+
+func (a *localApplication) Download(ctx context.Context, target string, progress ProgressFunc) error {
 	// Create the target path.
 	err := os.MkdirAll(target, 0o700)
 	if err != nil {
 		return err
 	}
 
+	selected := []string{}
+
 	for _, asset := range a.assets {
 		appName := strings.TrimSuffix(filepath.Base(asset), ".raw")
 
@@ -206,8 +268,14 @@ func (a *localApplication) Download(ctx context.Context, target string) error {
 			continue
 		}
 
+		selected = append(selected, asset)
+	}
+
+	tr := newTransfer(assetsSize(selected), progress)
+
+	for _, asset := range selected {
 		// Copy the application.
-		err = a.provider.copyAsset(ctx, filepath.Base(asset), target)
+		err = a.provider.copyAsset(ctx, filepath.Base(asset), target, tr)
 		if err != nil {
 			return err
 		}
@@ -222,17 +290,28 @@ type localOSUpdate struct {
 
 	assets  []string
 	version string
+	notes   ReleaseNotes
+
+	published time.Time
 }
 
 func (o *localOSUpdate) Version() string {
 	return o.version
 }
 
+func (o *localOSUpdate) PublishedAt() time.Time {
+	return o.published
+}
+
+func (o *localOSUpdate) ReleaseNotes() ReleaseNotes {
+	return o.notes
+}
+
 func (o *localOSUpdate) IsNewerThan(otherVersion string) bool {
 	return datetimeComparison(o.version, otherVersion)
 }
 
-func (o *localOSUpdate) Download(ctx context.Context, target string) error {
+func (o *localOSUpdate) Download(ctx context.Context, target string, progress ProgressFunc) error {
 	// Clear the path.
 	err := os.RemoveAll(target)
 	if err != nil && !os.IsNotExist(err) {
@@ -245,6 +324,8 @@ func (o *localOSUpdate) Download(ctx context.Context, target string) error {
 		return err
 	}
 
+	selected := []string{}
+
 	for _, asset := range o.assets {
 		// Only select OS files.
 		if !strings.HasPrefix(filepath.Base(asset), "IncusOS_") {
@@ -262,8 +343,14 @@ func (o *localOSUpdate) Download(ctx context.Context, target string) error {
 			continue
 		}
 
+		selected = append(selected, asset)
+	}
+
+	tr := newTransfer(assetsSize(selected), progress)
+
+	for _, asset := range selected {
 		// Download the actual update.
-		err = o.provider.copyAsset(ctx, filepath.Base(asset), target)
+		err = o.provider.copyAsset(ctx, filepath.Base(asset), target, tr)
 		if err != nil {
 			return err
 		}
@@ -271,3 +358,19 @@ func (o *localOSUpdate) Download(ctx context.Context, target string) error {
 
 	return nil
 }
+
+// assetsSize returns the combined size of the provided files.
+func assetsSize(assets []string) int64 {
+	total := int64(0)
+
+	for _, asset := range assets {
+		fi, err := os.Stat(asset)
+		if err != nil {
+			continue
+		}
+
+		total += fi.Size()
+	}
+
+	return total
+}
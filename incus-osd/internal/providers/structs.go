@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"strconv"
+	"time"
 )
 
 // Application represents an application to be installed on top of Incus OS.
@@ -10,16 +11,19 @@ type Application interface {
 	Name() string
 	Version() string
 	IsNewerThan(otherVersion string) bool
+	PublishedAt() time.Time
 
-	Download(ctx context.Context, targetPath string) error
+	Download(ctx context.Context, targetPath string, progress ProgressFunc) error
 }
 
 // OSUpdate represents a full OS update.
 type OSUpdate interface {
 	Version() string
 	IsNewerThan(otherVersion string) bool
+	PublishedAt() time.Time
+	ReleaseNotes() ReleaseNotes
 
-	Download(ctx context.Context, targetPath string) error
+	Download(ctx context.Context, targetPath string, progress ProgressFunc) error
 }
 
 // Provider represents an update/application provider.
@@ -27,6 +31,9 @@ type Provider interface {
 	ClearCache(ctx context.Context) error
 
 	Type() string
+	Channel() string
+	PinnedVersion() string
+	Config() map[string]string
 
 	GetOSUpdate(ctx context.Context) (OSUpdate, error)
 	GetApplication(ctx context.Context, name string) (Application, error)
@@ -1,10 +1,13 @@
 package rest
 
 import (
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
 	"github.com/lxc/incus-os/incus-osd/internal/systemd"
 )
@@ -53,3 +56,187 @@ func (s *Server) apiSystemEncryption(w http.ResponseWriter, r *http.Request) {
 
 	_ = s.state.Save(r.Context())
 }
+
+func (s *Server) apiSystemEncryptionKeySlots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the key slots of all encrypted volumes.
+		slots, err := systemd.GetKeySlots(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		_ = response.SyncResponse(true, slots).Render(w)
+	case http.MethodPost:
+		// Add a key slot.
+		req := &api.SystemEncryptionKeySlotPost{}
+
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		key, err := systemd.AddKeySlot(r.Context(), s.state, *req)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Return the generated recovery key, if any.
+		_ = response.SyncResponse(true, map[string]string{"recovery_key": key}).Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+func (s *Server) apiSystemEncryptionKeySlotsEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodDelete {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	slot, err := strconv.Atoi(r.PathValue("slot"))
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// Remove the key slot.
+	err = systemd.DeleteKeySlot(r.Context(), s.state, r.PathValue("volume"), slot)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+
+	_ = s.state.Save(r.Context())
+}
+
+func (s *Server) apiSystemEncryptionReencrypt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the progress of the last re-encryption.
+		_ = response.SyncResponse(true, s.state.System.Encryption.State.Reencryption).Render(w)
+	case http.MethodPost:
+		// Start re-encrypting a volume with a new volume key.
+		req := &api.SystemEncryptionReencryptionPost{}
+
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = systemd.StartReencryption(r.Context(), s.state, req.Volume)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+func (s *Server) apiSystemEncryptionRotate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// Replace the recovery key, the new key is only ever returned here.
+	key, err := systemd.RotateRecoveryKey(r.Context(), s.state)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, map[string]string{"recovery_key": key}).Render(w)
+
+	_ = s.state.Save(r.Context())
+}
+
+func (s *Server) apiSystemEncryptionTang(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the current Tang binding, if any.
+		_ = response.SyncResponse(true, s.state.System.Encryption.Config.Tang).Render(w)
+	case http.MethodPut:
+		// Replace the Tang binding.
+		newConfig := &api.SystemEncryptionTang{}
+
+		err := json.NewDecoder(r.Body).Decode(newConfig)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = systemd.ValidateTang(*newConfig)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = systemd.ApplyTang(r.Context(), s.state, newConfig)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Encryption.Config.Tang = newConfig
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+	case http.MethodDelete:
+		// Remove the Tang binding.
+		err := systemd.ApplyTang(r.Context(), s.state, nil)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Encryption.Config.Tang = nil
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
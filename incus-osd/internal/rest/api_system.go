@@ -32,9 +32,9 @@ func (s *Server) apiSystem(w http.ResponseWriter, r *http.Request) {
 
 	switch req.Action {
 	case "shutdown", "poweroff":
-		close(s.state.TriggerShutdown)
+		s.state.RequestShutdown()
 	case "reboot":
-		close(s.state.TriggerReboot)
+		s.state.RequestReboot()
 	case "update":
 		s.state.TriggerUpdate <- true
 	default:
@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/backup"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+func (s *Server) apiSystemBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Content-Type", "application/json")
+
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := &api.SystemBackupPost{}
+
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	data, err := backup.Create(r.Context(), s.state, req.Passphrase)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// Without a target, return the encrypted archive directly.
+	if req.Target == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", "attachment; filename=incus-os-backup.bin")
+
+		_, _ = w.Write(data)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	err = backup.Upload(r.Context(), req.Target, data)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
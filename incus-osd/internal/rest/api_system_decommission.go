@@ -0,0 +1,105 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// decommissionTimeout is how long a decommission token remains valid.
+const decommissionTimeout = 5 * time.Minute
+
+func (s *Server) apiSystemDecommission(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := &api.SystemDecommissionPost{}
+
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	s.decommissionLock.Lock()
+	defer s.decommissionLock.Unlock()
+
+	// Without a token, issue a new one which must be passed back to confirm.
+	if req.Token == "" {
+		rawToken := make([]byte, 16)
+
+		_, err := rand.Read(rawToken)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.decommission = &api.SystemDecommission{
+			Token:   hex.EncodeToString(rawToken),
+			Expires: time.Now().Add(decommissionTimeout),
+		}
+
+		_ = response.SyncResponse(true, s.decommission).Render(w)
+
+		return
+	}
+
+	if s.decommission == nil || time.Now().After(s.decommission.Expires) || subtle.ConstantTimeCompare([]byte(req.Token), []byte(s.decommission.Token)) != 1 {
+		_ = response.BadRequest(errors.New("invalid or expired decommission token")).Render(w)
+
+		return
+	}
+
+	s.decommission = nil
+
+	_ = response.EmptySyncResponse.Render(w)
+
+	// The request context ends with the response, so decommission in the background.
+	go decommissionSystem(context.Background())
+}
+
+// decommissionSystem destroys all data on the system, then powers it off. The encryption headers
+// are destroyed first, as the data becomes unrecoverable right away and this is the quickest step.
+func decommissionSystem(ctx context.Context) {
+	slog.Warn("Decommissioning the system, all data will be destroyed")
+
+	err := systemd.EraseEncryptedVolumes(ctx)
+	if err != nil {
+		slog.Error("Failed to erase the encrypted volumes", "err", err.Error())
+	}
+
+	err = systemd.ClearTPM()
+	if err != nil {
+		slog.Error("Failed to request a TPM clear", "err", err.Error())
+	}
+
+	err = storage.SecureEraseDrives(ctx)
+	if err != nil {
+		slog.Error("Failed to erase drives", "err", err.Error())
+	}
+
+	// The boot disk is gone, so power off without running the usual shutdown.
+	err = systemd.SystemPowerOffImmediate()
+	if err != nil {
+		slog.Error("Failed to power off the system", "err", err.Error())
+	}
+}
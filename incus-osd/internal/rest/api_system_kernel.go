@@ -0,0 +1,64 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+func (s *Server) apiSystemKernel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		active, err := systemd.ActiveKernelParameters(s.state.System.Kernel.Config.Parameters)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Return the current kernel configuration and the parameters in effect.
+		ret := s.state.System.Kernel
+		ret.State.Active = active
+
+		_ = response.SyncResponse(true, ret).Render(w)
+	case http.MethodPut:
+		// Replace the kernel configuration.
+		newConfig := &api.SystemKernel{}
+
+		err := json.NewDecoder(r.Body).Decode(newConfig)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = systemd.ValidateKernelParameters(newConfig.Config.Parameters)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = systemd.ApplyKernelParameters(r.Context(), newConfig.Config.Parameters)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Kernel.Config = newConfig.Config
+		s.state.System.Kernel.State.RebootRequired = true
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
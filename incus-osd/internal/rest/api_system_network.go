@@ -1,9 +1,13 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/lxc/incus-os/incus-osd/api"
@@ -15,14 +19,51 @@ import (
 func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
+	s.state.NetworkLock.Lock()
+	defer s.state.NetworkLock.Unlock()
+
 	switch r.Method {
 	case http.MethodGet:
-		// Return the current network configuration.
-		_ = response.SyncResponse(true, s.state.System.Network).Render(w)
+		// Refresh the runtime network state.
+		err := systemd.UpdateNetworkState(r.Context(), &s.state.System.Network)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Return the current network configuration and state, without the secrets.
+		ret := s.state.System.Network
+		ret.Config = systemd.RedactNetworkSecrets(ret.Config)
+
+		_ = response.SyncResponse(true, ret).Render(w)
 	case http.MethodPatch, http.MethodPut:
 		// Apply an update or completely replace the network configuration.
 		newConfig := &api.SystemNetwork{}
 
+		// If requested, the change must be confirmed within the given number of seconds or it will be reverted.
+		confirmTimeout := 0
+		if r.URL.Query().Get("confirm_timeout") != "" {
+			var err error
+
+			confirmTimeout, err = strconv.Atoi(r.URL.Query().Get("confirm_timeout"))
+			if err != nil || confirmTimeout <= 0 {
+				_ = response.BadRequest(errors.New("invalid confirmation timeout")).Render(w)
+
+				return
+			}
+		}
+
+		s.networkConfirmLock.Lock()
+		pending := s.networkConfirm != nil
+		s.networkConfirmLock.Unlock()
+
+		if pending {
+			_ = response.BadRequest(errors.New("a network configuration change is already pending confirmation")).Render(w)
+
+			return
+		}
+
 		// If updating, grab the current configuration.
 		if r.Method == http.MethodPatch {
 			// We make a copy of the current network configuration so we don't corrupt
@@ -50,6 +91,9 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Keep the current secrets which were omitted or sent back redacted.
+		systemd.RestoreNetworkSecrets(newConfig.Config, s.state.System.Network.Config)
+
 		// Don't allow a new configuration that doesn't define any interfaces, bonds, or vlans.
 		if seed.NetworkConfigHasEmptyDevices(*newConfig.Config) {
 			_ = response.BadRequest(errors.New("network configuration has no devices defined")).Render(w)
@@ -57,8 +101,43 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Apply the updated configuration.
+		// Check the configuration is consistent, reporting every offending field.
+		err = systemd.ValidateNetworkConfiguration(*newConfig.Config)
+		if err != nil {
+			var validationErr *systemd.NetworkConfigValidationError
+			if errors.As(err, &validationErr) {
+				_ = response.BadRequestWithMetadata(err, validationErr.Errors).Render(w)
+
+				return
+			}
+
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// If requested, only return the configuration files that would be generated.
+		if r.URL.Query().Get("dry-run") == "true" {
+			files, err := systemd.RenderNetworkConfiguration(newConfig.Config)
+			if err != nil {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+
+			systemd.RedactNetworkFiles(files, newConfig.Config)
+
+			_ = response.SyncResponse(true, files).Render(w)
+
+			return
+		}
+
+		// Apply the updated configuration, making sure the watchdog doesn't act on the replaced one.
+		systemd.StopNetworkWatchdog()
+
+		oldConfig := s.state.System.Network.Config
 		s.state.System.Network.Config = newConfig.Config
+		s.state.System.Network.State.Warning = ""
 		err = systemd.ApplyNetworkConfiguration(r.Context(), s.state.System.Network.Config, 30*time.Second)
 		if err != nil {
 			_ = response.BadRequest(err).Render(w)
@@ -66,6 +145,23 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Only persist the new configuration once confirmed.
+		if confirmTimeout > 0 {
+			s.waitForNetworkConfirmation(oldConfig, time.Duration(confirmTimeout)*time.Second, r.URL.Query().Get("probe"))
+
+			_ = response.EmptySyncResponse.Render(w)
+
+			return
+		}
+
+		// Revert to the last known good configuration if the uplinks don't come online.
+		systemd.StartNetworkWatchdog(s.state, oldConfig)
+
+		// If requested, check that packets of the configured MTU actually pass.
+		if r.URL.Query().Get("check_mtu") == "true" {
+			s.checkNetworkMTU(r.Context())
+		}
+
 		_ = response.EmptySyncResponse.Render(w)
 
 		_ = s.state.Save(r.Context())
@@ -74,3 +170,203 @@ func (s *Server) apiSystemNetwork(w http.ResponseWriter, r *http.Request) {
 		_ = response.NotImplemented(nil).Render(w)
 	}
 }
+
+func (s *Server) apiSystemNetworkConfirm(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	s.networkConfirmLock.Lock()
+	defer s.networkConfirmLock.Unlock()
+
+	if s.networkConfirm == nil {
+		_ = response.BadRequest(errors.New("no network configuration change is pending confirmation")).Render(w)
+
+		return
+	}
+
+	close(s.networkConfirm)
+	s.networkConfirm = nil
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+func (s *Server) apiSystemNetworkLLDP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	neighbors, err := systemd.GetLLDPNeighbors(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, neighbors).Render(w)
+}
+
+func (s *Server) apiSystemNetworkMTU(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	s.state.NetworkLock.Lock()
+	networkCfg := s.state.System.Network.Config
+	s.state.NetworkLock.Unlock()
+
+	checks, err := systemd.CheckNetworkMTU(r.Context(), networkCfg)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, checks).Render(w)
+}
+
+func (s *Server) apiSystemNetworkState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	s.state.NetworkLock.Lock()
+	networkCfg := s.state.System.Network.Config
+	s.state.NetworkLock.Unlock()
+
+	devices, err := systemd.GetNetworkDeviceState(r.Context(), networkCfg)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, devices).Render(w)
+}
+
+// checkNetworkMTU validates the path MTU of the applied network configuration, recording a warning on failure.
+func (s *Server) checkNetworkMTU(ctx context.Context) {
+	checks, err := systemd.CheckNetworkMTU(ctx, s.state.System.Network.Config)
+	if err != nil {
+		slog.Warn("Failed to check the network MTU", "err", err)
+
+		return
+	}
+
+	for _, check := range checks {
+		if !check.Success {
+			slog.Warn("Packets of the configured MTU don't reach the target", "target", check.Target, "device", check.Device, "mtu", check.MTU, "err", check.Error)
+
+			s.state.System.Network.State.Warning = fmt.Sprintf("Packets of MTU %d on %q don't reach %q", check.MTU, check.Device, check.Target)
+		}
+	}
+}
+
+// waitForNetworkConfirmation waits in the background for a network configuration change to be confirmed.
+// If not confirmed in time, or if the optional probe target becomes unreachable, the previous
+// configuration is restored. Must be called with the network lock held.
+func (s *Server) waitForNetworkConfirmation(oldConfig *api.SystemNetworkConfig, timeout time.Duration, probe string) {
+	confirm := make(chan struct{})
+	newConfig := s.state.System.Network.Config
+
+	s.networkConfirmLock.Lock()
+	s.networkConfirm = confirm
+	s.networkConfirmLock.Unlock()
+
+	// Until confirmed, the last known good configuration is the one brought up on the next boot.
+	if s.state.System.NetworkLastKnownGood == nil {
+		s.state.System.NetworkLastKnownGood = oldConfig
+	}
+
+	s.state.System.NetworkUnconfirmed = true
+
+	go func() {
+		ctx := context.Background()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+
+		rollback := false
+
+	wait:
+		for {
+			select {
+			case <-confirm:
+				break wait
+			case <-timer.C:
+				slog.Warn("Network configuration change wasn't confirmed in time")
+
+				rollback = true
+
+				break wait
+			case <-ticker.C:
+				if probe == "" {
+					continue
+				}
+
+				err := systemd.CheckConnectivity(ctx, probe, 3*time.Second)
+				if err != nil {
+					slog.Warn("Lost connectivity to network probe target", "target", probe, "err", err)
+
+					rollback = true
+
+					break wait
+				}
+			}
+		}
+
+		s.state.NetworkLock.Lock()
+		defer s.state.NetworkLock.Unlock()
+
+		// The change may have been confirmed while waiting for the lock.
+		if rollback {
+			s.networkConfirmLock.Lock()
+			rollback = s.networkConfirm == confirm
+			if rollback {
+				s.networkConfirm = nil
+			}
+
+			s.networkConfirmLock.Unlock()
+		}
+
+		s.state.System.NetworkUnconfirmed = false
+
+		if rollback {
+			slog.Info("Restoring previous network configuration")
+
+			s.state.System.Network.Config = oldConfig
+
+			err := systemd.ApplyNetworkConfiguration(ctx, oldConfig, 30*time.Second)
+			if err != nil {
+				slog.Error("Failed to restore previous network configuration", "err", err)
+			}
+		} else {
+			s.state.System.NetworkLastKnownGood = newConfig
+		}
+
+		_ = s.state.Save(ctx)
+	}()
+}
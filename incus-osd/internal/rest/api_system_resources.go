@@ -0,0 +1,63 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+func (s *Server) apiSystemResources(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		swap, err := systemd.GetSwapDevices()
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Return the current resources configuration and swap state.
+		ret := s.state.System.Resources
+		ret.State.Swap = swap
+
+		_ = response.SyncResponse(true, ret).Render(w)
+	case http.MethodPut:
+		// Replace the resources configuration.
+		newConfig := &api.SystemResources{}
+
+		err := json.NewDecoder(r.Body).Decode(newConfig)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = systemd.ValidateResources(newConfig.Config)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = systemd.ApplyResources(r.Context(), newConfig.Config)
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Resources.Config = newConfig.Config
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
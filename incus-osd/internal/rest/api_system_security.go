@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/firewall"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+)
+
+func (s *Server) apiSystemSecurity(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the current security configuration.
+		_ = response.SyncResponse(true, s.state.System.Security).Render(w)
+	case http.MethodPut:
+		// Replace the security configuration.
+		newConfig := &api.SystemSecurity{}
+
+		err := json.NewDecoder(r.Body).Decode(newConfig)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// Apply the updated firewall rules.
+		err = firewall.ApplyFirewall(r.Context(), newConfig.Config.Firewall)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		newConfig.State = s.state.System.Security.State
+		s.state.System.Security = *newConfig
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
@@ -0,0 +1,222 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/secureboot"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+func (s *Server) apiSystemSecuritySecureBoot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	status, err := secureboot.GetStatus(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	status.Updates = s.state.System.Security.State.SecureBootUpdates
+	if status.Updates == nil {
+		status.Updates = []api.SystemSecuritySecureBootUpdate{}
+	}
+
+	_ = response.SyncResponse(true, status).Render(w)
+}
+
+func (s *Server) apiSystemSecuritySecureBootUpdates(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the Secure Boot key updates.
+		_ = response.SyncResponse(true, s.state.System.Security.State.SecureBootUpdates).Render(w)
+	case http.MethodPost:
+		// Stage a new Secure Boot key update.
+		req := &api.SystemSecuritySecureBootUpdatePost{}
+
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		update, err := secureboot.StageUpdate(r.Context(), req.Variable, req.Append, req.Data)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		for _, existing := range s.state.System.Security.State.SecureBootUpdates {
+			if existing.ID == update.ID {
+				_ = response.BadRequest(errors.New("the Secure Boot key update is already staged")).Render(w)
+
+				return
+			}
+		}
+
+		s.state.System.Security.State.SecureBootUpdates = append(s.state.System.Security.State.SecureBootUpdates, *update)
+
+		_ = response.SyncResponse(true, update).Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+func (s *Server) apiSystemSecuritySecureBootUpdatesEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	updates := s.state.System.Security.State.SecureBootUpdates
+
+	idx := slices.IndexFunc(updates, func(update api.SystemSecuritySecureBootUpdate) bool { return update.ID == r.PathValue("id") })
+	if idx < 0 {
+		_ = response.NotFound(nil).Render(w)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the Secure Boot key update.
+		_ = response.SyncResponse(true, updates[idx]).Render(w)
+	case http.MethodDelete:
+		// Drop the Secure Boot key update, only reverting the state tracking for applied ones.
+		err := secureboot.RemoveUpdate(r.Context(), updates[idx])
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Security.State.SecureBootUpdates = slices.Delete(updates, idx, idx+1)
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+func (s *Server) apiSystemSecuritySecureBootEnroll(w http.ResponseWriter, r *http.Request) {
+	s.applySecureBootUpdates(w, r, true)
+}
+
+func (s *Server) apiSystemSecuritySecureBootActivate(w http.ResponseWriter, r *http.Request) {
+	s.applySecureBootUpdates(w, r, false)
+}
+
+// applySecureBootUpdates applies the staged append (enroll) or replacement (activate) updates.
+func (s *Server) applySecureBootUpdates(w http.ResponseWriter, r *http.Request, appendWrite bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	updates := s.state.System.Security.State.SecureBootUpdates
+
+	// Select the staged updates, in the order in which they must be applied.
+	selected := []int{}
+
+	for _, variable := range secureboot.Variables {
+		for i, update := range updates {
+			if update.Status == "staged" && update.Append == appendWrite && update.Variable == variable {
+				selected = append(selected, i)
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		_ = response.BadRequest(errors.New("no staged Secure Boot key update to apply")).Render(w)
+
+		return
+	}
+
+	// Ensure the system will still boot before dropping any certificate.
+	if !appendWrite {
+		ukis, err := systemd.BootEntries()
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Include a downloaded update which hasn't been installed yet.
+		if s.state.OS.NextRelease != "" {
+			stagedUKI := filepath.Join(systemd.SystemUpdatesPath, "IncusOS_"+s.state.OS.NextRelease+".efi")
+
+			_, err := os.Stat(stagedUKI)
+			if err == nil {
+				ukis = append(ukis, stagedUKI)
+			}
+		}
+
+		components := secureboot.BootComponents(ukis...)
+
+		for _, i := range selected {
+			err = secureboot.CheckActivation(r.Context(), updates[i], components)
+			if err != nil {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+		}
+	}
+
+	status := "activated"
+	if appendWrite {
+		status = "enrolled"
+	}
+
+	var applyErr error
+
+	for _, i := range selected {
+		err := secureboot.ApplyUpdate(r.Context(), updates[i])
+		if err != nil {
+			updates[i].Status = "failed"
+			updates[i].Error = err.Error()
+			applyErr = fmt.Errorf("failed to apply the %s update %q: %w", updates[i].Variable, updates[i].ID, err)
+
+			break
+		}
+
+		updates[i].Status = status
+		updates[i].Error = ""
+	}
+
+	_ = s.state.Save(r.Context())
+
+	if applyErr != nil {
+		_ = response.InternalError(applyErr).Render(w)
+
+		return
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
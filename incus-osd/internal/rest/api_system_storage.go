@@ -0,0 +1,286 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/storage"
+	"github.com/lxc/incus-os/incus-osd/internal/zfs"
+)
+
+func (s *Server) apiSystemStorage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		drives, err := storage.GetDrives(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		pools, err := zfs.GetPools(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		// Return the stored configuration and maintenance state, along with the current drives and pools.
+		ret := s.state.System.Storage
+		ret.State.Drives = drives
+		ret.State.Pools = pools
+
+		_ = response.SyncResponse(true, ret).Render(w)
+	case http.MethodPut:
+		// Replace the storage configuration.
+		newConfig := &api.SystemStorage{}
+
+		err := json.NewDecoder(r.Body).Decode(newConfig)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = zfs.ValidateScrubConfig(newConfig.Config.Scrub)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = zfs.ValidateTrimConfig(newConfig.Config.Trim)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		s.state.System.Storage.Config = newConfig.Config
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+func (*Server) apiSystemStorageDrives(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// Return the full inventory of drives.
+	drives, err := storage.GetDrives(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, drives).Render(w)
+}
+
+func (*Server) apiSystemStorageDrivesLocate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPut {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := &api.SystemStorageDriveLocatePut{}
+
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// Blink (or stop blinking) the locate LED of the drive.
+	err = storage.SetDriveLocate(r.Context(), r.PathValue("name"), req.Enabled)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	_ = response.EmptySyncResponse.Render(w)
+}
+
+func (*Server) apiSystemStorageGrow(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// Grow the local storage if the boot disk was enlarged.
+	grown, err := storage.GrowLocalData(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, map[string]bool{"grown": grown}).Render(w)
+}
+
+func (*Server) apiSystemStoragePools(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Get the list of pools.
+		pools, err := zfs.GetPools(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		urls := []string{}
+		for _, pool := range pools {
+			urls = append(urls, "/1.0/system/storage/pools/"+pool.Name)
+		}
+
+		_ = response.SyncResponse(true, urls).Render(w)
+	case http.MethodPost:
+		// Create or import a pool.
+		req := &api.SystemStoragePoolPost{}
+
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		if req.Name == "" {
+			_ = response.BadRequest(errors.New("no pool name provided")).Render(w)
+
+			return
+		}
+
+		if req.Import {
+			err = zfs.ImportPool(r.Context(), req.Name, req.EncryptionKey)
+			if err != nil {
+				_ = response.BadRequest(err).Render(w)
+
+				return
+			}
+
+			_ = response.EmptySyncResponse.Render(w)
+
+			return
+		}
+
+		// Only allow creating pools on unused drives.
+		drives, err := storage.GetDrives(r.Context())
+		if err != nil {
+			_ = response.InternalError(err).Render(w)
+
+			return
+		}
+
+		devices := make([]string, 0, len(req.Devices))
+
+		for _, device := range req.Devices {
+			var found *api.SystemStorageDrive
+
+			for _, drive := range drives {
+				if device == drive.Device || (drive.ID != "" && device == drive.ID) {
+					found = &drive
+
+					break
+				}
+			}
+
+			if found == nil {
+				_ = response.BadRequest(fmt.Errorf("drive %q doesn't exist", device)).Render(w)
+
+				return
+			}
+
+			if found.InUse {
+				_ = response.BadRequest(fmt.Errorf("drive %q is already in use", device)).Render(w)
+
+				return
+			}
+
+			// Prefer stable device paths.
+			if found.ID != "" {
+				devices = append(devices, found.ID)
+			} else {
+				devices = append(devices, found.Device)
+			}
+		}
+
+		err = zfs.CreatePool(r.Context(), req.Name, req.Topology, devices)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		_ = response.EmptySyncResponse.Render(w)
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+func (*Server) apiSystemStoragePoolsEndpoint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		pool, err := zfs.GetPool(r.Context(), name)
+		if err != nil {
+			_ = response.NotFound(err).Render(w)
+
+			return
+		}
+
+		// The "local" pool key is exposed through the system encryption endpoint.
+		if name != zfs.LocalPoolName {
+			pool.EncryptionKey, err = zfs.GetPoolEncryptionKey(name)
+			if err != nil {
+				_ = response.InternalError(err).Render(w)
+
+				return
+			}
+		}
+
+		_ = response.SyncResponse(true, pool).Render(w)
+	case http.MethodDelete:
+		err := zfs.DestroyPool(r.Context(), name)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		_ = response.EmptySyncResponse.Render(w)
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
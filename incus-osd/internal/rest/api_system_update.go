@@ -0,0 +1,241 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/bundle"
+	"github.com/lxc/incus-os/incus-osd/internal/providers"
+	"github.com/lxc/incus-os/incus-osd/internal/rest/response"
+	"github.com/lxc/incus-os/incus-osd/internal/schedule"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+func (s *Server) apiSystemUpdate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		// Return the current update configuration.
+		_ = response.SyncResponse(true, s.state.System.Update).Render(w)
+	case http.MethodPut:
+		// Replace the update configuration.
+		newConfig := &api.SystemUpdate{}
+
+		err := json.NewDecoder(r.Body).Decode(newConfig)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = providers.ValidateChannel(newConfig.Config.Channel)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = providers.ValidateVersion(newConfig.Config.PinnedVersion)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		if newConfig.Config.DownloadRateLimit < 0 {
+			_ = response.BadRequest(errors.New("download rate limit can't be negative")).Render(w)
+
+			return
+		}
+
+		if newConfig.Config.ClusterReboot.MaxConcurrent < 0 {
+			_ = response.BadRequest(errors.New("maximum concurrent cluster reboots can't be negative")).Render(w)
+
+			return
+		}
+
+		if newConfig.Config.PeerCache.Port < 0 || newConfig.Config.PeerCache.Port > 65535 {
+			_ = response.BadRequest(errors.New("invalid peer cache port")).Render(w)
+
+			return
+		}
+
+		for _, peer := range newConfig.Config.PeerCache.Peers {
+			if peer == "" || strings.ContainsAny(peer, ",/") {
+				_ = response.BadRequest(fmt.Errorf("invalid peer address %q", peer)).Render(w)
+
+				return
+			}
+		}
+
+		err = schedule.ValidateWindows(newConfig.Config.MaintenanceWindows)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = schedule.ValidateRollout(newConfig.Config.Rollout)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = providers.ValidateChannel(newConfig.Config.Applications.Channel)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = schedule.ValidateFrequency(newConfig.Config.Applications.Frequency)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		err = schedule.ValidateWindows(newConfig.Config.Applications.MaintenanceWindows)
+		if err != nil {
+			_ = response.BadRequest(err).Render(w)
+
+			return
+		}
+
+		// The signing configuration can only be set through the seed.
+		newConfig.Config.Signing = s.state.System.Update.Config.Signing
+
+		providerChanged := newConfig.Config.Channel != s.state.System.Update.Config.Channel || newConfig.Config.PinnedVersion != s.state.System.Update.Config.PinnedVersion
+		s.state.System.Update.Config = newConfig.Config
+
+		_ = response.EmptySyncResponse.Render(w)
+
+		_ = s.state.Save(r.Context())
+
+		// Check for updates from the new channel or pinned release right away.
+		if providerChanged {
+			select {
+			case s.state.TriggerUpdate <- true:
+			default:
+			}
+		}
+	default:
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+	}
+}
+
+func (s *Server) apiSystemUpdateBundle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// Bundles are large, so don't time out while receiving one.
+	_ = http.NewResponseController(w).SetReadDeadline(time.Time{})
+
+	// Extract the update bundle from the request body.
+	path, err := bundle.NewExtractPath()
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	version, err := bundle.Extract(r.Body, path)
+	if err != nil {
+		_ = os.RemoveAll(path)
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// Apply the update in the background.
+	select {
+	case s.state.TriggerBundleUpdate <- path:
+	default:
+		_ = os.RemoveAll(path)
+		_ = response.BadRequest(errors.New("an update from a bundle is already pending")).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, map[string]string{"release": version}).Render(w)
+}
+
+func (s *Server) apiSystemUpdateSlots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodGet {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	// Return the state of the OS image slots.
+	slots, err := systemd.GetUpdateSlots(r.Context(), s.state.OS.RunningRelease)
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, slots).Render(w)
+}
+
+func (s *Server) apiSystemUpdateRollback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method != http.MethodPost {
+		// If none of the supported methods, return NotImplemented.
+		_ = response.NotImplemented(nil).Render(w)
+
+		return
+	}
+
+	req := &api.SystemUpdateRollbackPost{}
+
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// Switch the default boot entry back to the previous OS image.
+	previous, err := systemd.RollbackRelease(s.state.OS.RunningRelease)
+	if err != nil {
+		_ = response.BadRequest(err).Render(w)
+
+		return
+	}
+
+	// The running release is then considered as having failed to boot, so isn't installed again.
+	s.state.OS.NextRelease = s.state.OS.RunningRelease
+
+	err = s.state.Save(r.Context())
+	if err != nil {
+		_ = response.InternalError(err).Render(w)
+
+		return
+	}
+
+	_ = response.SyncResponse(true, map[string]string{"release": previous}).Render(w)
+
+	if req.Reboot {
+		s.state.RequestReboot()
+	}
+}
@@ -197,6 +197,12 @@ func BadRequest(err error) Response {
 	return &errorResponse{http.StatusBadRequest, err.Error()}
 }
 
+// BadRequestWithMetadata returns a bad request response (400) with the given error, along with
+// metadata describing the error in more detail.
+func BadRequestWithMetadata(err error, metadata any) Response {
+	return &errorMetadataResponse{errorResponse{http.StatusBadRequest, err.Error()}, metadata}
+}
+
 // Conflict returns a conflict response (409) with the given error.
 func Conflict(err error) Response {
 	message := "already exists"
@@ -289,6 +295,36 @@ func (r *errorResponse) Render(w http.ResponseWriter) error {
 	return nil
 }
 
+// Error response with metadata.
+type errorMetadataResponse struct {
+	errorResponse
+
+	metadata any // Metadata to return in the Metadata field of the response body.
+}
+
+func (r *errorMetadataResponse) Render(w http.ResponseWriter) error {
+	resp := api.ResponseRaw{
+		Type:     api.ErrorResponse,
+		Error:    r.msg,
+		Code:     r.code,
+		Metadata: r.metadata,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if w.Header().Get("Connection") != "keep-alive" {
+		w.WriteHeader(r.code)
+	}
+
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // FileResponseEntry represents a file response entry.
 type FileResponseEntry struct {
 	// Required.
@@ -6,8 +6,10 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/lxc/incus-os/incus-osd/api"
 	"github.com/lxc/incus-os/incus-osd/internal/state"
 )
 
@@ -15,6 +17,14 @@ import (
 type Server struct {
 	socketPath string
 	state      *state.State
+
+	// Pending network configuration change awaiting confirmation.
+	networkConfirm     chan struct{}
+	networkConfirmLock sync.Mutex
+
+	// Pending decommission awaiting confirmation.
+	decommission     *api.SystemDecommission
+	decommissionLock sync.Mutex
 }
 
 // NewServer returns a REST API server object.
@@ -53,8 +63,37 @@ func (s *Server) Serve(_ context.Context) error {
 	router.HandleFunc("/1.0/services", s.apiServices)
 	router.HandleFunc("/1.0/services/{name}", s.apiServicesEndpoint)
 	router.HandleFunc("/1.0/system", s.apiSystem)
+	router.HandleFunc("/1.0/system/backup", s.apiSystemBackup)
+	router.HandleFunc("/1.0/system/decommission", s.apiSystemDecommission)
 	router.HandleFunc("/1.0/system/encryption", s.apiSystemEncryption)
+	router.HandleFunc("/1.0/system/encryption/keyslots", s.apiSystemEncryptionKeySlots)
+	router.HandleFunc("/1.0/system/encryption/keyslots/{volume}/{slot}", s.apiSystemEncryptionKeySlotsEndpoint)
+	router.HandleFunc("/1.0/system/encryption/reencrypt", s.apiSystemEncryptionReencrypt)
+	router.HandleFunc("/1.0/system/encryption/rotate", s.apiSystemEncryptionRotate)
+	router.HandleFunc("/1.0/system/encryption/tang", s.apiSystemEncryptionTang)
+	router.HandleFunc("/1.0/system/kernel", s.apiSystemKernel)
 	router.HandleFunc("/1.0/system/network", s.apiSystemNetwork)
+	router.HandleFunc("/1.0/system/network/confirm", s.apiSystemNetworkConfirm)
+	router.HandleFunc("/1.0/system/network/lldp", s.apiSystemNetworkLLDP)
+	router.HandleFunc("/1.0/system/network/mtu", s.apiSystemNetworkMTU)
+	router.HandleFunc("/1.0/system/network/state", s.apiSystemNetworkState)
+	router.HandleFunc("/1.0/system/resources", s.apiSystemResources)
+	router.HandleFunc("/1.0/system/security", s.apiSystemSecurity)
+	router.HandleFunc("/1.0/system/security/secureboot", s.apiSystemSecuritySecureBoot)
+	router.HandleFunc("/1.0/system/security/secureboot/activate", s.apiSystemSecuritySecureBootActivate)
+	router.HandleFunc("/1.0/system/security/secureboot/enroll", s.apiSystemSecuritySecureBootEnroll)
+	router.HandleFunc("/1.0/system/security/secureboot/updates", s.apiSystemSecuritySecureBootUpdates)
+	router.HandleFunc("/1.0/system/security/secureboot/updates/{id}", s.apiSystemSecuritySecureBootUpdatesEndpoint)
+	router.HandleFunc("/1.0/system/storage", s.apiSystemStorage)
+	router.HandleFunc("/1.0/system/storage/drives", s.apiSystemStorageDrives)
+	router.HandleFunc("/1.0/system/storage/drives/{name}/locate", s.apiSystemStorageDrivesLocate)
+	router.HandleFunc("/1.0/system/storage/grow", s.apiSystemStorageGrow)
+	router.HandleFunc("/1.0/system/storage/pools", s.apiSystemStoragePools)
+	router.HandleFunc("/1.0/system/storage/pools/{name}", s.apiSystemStoragePoolsEndpoint)
+	router.HandleFunc("/1.0/system/update", s.apiSystemUpdate)
+	router.HandleFunc("/1.0/system/update/bundle", s.apiSystemUpdateBundle)
+	router.HandleFunc("/1.0/system/update/rollback", s.apiSystemUpdateRollback)
+	router.HandleFunc("/1.0/system/update/slots", s.apiSystemUpdateSlots)
 
 	// Setup server.
 	server := &http.Server{
@@ -0,0 +1,3 @@
+// Package schedule handles the maintenance windows during which disruptive operations, such as
+// applying updates and rebooting, are allowed, as well as the staged rollout of new releases.
+package schedule
@@ -0,0 +1,33 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// minFrequency is the shortest allowed interval between update checks.
+const minFrequency = 5 * time.Minute
+
+// ValidateFrequency checks an update check frequency, a duration such as "1h" or "30m".
+func ValidateFrequency(frequency string) error {
+	if frequency == "" {
+		return nil
+	}
+
+	d, err := time.ParseDuration(frequency)
+	if err != nil || d < minFrequency {
+		return fmt.Errorf("invalid frequency %q, must be a duration of at least %s", frequency, minFrequency)
+	}
+
+	return nil
+}
+
+// Frequency returns the interval described by the frequency, or the fallback if unset or invalid.
+func Frequency(frequency string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(frequency)
+	if err != nil || d < minFrequency {
+		return fallback
+	}
+
+	return d
+}
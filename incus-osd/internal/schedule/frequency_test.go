@@ -0,0 +1,21 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrequency(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateFrequency(""))
+	require.NoError(t, ValidateFrequency("1h"))
+	require.Error(t, ValidateFrequency("1s"))
+	require.Error(t, ValidateFrequency("daily"))
+
+	require.Equal(t, time.Hour, Frequency("1h", 6*time.Hour))
+	require.Equal(t, 6*time.Hour, Frequency("", 6*time.Hour))
+	require.Equal(t, 6*time.Hour, Frequency("1s", 6*time.Hour))
+}
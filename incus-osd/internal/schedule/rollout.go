@@ -0,0 +1,55 @@
+package schedule
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// ValidateRollout checks the rollout policy.
+func ValidateRollout(rollout api.SystemUpdateRollout) error {
+	if rollout.Cohort != "" && rollout.Cohort != "canary" && rollout.Cohort != "main" {
+		return fmt.Errorf("invalid rollout cohort %q", rollout.Cohort)
+	}
+
+	if rollout.CanaryPercentage < 0 || rollout.CanaryPercentage > 100 {
+		return fmt.Errorf("invalid canary percentage %d", rollout.CanaryPercentage)
+	}
+
+	if rollout.Delay != "" {
+		d, err := time.ParseDuration(rollout.Delay)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid rollout delay %q", rollout.Delay)
+		}
+	}
+
+	return nil
+}
+
+// IsCanary returns whether the system is part of the canary cohort, either explicitly or through
+// its machine ID falling within the canary percentage. The selection is stable across releases.
+func IsCanary(rollout api.SystemUpdateRollout, machineID string) bool {
+	switch rollout.Cohort {
+	case "canary":
+		return true
+	case "main":
+		return false
+	}
+
+	hash := sha256.Sum256([]byte(machineID))
+
+	return binary.BigEndian.Uint64(hash[:8])%100 < uint64(rollout.CanaryPercentage) //nolint:gosec
+}
+
+// RolloutTime returns when a release published at the provided time may be applied to the system.
+func RolloutTime(rollout api.SystemUpdateRollout, machineID string, published time.Time) time.Time {
+	delay, err := time.ParseDuration(rollout.Delay)
+	if err != nil || published.IsZero() || IsCanary(rollout, machineID) {
+		return published
+	}
+
+	return published.Add(delay)
+}
@@ -0,0 +1,52 @@
+package schedule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestValidateRollout(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateRollout(api.SystemUpdateRollout{}))
+	require.NoError(t, ValidateRollout(api.SystemUpdateRollout{Cohort: "canary", CanaryPercentage: 5, Delay: "48h"}))
+	require.Error(t, ValidateRollout(api.SystemUpdateRollout{Cohort: "early"}))
+	require.Error(t, ValidateRollout(api.SystemUpdateRollout{CanaryPercentage: 101}))
+	require.Error(t, ValidateRollout(api.SystemUpdateRollout{Delay: "two days"}))
+}
+
+func TestIsCanary(t *testing.T) {
+	t.Parallel()
+
+	canaries := 0
+
+	for i := range 1000 {
+		machineID := fmt.Sprintf("%032x", i)
+
+		if IsCanary(api.SystemUpdateRollout{CanaryPercentage: 5}, machineID) {
+			canaries++
+		}
+
+		require.True(t, IsCanary(api.SystemUpdateRollout{Cohort: "canary"}, machineID))
+		require.False(t, IsCanary(api.SystemUpdateRollout{Cohort: "main", CanaryPercentage: 100}, machineID))
+		require.False(t, IsCanary(api.SystemUpdateRollout{}, machineID))
+	}
+
+	require.InDelta(t, 50, canaries, 25)
+}
+
+func TestRolloutTime(t *testing.T) {
+	t.Parallel()
+
+	published := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	require.Equal(t, published, RolloutTime(api.SystemUpdateRollout{}, "id", published))
+	require.Equal(t, published, RolloutTime(api.SystemUpdateRollout{Cohort: "canary", Delay: "48h"}, "id", published))
+	require.Equal(t, published.Add(48*time.Hour), RolloutTime(api.SystemUpdateRollout{Cohort: "main", Delay: "48h"}, "id", published))
+	require.True(t, RolloutTime(api.SystemUpdateRollout{Cohort: "main", Delay: "48h"}, "id", time.Time{}).IsZero())
+}
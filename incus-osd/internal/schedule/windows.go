@@ -0,0 +1,102 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zone database, in case the system lacks one.
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+var weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// ValidateWindows checks the maintenance windows.
+func ValidateWindows(windows []api.SystemUpdateMaintenanceWindow) error {
+	for _, window := range windows {
+		for _, day := range window.Days {
+			if !slices.Contains(weekdays, strings.ToLower(day)) {
+				return fmt.Errorf("invalid day %q", day)
+			}
+		}
+
+		if window.Start == "" || window.End == "" {
+			return errors.New("both the start and end of the window must be provided")
+		}
+
+		for _, value := range []string{window.Start, window.End} {
+			_, err := time.Parse("15:04", value)
+			if err != nil {
+				return fmt.Errorf("invalid window time %q", value)
+			}
+		}
+
+		_, err := time.LoadLocation(window.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone %q", window.Timezone)
+		}
+	}
+
+	return nil
+}
+
+// InWindow returns whether the time falls within any of the maintenance windows. A window spanning
+// midnight belongs to the day it starts on.
+func InWindow(windows []api.SystemUpdateMaintenanceWindow, now time.Time) bool {
+	for _, window := range windows {
+		if inWindow(window, now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func inWindow(window api.SystemUpdateMaintenanceWindow, now time.Time) bool {
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		return false
+	}
+
+	start, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return false
+	}
+
+	end, err := time.Parse("15:04", window.End)
+	if err != nil {
+		return false
+	}
+
+	now = now.In(loc)
+	current := now.Hour()*60 + now.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	day := now.Weekday()
+
+	if startMinutes <= endMinutes {
+		if current < startMinutes || current >= endMinutes {
+			return false
+		}
+	} else {
+		switch {
+		case current >= startMinutes:
+		case current < endMinutes:
+			// Past midnight, the window started the previous day.
+			day = (day + 6) % 7
+		default:
+			return false
+		}
+	}
+
+	if len(window.Days) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(window.Days, func(entry string) bool {
+		return strings.ToLower(entry) == weekdays[day]
+	})
+}
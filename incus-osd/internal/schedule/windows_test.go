@@ -0,0 +1,41 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestValidateWindows(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateWindows(nil))
+	require.NoError(t, ValidateWindows([]api.SystemUpdateMaintenanceWindow{{Days: []string{"Saturday"}, Start: "22:00", End: "04:00", Timezone: "UTC"}}))
+	require.Error(t, ValidateWindows([]api.SystemUpdateMaintenanceWindow{{Days: []string{"someday"}, Start: "22:00", End: "04:00"}}))
+	require.Error(t, ValidateWindows([]api.SystemUpdateMaintenanceWindow{{Start: "22:00"}}))
+	require.Error(t, ValidateWindows([]api.SystemUpdateMaintenanceWindow{{Start: "25:00", End: "04:00"}}))
+	require.Error(t, ValidateWindows([]api.SystemUpdateMaintenanceWindow{{Start: "22:00", End: "04:00", Timezone: "Nowhere/City"}}))
+}
+
+func TestInWindow(t *testing.T) {
+	t.Parallel()
+
+	windows := []api.SystemUpdateMaintenanceWindow{{Days: []string{"saturday"}, Start: "22:00", End: "04:00", Timezone: "UTC"}}
+
+	// Saturday 2025-06-07.
+	require.True(t, InWindow(windows, time.Date(2025, 6, 7, 23, 0, 0, 0, time.UTC)))
+	require.True(t, InWindow(windows, time.Date(2025, 6, 8, 3, 59, 0, 0, time.UTC)))
+	require.False(t, InWindow(windows, time.Date(2025, 6, 8, 4, 0, 0, 0, time.UTC)))
+	require.False(t, InWindow(windows, time.Date(2025, 6, 7, 3, 0, 0, 0, time.UTC)))
+	require.False(t, InWindow(windows, time.Date(2025, 6, 8, 23, 0, 0, 0, time.UTC)))
+
+	// The time zone of the window applies.
+	windows = []api.SystemUpdateMaintenanceWindow{{Start: "02:00", End: "03:00", Timezone: "America/New_York"}}
+	require.True(t, InWindow(windows, time.Date(2025, 6, 7, 6, 30, 0, 0, time.UTC)))
+	require.False(t, InWindow(windows, time.Date(2025, 6, 7, 2, 30, 0, 0, time.UTC)))
+
+	require.False(t, InWindow(nil, time.Now()))
+}
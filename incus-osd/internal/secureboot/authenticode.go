@@ -0,0 +1,90 @@
+package secureboot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// authenticodeHash returns the SHA256 Authenticode hash of a PE image, as listed in db and dbx.
+func authenticodeHash(data []byte) ([]byte, error) {
+	f, err := pe.NewFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	if len(data) < 0x40 {
+		return nil, errors.New("truncated PE image")
+	}
+
+	// Locate the optional header fields excluded from the hash.
+	optHeader := int(binary.LittleEndian.Uint32(data[0x3c:])) + 24
+	checksum := optHeader + 64
+
+	var certEntry int
+	var sizeOfHeaders uint32
+	var certDir pe.DataDirectory
+
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		certEntry = optHeader + 128
+		sizeOfHeaders = oh.SizeOfHeaders
+		certDir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+	case *pe.OptionalHeader64:
+		certEntry = optHeader + 144
+		sizeOfHeaders = oh.SizeOfHeaders
+		certDir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+	default:
+		return nil, errors.New("missing PE optional header")
+	}
+
+	if certEntry+8 > int(sizeOfHeaders) || int(sizeOfHeaders) > len(data) {
+		return nil, errors.New("invalid PE headers")
+	}
+
+	h := sha256.New()
+
+	// Hash the headers, skipping the checksum and the certificate table entry.
+	h.Write(data[:checksum])
+	h.Write(data[checksum+4 : certEntry])
+	h.Write(data[certEntry+8 : sizeOfHeaders])
+
+	// Hash the sections, in the order they appear in the file.
+	sections := make([]*pe.Section, 0, len(f.Sections))
+	for _, section := range f.Sections {
+		if section.Size > 0 {
+			sections = append(sections, section)
+		}
+	}
+
+	sort.Slice(sections, func(i int, j int) bool { return sections[i].Offset < sections[j].Offset })
+
+	hashed := uint64(sizeOfHeaders)
+
+	for _, section := range sections {
+		end := uint64(section.Offset) + uint64(section.Size)
+		if end > uint64(len(data)) {
+			return nil, errors.New("invalid PE section")
+		}
+
+		h.Write(data[section.Offset:end])
+		hashed = max(hashed, end)
+	}
+
+	// Hash any trailing data, excluding the certificate table.
+	end := uint64(len(data))
+	if certDir.Size > 0 && uint64(certDir.VirtualAddress) >= hashed && uint64(certDir.VirtualAddress) <= end {
+		end = uint64(certDir.VirtualAddress)
+	}
+
+	if hashed < end {
+		h.Write(data[hashed:end])
+	}
+
+	return h.Sum(nil), nil
+}
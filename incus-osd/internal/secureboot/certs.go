@@ -0,0 +1,130 @@
+package secureboot
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// EFIVarsPath is where the EFI variables are exposed.
+var EFIVarsPath = "/sys/firmware/efi/efivars"
+
+// efiGlobalVariable is the vendor GUID of the Secure Boot PK and KEK variables.
+const efiGlobalVariable = "8be4df61-93ca-11d2-aa0d-00e098032b8c"
+
+// efiImageSecurityDatabase is the vendor GUID of the Secure Boot db and dbx variables.
+const efiImageSecurityDatabase = "d719b2cb-3d3a-4596-a3bc-dad00e67656f"
+
+// efiCertX509 is the EFI_CERT_X509_GUID signature type, in its on-disk byte order.
+var efiCertX509 = []byte{0xa1, 0x59, 0xc0, 0xa5, 0xe4, 0x94, 0xa7, 0x4a, 0x87, 0xb5, 0xab, 0x15, 0x5c, 0x2b, 0xf0, 0x72}
+
+// efiCertSHA256 is the EFI_CERT_SHA256_GUID signature type, in its on-disk byte order.
+var efiCertSHA256 = []byte{0x26, 0x16, 0xc4, 0xc1, 0x4c, 0x50, 0x92, 0x40, 0xac, 0xa9, 0x41, 0xf9, 0x36, 0x93, 0x43, 0x28}
+
+// GetCertificates returns the X.509 certificates enrolled in a Secure Boot variable ("PK", "KEK" or "db").
+func GetCertificates(_ context.Context, variable string) ([]*x509.Certificate, error) {
+	data, err := readVariable(variable)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSignatureLists(data)
+}
+
+// variableFile returns the efivarfs file of a Secure Boot variable.
+func variableFile(variable string) string {
+	vendor := efiGlobalVariable
+	if variable == "db" || variable == "dbx" {
+		vendor = efiImageSecurityDatabase
+	}
+
+	return filepath.Join(EFIVarsPath, variable+"-"+vendor)
+}
+
+// readVariable returns the content of a Secure Boot variable, without its attributes.
+func readVariable(variable string) ([]byte, error) {
+	data, err := os.ReadFile(variableFile(variable))
+	if err != nil {
+		return nil, err
+	}
+
+	// Skip the variable attributes.
+	if len(data) < 4 {
+		return nil, fmt.Errorf("invalid EFI variable %q", variable)
+	}
+
+	return data[4:], nil
+}
+
+// parseSignatureLists returns the X.509 certificates from a series of EFI_SIGNATURE_LIST.
+func parseSignatureLists(data []byte) ([]*x509.Certificate, error) {
+	certs, _, err := parseSignatureListEntries(data)
+
+	return certs, err
+}
+
+// parseSignatureListEntries returns the X.509 certificates and SHA256 hashes from a series of
+// EFI_SIGNATURE_LIST, ignoring other kinds of entries.
+func parseSignatureListEntries(data []byte) ([]*x509.Certificate, [][]byte, error) {
+	certs := []*x509.Certificate{}
+	hashes := [][]byte{}
+
+	for len(data) > 0 {
+		if len(data) < 28 {
+			return nil, nil, errors.New("truncated EFI signature list")
+		}
+
+		listSize := binary.LittleEndian.Uint32(data[16:20])
+		headerSize := binary.LittleEndian.Uint32(data[20:24])
+		signatureSize := binary.LittleEndian.Uint32(data[24:28])
+
+		if listSize < 28+headerSize || uint64(listSize) > uint64(len(data)) || signatureSize <= 16 {
+			return nil, nil, errors.New("invalid EFI signature list")
+		}
+
+		for entry := data[28+headerSize : listSize]; len(entry) >= int(signatureSize); entry = entry[signatureSize:] {
+			switch {
+			case bytes.Equal(data[0:16], efiCertX509):
+				cert, err := x509.ParseCertificate(entry[16:signatureSize])
+				if err != nil {
+					return nil, nil, err
+				}
+
+				certs = append(certs, cert)
+			case bytes.Equal(data[0:16], efiCertSHA256) && signatureSize == 16+32:
+				hashes = append(hashes, entry[16:signatureSize])
+			}
+		}
+
+		data = data[listSize:]
+	}
+
+	return certs, hashes, nil
+}
+
+// ParseCertificates parses a list of PEM encoded certificates.
+func ParseCertificates(pems []string) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+
+	for _, entry := range pems {
+		block, _ := pem.Decode([]byte(entry))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return nil, errors.New("invalid PEM encoded certificate")
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certs = append(certs, cert)
+	}
+
+	return certs, nil
+}
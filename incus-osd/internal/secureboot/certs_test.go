@@ -0,0 +1,106 @@
+package secureboot
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testCertificate(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return der
+}
+
+// signatureList builds an EFI_SIGNATURE_LIST holding the entries.
+func signatureList(sigType []byte, entries ...[]byte) []byte {
+	signatureSize := 16 + len(entries[0])
+
+	data := make([]byte, 28)
+	copy(data, sigType)
+	binary.LittleEndian.PutUint32(data[16:], uint32(28+signatureSize*len(entries))) //nolint:gosec
+	binary.LittleEndian.PutUint32(data[24:], uint32(signatureSize))                 //nolint:gosec
+
+	for _, entry := range entries {
+		data = append(data, make([]byte, 16)...)
+		data = append(data, entry...)
+	}
+
+	return data
+}
+
+func TestParseSignatureLists(t *testing.T) {
+	t.Parallel()
+
+	cert := testCertificate(t)
+	data := signatureList(efiCertSHA256, make([]byte, 32), make([]byte, 32))
+	data = append(data, signatureList(efiCertX509, cert)...)
+
+	certs, err := parseSignatureLists(data)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	require.Equal(t, "test", certs[0].Subject.CommonName)
+
+	_, hashes, err := parseSignatureListEntries(data)
+	require.NoError(t, err)
+	require.Len(t, hashes, 2)
+
+	_, err = parseSignatureLists(data[:20])
+	require.Error(t, err)
+}
+
+func TestParseCertificates(t *testing.T) {
+	t.Parallel()
+
+	cert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testCertificate(t)}))
+
+	certs, err := ParseCertificates([]string{cert})
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+
+	_, err = ParseCertificates([]string{"not a certificate"})
+	require.Error(t, err)
+}
+
+func TestSignedFiles(t *testing.T) {
+	t.Parallel()
+
+	path := t.TempDir()
+
+	for _, name := range []string{"IncusOS_202501010000.efi", "IncusOS_202501010000.usr-x86-64.6ba6f8f2.raw", "IncusOS_202501010000.usr-x86-64-verity-sig.a2e1c4d0.raw", "IncusOS_202412010000.efi"} {
+		err := os.WriteFile(filepath.Join(path, name), nil, 0o600)
+		require.NoError(t, err)
+	}
+
+	ukis, signatures, err := signedFiles(path, "202501010000")
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(path, "IncusOS_202501010000.efi")}, ukis)
+	require.Equal(t, []string{filepath.Join(path, "IncusOS_202501010000.usr-x86-64-verity-sig.a2e1c4d0.raw")}, signatures)
+
+	_, _, err = signedFiles(path, "202412010000")
+	require.Error(t, err)
+}
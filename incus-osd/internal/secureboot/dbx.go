@@ -0,0 +1,151 @@
+package secureboot
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// BootLoaderPaths lists the boot loaders which must not be revoked, as globs.
+var BootLoaderPaths = []string{"/boot/EFI/BOOT/*.EFI", "/boot/EFI/systemd/*.efi"}
+
+// ErrRevoked is returned when a dbx update would revoke a boot component.
+var ErrRevoked = errors.New("boot component revoked by the dbx update")
+
+// parseDBXUpdate returns the revoked certificates and hashes of a signed dbx update.
+func parseDBXUpdate(data []byte) ([]*x509.Certificate, [][]byte, error) {
+	lists, err := updateSignatureLists(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certs, hashes, err := parseSignatureListEntries(lists)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(certs) == 0 && len(hashes) == 0 {
+		return nil, nil, errors.New("the dbx update doesn't revoke anything")
+	}
+
+	return certs, hashes, nil
+}
+
+// CheckRevoked checks that none of the provided boot components is revoked by the dbx update,
+// either through its Authenticode hash or its signing certificate.
+func CheckRevoked(ctx context.Context, updateFile string, components []string) error {
+	// #nosec G304
+	data, err := os.ReadFile(updateFile)
+	if err != nil {
+		return err
+	}
+
+	revokedCerts, revokedHashes, err := parseDBXUpdate(data)
+	if err != nil {
+		return err
+	}
+
+	certFiles := []string{}
+
+	if len(revokedCerts) > 0 {
+		tmpDir, err := os.MkdirTemp("", "incus-os-dbx-")
+		if err != nil {
+			return err
+		}
+
+		defer os.RemoveAll(tmpDir)
+
+		certFiles, _, err = writeCertificates(tmpDir, revokedCerts)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, component := range components {
+		// #nosec G304
+		content, err := os.ReadFile(component)
+		if err != nil {
+			return err
+		}
+
+		hash, err := authenticodeHash(content)
+		if err != nil {
+			return fmt.Errorf("failed to hash %q: %w", component, err)
+		}
+
+		if slices.ContainsFunc(revokedHashes, func(revoked []byte) bool { return bytes.Equal(revoked, hash) }) {
+			return fmt.Errorf("%w: %q", ErrRevoked, component)
+		}
+
+		for _, certFile := range certFiles {
+			if verifyUKI(ctx, component, []string{certFile}) == nil {
+				return fmt.Errorf("%w: %q (signing certificate)", ErrRevoked, component)
+			}
+		}
+	}
+
+	return nil
+}
+
+// BootComponents returns the installed boot loaders along with the provided unified kernel images.
+func BootComponents(ukis ...string) []string {
+	components := slices.Clone(ukis)
+
+	for _, pattern := range BootLoaderPaths {
+		matches, _ := filepath.Glob(pattern)
+		components = append(components, matches...)
+	}
+
+	return components
+}
+
+// ApplyDBXUpdate appends a signed dbx update to the firmware, after checking that it doesn't revoke
+// any of the boot components. Returns false if the dbx already contains all of its entries.
+func ApplyDBXUpdate(ctx context.Context, updateFile string, components []string) (bool, error) {
+	// #nosec G304
+	data, err := os.ReadFile(updateFile)
+	if err != nil {
+		return false, err
+	}
+
+	_, revokedHashes, err := parseDBXUpdate(data)
+	if err != nil {
+		return false, err
+	}
+
+	// Skip updates which were already applied.
+	current, err := readVariable("dbx")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	_, currentHashes, err := parseSignatureListEntries(current)
+	if err != nil {
+		return false, err
+	}
+
+	applied := len(revokedHashes) > 0
+	for _, hash := range revokedHashes {
+		if !slices.ContainsFunc(currentHashes, func(current []byte) bool { return bytes.Equal(current, hash) }) {
+			applied = false
+
+			break
+		}
+	}
+
+	if applied {
+		return false, nil
+	}
+
+	err = CheckRevoked(ctx, updateFile, components)
+	if err != nil {
+		return false, err
+	}
+
+	return true, writeVariable(ctx, "dbx", data, true)
+}
@@ -0,0 +1,110 @@
+package secureboot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testPE builds a minimal PE32+ image with a single section.
+func testPE() []byte {
+	data := make([]byte, 0x400)
+
+	// DOS header.
+	copy(data, "MZ")
+	binary.LittleEndian.PutUint32(data[0x3c:], 0x40)
+
+	// PE signature and COFF header.
+	copy(data[0x40:], "PE\x00\x00")
+	binary.LittleEndian.PutUint16(data[0x44:], 0x8664)
+	binary.LittleEndian.PutUint16(data[0x46:], 1)
+	binary.LittleEndian.PutUint16(data[0x54:], 240)
+
+	// Optional header.
+	opt := 0x58
+	binary.LittleEndian.PutUint16(data[opt:], 0x20b)
+	binary.LittleEndian.PutUint32(data[opt+32:], 0x200)
+	binary.LittleEndian.PutUint32(data[opt+36:], 0x200)
+	binary.LittleEndian.PutUint32(data[opt+56:], 0x1000)
+	binary.LittleEndian.PutUint32(data[opt+60:], 0x200)
+	binary.LittleEndian.PutUint32(data[opt+108:], 16)
+
+	// Section header.
+	section := opt + 240
+	copy(data[section:], ".text")
+	binary.LittleEndian.PutUint32(data[section+8:], 0x200)
+	binary.LittleEndian.PutUint32(data[section+12:], 0x1000)
+	binary.LittleEndian.PutUint32(data[section+16:], 0x200)
+	binary.LittleEndian.PutUint32(data[section+20:], 0x200)
+
+	copy(data[0x200:], "section content")
+
+	return data
+}
+
+func TestAuthenticodeHash(t *testing.T) {
+	t.Parallel()
+
+	image := testPE()
+
+	hash, err := authenticodeHash(image)
+	require.NoError(t, err)
+
+	// The checksum and signature aren't part of the hash.
+	signed := append([]byte{}, image...)
+	binary.LittleEndian.PutUint32(signed[0x58+64:], 0x1234)
+	binary.LittleEndian.PutUint32(signed[0x58+144:], uint32(len(image)))
+	binary.LittleEndian.PutUint32(signed[0x58+148:], 16)
+	signed = append(signed, make([]byte, 16)...)
+
+	signedHash, err := authenticodeHash(signed)
+	require.NoError(t, err)
+	require.Equal(t, hash, signedHash)
+
+	// The content is.
+	modified := append([]byte{}, image...)
+	modified[0x200] = 'S'
+
+	modifiedHash, err := authenticodeHash(modified)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, modifiedHash)
+
+	_, err = authenticodeHash([]byte("not a PE image"))
+	require.Error(t, err)
+}
+
+func TestCheckRevoked(t *testing.T) {
+	t.Parallel()
+
+	path := t.TempDir()
+	image := filepath.Join(path, "IncusOS_202501010000.efi")
+
+	err := os.WriteFile(image, testPE(), 0o600)
+	require.NoError(t, err)
+
+	hash, err := authenticodeHash(testPE())
+	require.NoError(t, err)
+
+	otherHash := sha256.Sum256([]byte("other"))
+
+	// A dbx update revoking the image.
+	revoking := filepath.Join(path, "revoking.auth")
+	err = os.WriteFile(revoking, authenticatedUpdate([]byte("signature"), signatureList(efiCertSHA256, otherHash[:], hash)), 0o600)
+	require.NoError(t, err)
+
+	err = CheckRevoked(context.Background(), revoking, []string{image})
+	require.ErrorIs(t, err, ErrRevoked)
+
+	// A dbx update revoking something else.
+	other := filepath.Join(path, "other.auth")
+	err = os.WriteFile(other, authenticatedUpdate([]byte("signature"), signatureList(efiCertSHA256, otherHash[:])), 0o600)
+	require.NoError(t, err)
+
+	err = CheckRevoked(context.Background(), other, []string{image})
+	require.NoError(t, err)
+}
@@ -0,0 +1,2 @@
+// Package secureboot handles the Secure Boot certificates and the verification of the signed OS images.
+package secureboot
@@ -0,0 +1,278 @@
+package secureboot
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// StagedUpdatesPath is where the staged Secure Boot variable updates are kept.
+var StagedUpdatesPath = "/var/lib/incus-os/secureboot"
+
+// Variables lists the Secure Boot variables which can be updated, in the order updates are applied.
+var Variables = []string{"db", "KEK", "PK"}
+
+// EFI variable attributes of authenticated Secure Boot variable writes.
+const (
+	efiVariableNonVolatile                       = 0x00000001
+	efiVariableBootserviceAccess                 = 0x00000002
+	efiVariableRuntimeAccess                     = 0x00000004
+	efiVariableTimeBasedAuthenticatedWriteAccess = 0x00000020
+	efiVariableAppendWrite                       = 0x00000040
+)
+
+// winCertTypeEFIGUID is the WIN_CERTIFICATE type of the EFI_VARIABLE_AUTHENTICATION_2 signature.
+const winCertTypeEFIGUID = 0x0EF1
+
+// GetStatus returns the current Secure Boot state and enrolled certificates.
+func GetStatus(ctx context.Context) (*api.SystemSecuritySecureBoot, error) {
+	status := &api.SystemSecuritySecureBoot{
+		Keys: map[string][]api.SystemSecuritySecureBootCertificate{},
+	}
+
+	var err error
+
+	status.Enabled, err = readBoolVariable("SecureBoot")
+	if err != nil {
+		return nil, err
+	}
+
+	status.SetupMode, err = readBoolVariable("SetupMode")
+	if err != nil {
+		return nil, err
+	}
+
+	dbx, err := readVariable("dbx")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	dbxCerts, dbxHashes, err := parseSignatureListEntries(dbx)
+	if err != nil {
+		return nil, err
+	}
+
+	status.Revocations = len(dbxCerts) + len(dbxHashes)
+
+	for _, variable := range Variables {
+		certs, err := GetCertificates(ctx, variable)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		status.Keys[variable] = describeCertificates(certs)
+	}
+
+	return status, nil
+}
+
+// readBoolVariable reads a single byte boolean EFI global variable.
+func readBoolVariable(variable string) (bool, error) {
+	data, err := os.ReadFile(filepath.Join(EFIVarsPath, variable+"-"+efiGlobalVariable))
+	if err != nil {
+		return false, err
+	}
+
+	return len(data) >= 5 && data[4] == 1, nil
+}
+
+// describeCertificates returns the API representation of the certificates.
+func describeCertificates(certs []*x509.Certificate) []api.SystemSecuritySecureBootCertificate {
+	ret := make([]api.SystemSecuritySecureBootCertificate, 0, len(certs))
+
+	for _, cert := range certs {
+		fingerprint := sha256.Sum256(cert.Raw)
+
+		ret = append(ret, api.SystemSecuritySecureBootCertificate{
+			Subject:     cert.Subject.String(),
+			Fingerprint: hex.EncodeToString(fingerprint[:]),
+			NotAfter:    cert.NotAfter,
+		})
+	}
+
+	return ret
+}
+
+// parseUpdate returns the certificates set by a signed Secure Boot variable update.
+func parseUpdate(variable string, data []byte) ([]*x509.Certificate, error) {
+	if !slices.Contains(Variables, variable) {
+		return nil, fmt.Errorf("invalid Secure Boot variable %q", variable)
+	}
+
+	lists, err := updateSignatureLists(data)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := parseSignatureLists(lists)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("the Secure Boot variable update doesn't contain any certificate")
+	}
+
+	if variable == "PK" && len(certs) != 1 {
+		return nil, errors.New("the platform key update must contain a single certificate")
+	}
+
+	return certs, nil
+}
+
+// updateSignatureLists returns the signature lists of a signed Secure Boot variable update.
+func updateSignatureLists(data []byte) ([]byte, error) {
+	// Skip the EFI_TIME and WIN_CERTIFICATE_UEFI_GUID headers.
+	if len(data) < 16+24 {
+		return nil, errors.New("truncated Secure Boot variable update")
+	}
+
+	certLength := binary.LittleEndian.Uint32(data[16:20])
+	certType := binary.LittleEndian.Uint16(data[22:24])
+
+	if certType != winCertTypeEFIGUID || certLength < 24 || uint64(16+certLength) > uint64(len(data)) {
+		return nil, errors.New("the Secure Boot variable update isn't signed")
+	}
+
+	return data[16+certLength:], nil
+}
+
+// StageUpdate validates and stores a signed Secure Boot variable update for later application.
+func StageUpdate(_ context.Context, variable string, appendWrite bool, data []byte) (*api.SystemSecuritySecureBootUpdate, error) {
+	certs, err := parseUpdate(variable, data)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+	id := hex.EncodeToString(hash[:6])
+
+	err = os.MkdirAll(StagedUpdatesPath, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.WriteFile(filepath.Join(StagedUpdatesPath, id+".auth"), data, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SystemSecuritySecureBootUpdate{
+		ID:           id,
+		Variable:     variable,
+		Append:       appendWrite,
+		Certificates: describeCertificates(certs),
+		Status:       "staged",
+		StagedAt:     time.Now(),
+	}, nil
+}
+
+// RemoveUpdate removes a staged Secure Boot variable update.
+func RemoveUpdate(_ context.Context, update api.SystemSecuritySecureBootUpdate) error {
+	err := os.Remove(filepath.Join(StagedUpdatesPath, update.ID+".auth"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// CheckActivation ensures the system will still boot once a replacement update is applied, by
+// checking that every boot component (boot loaders and unified kernel images) is signed by one of
+// the new db certificates.
+func CheckActivation(ctx context.Context, update api.SystemSecuritySecureBootUpdate, components []string) error {
+	if update.Append || update.Variable != "db" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(StagedUpdatesPath, update.ID+".auth"))
+	if err != nil {
+		return err
+	}
+
+	certs, err := parseUpdate(update.Variable, data)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "incus-os-secureboot-")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	certFiles, _, err := writeCertificates(tmpDir, certs)
+	if err != nil {
+		return err
+	}
+
+	for _, component := range components {
+		err = verifyUKI(ctx, component, certFiles)
+		if err != nil {
+			return fmt.Errorf("boot component %q isn't signed by the new db certificates: %w", component, err)
+		}
+	}
+
+	return nil
+}
+
+// ApplyUpdate writes a staged Secure Boot variable update to the firmware, which checks its signature.
+func ApplyUpdate(ctx context.Context, update api.SystemSecuritySecureBootUpdate) error {
+	data, err := os.ReadFile(filepath.Join(StagedUpdatesPath, update.ID+".auth"))
+	if err != nil {
+		return err
+	}
+
+	return writeVariable(ctx, update.Variable, data, update.Append)
+}
+
+// writeVariable writes a signed update to a Secure Boot variable.
+func writeVariable(ctx context.Context, variable string, data []byte, appendWrite bool) error {
+	varFile := variableFile(variable)
+
+	// The kernel protects the Secure Boot variables against accidental writes.
+	_, err := os.Stat(varFile)
+	if err == nil {
+		_, err = subprocess.RunCommandContext(ctx, "chattr", "-i", varFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	attributes := uint32(efiVariableNonVolatile | efiVariableBootserviceAccess | efiVariableRuntimeAccess | efiVariableTimeBasedAuthenticatedWriteAccess)
+	if appendWrite {
+		attributes |= efiVariableAppendWrite
+	}
+
+	// efivarfs expects the variable attributes followed by the data, in a single write.
+	buf := binary.LittleEndian.AppendUint32(nil, attributes)
+	buf = append(buf, data...)
+
+	// #nosec G304
+	fd, err := os.OpenFile(varFile, os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	_, err = fd.Write(buf)
+	if err != nil {
+		return fmt.Errorf("firmware rejected the %s update: %w", variable, err)
+	}
+
+	return nil
+}
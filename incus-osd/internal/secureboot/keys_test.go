@@ -0,0 +1,53 @@
+package secureboot
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// authenticatedUpdate wraps signature lists into a signed variable update, with a dummy signature.
+func authenticatedUpdate(signature []byte, lists ...[]byte) []byte {
+	data := make([]byte, 16+24)
+	binary.LittleEndian.PutUint32(data[16:], uint32(24+len(signature))) //nolint:gosec
+	binary.LittleEndian.PutUint16(data[20:], 0x0200)
+	binary.LittleEndian.PutUint16(data[22:], winCertTypeEFIGUID)
+
+	data = append(data, signature...)
+	for _, list := range lists {
+		data = append(data, list...)
+	}
+
+	return data
+}
+
+func TestParseUpdate(t *testing.T) {
+	t.Parallel()
+
+	cert1 := testCertificate(t)
+	cert2 := testCertificate(t)
+
+	update := authenticatedUpdate([]byte("signature"), signatureList(efiCertX509, cert1), signatureList(efiCertX509, cert2))
+
+	certs, err := parseUpdate("db", update)
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+
+	// The platform key is a single certificate.
+	_, err = parseUpdate("PK", update)
+	require.Error(t, err)
+
+	_, err = parseUpdate("PK", authenticatedUpdate([]byte("signature"), signatureList(efiCertX509, cert1)))
+	require.NoError(t, err)
+
+	// Invalid updates.
+	_, err = parseUpdate("dbx", update)
+	require.Error(t, err)
+
+	_, err = parseUpdate("db", signatureList(efiCertX509, cert1))
+	require.Error(t, err)
+
+	_, err = parseUpdate("db", authenticatedUpdate([]byte("signature")))
+	require.Error(t, err)
+}
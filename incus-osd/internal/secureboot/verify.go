@@ -0,0 +1,195 @@
+package secureboot
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// veritySignature is the content of a dm-verity signature partition.
+type veritySignature struct {
+	RootHash  string `json:"rootHash"`
+	Signature string `json:"signature"`
+}
+
+// VerifyUpdate checks that the OS update files of the release, in the provided path, are signed by
+// one of the trusted certificates: the unified kernel image through its Authenticode signature and
+// the /usr partition through its dm-verity root hash signature. Unless exclusive, the certificates
+// enrolled in the Secure Boot db are trusted along with the provided ones.
+func VerifyUpdate(ctx context.Context, path string, version string, certificates []string, exclusive bool) error {
+	certs, err := ParseCertificates(certificates)
+	if err != nil {
+		return err
+	}
+
+	if !exclusive {
+		dbCerts, err := GetCertificates(ctx, "db")
+		if err != nil {
+			return fmt.Errorf("failed to get the Secure Boot db certificates: %w", err)
+		}
+
+		certs = append(certs, dbCerts...)
+	}
+
+	if len(certs) == 0 {
+		return errors.New("no trusted update signing certificate")
+	}
+
+	// Write the trusted certificates for the verification tools.
+	tmpDir, err := os.MkdirTemp("", "incus-os-verify-")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	certFiles, bundleFile, err := writeCertificates(tmpDir, certs)
+	if err != nil {
+		return err
+	}
+
+	// Check every signed file of the release.
+	ukis, signatures, err := signedFiles(path, version)
+	if err != nil {
+		return err
+	}
+
+	for _, uki := range ukis {
+		err = verifyUKI(ctx, uki, certFiles)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, signature := range signatures {
+		err = verifyVeritySignature(ctx, signature, bundleFile, tmpDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeCertificates writes each certificate to its own PEM file, as well as a bundle of them all.
+func writeCertificates(path string, certs []*x509.Certificate) ([]string, string, error) {
+	certFiles := make([]string, 0, len(certs))
+	bundle := []byte{}
+
+	for i, cert := range certs {
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		certFile := filepath.Join(path, "cert-"+strconv.Itoa(i)+".pem")
+
+		err := os.WriteFile(certFile, data, 0o600)
+		if err != nil {
+			return nil, "", err
+		}
+
+		certFiles = append(certFiles, certFile)
+		bundle = append(bundle, data...)
+	}
+
+	bundleFile := filepath.Join(path, "bundle.pem")
+
+	err := os.WriteFile(bundleFile, bundle, 0o600)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return certFiles, bundleFile, nil
+}
+
+// signedFiles returns the unified kernel images and dm-verity signatures of a release, failing if
+// either is missing.
+func signedFiles(path string, version string) ([]string, []string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ukis := []string{}
+	signatures := []string{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "IncusOS_"+version+".") {
+			continue
+		}
+
+		if strings.HasSuffix(name, ".efi") {
+			ukis = append(ukis, filepath.Join(path, name))
+		} else if strings.Contains(name, "-verity-sig.") {
+			signatures = append(signatures, filepath.Join(path, name))
+		}
+	}
+
+	if len(ukis) == 0 || len(signatures) == 0 {
+		return nil, nil, fmt.Errorf("missing signed files for release %q", version)
+	}
+
+	return ukis, signatures, nil
+}
+
+// verifyUKI checks the Authenticode signature of a unified kernel image.
+func verifyUKI(ctx context.Context, uki string, certFiles []string) error {
+	for _, certFile := range certFiles {
+		_, err := subprocess.RunCommandContext(ctx, "sbverify", "--cert", certFile, uki)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%q isn't signed by a trusted certificate", filepath.Base(uki))
+}
+
+// verifyVeritySignature checks the PKCS#7 signature of the dm-verity root hash.
+func verifyVeritySignature(ctx context.Context, signatureFile string, bundleFile string, tmpDir string) error {
+	// #nosec G304
+	data, err := os.ReadFile(signatureFile)
+	if err != nil {
+		return err
+	}
+
+	// The partition holds the JSON object, padded with zeroes.
+	var sig veritySignature
+
+	err = json.Unmarshal([]byte(strings.TrimRight(string(data), "\x00")), &sig)
+	if err != nil {
+		return fmt.Errorf("invalid dm-verity signature %q: %w", filepath.Base(signatureFile), err)
+	}
+
+	rawSig, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || sig.RootHash == "" {
+		return fmt.Errorf("invalid dm-verity signature %q", filepath.Base(signatureFile))
+	}
+
+	contentFile := filepath.Join(tmpDir, "roothash")
+	sigFile := filepath.Join(tmpDir, "roothash.p7s")
+
+	err = os.WriteFile(contentFile, []byte(sig.RootHash), 0o600)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(sigFile, rawSig, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "openssl", "cms", "-verify", "-binary", "-inform", "DER", "-in", sigFile, "-content", contentFile, "-CAfile", bundleFile, "-purpose", "any", "-out", os.DevNull)
+	if err != nil {
+		return fmt.Errorf("%q isn't signed by a trusted certificate", filepath.Base(signatureFile))
+	}
+
+	return nil
+}
@@ -1,12 +1,17 @@
 package seed
 
+import (
+	"context"
+)
+
 // InstallSeed defines a struct to hold install configuration.
 type InstallSeed struct {
 	Version string `json:"version" yaml:"version"`
 
-	ForceInstall bool               `json:"force_install" yaml:"force_install"` // If true, ignore any existing data on target install disk.
-	ForceReboot  bool               `json:"force_reboot"  yaml:"force_reboot"`  // If true, reboot the system automatically upon completion rather than waiting for the install media to be removed.
-	Target       *InstallSeedTarget `json:"target"        yaml:"target"`        // Optional selector for the target install disk; if not set, expect a single drive to be present.
+	ForceInstall bool                  `json:"force_install" yaml:"force_install"` // If true, ignore any existing data on target install disk.
+	ForceReboot  bool                  `json:"force_reboot"  yaml:"force_reboot"`  // If true, reboot the system automatically upon completion rather than waiting for the install media to be removed.
+	Target       *InstallSeedTarget    `json:"target"        yaml:"target"`        // Optional selector for the target install disk; if not set, expect a single drive to be present.
+	LocalPool    *InstallSeedLocalPool `json:"local_pool"    yaml:"local_pool"`    // Optional layout of the "local" ZFS pool; if not set, the pool only uses the target install disk.
 }
 
 // InstallSeedTarget defines options used to select the target install disk.
@@ -14,6 +19,14 @@ type InstallSeedTarget struct {
 	ID string `json:"id" yaml:"id"` // Name as listed in /dev/disk/by-id/, glob supported.
 }
 
+// InstallSeedLocalPool defines the layout of the "local" ZFS pool, combining the data partition of the
+// target install disk with additional devices. The usable size of each device is limited to the size
+// of the data partition.
+type InstallSeedLocalPool struct {
+	Topology string   `json:"topology"          yaml:"topology"`          // Either "mirror", "raidz1" or "raidz2".
+	Devices  []string `json:"devices,omitempty" yaml:"devices,omitempty"` // Additional devices, as listed in /dev/disk/by-id/, glob supported.
+}
+
 // GetInstallConfig extracts the list of applications from the seed data.
 func GetInstallConfig(partition string) (*InstallSeed, error) {
 	// Get the install configuration.
@@ -26,3 +39,16 @@ func GetInstallConfig(partition string) (*InstallSeed, error) {
 
 	return &config, nil
 }
+
+// GetLocalPool extracts the layout of the "local" ZFS pool recorded by the installer from the seed data.
+// The devices are then provided as full /dev/disk/by-id/ paths.
+func GetLocalPool(_ context.Context, partition string) (*InstallSeedLocalPool, error) {
+	var config InstallSeedLocalPool
+
+	err := parseFileContents(partition, "local-pool", &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
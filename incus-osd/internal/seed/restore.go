@@ -0,0 +1,27 @@
+package seed
+
+import (
+	"context"
+)
+
+// Restore represents a backup of the system state to restore on first start.
+type Restore struct {
+	Version string `json:"version" yaml:"version"`
+
+	// Source is either a HTTP(S) URL or the path to a local file.
+	Source     string `json:"source"     yaml:"source"`
+	Passphrase string `json:"passphrase" yaml:"passphrase"`
+}
+
+// GetRestore extracts the backup to restore from the seed data.
+func GetRestore(_ context.Context, partition string) (*Restore, error) {
+	// Get the restore configuration.
+	var restore Restore
+
+	err := parseFileContents(partition, "restore", &restore)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restore, nil
+}
@@ -0,0 +1,30 @@
+package seed
+
+import (
+	"context"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Services represents the initial configuration of the system services.
+type Services struct {
+	Ceph      *api.ServiceCeph      `json:"ceph,omitempty"      yaml:"ceph,omitempty"`
+	ISCSI     *api.ServiceISCSI     `json:"iscsi,omitempty"     yaml:"iscsi,omitempty"`
+	Multipath *api.ServiceMultipath `json:"multipath,omitempty" yaml:"multipath,omitempty"`
+	NVME      *api.ServiceNVME      `json:"nvme,omitempty"      yaml:"nvme,omitempty"`
+
+	Version string `json:"version" yaml:"version"`
+}
+
+// GetServices extracts the services configuration from the seed data.
+func GetServices(_ context.Context, partition string) (*Services, error) {
+	// Get the services configuration.
+	var services Services
+
+	err := parseFileContents(partition, "services", &services)
+	if err != nil {
+		return nil, err
+	}
+
+	return &services, nil
+}
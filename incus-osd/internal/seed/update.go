@@ -0,0 +1,35 @@
+package seed
+
+import (
+	"context"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// Update represents the initial update configuration.
+type Update struct {
+	Version string `json:"version" yaml:"version"`
+
+	Channel string `json:"channel" yaml:"channel"`
+
+	DownloadRateLimit int64 `json:"download_rate_limit" yaml:"download_rate_limit"`
+
+	Signing *api.SystemUpdateSigning `json:"signing,omitempty" yaml:"signing,omitempty"`
+
+	Rollout *api.SystemUpdateRollout `json:"rollout,omitempty" yaml:"rollout,omitempty"`
+
+	MaintenanceWindows []api.SystemUpdateMaintenanceWindow `json:"maintenance_windows,omitempty" yaml:"maintenance_windows,omitempty"`
+}
+
+// GetUpdate extracts the update configuration from the seed data.
+func GetUpdate(_ context.Context, partition string) (*Update, error) {
+	// Get the update configuration.
+	var update Update
+
+	err := parseFileContents(partition, "update", &update)
+	if err != nil {
+		return nil, err
+	}
+
+	return &update, nil
+}
@@ -8,17 +8,23 @@ import (
 )
 
 // ValidNames contains the list of all valid services.
-var ValidNames = []string{"iscsi", "lvm", "nvme", "ovn"}
+var ValidNames = []string{"ceph", "iscsi", "keepalived", "lvm", "multipath", "nvme", "ovn"}
 
 // Load returns a handler for the given system service.
 func Load(ctx context.Context, s *state.State, name string) (Service, error) {
 	var srv Service
 
 	switch name {
+	case "ceph":
+		srv = &Ceph{state: s}
 	case "iscsi":
 		srv = &ISCSI{state: s}
+	case "keepalived":
+		srv = &Keepalived{state: s}
 	case "lvm":
 		srv = &LVM{state: s}
+	case "multipath":
+		srv = &Multipath{state: s}
 	case "nvme":
 		srv = &NVME{state: s}
 	case "ovn":
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+var cephNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Ceph represents the system Ceph client service.
+type Ceph struct {
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *Ceph) Get(_ context.Context) (any, error) {
+	// Initialize cluster list if missing.
+	if n.state.Services.Ceph.Config.Clusters == nil {
+		n.state.Services.Ceph.Config.Clusters = map[string]api.ServiceCephCluster{}
+	}
+
+	return n.state.Services.Ceph, nil
+}
+
+// Update updates the service configuration.
+func (n *Ceph) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceCeph)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceCeph", req)
+	}
+
+	// Validate the clusters.
+	for name, cluster := range newState.Config.Clusters {
+		err := validateCephCluster(name, cluster)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Save the state on return.
+	defer n.state.Save(ctx)
+
+	// Disable the service.
+	err := n.Stop(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Update the configuration.
+	n.state.Services.Ceph.Config = newState.Config
+
+	// Bring the service back up.
+	err = n.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Stop stops the service.
+func (n *Ceph) Stop(_ context.Context) error {
+	if !n.state.Services.Ceph.Config.Enabled {
+		return nil
+	}
+
+	// Remove the generated configuration.
+	err := os.RemoveAll("/etc/ceph")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Start starts the service.
+func (n *Ceph) Start(ctx context.Context) error {
+	if !n.state.Services.Ceph.Config.Enabled {
+		return nil
+	}
+
+	// Ensure we have the right modules.
+	for _, module := range []string{"rbd", "ceph"} {
+		_, err := subprocess.RunCommandContext(ctx, "modprobe", module)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create the Ceph config directory if missing.
+	err := os.Mkdir("/etc/ceph", 0o755)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	// Generate the cluster configuration and keyrings.
+	for name, cluster := range n.state.Services.Ceph.Config.Clusters {
+		err := os.WriteFile(filepath.Join("/etc/ceph", name+".conf"), []byte(generateCephConf(cluster)), 0o644)
+		if err != nil {
+			return err
+		}
+
+		for entity, key := range cluster.Keyrings {
+			err := os.WriteFile(filepath.Join("/etc/ceph", fmt.Sprintf("%s.%s.keyring", name, entity)), []byte(fmt.Sprintf("[%s]\n\tkey = %s\n", entity, key)), 0o600)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *Ceph) ShouldStart() bool {
+	return n.state.Services.Ceph.Config.Enabled
+}
+
+// Struct returns the API struct for the Ceph service.
+func (*Ceph) Struct() any {
+	return &api.ServiceCeph{}
+}
+
+func (*Ceph) init(_ context.Context) error {
+	return nil
+}
+
+// validateCephCluster checks the configuration of a Ceph cluster.
+func validateCephCluster(name string, cluster api.ServiceCephCluster) error {
+	if !cephNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid Ceph cluster name %q", name)
+	}
+
+	if len(cluster.Monitors) == 0 {
+		return fmt.Errorf("no monitors provided for Ceph cluster %q", name)
+	}
+
+	if cluster.MSMode != "" && !slices.Contains([]string{"crc", "secure", "prefer-crc", "prefer-secure"}, cluster.MSMode) {
+		return fmt.Errorf("invalid messenger mode %q for Ceph cluster %q", cluster.MSMode, name)
+	}
+
+	for entity, key := range cluster.Keyrings {
+		client, found := strings.CutPrefix(entity, "client.")
+		if !found || !cephNameRegex.MatchString(client) {
+			return fmt.Errorf("invalid Ceph client name %q for Ceph cluster %q", entity, name)
+		}
+
+		if key == "" || strings.ContainsAny(key, "\n\r") {
+			return fmt.Errorf("invalid key for Ceph client %q of cluster %q", entity, name)
+		}
+	}
+
+	return nil
+}
+
+// generateCephConf generates the client configuration of a Ceph cluster.
+func generateCephConf(cluster api.ServiceCephCluster) string {
+	var sb strings.Builder
+
+	sb.WriteString("[global]\n")
+
+	if cluster.FSID != "" {
+		fmt.Fprintf(&sb, "fsid = %s\n", cluster.FSID)
+	}
+
+	fmt.Fprintf(&sb, "mon_host = %s\n", strings.Join(cluster.Monitors, ","))
+
+	if cluster.MSMode != "" {
+		fmt.Fprintf(&sb, "ms_client_mode = %s\n", cluster.MSMode)
+		fmt.Fprintf(&sb, "ms_mon_client_mode = %s\n", cluster.MSMode)
+	}
+
+	return sb.String()
+}
@@ -48,6 +48,14 @@ func (n *ISCSI) Update(ctx context.Context, req any) error {
 		return fmt.Errorf("request type \"%T\" isn't expected ServiceISCSI", req)
 	}
 
+	// Validate the targets.
+	for _, target := range newState.Config.Targets {
+		err := validateISCSITarget(target)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Save the state on return.
 	defer n.state.Save(ctx)
 
@@ -77,18 +85,8 @@ func (n *ISCSI) Stop(ctx context.Context) error {
 
 	// Disconnect from the targets.
 	for _, target := range n.state.Services.ISCSI.Config.Targets {
-		// Determine portal address.
-		portal := target.Address
-		if strings.Contains(portal, ":") {
-			portal = "[" + portal + "]"
-		}
-
-		if target.Port > 0 {
-			portal = fmt.Sprintf("%s:%d", portal, target.Port)
-		}
-
 		// Logout from the target.
-		_, err := subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "node", "-T", target.Target, "-p", portal, "--logout")
+		_, err := subprocess.RunCommandContext(ctx, "iscsiadm", append(iscsiNodeArgs(target), "--logout")...)
 		if err != nil {
 			return err
 		}
@@ -166,19 +164,39 @@ func (n *ISCSI) Start(ctx context.Context) error {
 
 	// Connect to the targets.
 	for _, target := range n.state.Services.ISCSI.Config.Targets {
-		// Determine portal address.
-		portal := target.Address
-		if strings.Contains(portal, ":") {
-			portal = "[" + portal + "]"
-		}
+		// Bind to the requested network interface.
+		if target.Interface != "" {
+			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "iface", "-I", target.Interface, "-o", "new")
+			if err != nil && !strings.Contains(err.Error(), "already exists") {
+				return err
+			}
 
-		if target.Port > 0 {
-			portal = fmt.Sprintf("%s:%d", portal, target.Port)
+			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "iface", "-I", target.Interface, "-o", "update", "-n", "iface.net_ifacename", "-v", target.Interface)
+			if err != nil {
+				return err
+			}
 		}
 
 		// Discover the targets.
+		discoveryArgs := []string{"-m", "discoverydb", "-t", "sendtargets", "-p", iscsiPortal(target)}
+		if target.Interface != "" {
+			discoveryArgs = append(discoveryArgs, "-I", target.Interface)
+		}
+
+		_, err = subprocess.RunCommandContext(ctx, "iscsiadm", append(discoveryArgs, "-o", "new")...)
+		if err != nil {
+			return err
+		}
+
+		for name, value := range iscsiCHAPSettings(target, "discovery.sendtargets.auth") {
+			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", append(discoveryArgs, "-o", "update", "-n", name, "-v", value)...)
+			if err != nil {
+				return err
+			}
+		}
+
 		for range 10 {
-			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "discovery", "-t", "sendtargets", "-p", portal)
+			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", append(discoveryArgs, "--discover")...)
 			if err == nil {
 				break
 			}
@@ -190,8 +208,16 @@ func (n *ISCSI) Start(ctx context.Context) error {
 			return err
 		}
 
+		// Configure the login credentials.
+		for name, value := range iscsiCHAPSettings(target, "node.session.auth") {
+			_, err = subprocess.RunCommandContext(ctx, "iscsiadm", append(iscsiNodeArgs(target), "-o", "update", "-n", name, "-v", value)...)
+			if err != nil {
+				return err
+			}
+		}
+
 		// Login to the target.
-		_, err = subprocess.RunCommandContext(ctx, "iscsiadm", "-m", "node", "-T", target.Target, "-p", portal, "--login")
+		_, err = subprocess.RunCommandContext(ctx, "iscsiadm", append(iscsiNodeArgs(target), "--login")...)
 		if err != nil {
 			return err
 		}
@@ -213,3 +239,68 @@ func (*ISCSI) Struct() any {
 func (*ISCSI) init(_ context.Context) error {
 	return nil
 }
+
+// validateISCSITarget checks that a target configuration is complete.
+func validateISCSITarget(target api.ServiceISCSITarget) error {
+	if target.Target == "" || target.Address == "" {
+		return errors.New("iSCSI targets require a target name and address")
+	}
+
+	if (target.CHAPUsername == "") != (target.CHAPPassword == "") {
+		return fmt.Errorf("both a CHAP username and password are required for target %q", target.Target)
+	}
+
+	if (target.CHAPUsernameIn == "") != (target.CHAPPasswordIn == "") {
+		return fmt.Errorf("both a mutual CHAP username and password are required for target %q", target.Target)
+	}
+
+	if target.CHAPUsernameIn != "" && target.CHAPUsername == "" {
+		return fmt.Errorf("mutual CHAP requires CHAP to be configured for target %q", target.Target)
+	}
+
+	return nil
+}
+
+// iscsiPortal returns the portal address of a target.
+func iscsiPortal(target api.ServiceISCSITarget) string {
+	portal := target.Address
+	if strings.Contains(portal, ":") {
+		portal = "[" + portal + "]"
+	}
+
+	if target.Port > 0 {
+		portal = fmt.Sprintf("%s:%d", portal, target.Port)
+	}
+
+	return portal
+}
+
+// iscsiNodeArgs returns the iscsiadm arguments selecting a target node.
+func iscsiNodeArgs(target api.ServiceISCSITarget) []string {
+	args := []string{"-m", "node", "-T", target.Target, "-p", iscsiPortal(target)}
+	if target.Interface != "" {
+		args = append(args, "-I", target.Interface)
+	}
+
+	return args
+}
+
+// iscsiCHAPSettings returns the iscsiadm CHAP settings for a target, using the provided key prefix.
+func iscsiCHAPSettings(target api.ServiceISCSITarget, prefix string) map[string]string {
+	if target.CHAPUsername == "" {
+		return nil
+	}
+
+	ret := map[string]string{
+		prefix + ".authmethod": "CHAP",
+		prefix + ".username":   target.CHAPUsername,
+		prefix + ".password":   target.CHAPPassword,
+	}
+
+	if target.CHAPUsernameIn != "" {
+		ret[prefix+".username_in"] = target.CHAPUsernameIn
+		ret[prefix+".password_in"] = target.CHAPPasswordIn
+	}
+
+	return ret
+}
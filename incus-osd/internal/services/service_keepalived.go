@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// Keepalived represents the system keepalived (VRRP) service.
+type Keepalived struct {
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *Keepalived) Get(_ context.Context) (any, error) {
+	return n.state.Services.Keepalived, nil
+}
+
+// Update updates the service configuration.
+func (n *Keepalived) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceKeepalived)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceKeepalived", req)
+	}
+
+	// Validate the new configuration before applying anything.
+	if newState.Config.Enabled {
+		err := validateKeepalived(newState.Config.VirtualRouters)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Save the state on return.
+	defer n.state.Save(ctx)
+
+	// Disable the service if requested.
+	if n.state.Services.Keepalived.Config.Enabled && !newState.Config.Enabled {
+		err := n.Stop(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update the configuration.
+	n.state.Services.Keepalived.Config = newState.Config
+
+	if !n.state.Services.Keepalived.Config.Enabled {
+		return nil
+	}
+
+	// (Re)start the service with the new configuration.
+	return n.Start(ctx)
+}
+
+// Stop stops the service.
+func (n *Keepalived) Stop(ctx context.Context) error {
+	if !n.state.Services.Keepalived.Config.Enabled {
+		return nil
+	}
+
+	err := systemd.StopUnit(ctx, "keepalived.service")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Start starts the service.
+func (n *Keepalived) Start(ctx context.Context) error {
+	if !n.state.Services.Keepalived.Config.Enabled {
+		return nil
+	}
+
+	// Generate configuration.
+	err := n.configure(ctx)
+	if err != nil {
+		return err
+	}
+
+	// (Re)start keepalived to pickup the configuration.
+	err = systemd.RestartUnit(ctx, "keepalived.service")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *Keepalived) ShouldStart() bool {
+	return n.state.Services.Keepalived.Config.Enabled
+}
+
+// Struct returns the API struct for the Keepalived service.
+func (*Keepalived) Struct() any {
+	return &api.ServiceKeepalived{}
+}
+
+func (n *Keepalived) configure(_ context.Context) error {
+	err := os.MkdirAll("/etc/keepalived/", 0o700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile("/etc/keepalived/keepalived.conf", []byte(generateKeepalivedConfig(n.state.Services.Keepalived.Config.VirtualRouters)), 0o600)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (*Keepalived) init(_ context.Context) error {
+	return nil
+}
+
+// validateKeepalived checks the VRRP instances.
+func validateKeepalived(routers []api.ServiceKeepalivedVirtualRouter) error {
+	names := map[string]bool{}
+	ids := map[string]bool{}
+
+	for _, r := range routers {
+		if r.Name == "" || names[r.Name] {
+			return fmt.Errorf("invalid or duplicate virtual router name %q", r.Name)
+		}
+
+		names[r.Name] = true
+
+		if r.Interface == "" {
+			return fmt.Errorf("virtual router %q is missing an interface", r.Name)
+		}
+
+		// The virtual router ID must be unique per interface.
+		if r.VirtualRouterID < 1 || r.VirtualRouterID > 255 || ids[fmt.Sprintf("%s/%d", r.Interface, r.VirtualRouterID)] {
+			return fmt.Errorf("invalid or duplicate virtual router ID %d for virtual router %q", r.VirtualRouterID, r.Name)
+		}
+
+		ids[fmt.Sprintf("%s/%d", r.Interface, r.VirtualRouterID)] = true
+
+		if r.Priority < 0 || r.Priority > 254 {
+			return fmt.Errorf("invalid priority %d for virtual router %q", r.Priority, r.Name)
+		}
+
+		if r.AdvertisementInterval < 0 {
+			return fmt.Errorf("invalid advertisement interval %d for virtual router %q", r.AdvertisementInterval, r.Name)
+		}
+
+		if len(r.Addresses) == 0 {
+			return fmt.Errorf("virtual router %q has no addresses", r.Name)
+		}
+
+		for _, addr := range r.Addresses {
+			_, _, err := net.ParseCIDR(addr)
+			if err != nil {
+				return fmt.Errorf("invalid address %q for virtual router %q", addr, r.Name)
+			}
+		}
+
+		for _, peer := range r.UnicastPeers {
+			if net.ParseIP(peer) == nil {
+				return fmt.Errorf("invalid unicast peer %q for virtual router %q", peer, r.Name)
+			}
+		}
+	}
+
+	if len(routers) == 0 {
+		return errors.New("no virtual routers defined")
+	}
+
+	return nil
+}
+
+// generateKeepalivedConfig returns the keepalived configuration for the given VRRP instances. All hosts
+// start as backup, letting the election based on priority decide which one holds the addresses.
+func generateKeepalivedConfig(routers []api.ServiceKeepalivedVirtualRouter) string {
+	ret := "# Configuration generated by Incus OS\n"
+
+	for _, r := range routers {
+		ret += fmt.Sprintf("\nvrrp_instance %s {\n", r.Name)
+		ret += "\tstate BACKUP\n"
+		ret += fmt.Sprintf("\tinterface %s\n", r.Interface)
+		ret += fmt.Sprintf("\tvirtual_router_id %d\n", r.VirtualRouterID)
+
+		if r.Priority > 0 {
+			ret += fmt.Sprintf("\tpriority %d\n", r.Priority)
+		}
+
+		if r.AdvertisementInterval > 0 {
+			ret += fmt.Sprintf("\tadvert_int %d\n", r.AdvertisementInterval)
+		}
+
+		if len(r.UnicastPeers) > 0 {
+			ret += "\tunicast_peer {\n"
+
+			for _, peer := range r.UnicastPeers {
+				ret += fmt.Sprintf("\t\t%s\n", peer)
+			}
+
+			ret += "\t}\n"
+		}
+
+		ret += "\tvirtual_ipaddress {\n"
+
+		for _, addr := range r.Addresses {
+			ret += fmt.Sprintf("\t\t%s\n", addr)
+		}
+
+		ret += "\t}\n}\n"
+	}
+
+	return ret
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+	"github.com/lxc/incus-os/incus-osd/internal/systemd"
+)
+
+// Multipath represents the system multipath service.
+type Multipath struct {
+	state *state.State
+}
+
+// Get returns the current service state.
+func (n *Multipath) Get(ctx context.Context) (any, error) {
+	// Initialize blacklist if missing.
+	if n.state.Services.Multipath.Config.Blacklist == nil {
+		n.state.Services.Multipath.Config.Blacklist = []string{}
+	}
+
+	n.state.Services.Multipath.State.Maps = []api.ServiceMultipathMap{}
+
+	// Get runtime details if enabled.
+	if n.state.Services.Multipath.Config.Enabled {
+		output, err := subprocess.RunCommandContext(ctx, "multipathd", "show", "maps", "raw", "format", "%n %w %N")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 3 {
+				continue
+			}
+
+			paths, _ := strconv.Atoi(fields[2])
+
+			n.state.Services.Multipath.State.Maps = append(n.state.Services.Multipath.State.Maps, api.ServiceMultipathMap{
+				Name:  fields[0],
+				WWID:  fields[1],
+				Paths: paths,
+			})
+		}
+	}
+
+	return n.state.Services.Multipath, nil
+}
+
+// Update updates the service configuration.
+func (n *Multipath) Update(ctx context.Context, req any) error {
+	newState, ok := req.(*api.ServiceMultipath)
+	if !ok {
+		return fmt.Errorf("request type \"%T\" isn't expected ServiceMultipath", req)
+	}
+
+	// Validate the configuration.
+	policy := newState.Config.PathGroupingPolicy
+	if policy != "" && !slices.Contains([]string{"failover", "multibus", "group_by_serial", "group_by_prio", "group_by_node_name"}, policy) {
+		return fmt.Errorf("invalid path grouping policy %q", policy)
+	}
+
+	selector, _, _ := strings.Cut(newState.Config.PathSelector, " ")
+	if selector != "" && !slices.Contains([]string{"round-robin", "queue-length", "service-time", "historical-service-time"}, selector) {
+		return fmt.Errorf("invalid path selector %q", newState.Config.PathSelector)
+	}
+
+	// Save the state on return.
+	defer n.state.Save(ctx)
+
+	// Disable the service.
+	err := n.Stop(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Update the configuration.
+	n.state.Services.Multipath.Config = newState.Config
+
+	// Bring the service back up.
+	err = n.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Stop stops the service.
+func (n *Multipath) Stop(ctx context.Context) error {
+	if !n.state.Services.Multipath.Config.Enabled {
+		return nil
+	}
+
+	// Stop the daemon.
+	err := systemd.StopUnit(ctx, "multipathd.socket", "multipathd.service")
+	if err != nil {
+		return err
+	}
+
+	// Flush the unused multipath devices, those in use are left alone.
+	_, _ = subprocess.RunCommandContext(ctx, "multipath", "-F")
+
+	return nil
+}
+
+// Start starts the service.
+func (n *Multipath) Start(ctx context.Context) error {
+	if !n.state.Services.Multipath.Config.Enabled {
+		return nil
+	}
+
+	// Ensure we have the right modules.
+	_, err := subprocess.RunCommandContext(ctx, "modprobe", "dm-multipath")
+	if err != nil {
+		return err
+	}
+
+	// Generate the configuration.
+	err = os.WriteFile("/etc/multipath.conf", []byte(generateMultipathConf(n.state.Services.Multipath)), 0o644)
+	if err != nil {
+		return err
+	}
+
+	// Start the daemon, which assembles the multipath devices.
+	err = systemd.StartUnit(ctx, "multipathd.service")
+	if err != nil {
+		return err
+	}
+
+	// Make sure the existing paths are picked up.
+	_, err = subprocess.RunCommandContext(ctx, "multipathd", "reconfigure")
+	if err != nil {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "udevadm", "settle")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ShouldStart returns true if the service should be started on boot.
+func (n *Multipath) ShouldStart() bool {
+	return n.state.Services.Multipath.Config.Enabled
+}
+
+// Struct returns the API struct for the multipath service.
+func (*Multipath) Struct() any {
+	return &api.ServiceMultipath{}
+}
+
+func (*Multipath) init(_ context.Context) error {
+	return nil
+}
+
+// generateMultipathConf generates the multipath-tools configuration. Only devices with multiple
+// paths are claimed, so that local drives remain directly usable.
+func generateMultipathConf(cfg api.ServiceMultipath) string {
+	var sb strings.Builder
+
+	sb.WriteString("defaults {\n")
+	sb.WriteString("\tfind_multipaths yes\n")
+
+	if cfg.Config.FriendlyNames {
+		sb.WriteString("\tuser_friendly_names yes\n")
+	} else {
+		sb.WriteString("\tuser_friendly_names no\n")
+	}
+
+	if cfg.Config.PathSelector != "" {
+		fmt.Fprintf(&sb, "\tpath_selector %q\n", cfg.Config.PathSelector)
+	}
+
+	if cfg.Config.PathGroupingPolicy != "" {
+		fmt.Fprintf(&sb, "\tpath_grouping_policy %s\n", cfg.Config.PathGroupingPolicy)
+	}
+
+	sb.WriteString("}\n")
+
+	if len(cfg.Config.Blacklist) > 0 {
+		sb.WriteString("\nblacklist {\n")
+
+		for _, wwid := range cfg.Config.Blacklist {
+			fmt.Fprintf(&sb, "\twwid %q\n", wwid)
+		}
+
+		sb.WriteString("}\n")
+	}
+
+	return sb.String()
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -57,11 +58,17 @@ func (n *NVME) Update(ctx context.Context, req any) error {
 		return fmt.Errorf("request type \"%T\" isn't expected ServiceNVME", req)
 	}
 
+	// Validate the configuration.
+	err := validateNVMEConfig(*newState)
+	if err != nil {
+		return err
+	}
+
 	// Save the state on return.
 	defer n.state.Save(ctx)
 
 	// Disable the service.
-	err := n.Stop(ctx)
+	err = n.Stop(ctx)
 	if err != nil {
 		return err
 	}
@@ -106,7 +113,14 @@ func (n *NVME) Start(ctx context.Context) error {
 	}
 
 	// Ensure we have the right modules.
-	for _, module := range []string{"nvme", "nvme-fabrics", "nvme-tcp"} {
+	modules := []string{"nvme", "nvme-fabrics", "nvme-tcp"}
+	for _, target := range n.state.Services.NVME.Config.Targets {
+		if target.Transport == "rdma" && !slices.Contains(modules, "nvme-rdma") {
+			modules = append(modules, "nvme-rdma")
+		}
+	}
+
+	for _, module := range modules {
 		_, err := subprocess.RunCommandContext(ctx, "modprobe", module)
 		if err != nil {
 			return err
@@ -119,9 +133,14 @@ func (n *NVME) Start(ctx context.Context) error {
 		return err
 	}
 
-	// Create the host NQN if missing.
+	// Use the configured host NQN, or create one if missing.
 	_, err = os.Stat("/etc/nvme/hostnqn")
-	if err != nil {
+	if n.state.Services.NVME.Config.HostNQN != "" {
+		err = os.WriteFile("/etc/nvme/hostnqn", []byte(n.state.Services.NVME.Config.HostNQN+"\n"), 0o600)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
 			return err
 		}
@@ -171,11 +190,13 @@ func (n *NVME) Start(ctx context.Context) error {
 	}
 
 	for _, target := range n.state.Services.NVME.Config.Targets {
+		args := nvmeTargetArgs(n.state.Services.NVME, target)
+
 		// Attempt to connect to the target (wait up to 5s).
 		//
 		// This isn't fatal as some controllers may be temporarily offline.
 		for range 10 {
-			_, err = subprocess.RunCommandContext(ctx, "nvme", "discover", "--transport", target.Transport, "--traddr", target.Address, "--trsvcid", strconv.Itoa(target.Port))
+			_, err = subprocess.RunCommandContext(ctx, "nvme", append([]string{"discover"}, args...)...)
 			if err == nil {
 				break
 			}
@@ -183,7 +204,7 @@ func (n *NVME) Start(ctx context.Context) error {
 			time.Sleep(500 * time.Millisecond)
 		}
 
-		_, err = fmt.Fprintf(f, "--transport=%s --traddr=%s --trsvcid=%d\n", target.Transport, target.Address, target.Port)
+		_, err = fmt.Fprintln(f, strings.Join(args, " "))
 		if err != nil {
 			return err
 		}
@@ -211,3 +232,57 @@ func (*NVME) Struct() any {
 func (*NVME) init(_ context.Context) error {
 	return nil
 }
+
+// validateNVMEConfig checks the NVME service configuration.
+func validateNVMEConfig(cfg api.ServiceNVME) error {
+	if cfg.Config.HostNQN != "" && !strings.HasPrefix(cfg.Config.HostNQN, "nqn.") {
+		return fmt.Errorf("invalid host NQN %q", cfg.Config.HostNQN)
+	}
+
+	if cfg.Config.ReconnectDelay < 0 {
+		return errors.New("reconnect delay can't be negative")
+	}
+
+	if cfg.Config.ControllerLossTimeout < -1 {
+		return errors.New("controller loss timeout must be -1 or greater")
+	}
+
+	for _, target := range cfg.Config.Targets {
+		if !slices.Contains([]string{"tcp", "rdma"}, target.Transport) {
+			return fmt.Errorf("unsupported NVME transport %q", target.Transport)
+		}
+
+		if target.Address == "" {
+			return errors.New("NVME targets require an address")
+		}
+
+		if target.HostInterface != "" && target.Transport != "tcp" {
+			return fmt.Errorf("a host interface can only be used with the TCP transport (target %q)", target.Address)
+		}
+	}
+
+	return nil
+}
+
+// nvmeTargetArgs returns the nvme-cli fabrics arguments for a target.
+func nvmeTargetArgs(cfg api.ServiceNVME, target api.ServiceNVMETarget) []string {
+	args := []string{"--transport=" + target.Transport, "--traddr=" + target.Address}
+
+	if target.Port > 0 {
+		args = append(args, "--trsvcid="+strconv.Itoa(target.Port))
+	}
+
+	if target.HostInterface != "" {
+		args = append(args, "--host-iface="+target.HostInterface)
+	}
+
+	if cfg.Config.ReconnectDelay > 0 {
+		args = append(args, "--reconnect-delay="+strconv.Itoa(cfg.Config.ReconnectDelay))
+	}
+
+	if cfg.Config.ControllerLossTimeout != 0 {
+		args = append(args, "--ctrl-loss-tmo="+strconv.Itoa(cfg.Config.ControllerLossTimeout))
+	}
+
+	return args
+}
@@ -12,6 +12,12 @@ func LoadOrCreate(ctx context.Context, path string) (*State, error) {
 	s := State{
 		path: path,
 
+		// Set up the triggers, before any goroutine can use them.
+		TriggerReboot:       make(chan error, 1),
+		TriggerShutdown:     make(chan error, 1),
+		TriggerUpdate:       make(chan bool, 1),
+		TriggerBundleUpdate: make(chan string, 1),
+
 		Applications: map[string]Application{},
 	}
 
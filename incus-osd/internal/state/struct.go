@@ -1,6 +1,8 @@
 package state
 
 import (
+	"sync"
+
 	"github.com/lxc/incus-os/incus-osd/api"
 )
 
@@ -25,19 +27,35 @@ type State struct {
 	TriggerShutdown chan error `json:"-"`
 	TriggerUpdate   chan bool  `json:"-"`
 
+	// Trigger for an update from an extracted update bundle, with its path.
+	TriggerBundleUpdate chan string `json:"-"`
+
+	// Serializes changes to the network configuration, including the confirmation or revert of pending ones.
+	NetworkLock sync.Mutex `json:"-"`
+
 	Applications map[string]Application `json:"applications"`
 
 	OS OS `json:"os"`
 
 	Services struct {
-		ISCSI api.ServiceISCSI `json:"iscsi"`
-		LVM   api.ServiceLVM   `json:"lvm"`
-		NVME  api.ServiceNVME  `json:"nvme"`
-		OVN   api.ServiceOVN   `json:"ovn"`
+		Ceph       api.ServiceCeph       `json:"ceph"`
+		ISCSI      api.ServiceISCSI      `json:"iscsi"`
+		Keepalived api.ServiceKeepalived `json:"keepalived"`
+		LVM        api.ServiceLVM        `json:"lvm"`
+		Multipath  api.ServiceMultipath  `json:"multipath"`
+		NVME       api.ServiceNVME       `json:"nvme"`
+		OVN        api.ServiceOVN        `json:"ovn"`
 	} `json:"services"`
 
 	System struct {
-		Encryption api.SystemEncryption `json:"encryption"`
-		Network    api.SystemNetwork    `json:"network"`
+		Encryption           api.SystemEncryption     `json:"encryption"`
+		Kernel               api.SystemKernel         `json:"kernel"`
+		Network              api.SystemNetwork        `json:"network"`
+		NetworkLastKnownGood *api.SystemNetworkConfig `json:"network_last_known_good,omitempty"`
+		NetworkUnconfirmed   bool                     `json:"network_unconfirmed,omitempty"`
+		Resources            api.SystemResources      `json:"resources"`
+		Security             api.SystemSecurity       `json:"security"`
+		Storage              api.SystemStorage        `json:"storage"`
+		Update               api.SystemUpdate         `json:"update"`
 	} `json:"system"`
 }
@@ -0,0 +1,17 @@
+package state
+
+// RequestReboot asks the daemon to reboot the system. Requests made while one is already pending are ignored.
+func (s *State) RequestReboot() {
+	select {
+	case s.TriggerReboot <- nil:
+	default:
+	}
+}
+
+// RequestShutdown asks the daemon to shut the system down. Requests made while one is already pending are ignored.
+func (s *State) RequestShutdown() {
+	select {
+	case s.TriggerShutdown <- nil:
+	default:
+	}
+}
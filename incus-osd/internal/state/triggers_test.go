@@ -0,0 +1,25 @@
+package state
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestReboot(t *testing.T) {
+	t.Parallel()
+
+	s, err := LoadOrCreate(context.Background(), filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+
+	// Repeated requests don't block nor panic.
+	s.RequestReboot()
+	s.RequestReboot()
+	s.RequestShutdown()
+	s.RequestShutdown()
+
+	require.Len(t, s.TriggerReboot, 1)
+	require.Len(t, s.TriggerShutdown, 1)
+}
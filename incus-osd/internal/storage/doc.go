@@ -0,0 +1,2 @@
+// Package storage is used to inspect the system's block devices.
+package storage
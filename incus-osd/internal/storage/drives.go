@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+type lsblkDevice struct {
+	KName      string        `json:"kname"`
+	ID         string        `json:"id-link"` //nolint:tagliatelle
+	Model      string        `json:"model"`
+	Serial     string        `json:"serial"`
+	Revision   string        `json:"rev"`
+	Transport  string        `json:"tran"`
+	Rotational bool          `json:"rota"`
+	Size       int64         `json:"size"`
+	Removable  bool          `json:"rm"`
+	Type       string        `json:"type"`
+	FSType     string        `json:"fstype"`
+	Label      string        `json:"label"`
+	Mountpoint string        `json:"mountpoint"`
+	Children   []lsblkDevice `json:"children"`
+}
+
+type lsblkOutput struct {
+	Blockdevices []lsblkDevice `json:"blockdevices"`
+}
+
+// GetDrives returns the list of drives present on the system, along with their SMART data and the
+// ZFS pools using them. A drive is considered in use if it holds any partition, filesystem or mount.
+// Drives reachable through multiple paths are listed once, as their multipath device.
+func GetDrives(ctx context.Context) ([]api.SystemStorageDrive, error) {
+	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-J", "-b", "-p", "-o", "KNAME,ID-LINK,MODEL,SERIAL,REV,TRAN,ROTA,SIZE,RM,TYPE,FSTYPE,LABEL,MOUNTPOINT")
+	if err != nil {
+		return nil, err
+	}
+
+	devices := lsblkOutput{}
+
+	err = json.Unmarshal([]byte(output), &devices)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []api.SystemStorageDrive{}
+
+	for _, dev := range selectDrives(devices.Blockdevices) {
+		drive := api.SystemStorageDrive{
+			ID:        dev.ID,
+			Device:    dev.KName,
+			Model:     strings.TrimSpace(dev.Model),
+			Serial:    strings.TrimSpace(dev.Serial),
+			Size:      dev.Size,
+			Removable: dev.Removable,
+			InUse:     len(dev.Children) > 0 || dev.FSType != "" || dev.Mountpoint != "",
+
+			Firmware:   strings.TrimSpace(dev.Revision),
+			Bus:        dev.Transport,
+			Rotational: dev.Rotational,
+			Pools:      drivePools(dev),
+		}
+
+		if drive.ID != "" {
+			drive.ID = "/dev/disk/by-id/" + drive.ID
+		}
+
+		// Not all drives expose SMART data, so only include it when available.
+		smart, err := GetSMART(ctx, drive.Device)
+		if err == nil {
+			drive.SMART = smart
+			drive.Temperature = smart.Temperature
+		}
+
+		ret = append(ret, drive)
+	}
+
+	return ret, nil
+}
+
+// selectDrives returns the physical drives, replacing the paths of multipath drives by their
+// multipath device.
+func selectDrives(devices []lsblkDevice) []lsblkDevice {
+	ret := []lsblkDevice{}
+	seen := map[string]bool{}
+
+	for _, dev := range devices {
+		// Only consider physical drives.
+		if dev.Type != "disk" || strings.HasPrefix(dev.KName, "/dev/zram") {
+			continue
+		}
+
+		isPath := false
+
+		for _, child := range dev.Children {
+			if child.Type != "mpath" {
+				continue
+			}
+
+			isPath = true
+
+			if seen[child.KName] {
+				continue
+			}
+
+			seen[child.KName] = true
+
+			// Multipath devices don't report the drive details.
+			if child.Model == "" {
+				child.Model = dev.Model
+			}
+
+			if child.Serial == "" {
+				child.Serial = dev.Serial
+			}
+
+			if child.Revision == "" {
+				child.Revision = dev.Revision
+			}
+
+			if child.Transport == "" {
+				child.Transport = dev.Transport
+			}
+
+			child.Rotational = dev.Rotational
+
+			ret = append(ret, child)
+		}
+
+		if !isPath {
+			ret = append(ret, dev)
+		}
+	}
+
+	return ret
+}
+
+// drivePools returns the ZFS pools using a drive or any of its partitions. The label of a ZFS
+// member is the name of its pool.
+func drivePools(dev lsblkDevice) []string {
+	ret := []string{}
+
+	if dev.FSType == "zfs_member" && dev.Label != "" {
+		ret = append(ret, dev.Label)
+	}
+
+	for _, child := range dev.Children {
+		for _, pool := range drivePools(child) {
+			if !slices.Contains(ret, pool) {
+				ret = append(ret, pool)
+			}
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var lsblkMultipath = `{
+  "blockdevices": [
+    {"kname": "/dev/sda", "id-link": "wwn-0x5000", "model": "SAN LUN", "serial": "ABC", "size": 1073741824, "rm": false, "type": "disk", "fstype": "mpath_member", "mountpoint": null,
+     "children": [{"kname": "/dev/dm-3", "id-link": "dm-uuid-mpath-3600a", "model": null, "serial": null, "size": 1073741824, "rm": false, "type": "mpath", "fstype": null, "mountpoint": null}]},
+    {"kname": "/dev/sdb", "id-link": "wwn-0x5000", "model": "SAN LUN", "serial": "ABC", "size": 1073741824, "rm": false, "type": "disk", "fstype": "mpath_member", "mountpoint": null,
+     "children": [{"kname": "/dev/dm-3", "id-link": "dm-uuid-mpath-3600a", "model": null, "serial": null, "size": 1073741824, "rm": false, "type": "mpath", "fstype": null, "mountpoint": null}]},
+    {"kname": "/dev/nvme0n1", "id-link": "nvme-disk", "model": "NVMe", "serial": "XYZ", "size": 2147483648, "rm": false, "type": "disk", "fstype": null, "mountpoint": null},
+    {"kname": "/dev/zram0", "id-link": null, "model": null, "serial": null, "size": 1073741824, "rm": false, "type": "disk", "fstype": "swap", "mountpoint": "[SWAP]"}
+  ]
+}`
+
+func TestSelectDrives(t *testing.T) {
+	t.Parallel()
+
+	devices := lsblkOutput{}
+	require.NoError(t, json.Unmarshal([]byte(lsblkMultipath), &devices))
+
+	drives := selectDrives(devices.Blockdevices)
+	require.Len(t, drives, 2)
+	require.Equal(t, "/dev/dm-3", drives[0].KName)
+	require.Equal(t, "dm-uuid-mpath-3600a", drives[0].ID)
+	require.Equal(t, "SAN LUN", drives[0].Model)
+	require.Equal(t, "ABC", drives[0].Serial)
+	require.Equal(t, "/dev/nvme0n1", drives[1].KName)
+}
+
+var lsblkPools = `{
+  "kname": "/dev/sda", "type": "disk", "fstype": null, "label": null,
+  "children": [
+    {"kname": "/dev/sda1", "type": "part", "fstype": "vfat", "label": "ESP"},
+    {"kname": "/dev/sda9", "type": "part", "fstype": "zfs_member", "label": "local"},
+    {"kname": "/dev/sda10", "type": "part", "fstype": "zfs_member", "label": "tank"},
+    {"kname": "/dev/sda11", "type": "part", "fstype": "zfs_member", "label": "tank"}
+  ]
+}`
+
+func TestDrivePools(t *testing.T) {
+	t.Parallel()
+
+	dev := lsblkDevice{}
+	require.NoError(t, json.Unmarshal([]byte(lsblkPools), &dev))
+
+	require.Equal(t, []string{"local", "tank"}, drivePools(dev))
+	require.Empty(t, drivePools(dev.Children[0]))
+}
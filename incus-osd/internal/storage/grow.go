@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/internal/zfs"
+)
+
+// localDataPartition is the partition holding the "local" ZFS pool, always the last one on the boot disk.
+var localDataPartition = "/dev/disk/by-partlabel/local-data"
+
+// growThreshold is the minimum free space, in 512 bytes sectors, after the last partition for it to
+// be grown (16MiB), leaving room for the backup GPT header and alignment.
+const growThreshold = 32768
+
+// GrowLocalData grows the "local-data" partition and the "local" ZFS pool to use any free space at
+// the end of the boot disk, such as after the virtual disk of a VM was enlarged. The "local" ZFS
+// pool is natively encrypted, so there is no LUKS volume to resize. Returns whether the pool was grown.
+func GrowLocalData(ctx context.Context) (bool, error) {
+	part, err := filepath.EvalSymlinks(localDataPartition)
+	if err != nil {
+		return false, err
+	}
+
+	partName := filepath.Base(part)
+
+	// The parent of the partition in sysfs is the disk.
+	diskPath, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", partName, ".."))
+	if err != nil {
+		return false, err
+	}
+
+	diskName := filepath.Base(diskPath)
+
+	diskSize, err := readSysfsBlockValue(diskName, "size")
+	if err != nil {
+		return false, err
+	}
+
+	partStart, err := readSysfsBlockValue(partName, "start")
+	if err != nil {
+		return false, err
+	}
+
+	partSize, err := readSysfsBlockValue(partName, "size")
+	if err != nil {
+		return false, err
+	}
+
+	// The partition may already have been grown by systemd-repart during boot, in which case only
+	// the pool needs expanding.
+	if hasFreeSpace(diskSize, partStart, partSize) {
+		slog.Info("Growing local storage to use free disk space", "disk", "/dev/"+diskName, "free", (diskSize-partStart-partSize)*512)
+
+		// Let systemd-repart relocate the backup GPT header and grow the last partition.
+		_, err = subprocess.RunCommandContext(ctx, "systemd-repart", "--dry-run=no", "--no-pager", "/dev/"+diskName)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return zfs.ExpandLocalPool(ctx)
+}
+
+// hasFreeSpace returns whether there is enough free space after the partition to grow it.
+func hasFreeSpace(diskSize int64, partStart int64, partSize int64) bool {
+	return diskSize-(partStart+partSize) >= growThreshold
+}
+
+// readSysfsBlockValue reads an integer attribute of a block device from sysfs.
+func readSysfsBlockValue(name string, attribute string) (int64, error) {
+	content, err := os.ReadFile(filepath.Join("/sys/class/block", name, attribute))
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, errors.New("invalid " + attribute + " value for block device " + name)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasFreeSpace(t *testing.T) {
+	t.Parallel()
+
+	// Partition ending right before the backup GPT header.
+	require.False(t, hasFreeSpace(209715200, 73400320, 136314847))
+
+	// Disk enlarged from 100GiB to 200GiB.
+	require.True(t, hasFreeSpace(419430400, 73400320, 136314847))
+}
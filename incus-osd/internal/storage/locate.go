@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// sysfsRoot is the root of the sysfs filesystem.
+var sysfsRoot = "/sys"
+
+// SetDriveLocate turns the locate LED of a drive, identified by its kernel name (such as "sda"), on
+// or off. The enclosure slot exposed by the kernel SES driver is used when available, otherwise
+// ledctl drives the LED through the enclosure or the NVMe slot (VMD or NPEM).
+func SetDriveLocate(ctx context.Context, name string, enabled bool) error {
+	if name == "" || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid drive name %q", name)
+	}
+
+	_, err := os.Stat(filepath.Join(sysfsRoot, "class", "block", name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("drive %q doesn't exist", name)
+		}
+
+		return err
+	}
+
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+
+	locateFile := findEnclosureLocate(sysfsRoot, name)
+	if locateFile != "" {
+		return os.WriteFile(locateFile, []byte(value), 0o200)
+	}
+
+	pattern := "locate_off"
+	if enabled {
+		pattern = "locate"
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "ledctl", pattern+"=/dev/"+name)
+	if err != nil {
+		return fmt.Errorf("failed to set locate LED for drive %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// findEnclosureLocate returns the locate attribute of the enclosure slot holding the drive, if any.
+func findEnclosureLocate(root string, name string) string {
+	matches, _ := filepath.Glob(filepath.Join(root, "class", "block", name, "device", "enclosure_device:*", "locate"))
+	if len(matches) == 0 {
+		return ""
+	}
+
+	return matches[0]
+}
@@ -0,0 +1,23 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindEnclosureLocate(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+
+	slot := filepath.Join(root, "class", "block", "sda", "device", "enclosure_device:Slot 04")
+	require.NoError(t, os.MkdirAll(slot, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(slot, "locate"), []byte("0"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "class", "block", "nvme0n1", "device"), 0o755))
+
+	require.Equal(t, filepath.Join(slot, "locate"), findEnclosureLocate(root, "sda"))
+	require.Empty(t, findEnclosureLocate(root, "nvme0n1"))
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"slices"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// SMARTCheckInterval is how often the drives' SMART data is collected.
+var SMARTCheckInterval = time.Hour
+
+type smartctlOutput struct {
+	SmartSupport struct {
+		Enabled bool `json:"enabled"`
+	} `json:"smart_support"`
+
+	SmartStatus struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+
+	Temperature struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+
+	PowerOnTime struct {
+		Hours int64 `json:"hours"`
+	} `json:"power_on_time"`
+
+	ATASmartAttributes struct {
+		Table []struct {
+			ID         int    `json:"id"`
+			Name       string `json:"name"`
+			Value      int    `json:"value"`
+			Worst      int    `json:"worst"`
+			Thresh     int    `json:"thresh"`
+			WhenFailed string `json:"when_failed"`
+			Flags      struct {
+				Prefailure bool `json:"prefailure"`
+			} `json:"flags"`
+			Raw struct {
+				Value int64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+
+	NVMeSmartHealthInformationLog *struct {
+		CriticalWarning int   `json:"critical_warning"`
+		AvailableSpare  int   `json:"available_spare"`
+		SpareThreshold  int   `json:"available_spare_threshold"`
+		PercentageUsed  int   `json:"percentage_used"`
+		MediaErrors     int64 `json:"media_errors"`
+		UnsafeShutdowns int64 `json:"unsafe_shutdowns"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// GetSMART returns the SMART health information of a drive.
+func GetSMART(ctx context.Context, device string) (*api.SystemStorageDriveSMART, error) {
+	output, err := subprocess.RunCommandContext(ctx, "smartctl", "-j", "-a", device)
+	if err != nil {
+		// smartctl uses its exit status as a bitmask, only the two lowest bits indicate
+		// that no data could be retrieved.
+		var runErr subprocess.RunError
+
+		var exitErr *exec.ExitError
+
+		if !errors.As(err, &runErr) || !errors.As(err, &exitErr) || exitErr.ExitCode()&0x3 != 0 {
+			return nil, err
+		}
+
+		output = runErr.StdOut().String()
+	}
+
+	return parseSMART([]byte(output))
+}
+
+// MonitorSMART periodically collects the SMART data of all drives and logs a warning each time a
+// new pre-failure condition is detected.
+func MonitorSMART(ctx context.Context) {
+	seen := map[string][]string{}
+
+	for {
+		drives, err := GetDrives(ctx)
+		if err != nil {
+			slog.Error("Failed to get drives for SMART monitoring", "err", err.Error())
+		}
+
+		for _, drive := range drives {
+			if drive.SMART == nil {
+				continue
+			}
+
+			for _, warning := range drive.SMART.Warnings {
+				if slices.Contains(seen[drive.Device], warning) {
+					continue
+				}
+
+				slog.Warn("Drive health warning", "device", drive.Device, "model", drive.Model, "serial", drive.Serial, "warning", warning)
+			}
+
+			seen[drive.Device] = drive.SMART.Warnings
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(SMARTCheckInterval):
+		}
+	}
+}
+
+// parseSMART converts the smartctl JSON output and flags any pre-failure condition.
+func parseSMART(data []byte) (*api.SystemStorageDriveSMART, error) {
+	out := smartctlOutput{}
+
+	err := json.Unmarshal(data, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &api.SystemStorageDriveSMART{
+		Enabled:      out.SmartSupport.Enabled,
+		Passed:       out.SmartStatus.Passed,
+		Temperature:  out.Temperature.Current,
+		PowerOnHours: out.PowerOnTime.Hours,
+	}
+
+	if !ret.Passed {
+		ret.Warnings = append(ret.Warnings, "SMART overall health self-assessment failed")
+	}
+
+	for _, attr := range out.ATASmartAttributes.Table {
+		ret.Attributes = append(ret.Attributes, api.SystemStorageDriveSMARTAttribute{
+			ID:         attr.ID,
+			Name:       attr.Name,
+			Value:      attr.Value,
+			Worst:      attr.Worst,
+			Threshold:  attr.Thresh,
+			Raw:        attr.Raw.Value,
+			PreFailure: attr.Flags.Prefailure,
+			WhenFailed: attr.WhenFailed,
+		})
+
+		if attr.WhenFailed != "" || (attr.Flags.Prefailure && attr.Thresh > 0 && attr.Value <= attr.Thresh) {
+			ret.Warnings = append(ret.Warnings, fmt.Sprintf("Attribute %s (%d) crossed its failure threshold", attr.Name, attr.ID))
+		}
+	}
+
+	nvme := out.NVMeSmartHealthInformationLog
+	if nvme != nil {
+		ret.NVMe = &api.SystemStorageDriveSMARTNVMe{
+			CriticalWarning:         nvme.CriticalWarning,
+			AvailableSpare:          nvme.AvailableSpare,
+			AvailableSpareThreshold: nvme.SpareThreshold,
+			PercentageUsed:          nvme.PercentageUsed,
+			MediaErrors:             nvme.MediaErrors,
+			UnsafeShutdowns:         nvme.UnsafeShutdowns,
+		}
+
+		if nvme.CriticalWarning != 0 {
+			ret.Warnings = append(ret.Warnings, fmt.Sprintf("NVMe critical warning 0x%02x reported", nvme.CriticalWarning))
+		}
+
+		if nvme.AvailableSpare < nvme.SpareThreshold {
+			ret.Warnings = append(ret.Warnings, "NVMe available spare below threshold")
+		}
+
+		if nvme.PercentageUsed >= 100 {
+			ret.Warnings = append(ret.Warnings, "NVMe endurance exhausted")
+		}
+
+		if nvme.MediaErrors > 0 {
+			ret.Warnings = append(ret.Warnings, fmt.Sprintf("NVMe reported %d media errors", nvme.MediaErrors))
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var smartctlATA = `{
+  "smart_support": {"available": true, "enabled": true},
+  "smart_status": {"passed": true},
+  "temperature": {"current": 34},
+  "power_on_time": {"hours": 12345},
+  "ata_smart_attributes": {
+    "table": [
+      {"id": 5, "name": "Reallocated_Sector_Ct", "value": 100, "worst": 100, "thresh": 10, "when_failed": "", "flags": {"prefailure": true}, "raw": {"value": 0}},
+      {"id": 10, "name": "Spin_Retry_Count", "value": 9, "worst": 9, "thresh": 97, "when_failed": "", "flags": {"prefailure": true}, "raw": {"value": 3}},
+      {"id": 194, "name": "Temperature_Celsius", "value": 34, "worst": 50, "thresh": 0, "when_failed": "", "flags": {"prefailure": false}, "raw": {"value": 34}}
+    ]
+  }
+}`
+
+var smartctlNVMe = `{
+  "smart_support": {"available": true, "enabled": true},
+  "smart_status": {"passed": false},
+  "temperature": {"current": 41},
+  "power_on_time": {"hours": 42},
+  "nvme_smart_health_information_log": {
+    "critical_warning": 4,
+    "available_spare": 5,
+    "available_spare_threshold": 10,
+    "percentage_used": 3,
+    "media_errors": 0,
+    "unsafe_shutdowns": 7
+  }
+}`
+
+func TestParseSMART(t *testing.T) {
+	t.Parallel()
+
+	smart, err := parseSMART([]byte(smartctlATA))
+	require.NoError(t, err)
+	require.True(t, smart.Enabled)
+	require.True(t, smart.Passed)
+	require.Equal(t, 34, smart.Temperature)
+	require.Equal(t, int64(12345), smart.PowerOnHours)
+	require.Len(t, smart.Attributes, 3)
+	require.Nil(t, smart.NVMe)
+	require.Equal(t, []string{"Attribute Spin_Retry_Count (10) crossed its failure threshold"}, smart.Warnings)
+
+	smart, err = parseSMART([]byte(smartctlNVMe))
+	require.NoError(t, err)
+	require.NotNil(t, smart.NVMe)
+	require.Equal(t, int64(7), smart.NVMe.UnsafeShutdowns)
+	require.Equal(t, []string{"SMART overall health self-assessment failed", "NVMe critical warning 0x04 reported", "NVMe available spare below threshold"}, smart.Warnings)
+}
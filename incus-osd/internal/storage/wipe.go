@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// remoteTransports lists the transports of drives which aren't local to the system.
+var remoteTransports = []string{"fc", "iscsi"}
+
+type lsblkWipeDevice struct {
+	KName     string `json:"kname"`
+	Type      string `json:"type"`
+	Transport string `json:"tran"`
+	Removable bool   `json:"rm"`
+}
+
+// SecureEraseDrives erases all local drives, using the drive's own secure erase when supported and
+// falling back to discarding then wiping the signatures of the drive. Remote and removable drives are
+// left untouched. The boot disk is erased last, as the system stops being usable afterwards.
+func SecureEraseDrives(ctx context.Context) error {
+	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-J", "-d", "-p", "-o", "KNAME,TYPE,TRAN,RM")
+	if err != nil {
+		return err
+	}
+
+	devices := struct {
+		Blockdevices []lsblkWipeDevice `json:"blockdevices"`
+	}{}
+
+	err = json.Unmarshal([]byte(output), &devices)
+	if err != nil {
+		return err
+	}
+
+	bootDisk, err := getBootDisk()
+	if err != nil {
+		return err
+	}
+
+	drives := selectEraseDrives(devices.Blockdevices, bootDisk)
+
+	var errs []error
+
+	for _, drive := range drives {
+		err := secureErase(ctx, drive)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// getBootDisk returns the disk holding the "local-data" partition.
+func getBootDisk() (string, error) {
+	part, err := filepath.EvalSymlinks(localDataPartition)
+	if err != nil {
+		return "", err
+	}
+
+	diskPath, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", filepath.Base(part), ".."))
+	if err != nil {
+		return "", err
+	}
+
+	return "/dev/" + filepath.Base(diskPath), nil
+}
+
+// selectEraseDrives returns the drives to erase, with the boot disk last.
+func selectEraseDrives(devices []lsblkWipeDevice, bootDisk string) []string {
+	ret := []string{}
+	hasBootDisk := false
+
+	for _, dev := range devices {
+		if dev.Type != "disk" || dev.Removable || strings.HasPrefix(dev.KName, "/dev/zram") {
+			continue
+		}
+
+		if slices.Contains(remoteTransports, dev.Transport) {
+			continue
+		}
+
+		if dev.KName == bootDisk {
+			hasBootDisk = true
+
+			continue
+		}
+
+		ret = append(ret, dev.KName)
+	}
+
+	if hasBootDisk {
+		ret = append(ret, bootDisk)
+	}
+
+	return ret
+}
+
+// eraseCommands returns the commands to try, in order, to erase a drive.
+func eraseCommands(device string) [][]string {
+	ret := [][]string{}
+
+	// NVMe drives support a cryptographic or user data erase through a format.
+	if strings.HasPrefix(filepath.Base(device), "nvme") {
+		ret = append(ret, []string{"nvme", "format", "--ses=2", "--force", device}, []string{"nvme", "format", "--ses=1", "--force", device})
+	}
+
+	return append(ret,
+		[]string{"blkdiscard", "--secure", "--force", device},
+		[]string{"blkdiscard", "--force", device},
+		[]string{"wipefs", "--all", "--force", device},
+	)
+}
+
+// secureErase erases a drive using the first supported method.
+func secureErase(ctx context.Context, device string) error {
+	var err error
+
+	for _, cmd := range eraseCommands(device) {
+		_, err = subprocess.RunCommandContext(ctx, cmd[0], cmd[1:]...)
+		if err == nil {
+			slog.Info("Erased drive", "device", device, "method", strings.Join(cmd[:2], " "))
+
+			return nil
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectEraseDrives(t *testing.T) {
+	t.Parallel()
+
+	devices := []lsblkWipeDevice{
+		{KName: "/dev/sda", Type: "disk", Transport: "sata"},
+		{KName: "/dev/nvme0n1", Type: "disk", Transport: "nvme"},
+		{KName: "/dev/sdb", Type: "disk", Transport: "iscsi"},
+		{KName: "/dev/sdc", Type: "disk", Transport: "usb", Removable: true},
+		{KName: "/dev/zram0", Type: "disk"},
+		{KName: "/dev/sr0", Type: "rom", Transport: "sata"},
+		{KName: "/dev/vdb", Type: "disk"},
+	}
+
+	require.Equal(t, []string{"/dev/nvme0n1", "/dev/vdb", "/dev/sda"}, selectEraseDrives(devices, "/dev/sda"))
+}
+
+func TestEraseCommands(t *testing.T) {
+	t.Parallel()
+
+	cmds := eraseCommands("/dev/nvme0n1")
+	require.Len(t, cmds, 5)
+	require.Equal(t, "nvme", cmds[0][0])
+
+	cmds = eraseCommands("/dev/sda")
+	require.Len(t, cmds, 3)
+	require.Equal(t, []string{"blkdiscard", "--secure", "--force", "/dev/sda"}, cmds[0])
+}
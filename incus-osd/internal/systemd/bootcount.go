@@ -0,0 +1,155 @@
+package systemd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// IsReleaseBootFailed returns whether the boot entry of a release ran out of boot attempts, having
+// never been marked as good, in which case systemd-boot falls back to the previous release.
+func IsReleaseBootFailed(version string) bool {
+	entries, err := filepath.Glob(filepath.Join(BootEntriesPath, "IncusOS_"+version+"+*.efi"))
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		triesLeft, ok := bootEntryTriesLeft(filepath.Base(entry))
+		if ok && triesLeft == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MarkBootReady records that the system fully started, allowing the boot entry to be marked as good.
+func MarkBootReady() error {
+	return os.WriteFile(BootReadyFile, nil, 0o600)
+}
+
+// bootEntryTriesLeft returns the boot attempts left from the name of a boot entry using boot
+// counting ("<name>+<left>[-<done>].efi").
+func bootEntryTriesLeft(name string) (int, bool) {
+	triesLeft, _, ok := bootEntryCounters(name)
+
+	return triesLeft, ok
+}
+
+// bootEntryCounters returns the boot attempts left and done from the name of a boot entry using
+// boot counting.
+func bootEntryCounters(name string) (int, int, bool) {
+	_, counter, ok := strings.Cut(strings.TrimSuffix(name, ".efi"), "+")
+	if !ok {
+		return 0, 0, false
+	}
+
+	left, done, _ := strings.Cut(counter, "-")
+
+	triesLeft, err := strconv.Atoi(left)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	triesDone := 0
+
+	if done != "" {
+		triesDone, err = strconv.Atoi(done)
+		if err != nil {
+			return 0, 0, false
+		}
+	}
+
+	return triesLeft, triesDone, true
+}
+
+// RollbackRelease makes the previous release the default boot entry, by marking the boot entry of
+// the running release as bad. Returns the previous release.
+func RollbackRelease(runningRelease string) (string, error) {
+	entries, err := filepath.Glob(filepath.Join(BootEntriesPath, "IncusOS_*.efi"))
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, filepath.Base(entry))
+	}
+
+	previous := previousBootRelease(names, runningRelease)
+	if previous == "" {
+		return "", errors.New("no previous OS image is available")
+	}
+
+	for _, name := range names {
+		if bootEntryRelease(name) != runningRelease {
+			continue
+		}
+
+		// Entries with no boot attempts left are sorted last by systemd-boot.
+		err := os.Rename(filepath.Join(BootEntriesPath, name), filepath.Join(BootEntriesPath, "IncusOS_"+runningRelease+"+0.efi"))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return previous, nil
+}
+
+// bootEntryRelease returns the release of a boot entry.
+func bootEntryRelease(name string) string {
+	release := strings.TrimSuffix(strings.TrimPrefix(name, "IncusOS_"), ".efi")
+	release, _, _ = strings.Cut(release, "+")
+
+	return release
+}
+
+// previousBootRelease returns the newest release older than the running one among the boot entries.
+func previousBootRelease(names []string, runningRelease string) string {
+	previous := ""
+
+	for _, name := range names {
+		release := bootEntryRelease(name)
+
+		// Releases are YYYYMMDDhhmm, so compare as strings of the same length.
+		if len(release) != len(runningRelease) || release >= runningRelease || release <= previous {
+			continue
+		}
+
+		// Skip releases which already failed to boot.
+		triesLeft, ok := bootEntryTriesLeft(name)
+		if ok && triesLeft == 0 {
+			continue
+		}
+
+		previous = release
+	}
+
+	return previous
+}
+
+// BootEntry returns the path to the boot entry (unified kernel image) of a release.
+func BootEntry(release string) (string, error) {
+	entries, err := filepath.Glob(filepath.Join(BootEntriesPath, "IncusOS_*.efi"))
+	if err != nil {
+		return "", err
+	}
+
+	for _, entry := range entries {
+		if bootEntryRelease(filepath.Base(entry)) == release {
+			return entry, nil
+		}
+	}
+
+	return "", fmt.Errorf("no boot entry for release %q", release)
+}
+
+// BootEntries returns the paths to all installed boot entries (unified kernel images), covering the
+// running release, a staged update and the releases available for a rollback.
+func BootEntries() ([]string, error) {
+	return filepath.Glob(filepath.Join(BootEntriesPath, "IncusOS_*.efi"))
+}
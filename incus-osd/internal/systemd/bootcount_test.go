@@ -0,0 +1,42 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBootEntryTriesLeft(t *testing.T) {
+	t.Parallel()
+
+	left, ok := bootEntryTriesLeft("IncusOS_202506011200+2-1.efi")
+	require.True(t, ok)
+	require.Equal(t, 2, left)
+
+	left, ok = bootEntryTriesLeft("IncusOS_202506011200+0-3.efi")
+	require.True(t, ok)
+	require.Equal(t, 0, left)
+
+	left, ok = bootEntryTriesLeft("IncusOS_202506011200+3.efi")
+	require.True(t, ok)
+	require.Equal(t, 3, left)
+
+	_, ok = bootEntryTriesLeft("IncusOS_202506011200.efi")
+	require.False(t, ok)
+}
+
+func TestPreviousBootRelease(t *testing.T) {
+	t.Parallel()
+
+	names := []string{
+		"IncusOS_202506011200.efi",
+		"IncusOS_202506021200+0-3.efi",
+		"IncusOS_202506031200.efi",
+		"IncusOS_202506041200+2-1.efi",
+	}
+
+	require.Equal(t, "202506011200", previousBootRelease(names, "202506031200"))
+	require.Equal(t, "202506031200", previousBootRelease(names, "202506041200"))
+	require.Empty(t, previousBootRelease(names, "202506011200"))
+	require.Equal(t, "202506021200", bootEntryRelease("IncusOS_202506021200+0-3.efi"))
+}
@@ -0,0 +1,112 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// validateCLAT checks the CLAT configuration and that clatd is available.
+func validateCLAT(networkCfg api.SystemNetworkConfig) error {
+	if networkCfg.CLAT == nil {
+		return nil
+	}
+
+	clat := networkCfg.CLAT
+	devices := getNetworkConfigDevices(networkCfg)
+
+	if clat.Interface != "" && !slices.ContainsFunc(devices, func(dev networkConfigDevice) bool {
+		return dev.name == clat.Interface
+	}) {
+		return fmt.Errorf("CLAT interface %q doesn't exist", clat.Interface)
+	}
+
+	// The translator creates its own "clat" device.
+	if slices.ContainsFunc(devices, func(dev networkConfigDevice) bool {
+		return dev.name == "clat"
+	}) {
+		return errors.New("the device name \"clat\" is reserved when using a CLAT")
+	}
+
+	// Only the prefix lengths defined by RFC 6052 can embed IPv4 addresses.
+	if clat.PLATPrefix != "" {
+		ip, prefix, err := net.ParseCIDR(clat.PLATPrefix)
+		if err != nil || ip.To4() != nil {
+			return fmt.Errorf("invalid CLAT NAT64 prefix %q", clat.PLATPrefix)
+		}
+
+		ones, _ := prefix.Mask.Size()
+		if !slices.Contains([]int{32, 40, 48, 56, 64, 96}, ones) {
+			return fmt.Errorf("unsupported CLAT NAT64 prefix length /%d", ones)
+		}
+	}
+
+	for _, server := range clat.DNS64Servers {
+		ip := net.ParseIP(server)
+		if ip == nil || ip.To4() != nil {
+			return fmt.Errorf("invalid CLAT DNS64 server %q", server)
+		}
+	}
+
+	// DNSSEC validation fails for the AAAA records synthesized by DNS64.
+	if networkCfg.DNS != nil && networkCfg.DNS.DNSSEC == "yes" {
+		return errors.New("DNSSEC validation can't be enforced when using a CLAT")
+	}
+
+	_, err := exec.LookPath("clatd")
+	if err != nil {
+		return errors.New("the CLAT translator isn't available")
+	}
+
+	return nil
+}
+
+// writeCLATConfiguration writes the clatd configuration, or removes it if no CLAT is requested.
+func writeCLATConfiguration(networkCfg api.SystemNetworkConfig) error {
+	if networkCfg.CLAT == nil {
+		err := os.Remove(CLATConfigFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	return os.WriteFile(CLATConfigFile, []byte(generateCLATContents(*networkCfg.CLAT)), 0o644)
+}
+
+// restartCLAT (re)starts clatd if a CLAT is requested, otherwise stops it.
+func restartCLAT(ctx context.Context, networkCfg *api.SystemNetworkConfig) error {
+	if networkCfg.CLAT == nil {
+		_ = StopUnit(ctx, "clatd")
+
+		return nil
+	}
+
+	return RestartUnit(ctx, "clatd")
+}
+
+func generateCLATContents(clat api.SystemNetworkCLAT) string {
+	ret := "clat-dev=clat\n"
+
+	if clat.Interface != "" {
+		ret += fmt.Sprintf("plat-dev=%s\n", clat.Interface)
+	}
+
+	if clat.PLATPrefix != "" {
+		ret += fmt.Sprintf("plat-prefix=%s\n", clat.PLATPrefix)
+	}
+
+	if len(clat.DNS64Servers) > 0 {
+		ret += fmt.Sprintf("dns64-servers=%s\n", strings.Join(clat.DNS64Servers, ","))
+	}
+
+	return ret
+}
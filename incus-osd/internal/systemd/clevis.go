@@ -0,0 +1,157 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+type clevisTangPin struct {
+	URL        string `json:"url"`
+	Thumbprint string `json:"thp"` //nolint:tagliatelle
+}
+
+type clevisTPM2Pin struct {
+	PCRIDs string `json:"pcr_ids"`
+}
+
+type clevisSSSPolicy struct {
+	Threshold int `json:"t"`
+	Pins      struct {
+		Tang []clevisTangPin `json:"tang"`
+		TPM2 *clevisTPM2Pin  `json:"tpm2,omitempty"`
+	} `json:"pins"`
+}
+
+// ValidateTang checks that a Tang unlock policy is valid.
+func ValidateTang(cfg api.SystemEncryptionTang) error {
+	if len(cfg.Servers) == 0 {
+		return errors.New("at least one Tang server is required")
+	}
+
+	for _, server := range cfg.Servers {
+		u, err := url.Parse(server.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid Tang server URL %q", server.URL)
+		}
+
+		// Require the thumbprint so that the advertisement can be trusted without user interaction.
+		if server.Thumbprint == "" {
+			return fmt.Errorf("missing thumbprint for Tang server %q", server.URL)
+		}
+	}
+
+	shares := len(cfg.Servers)
+	if cfg.IncludeTPM {
+		shares++
+	}
+
+	if cfg.Threshold < 1 || cfg.Threshold > shares {
+		return fmt.Errorf("threshold must be between 1 and %d", shares)
+	}
+
+	return nil
+}
+
+// ApplyTang binds the root LUKS volume to the Tang servers using clevis, replacing any previous
+// binding, or removes the binding if no policy is provided. An enrolled recovery key is used to
+// authorize the change.
+func ApplyTang(ctx context.Context, s *state.State, cfg *api.SystemEncryptionTang) error {
+	if cfg != nil {
+		err := ValidateTang(*cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(s.System.Encryption.Config.RecoveryKeys) == 0 {
+		return errors.New("no recovery key available to authorize the change")
+	}
+
+	// Remove the existing clevis bindings.
+	output, err := subprocess.RunCommandContext(ctx, "clevis", "luks", "list", "-d", RootPartition)
+	if err != nil {
+		return err
+	}
+
+	for _, slot := range parseClevisSlots(output) {
+		_, err := subprocess.RunCommandContext(ctx, "clevis", "luks", "unbind", "-f", "-d", RootPartition, "-s", strconv.Itoa(slot))
+		if err != nil {
+			return err
+		}
+	}
+
+	if cfg == nil {
+		recordEncryptionAudit(s, "unbind", "root", -1, "clevis")
+
+		return nil
+	}
+
+	// Bind to the new policy.
+	policy, err := generateClevisPolicy(*cfg)
+	if err != nil {
+		return err
+	}
+
+	err = subprocess.RunCommandWithFds(ctx, strings.NewReader(s.System.Encryption.Config.RecoveryKeys[0]), nil, "clevis", "luks", "bind", "-y", "-k", "-", "-d", RootPartition, "sss", policy)
+	if err != nil {
+		return err
+	}
+
+	recordEncryptionAudit(s, "bind", "root", -1, "clevis")
+
+	return nil
+}
+
+// generateClevisPolicy generates the clevis Shamir Secret Sharing policy.
+func generateClevisPolicy(cfg api.SystemEncryptionTang) (string, error) {
+	policy := clevisSSSPolicy{
+		Threshold: cfg.Threshold,
+	}
+
+	for _, server := range cfg.Servers {
+		policy.Pins.Tang = append(policy.Pins.Tang, clevisTangPin{URL: server.URL, Thumbprint: server.Thumbprint})
+	}
+
+	// Match the Secure Boot state PCR used for the TPM-sealed key.
+	if cfg.IncludeTPM {
+		policy.Pins.TPM2 = &clevisTPM2Pin{PCRIDs: "7"}
+	}
+
+	content, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+// parseClevisSlots returns the LUKS key slots listed by "clevis luks list".
+func parseClevisSlots(output string) []int {
+	ret := []int{}
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		slot, _, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+
+		value, err := strconv.Atoi(strings.TrimSpace(slot))
+		if err != nil {
+			continue
+		}
+
+		ret = append(ret, value)
+	}
+
+	return ret
+}
@@ -0,0 +1,40 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestClevisPolicy(t *testing.T) {
+	t.Parallel()
+
+	cfg := api.SystemEncryptionTang{
+		Servers: []api.SystemEncryptionTangServer{
+			{URL: "http://tang1.example.com", Thumbprint: "abc"},
+			{URL: "https://tang2.example.com:8443", Thumbprint: "def"},
+		},
+		Threshold:  2,
+		IncludeTPM: true,
+	}
+
+	require.NoError(t, ValidateTang(cfg))
+
+	policy, err := generateClevisPolicy(cfg)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"t":2,"pins":{"tang":[{"url":"http://tang1.example.com","thp":"abc"},{"url":"https://tang2.example.com:8443","thp":"def"}],"tpm2":{"pcr_ids":"7"}}}`, policy)
+
+	cfg.Threshold = 4
+	require.Error(t, ValidateTang(cfg))
+
+	cfg.Threshold = 1
+	cfg.Servers[0].Thumbprint = ""
+	require.Error(t, ValidateTang(cfg))
+
+	require.Error(t, ValidateTang(api.SystemEncryptionTang{Threshold: 1}))
+
+	require.Equal(t, []int{1, 3}, parseClevisSlots("1: sss '{\"t\":1,\"pins\":{\"tang\":[{\"url\":\"http://tang1\"}]}}'\n3: tang '{\"url\":\"http://tang2\"}'\n"))
+	require.Empty(t, parseClevisSlots(""))
+}
@@ -0,0 +1,87 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// KernelAddonFile is the global systemd-stub addon holding the extra kernel command line parameters.
+var KernelAddonFile = "/boot/loader/addons/incus-os.addon.efi"
+
+// protectedKernelParameters can't be set as they'd bypass the image integrity or boot process.
+var protectedKernelParameters = []string{"init", "rdinit", "lockdown", "module.sig_enforce", "usrhash", "roothash", "systemd.verity", "systemd.unit", "rd.systemd.unit"}
+
+// ValidateKernelParameters checks the extra kernel command line parameters.
+func ValidateKernelParameters(parameters []string) error {
+	for _, parameter := range parameters {
+		if parameter == "" || strings.ContainsAny(parameter, " \t\n\"'\\") {
+			return fmt.Errorf("invalid kernel parameter %q", parameter)
+		}
+
+		name, _, _ := strings.Cut(parameter, "=")
+		if slices.Contains(protectedKernelParameters, name) {
+			return fmt.Errorf("kernel parameter %q can't be set", name)
+		}
+	}
+
+	return nil
+}
+
+// ApplyKernelParameters writes the command line addon, which is removed if there are no parameters.
+func ApplyKernelParameters(ctx context.Context, parameters []string) error {
+	if len(parameters) == 0 {
+		err := os.Remove(KernelAddonFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(KernelAddonFile), 0o755)
+	if err != nil {
+		return err
+	}
+
+	// Build the addon next to its final location, then move it into place.
+	tmpFile := KernelAddonFile + ".tmp"
+
+	_, err = subprocess.RunCommandContext(ctx, "ukify", "build", "--cmdline="+strings.Join(parameters, " "), "--output="+tmpFile)
+	if err != nil {
+		_ = os.Remove(tmpFile)
+
+		return err
+	}
+
+	return os.Rename(tmpFile, KernelAddonFile)
+}
+
+// ActiveKernelParameters returns the parameters found on the running kernel's command line.
+func ActiveKernelParameters(parameters []string) ([]string, error) {
+	cmdline, err := os.ReadFile("/proc/cmdline")
+	if err != nil {
+		return nil, err
+	}
+
+	return activeKernelParameters(parameters, string(cmdline)), nil
+}
+
+func activeKernelParameters(parameters []string, cmdline string) []string {
+	fields := strings.Fields(cmdline)
+	active := []string{}
+
+	for _, parameter := range parameters {
+		if slices.Contains(fields, parameter) {
+			active = append(active, parameter)
+		}
+	}
+
+	return active
+}
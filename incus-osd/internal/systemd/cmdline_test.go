@@ -0,0 +1,26 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateKernelParameters(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateKernelParameters([]string{"intel_iommu=on", "isolcpus=2-7", "quiet"}))
+	require.Error(t, ValidateKernelParameters([]string{"intel_iommu=on quiet"}))
+	require.Error(t, ValidateKernelParameters([]string{""}))
+	require.Error(t, ValidateKernelParameters([]string{"init=/bin/sh"}))
+	require.Error(t, ValidateKernelParameters([]string{"module.sig_enforce=0"}))
+}
+
+func TestActiveKernelParameters(t *testing.T) {
+	t.Parallel()
+
+	cmdline := "console=tty0 intel_iommu=on isolcpus=2-3\n"
+
+	require.Equal(t, []string{"intel_iommu=on"}, activeKernelParameters([]string{"intel_iommu=on", "isolcpus=2-7"}, cmdline))
+	require.Empty(t, activeKernelParameters(nil, cmdline))
+}
@@ -15,7 +15,7 @@ import (
 // GenerateRecoveryKey utilizes systemd-cryptenroll to generate a recovery key for the
 // root LUKS volume. Depends on an existing tpm2-backed key being enrolled and accessible.
 func GenerateRecoveryKey(ctx context.Context, s *state.State) error {
-	output, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--recovery-key", "/dev/disk/by-partlabel/root-x86-64")
+	output, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--recovery-key", RootPartition)
 	if err != nil {
 		return err
 	}
@@ -23,6 +23,8 @@ func GenerateRecoveryKey(ctx context.Context, s *state.State) error {
 	s.System.Encryption.Config.RecoveryKeys = append(s.System.Encryption.Config.RecoveryKeys, strings.TrimSuffix(output, "\n"))
 	s.System.Encryption.State.RecoveryKeysRetrieved = false
 
+	recordEncryptionAudit(s, "add", "root", -1, "recovery")
+
 	return nil
 }
 
@@ -33,8 +35,20 @@ func AddEncryptionKey(ctx context.Context, s *state.State, key string) error {
 		return errors.New("provided encryption key is already enrolled")
 	}
 
+	err := addEncryptionKey(ctx, s, key)
+	if err != nil {
+		return err
+	}
+
+	recordEncryptionAudit(s, "add", "root", -1, "password")
+
+	return nil
+}
+
+// addEncryptionKey enrolls a key in the root LUKS volume and records it.
+func addEncryptionKey(ctx context.Context, s *state.State, key string) error {
 	// Add the new encryption password. Need to pass to systemd-cryptenroll via NEWPASSWORD environment variable.
-	_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "NEWPASSWORD="+key), nil, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--password", "/dev/disk/by-partlabel/root-x86-64")
+	_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "NEWPASSWORD="+key), nil, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--password", RootPartition)
 	if err != nil {
 		return err
 	}
@@ -54,7 +68,7 @@ func DeleteEncryptionKey(ctx context.Context, s *state.State, key string) error
 	}
 
 	// First, wipe all recovery and password slots.
-	_, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--wipe-slot", "recovery,password", "/dev/disk/by-partlabel/root-x86-64")
+	_, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--wipe-slot", "recovery,password", RootPartition)
 	if err != nil {
 		return err
 	}
@@ -68,11 +82,13 @@ func DeleteEncryptionKey(ctx context.Context, s *state.State, key string) error
 			continue
 		}
 
-		err := AddEncryptionKey(ctx, s, existingKey)
+		err := addEncryptionKey(ctx, s, existingKey)
 		if err != nil {
 			return err
 		}
 	}
 
+	recordEncryptionAudit(s, "delete", "root", -1, "password")
+
 	return nil
 }
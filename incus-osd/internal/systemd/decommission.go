@@ -0,0 +1,44 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// tpmPPIRequestFile is the TPM Physical Presence Interface request file.
+var tpmPPIRequestFile = "/sys/class/tpm/tpm0/ppi/request"
+
+// EraseEncryptedVolumes destroys the LUKS headers, and so all the key slots, of the encrypted
+// volumes, making their data unrecoverable.
+func EraseEncryptedVolumes(ctx context.Context) error {
+	var errs []error
+
+	for _, partition := range EncryptedVolumes {
+		_, err := os.Stat(partition)
+		if err != nil {
+			continue
+		}
+
+		_, err = subprocess.RunCommandContext(ctx, "cryptsetup", "erase", "--batch-mode", partition)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ClearTPM requests the firmware to clear the TPM on the next boot, through the Physical Presence
+// Interface. The firmware may ask for a confirmation on the console.
+func ClearTPM() error {
+	// Operation 5 is "TPM2_Clear".
+	return os.WriteFile(tpmPPIRequestFile, []byte("5"), 0o200)
+}
+
+// SystemPowerOffImmediate powers off the system right away, without going through a shutdown.
+func SystemPowerOffImmediate() error {
+	return os.WriteFile("/proc/sysrq-trigger", []byte("o"), 0o200)
+}
@@ -1,18 +1,38 @@
 package systemd
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+	"golang.org/x/net/http/httpproxy"
 
 	"github.com/lxc/incus-os/incus-osd/api"
 )
 
+var (
+	proxyFunc     atomic.Pointer[func(*url.URL) (*url.URL, error)]
+	proxyFuncOnce sync.Once
+)
+
+// proxyEnvironmentVariables lists the environment variables managed by UpdateEnvironment.
+var proxyEnvironmentVariables = []string{"http_proxy", "https_proxy", "no_proxy", "auto_proxy"}
+
 // UpdateEnvironment updates the system-wide /etc/environment file as well as
 // updating the environment variables to the daemon's environment. For simplicity,
 // the existing /etc/environment file is deleted, then re-created with whatever is
 // passed to this function.
-func UpdateEnvironment(proxyCfg *api.SystemNetworkProxy) error {
+//
+// The proxy configuration is also applied to the daemon's own HTTP client, used by the
+// update client and providers, and to the service manager so that applications started
+// afterwards get the same configuration.
+func UpdateEnvironment(ctx context.Context, proxyCfg *api.SystemNetworkProxy) error {
 	// Remove any existing /etc/environment file.
 	err := os.Remove("/etc/environment")
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
@@ -20,45 +40,115 @@ func UpdateEnvironment(proxyCfg *api.SystemNetworkProxy) error {
 	}
 
 	// Unset proxy environment variables.
-	for _, envVarName := range []string{"http_proxy", "https_proxy", "no_proxy"} {
+	for _, envVarName := range proxyEnvironmentVariables {
 		err = os.Unsetenv(envVarName)
 		if err != nil {
 			return err
 		}
 	}
 
+	_, err = subprocess.RunCommandContext(ctx, "systemctl", append([]string{"unset-environment"}, proxyEnvironmentVariables...)...)
+	if err != nil {
+		return err
+	}
+
 	// If no proxy configuration provided, return here.
 	if proxyCfg == nil {
+		setProxyConfig(&httpproxy.Config{})
+
 		return nil
 	}
 
-	// Set any defined environment variables.
-	if proxyCfg.HTTPProxy != "" {
-		err := writeAndSet("http_proxy", proxyCfg.HTTPProxy)
+	env, err := generateProxyEnvironment(*proxyCfg)
+	if err != nil {
+		return err
+	}
+
+	// Proxy credentials must not be readable by unprivileged users.
+	mode := os.FileMode(0o644)
+	if proxyCfg.Username != "" {
+		mode = 0o600
+	}
+
+	for _, envVarName := range proxyEnvironmentVariables {
+		value, ok := env[envVarName]
+		if !ok {
+			continue
+		}
+
+		err := writeAndSet(envVarName, value, mode)
 		if err != nil {
 			return err
 		}
-	}
 
-	if proxyCfg.HTTPSProxy != "" {
-		err := writeAndSet("https_proxy", proxyCfg.HTTPSProxy)
+		_, err = subprocess.RunCommandContext(ctx, "systemctl", "set-environment", envVarName+"="+value)
 		if err != nil {
 			return err
 		}
 	}
 
-	if proxyCfg.NoProxy != "" {
-		err := writeAndSet("no_proxy", proxyCfg.NoProxy)
+	setProxyConfig(&httpproxy.Config{
+		HTTPProxy:  env["http_proxy"],
+		HTTPSProxy: env["https_proxy"],
+		NoProxy:    env["no_proxy"],
+	})
+
+	return nil
+}
+
+// generateProxyEnvironment returns the proxy environment variables for the provided configuration.
+// Any credentials are added to the proxy URLs.
+func generateProxyEnvironment(proxyCfg api.SystemNetworkProxy) (map[string]string, error) {
+	ret := map[string]string{}
+
+	for envVarName, proxy := range map[string]string{"http_proxy": proxyCfg.HTTPProxy, "https_proxy": proxyCfg.HTTPSProxy} {
+		if proxy == "" {
+			continue
+		}
+
+		u, err := url.Parse(proxy)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("invalid proxy %q: %w", proxy, err)
 		}
+
+		if proxyCfg.Username != "" && u.User == nil {
+			u.User = url.UserPassword(proxyCfg.Username, proxyCfg.Password)
+		}
+
+		ret[envVarName] = u.String()
 	}
 
-	return nil
+	if proxyCfg.NoProxy != "" {
+		ret["no_proxy"] = proxyCfg.NoProxy
+	}
+
+	if proxyCfg.PACURL != "" {
+		ret["auto_proxy"] = proxyCfg.PACURL
+	}
+
+	return ret, nil
+}
+
+// setProxyConfig applies the proxy configuration to the daemon's default HTTP transport. This is required
+// as the standard library only reads the proxy environment variables once.
+func setProxyConfig(cfg *httpproxy.Config) {
+	f := cfg.ProxyFunc()
+	proxyFunc.Store(&f)
+
+	proxyFuncOnce.Do(func() {
+		transport, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			return
+		}
+
+		transport.Proxy = func(r *http.Request) (*url.URL, error) {
+			return (*proxyFunc.Load())(r.URL)
+		}
+	})
 }
 
-func writeAndSet(key string, value string) error {
-	envFile, err := os.OpenFile("/etc/environment", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o0644) //nolint:gosec
+func writeAndSet(key string, value string, mode os.FileMode) error {
+	envFile, err := os.OpenFile("/etc/environment", os.O_CREATE|os.O_APPEND|os.O_WRONLY, mode) //nolint:gosec
 	if err != nil {
 		return err
 	}
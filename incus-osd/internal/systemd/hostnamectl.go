@@ -15,3 +15,14 @@ func SetHostname(ctx context.Context, hostname string) error {
 
 	return nil
 }
+
+// ResetStaticHostname clears the system's static hostname, allowing a transient hostname, for example
+// one received from a DHCP server, to be used.
+func ResetStaticHostname(ctx context.Context) error {
+	_, err := subprocess.RunCommandContext(ctx, "hostnamectl", "--static", "hostname", "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// maxEncryptionAuditEntries is the number of encryption audit entries kept in the state.
+const maxEncryptionAuditEntries = 100
+
+// EncryptedVolumes maps the name of the encrypted volumes to their partition.
+var EncryptedVolumes = map[string]string{
+	"root": RootPartition,
+	"swap": SwapPartition,
+}
+
+type luksDump struct {
+	Keyslots map[string]json.RawMessage `json:"keyslots"`
+	Tokens   map[string]struct {
+		Type     string   `json:"type"`
+		Keyslots []string `json:"keyslots"`
+	} `json:"tokens"`
+}
+
+// GetKeySlots returns the LUKS key slots of all encrypted volumes.
+func GetKeySlots(ctx context.Context) ([]api.SystemEncryptionKeySlot, error) {
+	ret := []api.SystemEncryptionKeySlot{}
+
+	volumes := make([]string, 0, len(EncryptedVolumes))
+	for volume := range EncryptedVolumes {
+		volumes = append(volumes, volume)
+	}
+
+	sort.Strings(volumes)
+
+	for _, volume := range volumes {
+		slots, err := getVolumeKeySlots(ctx, volume)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, slots...)
+	}
+
+	return ret, nil
+}
+
+// AddKeySlot adds a recovery key or passphrase to an encrypted volume, returning the generated
+// recovery key if any. Recovery keys and passphrases added to the root volume are also stored in
+// the list of recovery keys.
+func AddKeySlot(ctx context.Context, s *state.State, req api.SystemEncryptionKeySlotPost) (string, error) {
+	partition, ok := EncryptedVolumes[req.Volume]
+	if !ok {
+		return "", fmt.Errorf("unknown encrypted volume %q", req.Volume)
+	}
+
+	slotsBefore, err := getVolumeKeySlots(ctx, req.Volume)
+	if err != nil {
+		return "", err
+	}
+
+	key := ""
+
+	switch req.Type {
+	case "recovery":
+		output, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--recovery-key", partition)
+		if err != nil {
+			return "", err
+		}
+
+		key = strings.TrimSuffix(output, "\n")
+	case "password":
+		if req.Passphrase == "" {
+			return "", errors.New("no passphrase provided")
+		}
+
+		if slices.Contains(s.System.Encryption.Config.RecoveryKeys, req.Passphrase) {
+			return "", errors.New("provided passphrase is already enrolled")
+		}
+
+		_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "NEWPASSWORD="+req.Passphrase), nil, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--password", partition)
+		if err != nil {
+			return "", err
+		}
+
+		key = req.Passphrase
+	default:
+		return "", fmt.Errorf("unsupported key slot type %q", req.Type)
+	}
+
+	if req.Volume == "root" {
+		s.System.Encryption.Config.RecoveryKeys = append(s.System.Encryption.Config.RecoveryKeys, key)
+	}
+
+	// Find the new slot for the audit log.
+	slot := -1
+
+	slotsAfter, err := getVolumeKeySlots(ctx, req.Volume)
+	if err == nil {
+		for _, entry := range slotsAfter {
+			if !slices.Contains(slotsBefore, entry) {
+				slot = entry.Slot
+			}
+		}
+	}
+
+	recordEncryptionAudit(s, "add", req.Volume, slot, req.Type)
+
+	if req.Type == "recovery" {
+		return key, nil
+	}
+
+	return "", nil
+}
+
+// DeleteKeySlot wipes a key slot from an encrypted volume. The TPM-sealed key slot and the last
+// remaining key slot can't be removed.
+func DeleteKeySlot(ctx context.Context, s *state.State, volume string, slot int) error {
+	_, ok := EncryptedVolumes[volume]
+	if !ok {
+		return fmt.Errorf("unknown encrypted volume %q", volume)
+	}
+
+	slots, err := getVolumeKeySlots(ctx, volume)
+	if err != nil {
+		return err
+	}
+
+	idx := slices.IndexFunc(slots, func(entry api.SystemEncryptionKeySlot) bool { return entry.Slot == slot })
+	if idx == -1 {
+		return fmt.Errorf("key slot %d doesn't exist on volume %q", slot, volume)
+	}
+
+	slotType := slots[idx].Type
+
+	if slotType == "tpm2" {
+		return errors.New("the TPM key slot can't be removed")
+	}
+
+	if len(slots) == 1 {
+		return errors.New("the last key slot can't be removed")
+	}
+
+	err = wipeKeySlot(ctx, s, volume, slot)
+	if err != nil {
+		return err
+	}
+
+	recordEncryptionAudit(s, "delete", volume, slot, slotType)
+
+	return nil
+}
+
+// RotateRecoveryKey enrolls a new recovery key in the root volume, then removes the previous
+// recovery keys. If the previous keys can't be removed, the new key is removed again so the
+// volume is left unchanged. The new key isn't stored and must be saved by the caller.
+func RotateRecoveryKey(ctx context.Context, s *state.State) (string, error) {
+	slotsBefore, err := getVolumeKeySlots(ctx, "root")
+	if err != nil {
+		return "", err
+	}
+
+	output, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--recovery-key", RootPartition)
+	if err != nil {
+		return "", err
+	}
+
+	key := strings.TrimSuffix(output, "\n")
+
+	slotsAfter, err := getVolumeKeySlots(ctx, "root")
+	if err != nil {
+		return "", err
+	}
+
+	newSlot := -1
+
+	for _, entry := range slotsAfter {
+		if !slices.Contains(slotsBefore, entry) {
+			newSlot = entry.Slot
+		}
+	}
+
+	if newSlot == -1 {
+		return "", errors.New("failed to find the new recovery key slot")
+	}
+
+	// Remove the previous recovery keys.
+	for _, entry := range slotsBefore {
+		if entry.Type != "recovery" {
+			continue
+		}
+
+		err := wipeKeySlot(ctx, s, "root", entry.Slot)
+		if err != nil {
+			// Roll back to keep a single recovery key.
+			_, _ = subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--wipe-slot", strconv.Itoa(newSlot), RootPartition)
+
+			return "", fmt.Errorf("failed to remove previous recovery key: %w", err)
+		}
+
+		recordEncryptionAudit(s, "delete", "root", entry.Slot, "recovery")
+	}
+
+	recordEncryptionAudit(s, "add", "root", newSlot, "recovery")
+
+	return key, nil
+}
+
+// wipeKeySlot wipes a key slot from an encrypted volume, forgetting any stored key matching it.
+func wipeKeySlot(ctx context.Context, s *state.State, volume string, slot int) error {
+	partition := EncryptedVolumes[volume]
+
+	// Find any stored recovery key matching the slot.
+	var slotKeys []string
+
+	if volume == "root" {
+		for _, key := range s.System.Encryption.Config.RecoveryKeys {
+			err := subprocess.RunCommandWithFds(ctx, strings.NewReader(key), nil, "cryptsetup", "open", "--test-passphrase", "--key-slot", strconv.Itoa(slot), "--key-file", "-", partition)
+			if err == nil {
+				slotKeys = append(slotKeys, key)
+			}
+		}
+	}
+
+	_, err := subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--wipe-slot", strconv.Itoa(slot), partition)
+	if err != nil {
+		return err
+	}
+
+	s.System.Encryption.Config.RecoveryKeys = slices.DeleteFunc(s.System.Encryption.Config.RecoveryKeys, func(key string) bool {
+		return slices.Contains(slotKeys, key)
+	})
+
+	return nil
+}
+
+// getVolumeKeySlots returns the LUKS key slots of an encrypted volume.
+func getVolumeKeySlots(ctx context.Context, volume string) ([]api.SystemEncryptionKeySlot, error) {
+	output, err := subprocess.RunCommandContext(ctx, "cryptsetup", "luksDump", "--dump-json-metadata", EncryptedVolumes[volume])
+	if err != nil {
+		return nil, err
+	}
+
+	return parseKeySlots(volume, output)
+}
+
+// parseKeySlots classifies the key slots of a LUKS2 JSON metadata dump based on their tokens.
+func parseKeySlots(volume string, metadata string) ([]api.SystemEncryptionKeySlot, error) {
+	dump := luksDump{}
+
+	err := json.Unmarshal([]byte(metadata), &dump)
+	if err != nil {
+		return nil, err
+	}
+
+	// Map the key slots to the type of their token.
+	slotTypes := map[string]string{}
+
+	for _, token := range dump.Tokens {
+		slotType := "other"
+
+		switch token.Type {
+		case "systemd-tpm2":
+			slotType = "tpm2"
+		case "systemd-recovery":
+			slotType = "recovery"
+		case "clevis":
+			slotType = "clevis"
+		}
+
+		for _, slot := range token.Keyslots {
+			slotTypes[slot] = slotType
+		}
+	}
+
+	ret := []api.SystemEncryptionKeySlot{}
+
+	for slot := range dump.Keyslots {
+		value, err := strconv.Atoi(slot)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key slot %q", slot)
+		}
+
+		// Key slots without a token are unlocked by a passphrase.
+		slotType, ok := slotTypes[slot]
+		if !ok {
+			slotType = "password"
+		}
+
+		ret = append(ret, api.SystemEncryptionKeySlot{
+			Volume: volume,
+			Slot:   value,
+			Type:   slotType,
+		})
+	}
+
+	sort.Slice(ret, func(i int, j int) bool { return ret[i].Slot < ret[j].Slot })
+
+	return ret, nil
+}
+
+// recordEncryptionAudit logs a change to the encryption keys and keeps track of it in the state.
+func recordEncryptionAudit(s *state.State, action string, volume string, slot int, slotType string) {
+	slog.Info("Encryption key change", "action", action, "volume", volume, "slot", slot, "type", slotType)
+
+	s.System.Encryption.State.Audit = append(s.System.Encryption.State.Audit, api.SystemEncryptionAuditEntry{
+		Time:   time.Now().UTC(),
+		Action: action,
+		Volume: volume,
+		Slot:   slot,
+		Type:   slotType,
+	})
+
+	if len(s.System.Encryption.State.Audit) > maxEncryptionAuditEntries {
+		s.System.Encryption.State.Audit = s.System.Encryption.State.Audit[len(s.System.Encryption.State.Audit)-maxEncryptionAuditEntries:]
+	}
+}
@@ -0,0 +1,52 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+var luksDumpMetadata = `{
+  "keyslots": {
+    "0": {"type": "luks2"},
+    "1": {"type": "luks2"},
+    "2": {"type": "luks2"},
+    "3": {"type": "luks2"},
+    "10": {"type": "luks2"}
+  },
+  "tokens": {
+    "0": {"type": "systemd-tpm2", "keyslots": ["0"]},
+    "1": {"type": "systemd-recovery", "keyslots": ["1"]},
+    "2": {"type": "clevis", "keyslots": ["3"]},
+    "3": {"type": "custom", "keyslots": ["10"]}
+  }
+}`
+
+func TestKeySlots(t *testing.T) {
+	t.Parallel()
+
+	slots, err := parseKeySlots("root", luksDumpMetadata)
+	require.NoError(t, err)
+	require.Equal(t, []api.SystemEncryptionKeySlot{
+		{Volume: "root", Slot: 0, Type: "tpm2"},
+		{Volume: "root", Slot: 1, Type: "recovery"},
+		{Volume: "root", Slot: 2, Type: "password"},
+		{Volume: "root", Slot: 3, Type: "clevis"},
+		{Volume: "root", Slot: 10, Type: "other"},
+	}, slots)
+
+	_, err = parseKeySlots("root", "invalid")
+	require.Error(t, err)
+
+	// The audit log is bounded.
+	s := &state.State{}
+	for i := range maxEncryptionAuditEntries + 5 {
+		recordEncryptionAudit(s, "add", "root", i, "password")
+	}
+
+	require.Len(t, s.System.Encryption.State.Audit, maxEncryptionAuditEntries)
+	require.Equal(t, 5, s.System.Encryption.State.Audit[0].Slot)
+}
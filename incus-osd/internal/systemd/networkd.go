@@ -2,17 +2,25 @@ package systemd
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net"
 	"os"
+	"os/user"
 	"path/filepath"
-	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
 
 	"github.com/lxc/incus-os/incus-osd/api"
 )
@@ -53,6 +61,12 @@ func generateNetworkConfiguration(_ context.Context, networkCfg *api.SystemNetwo
 		}
 	}
 
+	// Write any WireGuard keys referenced by the .netdev files.
+	err = writeWireGuardKeys(*networkCfg)
+	if err != nil {
+		return err
+	}
+
 	// Generate .network files.
 	for _, cfg := range generateNetworkFileContents(*networkCfg) {
 		err := os.WriteFile(filepath.Join(SystemdNetworkConfigPath, cfg.Name), []byte(cfg.Contents), 0o644)
@@ -63,7 +77,7 @@ func generateNetworkConfiguration(_ context.Context, networkCfg *api.SystemNetwo
 
 	// Generate systemd-timesyncd configuration if any timeservers are defined.
 	ntpCfg := ""
-	if networkCfg.NTP != nil {
+	if networkCfg.NTP != nil && !useChrony(networkCfg) {
 		ntpCfg = generateTimesyncContents(*networkCfg.NTP)
 
 		if ntpCfg != "" {
@@ -79,7 +93,137 @@ func generateNetworkConfiguration(_ context.Context, networkCfg *api.SystemNetwo
 		_ = os.Remove(SystemdTimesyncConfigFile)
 	}
 
-	return nil
+	// Generate chrony configuration if it's the selected time synchronization backend.
+	if useChrony(networkCfg) {
+		err := os.MkdirAll(filepath.Dir(ChronyConfigFile), 0o755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(ChronyConfigFile, []byte(generateChronyContents(*networkCfg.NTP)), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Generate systemd-networkd configuration if any routing tables are defined.
+	networkdCfg := generateNetworkdContents(*networkCfg)
+	if networkdCfg != "" {
+		err := os.MkdirAll(filepath.Dir(SystemdNetworkdConfigFile), 0o755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(SystemdNetworkdConfigFile, []byte(networkdCfg), 0o644)
+		if err != nil {
+			return err
+		}
+	} else {
+		_ = os.Remove(SystemdNetworkdConfigFile)
+	}
+
+	// Generate systemd-resolved configuration if any global DNS options are defined.
+	resolvedCfg := generateResolvedContents(*networkCfg)
+	if resolvedCfg != "" {
+		err := os.MkdirAll(filepath.Dir(SystemdResolvedConfigFile), 0o755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(SystemdResolvedConfigFile, []byte(resolvedCfg), 0o644)
+		if err != nil {
+			return err
+		}
+	} else {
+		// If there's no DNS configuration, remove the old config file that might exist.
+		_ = os.Remove(SystemdResolvedConfigFile)
+	}
+
+	// Generate sysctl configuration to fully disable IPv6 on selected devices.
+	sysctlCfg := generateSysctlContents(*networkCfg)
+	if sysctlCfg != "" {
+		err := os.MkdirAll(filepath.Dir(SysctlNetworkConfigFile), 0o755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(SysctlNetworkConfigFile, []byte(sysctlCfg), 0o644)
+		if err != nil {
+			return err
+		}
+	} else {
+		_ = os.Remove(SysctlNetworkConfigFile)
+	}
+
+	// Generate pppd configuration for any PPPoE uplinks.
+	err = writePPPoEConfiguration(*networkCfg)
+	if err != nil {
+		return err
+	}
+
+	// Generate clatd configuration if a CLAT is requested.
+	err = writeCLATConfiguration(*networkCfg)
+	if err != nil {
+		return err
+	}
+
+	// Generate wpa_supplicant configuration for any interfaces requiring 802.1X authentication.
+	return writeWPASupplicantConfiguration(*networkCfg)
+}
+
+// RenderNetworkConfiguration returns the configuration files, indexed by their full path, that would be
+// written when applying the supplied network configuration. Files holding secrets, such as WireGuard keys
+// and 802.1X configuration, aren't included.
+func RenderNetworkConfiguration(networkCfg *api.SystemNetworkConfig) (map[string]string, error) {
+	if networkCfg == nil {
+		return nil, errors.New("no network configuration provided")
+	}
+
+	ret := map[string]string{}
+
+	files := generateLinkFileContents(*networkCfg)
+	files = append(files, generateNetdevFileContents(*networkCfg)...)
+	files = append(files, generateNetworkFileContents(*networkCfg)...)
+
+	for _, cfg := range files {
+		ret[filepath.Join(SystemdNetworkConfigPath, cfg.Name)] = cfg.Contents
+	}
+
+	if networkCfg.NTP != nil && !useChrony(networkCfg) {
+		ntpCfg := generateTimesyncContents(*networkCfg.NTP)
+		if ntpCfg != "" {
+			ret[SystemdTimesyncConfigFile] = ntpCfg
+		}
+	}
+
+	if useChrony(networkCfg) {
+		ret[ChronyConfigFile] = generateChronyContents(*networkCfg.NTP)
+	}
+
+	resolvedCfg := generateResolvedContents(*networkCfg)
+	if resolvedCfg != "" {
+		ret[SystemdResolvedConfigFile] = resolvedCfg
+	}
+
+	if networkCfg.CLAT != nil {
+		ret[CLATConfigFile] = generateCLATContents(*networkCfg.CLAT)
+	}
+
+	sysctlCfg := generateSysctlContents(*networkCfg)
+	if sysctlCfg != "" {
+		ret[SysctlNetworkConfigFile] = sysctlCfg
+	}
+
+	networkdCfg := generateNetworkdContents(*networkCfg)
+	if networkdCfg != "" {
+		ret[SystemdNetworkdConfigFile] = networkdCfg
+	}
+
+	for _, p := range networkCfg.PPPoE {
+		ret[pppoePeerFile(p.Name)] = generatePPPoEPeerContents(p)
+	}
+
+	return ret, nil
 }
 
 // ApplyNetworkConfiguration instructs systemd-networkd to apply the supplied network configuration.
@@ -88,6 +232,11 @@ func ApplyNetworkConfiguration(ctx context.Context, networkCfg *api.SystemNetwor
 		return errors.New("no network configuration provided")
 	}
 
+	err := ValidateNetworkConfiguration(*networkCfg)
+	if err != nil {
+		return err
+	}
+
 	// Get hostname and domain from network config, if defined.
 	hostname := ""
 	if networkCfg.DNS != nil && networkCfg.DNS.Hostname != "" {
@@ -97,14 +246,29 @@ func ApplyNetworkConfiguration(ctx context.Context, networkCfg *api.SystemNetwor
 		}
 	}
 
-	// Apply the configured hostname, or reset back to default if not set.
-	err := SetHostname(ctx, hostname)
+	// Apply the configured hostname, or reset back to default if not set. When using the hostname
+	// provided by DHCP, only clear the static hostname so the DHCP one can take effect.
+	if useDHCPHostname(networkCfg.DNS) {
+		err = ResetStaticHostname(ctx)
+	} else {
+		err = SetHostname(ctx, hostname)
+	}
+
 	if err != nil {
 		return err
 	}
 
 	// Set proxy environment variables, or clear existing ones if none are defined.
-	err = UpdateEnvironment(networkCfg.Proxy)
+	err = UpdateEnvironment(ctx, networkCfg.Proxy)
+	if err != nil {
+		return err
+	}
+
+	// The configuration was validated above, so there is at most one management VRF.
+	mgmtVRF, _ := getManagementVRF(*networkCfg)
+
+	// Keep track of the current configuration so only affected devices get reconfigured.
+	oldFiles, err := readNetworkdFiles()
 	if err != nil {
 		return err
 	}
@@ -114,40 +278,232 @@ func ApplyNetworkConfiguration(ctx context.Context, networkCfg *api.SystemNetwor
 		return err
 	}
 
-	err = waitForUdevInterfaceRename(ctx, 5*time.Second)
+	newFiles, err := readNetworkdFiles()
+	if err != nil {
+		return err
+	}
+
+	err = waitForUdevInterfaceRename(ctx, networkCfg, 5*time.Second)
+	if err != nil {
+		return err
+	}
+
+	// Reload networking after new config files have been generated, only restarting
+	// systemd-networkd if an existing link or netdev was modified.
+	err = reloadNetworkd(ctx, oldFiles, newFiles)
+	if err != nil {
+		return err
+	}
+
+	// (Re)start 802.1X authentication on any interfaces requiring it.
+	err = restartWPASupplicant(ctx, networkCfg)
+	if err != nil {
+		return err
+	}
+
+	// (Re)start any PPPoE uplinks.
+	err = restartPPPoE(ctx, networkCfg)
+	if err != nil {
+		return err
+	}
+
+	// Apply any per-device sysctls to existing devices, new devices get them applied by udev.
+	err = RestartUnit(ctx, "systemd-sysctl")
 	if err != nil {
 		return err
 	}
 
-	// Restart networking after new config files have been generated.
-	err = RestartUnit(ctx, "systemd-networkd")
+	// Restart systemd-resolved to pickup any DNS changes.
+	err = RestartUnit(ctx, "systemd-resolved")
 	if err != nil {
 		return err
 	}
 
 	// (Re)start NTP time synchronization. Since we might be overriding the default fallback NTP servers,
 	// the service is disabled by default and only started once we have performed the network (re)configuration.
-	err = RestartUnit(ctx, "systemd-timesyncd")
+	// Only one of systemd-timesyncd or chrony may be running at a time, the other one being masked
+	// so nothing else can start it.
+	if useChrony(networkCfg) {
+		err = MaskUnit(ctx, "systemd-timesyncd")
+		if err != nil {
+			return err
+		}
+
+		err = RestartUnit(ctx, "chrony")
+		if err != nil {
+			return err
+		}
+	} else {
+		_ = StopUnit(ctx, "chrony")
+
+		err = UnmaskUnit(ctx, "systemd-timesyncd")
+		if err != nil {
+			return err
+		}
+
+		err = RestartUnit(ctx, "systemd-timesyncd")
+		if err != nil {
+			return err
+		}
+	}
+
+	// (Re)start the CLAT, once resolved is able to provide the DNS64 answers used to discover the NAT64 prefix.
+	err = restartCLAT(ctx, networkCfg)
 	if err != nil {
 		return err
 	}
 
+	// Route outgoing management traffic through the management VRF, if any.
+	setManagementVRF(mgmtVRF)
+
 	// Wait for the network to apply.
-	return waitForNetworkOnline(ctx, networkCfg, timeout)
+	err = waitForNetworkOnline(ctx, networkCfg, timeout)
+	if err != nil {
+		return err
+	}
+
+	if networkCfg.DuplicateAddressDetection == "warn" {
+		for name, addresses := range getDuplicateAddresses(networkCfg, true) {
+			slog.Warn("Addresses are already in use on the network", "device", name, "addresses", addresses)
+		}
+	}
+
+	return nil
+}
+
+// readNetworkdFiles returns the contents of the existing .link, .netdev and .network files.
+func readNetworkdFiles() (map[string]string, error) {
+	ret := map[string]string{}
+
+	entries, err := os.ReadDir(SystemdNetworkConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ret, nil
+		}
+
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if !slices.Contains([]string{".link", ".netdev", ".network"}, filepath.Ext(entry.Name())) {
+			continue
+		}
+
+		contents, err := os.ReadFile(filepath.Join(SystemdNetworkConfigPath, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		ret[entry.Name()] = string(contents)
+	}
+
+	// Include the global systemd-networkd configuration, which is only applied on restart.
+	contents, err := os.ReadFile(SystemdNetworkdConfigFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err == nil {
+		ret[filepath.Base(SystemdNetworkdConfigFile)] = string(contents)
+	}
+
+	return ret, nil
+}
+
+// reloadNetworkd applies the new configuration files, either by reloading systemd-networkd and
+// reconfiguring only the affected devices, or by fully restarting it if required.
+func reloadNetworkd(ctx context.Context, oldFiles map[string]string, newFiles map[string]string) error {
+	devices, restart := getNetworkdChanges(oldFiles, newFiles)
+
+	if !restart {
+		_, err := subprocess.RunCommandContext(ctx, "networkctl", "reload")
+		if err == nil {
+			for _, dev := range devices {
+				_, err := subprocess.RunCommandContext(ctx, "networkctl", "reconfigure", dev)
+				if err != nil {
+					// The device may not exist yet, in which case it'll be configured once it appears.
+					slog.Debug("Failed to reconfigure network device", "device", dev, "err", err)
+				}
+			}
+
+			return nil
+		}
+
+		// Fallback to restarting systemd-networkd, for example if it isn't running yet.
+		slog.Debug("Failed to reload systemd-networkd", "err", err)
+	}
+
+	return RestartUnit(ctx, "systemd-networkd")
+}
+
+// getNetworkdChanges compares two sets of networkd configuration files, returning the list of devices
+// whose .network file was added, modified or removed, and whether a full restart of systemd-networkd is
+// required. Since networkd doesn't update existing netdevs on reload, any modified or removed .link or
+// .netdev file requires a restart, as does any change to the global networkd configuration.
+func getNetworkdChanges(oldFiles map[string]string, newFiles map[string]string) ([]string, bool) {
+	if len(oldFiles) == 0 {
+		return nil, true
+	}
+
+	devices := []string{}
+
+	addDevice := func(contents string) {
+		for _, line := range strings.Split(contents, "\n") {
+			name, found := strings.CutPrefix(line, "Name=")
+			if found {
+				if !slices.Contains(devices, name) {
+					devices = append(devices, name)
+				}
+
+				return
+			}
+		}
+	}
+
+	for name, oldContents := range oldFiles {
+		newContents, exists := newFiles[name]
+		if exists && newContents == oldContents {
+			continue
+		}
+
+		if filepath.Ext(name) != ".network" {
+			return nil, true
+		}
+
+		addDevice(oldContents)
+	}
+
+	for name, newContents := range newFiles {
+		oldContents, exists := oldFiles[name]
+		if exists && newContents == oldContents {
+			continue
+		}
+
+		if filepath.Ext(name) == ".link" || filepath.Ext(name) == ".conf" {
+			return nil, true
+		}
+
+		if filepath.Ext(name) == ".network" {
+			addDevice(newContents)
+		}
+	}
+
+	slices.Sort(devices)
+
+	return devices, false
 }
 
 // waitForUdevInterfaceRename waits up to a provided timeout for udev to pickup and process
 // the renaming of interfaces. At system startup there's a small race between udev being fully
-// started and our reconfiguring of the network, so we poll in a loop until we see the kernel
-// has been notified of the rename.
-func waitForUdevInterfaceRename(ctx context.Context, timeout time.Duration) error {
+// started and our reconfiguring of the network, so we poll in a loop until the expected
+// "en<MAC address>" devices show up. Devices which are missing once the timeout is reached
+// are only reported, unless none of them could be found.
+func waitForUdevInterfaceRename(ctx context.Context, networkCfg *api.SystemNetworkConfig, timeout time.Duration) error {
+	expectedNames := renamedInterfaceNames(*networkCfg)
+
 	endTime := time.Now().Add(timeout)
 
 	for {
-		if time.Now().After(endTime) {
-			return errors.New("timed out waiting for udev to rename interface(s)")
-		}
-
 		// Trigger udev rule update to pickup device names.
 		_, err := subprocess.RunCommandContext(ctx, "udevadm", "trigger", "--action=add")
 		if err != nil {
@@ -160,10 +516,26 @@ func waitForUdevInterfaceRename(ctx context.Context, timeout time.Duration) erro
 			return err
 		}
 
-		// Check if the kernel has noticed the renaming of (at least) one interface to
-		// the expected "en<MAC address>" format.
-		_, err = subprocess.RunCommandContext(ctx, "journalctl", "-t", "kernel", "-g", "en[[:xdigit:]]{12}: renamed from ")
-		if err == nil {
+		missingNames := []string{}
+
+		for _, name := range expectedNames {
+			_, err := os.Stat(filepath.Join("/sys/class/net", name))
+			if err != nil {
+				missingNames = append(missingNames, name)
+			}
+		}
+
+		if len(missingNames) == 0 {
+			return nil
+		}
+
+		if time.Now().After(endTime) {
+			if len(missingNames) == len(expectedNames) {
+				return errors.New("timed out waiting for udev to rename interface(s)")
+			}
+
+			slog.Warn("Some network interfaces weren't found", "interfaces", missingNames)
+
 			return nil
 		}
 
@@ -171,32 +543,42 @@ func waitForUdevInterfaceRename(ctx context.Context, timeout time.Duration) erro
 	}
 }
 
+// renamedInterfaceNames returns the names of the devices renamed by the generated .link files.
+func renamedInterfaceNames(networkCfg api.SystemNetworkConfig) []string {
+	ret := []string{}
+
+	for _, i := range networkCfg.Interfaces {
+		ret = append(ret, interfaceDeviceName(i))
+	}
+
+	for _, b := range networkCfg.Bonds {
+		for _, member := range b.Members {
+			ret = append(ret, "en"+strings.ToLower(strings.ReplaceAll(member, ":", "")))
+		}
+	}
+
+	return ret
+}
+
 // waitForNetworkOnline waits up to a provided timeout for configured network interfaces,
 // bonds, and vlans to configure their IP address(es) and come online.
 func waitForNetworkOnline(ctx context.Context, networkCfg *api.SystemNetworkConfig, timeout time.Duration) error {
-	isOnline := func(name string) bool {
-		output, err := subprocess.RunCommandContext(ctx, "networkctl", "status", name)
+	getNumberOfIPs := func(name string) int {
+		link, err := netlink.LinkByName(name)
 		if err != nil {
-			return false
+			return -1
 		}
 
-		return strings.Contains(output, "Online state: online")
-	}
-
-	getNumberOfIPs := func(name string) int {
-		ipAddressRegex := regexp.MustCompile(`inet6? (.+)/\d+ `)
-
-		output, err := subprocess.RunCommandContext(ctx, "ip", "address", "show", name)
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
 		if err != nil {
 			return -1
 		}
 
 		numIPs := 0
-		matches := ipAddressRegex.FindAllStringSubmatch(output, -1)
 
-		for _, addr := range matches {
-			// Don't count link-local address.
-			if strings.HasPrefix(addr[1], "fe80:") {
+		for _, addr := range addrs {
+			// Don't count IPv6 link-local address.
+			if addr.IP.To4() == nil && addr.IP.IsLinkLocalUnicast() {
 				continue
 			}
 
@@ -206,72 +588,255 @@ func waitForNetworkOnline(ctx context.Context, networkCfg *api.SystemNetworkConf
 		return numIPs
 	}
 
-	endTime := time.Now().Add(timeout)
+	// Allow for devices which are slow to get carrier, for example due to spanning tree.
+	carrierTimeout := time.Duration(0)
+
+	for _, online := range deviceOnlineCriteria(networkCfg) {
+		if online != nil && time.Duration(online.CarrierTimeout)*time.Second > carrierTimeout {
+			carrierTimeout = time.Duration(online.CarrierTimeout) * time.Second
+		}
+	}
+
+	startTime := time.Now()
+	endTime := startTime.Add(timeout + carrierTimeout)
 
 	devicesToCheck := make(map[string]int)
 
 	for _, i := range networkCfg.Interfaces {
-		if len(i.Addresses) == 0 {
+		addresses := deviceAddresses(i.Addresses, i.DisableIPv6)
+		if len(addresses) == 0 || (i.Online != nil && i.Online.Ignore) {
 			continue
 		}
 
-		devicesToCheck[i.Name] = len(i.Addresses)
+		devicesToCheck[i.Name] = numExpectedOnlineAddresses(addresses, i.Online)
 	}
 
 	for _, b := range networkCfg.Bonds {
-		if len(b.Addresses) == 0 {
+		addresses := deviceAddresses(b.Addresses, b.DisableIPv6)
+		if len(addresses) == 0 || (b.Online != nil && b.Online.Ignore) {
 			continue
 		}
 
-		devicesToCheck[b.Name] = len(b.Addresses)
+		devicesToCheck[b.Name] = numExpectedOnlineAddresses(addresses, b.Online)
 	}
 
 	for _, v := range networkCfg.VLANs {
-		if len(v.Addresses) == 0 {
+		addresses := deviceAddresses(v.Addresses, v.DisableIPv6)
+		if len(addresses) == 0 || (v.Online != nil && v.Online.Ignore) {
 			continue
 		}
 
-		devicesToCheck[v.Name] = len(v.Addresses)
+		devicesToCheck[v.Name] = numExpectedOnlineAddresses(addresses, v.Online)
 	}
 
-	for {
-		if time.Now().After(endTime) {
-			return errors.New("timed out waiting for network to come online")
+	for _, v := range networkCfg.VXLANs {
+		if len(v.Addresses) == 0 {
+			continue
 		}
 
-		allDevicesOnline := true
-		for name, numIPs := range devicesToCheck {
-			if !isOnline(name) || getNumberOfIPs(name) != numIPs {
-				allDevicesOnline = false
+		devicesToCheck[v.Name] = numExpectedAddresses(v.Addresses)
+	}
 
-				break
-			}
+	for _, t := range networkCfg.Tunnels {
+		if len(t.Addresses) == 0 {
+			continue
 		}
 
-		if allDevicesOnline {
-			return nil
+		devicesToCheck[t.Name] = numExpectedAddresses(t.Addresses)
+	}
+
+	for _, d := range networkCfg.Dummies {
+		if len(d.Addresses) == 0 || d.Online == nil || d.Online.Ignore {
+			continue
 		}
 
-		time.Sleep(500 * time.Millisecond)
+		devicesToCheck[d.Name] = numExpectedOnlineAddresses(d.Addresses, d.Online)
 	}
-}
 
-// generateLinkFileContents generates the contents of systemd.link files. Returns an array of ConfigFile structs.
+	for {
+		if time.Now().After(endTime) {
+			return errors.New("timed out waiting for network to come online")
+		}
+
+		// systemd-networkd may still be starting up, in which case no device is considered online.
+		links, err := getNetworkdLinks(ctx)
+		if err != nil {
+			links = map[string]networkdLink{}
+		}
+
+		// Addresses already in use on the network segment never get configured. IPv4 conflicts are only
+		// assumed once the address conflict detection had time to complete.
+		duplicates := map[string][]string{}
+		if networkCfg.DuplicateAddressDetection != "" {
+			duplicates = getDuplicateAddresses(networkCfg, time.Since(startTime) > 10*time.Second)
+
+			if len(duplicates) > 0 && networkCfg.DuplicateAddressDetection == "fail" {
+				return fmt.Errorf("duplicate addresses detected: %v", duplicates)
+			}
+		}
+
+		allDevicesOnline := true
+		for name, numIPs := range devicesToCheck {
+			if numIPs >= 0 {
+				numIPs -= len(duplicates[name])
+			}
+
+			if links[name].OnlineState != "online" || (numIPs >= 0 && getNumberOfIPs(name) != numIPs) {
+				allDevicesOnline = false
+
+				break
+			}
+		}
+
+		// Once all devices are up, check connectivity to any configured probe targets.
+		if allDevicesOnline {
+			allProbesSucceeded := true
+			for _, probe := range networkCfg.Probes {
+				err := CheckProbe(ctx, probe, 2*time.Second)
+				if err != nil {
+					allProbesSucceeded = false
+
+					break
+				}
+			}
+
+			if allProbesSucceeded {
+				return nil
+			}
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// getDuplicateAddresses returns the static addresses of each device which are already in use on the network
+// segment, based on the failed IPv6 duplicate address detection. If includeMissing is set, static IPv4
+// addresses missing from a device with carrier are assumed to have failed the address conflict detection.
+func getDuplicateAddresses(networkCfg *api.SystemNetworkConfig, includeMissing bool) map[string][]string {
+	ret := map[string][]string{}
+
+	checkDevice := func(name string, addresses []string) {
+		link, err := netlink.LinkByName(name)
+		if err != nil {
+			return
+		}
+
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return
+		}
+
+		for _, addr := range addresses {
+			ip, _, err := net.ParseCIDR(addr)
+			if err != nil {
+				continue
+			}
+
+			idx := slices.IndexFunc(addrs, func(a netlink.Addr) bool {
+				return a.IP.Equal(ip)
+			})
+
+			if idx >= 0 && addrs[idx].Flags&unix.IFA_F_DADFAILED != 0 {
+				ret[name] = append(ret[name], addr)
+			} else if idx < 0 && ip.To4() != nil && includeMissing && link.Attrs().RawFlags&unix.IFF_LOWER_UP != 0 {
+				ret[name] = append(ret[name], addr)
+			}
+		}
+	}
+
+	for _, i := range networkCfg.Interfaces {
+		checkDevice(i.Name, deviceAddresses(i.Addresses, i.DisableIPv6))
+	}
+
+	for _, b := range networkCfg.Bonds {
+		checkDevice(b.Name, deviceAddresses(b.Addresses, b.DisableIPv6))
+	}
+
+	for _, v := range networkCfg.VLANs {
+		checkDevice(v.Name, deviceAddresses(v.Addresses, v.DisableIPv6))
+	}
+
+	return ret
+}
+
+// deviceOnlineCriteria returns the custom online criteria of all configured devices.
+func deviceOnlineCriteria(networkCfg *api.SystemNetworkConfig) []*api.SystemNetworkOnline {
+	ret := []*api.SystemNetworkOnline{}
+
+	for _, i := range networkCfg.Interfaces {
+		ret = append(ret, i.Online)
+	}
+
+	for _, b := range networkCfg.Bonds {
+		ret = append(ret, b.Online)
+	}
+
+	for _, v := range networkCfg.VLANs {
+		ret = append(ret, v.Online)
+	}
+
+	for _, d := range networkCfg.Dummies {
+		ret = append(ret, d.Online)
+	}
+
+	return ret
+}
+
+// numExpectedOnlineAddresses returns the number of addresses a device should have before being considered
+// online, or -1 if custom online criteria are defined, in which case the networkd online state is sufficient.
+func numExpectedOnlineAddresses(addresses []string, online *api.SystemNetworkOnline) int {
+	if online != nil && (online.OperationalState != "" || online.AddressFamily != "") {
+		return -1
+	}
+
+	return numExpectedAddresses(addresses)
+}
+
+// numExpectedAddresses returns the number of addresses a device should end up with. An IPv4 link-local
+// address used as a DHCPv4 fallback replaces the DHCP one rather than being added to it.
+func numExpectedAddresses(addresses []string) int {
+	if slices.Contains(addresses, "dhcp4") && slices.Contains(addresses, "ipv4ll") {
+		return len(addresses) - 1
+	}
+
+	return len(addresses)
+}
+
+// generateLinkFileContents generates the contents of systemd.link files. Returns an array of ConfigFile structs.
 // https://www.freedesktop.org/software/systemd/man/latest/systemd.link.html
 func generateLinkFileContents(networkCfg api.SystemNetworkConfig) []networkdConfigFile {
 	ret := []networkdConfigFile{}
 
 	for _, i := range networkCfg.Interfaces {
 		strippedHwaddr := strings.ToLower(strings.ReplaceAll(i.Hwaddr, ":", ""))
+
+		// Interfaces keeping their kernel name aren't renamed, only a .link file applying any options is needed.
+		if i.KernelName != "" {
+			linkOptions := generateLinkOptionsContents(i)
+			if linkOptions == "" {
+				continue
+			}
+
+			ret = append(ret, networkdConfigFile{
+				Name: fmt.Sprintf("00-%s.link", i.KernelName),
+				Contents: fmt.Sprintf(`[Match]
+OriginalName=%s
+
+[Link]
+%s`, i.KernelName, linkOptions),
+			})
+
+			continue
+		}
+
 		ret = append(ret, networkdConfigFile{
 			Name: fmt.Sprintf("00-en%s.link", strippedHwaddr),
 			Contents: fmt.Sprintf(`[Match]
-PermanentMACAddress=%s
-
+%s
 [Link]
 NamePolicy=
-Name=en%s
-`, i.Hwaddr, strippedHwaddr),
+Name=%s
+%s`, generateLinkMatchContents(i), interfaceDeviceName(i), generateLinkOptionsContents(i)),
 		})
 	}
 
@@ -294,6 +859,105 @@ Name=en%s
 	return ret
 }
 
+// generateLinkMatchContents returns the [Match] entries of an interface's .link file. Interfaces are
+// matched by their permanent MAC address unless alternative selectors are provided.
+func generateLinkMatchContents(i api.SystemNetworkInterface) string {
+	if i.Match == nil || (i.Match.Path == "" && i.Match.Driver == "" && len(i.Match.Properties) == 0) {
+		return fmt.Sprintf("PermanentMACAddress=%s\n", i.Hwaddr)
+	}
+
+	ret := ""
+
+	if i.Match.Path != "" {
+		ret += fmt.Sprintf("Path=%s\n", i.Match.Path)
+	}
+
+	if i.Match.Driver != "" {
+		ret += fmt.Sprintf("Driver=%s\n", i.Match.Driver)
+	}
+
+	for _, property := range i.Match.Properties {
+		ret += fmt.Sprintf("Property=%s\n", property)
+	}
+
+	return ret
+}
+
+// generateLinkOptionsContents returns any additional [Link] entries of an interface's .link file.
+func generateLinkOptionsContents(i api.SystemNetworkInterface) string {
+	ret := generateDescriptionContents(i.Description)
+
+	if isValidAlternativeName(i.Description) {
+		ret += fmt.Sprintf("AlternativeName=%s\n", i.Description)
+	}
+
+	// Without a bridge, the MTU is set directly on the interface.
+	if i.Mode == "direct" && i.MTU != 0 {
+		ret += fmt.Sprintf("MTUBytes=%d\n", i.MTU)
+	}
+
+	// Without a bridge, an overridden MAC address is also applied directly to the interface.
+	if i.Mode == "direct" && i.OverrideHwaddr != "" {
+		ret += fmt.Sprintf("MACAddressPolicy=none\nMACAddress=%s\n", i.OverrideHwaddr)
+	}
+
+	if i.WakeOnLAN != "" {
+		ret += fmt.Sprintf("WakeOnLan=%s\n", i.WakeOnLAN)
+	}
+
+	if i.Tuning != nil {
+		ret += generateLinkTuningContents(*i.Tuning)
+	}
+
+	if i.SRIOV != nil && i.SRIOV.NumVFs > 0 {
+		ret += fmt.Sprintf("SR-IOVVirtualFunctions=%d\n", i.SRIOV.NumVFs)
+	}
+
+	return ret
+}
+
+func generateLinkTuningContents(tuning api.SystemNetworkInterfaceTuning) string {
+	ret := ""
+
+	if tuning.GRO != nil {
+		ret += fmt.Sprintf("GenericReceiveOffload=%s\n", strconv.FormatBool(*tuning.GRO))
+	}
+
+	if tuning.GSO != nil {
+		ret += fmt.Sprintf("GenericSegmentationOffload=%s\n", strconv.FormatBool(*tuning.GSO))
+	}
+
+	if tuning.TSO != nil {
+		ret += fmt.Sprintf("TCPSegmentationOffload=%s\n", strconv.FormatBool(*tuning.TSO))
+	}
+
+	if tuning.LRO != nil {
+		ret += fmt.Sprintf("LargeReceiveOffload=%s\n", strconv.FormatBool(*tuning.LRO))
+	}
+
+	if tuning.RXRingSize != 0 {
+		ret += fmt.Sprintf("RxBufferSize=%d\n", tuning.RXRingSize)
+	}
+
+	if tuning.TXRingSize != 0 {
+		ret += fmt.Sprintf("TxBufferSize=%d\n", tuning.TXRingSize)
+	}
+
+	if tuning.RXChannels != 0 {
+		ret += fmt.Sprintf("RxChannels=%d\n", tuning.RXChannels)
+	}
+
+	if tuning.TXChannels != 0 {
+		ret += fmt.Sprintf("TxChannels=%d\n", tuning.TXChannels)
+	}
+
+	if tuning.CombinedChannels != 0 {
+		ret += fmt.Sprintf("CombinedChannels=%d\n", tuning.CombinedChannels)
+	}
+
+	return ret
+}
+
 // generateNetdevFileContents generates the contents of systemd.netdev files. Returns an array of networkdConfigFile structs.
 // https://www.freedesktop.org/software/systemd/man/latest/systemd.netdev.html
 func generateNetdevFileContents(networkCfg api.SystemNetworkConfig) []networkdConfigFile {
@@ -306,17 +970,52 @@ func generateNetdevFileContents(networkCfg api.SystemNetworkConfig) []networkdCo
 		if i.MTU != 0 {
 			mtuString = fmt.Sprintf("MTUBytes=%d", i.MTU)
 		}
+
+		// Use a macvlan or ipvlan device rather than a bridge, or no device at all, if requested.
+		switch i.Mode {
+		case "direct":
+			continue
+		case "macvlan":
+			ret = append(ret, networkdConfigFile{
+				Name: fmt.Sprintf("10-mv%s.netdev", strippedHwaddr),
+				Contents: fmt.Sprintf(`[NetDev]
+Name=%s
+%sKind=macvlan
+%s%s
+
+[MACVLAN]
+Mode=bridge
+`, i.Name, generateDescriptionContents(i.Description), generateOverrideMACAddressContents(i), mtuString),
+			})
+
+			continue
+		case "ipvlan":
+			ret = append(ret, networkdConfigFile{
+				Name: fmt.Sprintf("10-iv%s.netdev", strippedHwaddr),
+				Contents: fmt.Sprintf(`[NetDev]
+Name=%s
+%sKind=ipvlan
+%s
+
+[IPVLAN]
+Mode=L2
+`, i.Name, generateDescriptionContents(i.Description), mtuString),
+			})
+
+			continue
+		}
+
 		ret = append(ret, networkdConfigFile{
 			Name: fmt.Sprintf("10-br%s.netdev", strippedHwaddr),
 			Contents: fmt.Sprintf(`[NetDev]
 Name=%s
-Kind=bridge
+%sKind=bridge
 MACAddress=%s
 %s
 
 [Bridge]
 VLANFiltering=true
-`, i.Name, i.Hwaddr, mtuString),
+%s%s`, i.Name, generateDescriptionContents(i.Description), interfaceMACAddress(i), mtuString, generateBridgeVLANProtocolContents(i.Name, networkCfg.VLANs), generateBridgeSectionContents(i.Bridge)),
 		})
 	}
 
@@ -345,7 +1044,7 @@ MACAddress=%s
 
 [Bond]
 Mode=%s
-`, strippedHwaddr, bondMacAddr, mtuString, b.Mode),
+%s`, strippedHwaddr, bondMacAddr, mtuString, b.Mode, generateBondSectionContents(b)),
 		})
 
 		// Bridge.
@@ -353,22 +1052,49 @@ Mode=%s
 			Name: fmt.Sprintf("11-br%s.netdev", strippedHwaddr),
 			Contents: fmt.Sprintf(`[NetDev]
 Name=%s
-Kind=bridge
+%sKind=bridge
 MACAddress=%s
 %s
 
 [Bridge]
 VLANFiltering=true
-`, b.Name, bondMacAddr, mtuString),
+%s%s`, b.Name, generateDescriptionContents(b.Description), bondMacAddr, mtuString, generateBridgeVLANProtocolContents(b.Name, networkCfg.VLANs), generateBridgeSectionContents(b.Bridge)),
 		})
 	}
 
 	// Create vlans.
 	for _, v := range networkCfg.VLANs {
+		mtuString := ""
+		if v.MTU != 0 {
+			mtuString = fmt.Sprintf("MTUBytes=%d", v.MTU)
+		}
+
+		// A vlan stacked on top of another vlan (QinQ) or a direct interface is a regular vlan device.
+		if isStackedVLANParent(networkCfg, v.Parent) {
+			protocolString := ""
+			if v.Protocol != "" {
+				protocolString = fmt.Sprintf("Protocol=%s\n", v.Protocol)
+			}
+
+			ret = append(ret, networkdConfigFile{
+				Name: fmt.Sprintf("12-%s.netdev", v.Name),
+				Contents: fmt.Sprintf(`[NetDev]
+Name=%s
+%sKind=vlan
+%s
+
+[VLAN]
+Id=%d
+%s`, v.Name, generateDescriptionContents(v.Description), mtuString, v.ID, protocolString),
+			})
+
+			continue
+		}
+
 		parentMACAddress := ""
 		for _, i := range networkCfg.Interfaces {
 			if i.Name == v.Parent {
-				parentMACAddress = i.Hwaddr
+				parentMACAddress = interfaceMACAddress(i)
 
 				break
 			}
@@ -387,22 +1113,78 @@ VLANFiltering=true
 			}
 		}
 
+		ret = append(ret, networkdConfigFile{
+			Name: fmt.Sprintf("12-%s.netdev", v.Name),
+			Contents: fmt.Sprintf(`[NetDev]
+Name=%s
+%sKind=veth
+MACAddress=%s
+%s
+
+[Peer]
+Name=vl%s
+`, v.Name, generateDescriptionContents(v.Description), parentMACAddress, mtuString, v.Name),
+		})
+	}
+
+	// Create vxlans.
+	for _, v := range networkCfg.VXLANs {
 		mtuString := ""
 		if v.MTU != 0 {
 			mtuString = fmt.Sprintf("MTUBytes=%d", v.MTU)
 		}
 
 		ret = append(ret, networkdConfigFile{
-			Name: fmt.Sprintf("12-%s.netdev", v.Name),
+			Name: fmt.Sprintf("13-%s.netdev", v.Name),
 			Contents: fmt.Sprintf(`[NetDev]
 Name=%s
-Kind=veth
-MACAddress=%s
+Kind=vxlan
 %s
 
-[Peer]
-Name=vl%s
-`, v.Name, parentMACAddress, mtuString, v.Name),
+[VXLAN]
+%s`, v.Name, mtuString, generateVXLANSectionContents(v)),
+		})
+	}
+
+	// Create tunnels.
+	for _, t := range networkCfg.Tunnels {
+		mtuString := ""
+		if t.MTU != 0 {
+			mtuString = fmt.Sprintf("MTUBytes=%d", t.MTU)
+		}
+
+		ret = append(ret, networkdConfigFile{
+			Name: fmt.Sprintf("14-%s.netdev", t.Name),
+			Contents: fmt.Sprintf(`[NetDev]
+Name=%s
+Kind=%s
+%s
+%s`, t.Name, t.Kind, mtuString, generateTunnelSectionContents(t)),
+		})
+	}
+
+	// Create vrfs.
+	for _, v := range networkCfg.VRFs {
+		ret = append(ret, networkdConfigFile{
+			Name: fmt.Sprintf("15-%s.netdev", v.Name),
+			Contents: fmt.Sprintf(`[NetDev]
+Name=%s
+Kind=vrf
+
+[VRF]
+Table=%d
+`, v.Name, v.Table),
+		})
+	}
+
+	// Create dummy devices.
+	for _, d := range networkCfg.Dummies {
+		ret = append(ret, networkdConfigFile{
+			Name: fmt.Sprintf("16-%s.netdev", d.Name),
+			Contents: fmt.Sprintf(`[NetDev]
+Name=%s
+Kind=dummy
+`, d.Name),
 		})
 	}
 
@@ -416,45 +1198,98 @@ func generateNetworkFileContents(networkCfg api.SystemNetworkConfig) []networkdC
 
 	// Create networks for each interface.
 	for _, i := range networkCfg.Interfaces {
-		strippedHwaddr := strings.ToLower(strings.ReplaceAll(i.Hwaddr, ":", ""))
 		cfgString := fmt.Sprintf(`[Match]
 Name=%s
 
 [Link]
 %s
 
-[DHCP]
-ClientIdentifier=mac
-RouteMetric=100
-UseMTU=true
-
+%s
 [Network]
-%s`, i.Name, generateLinkSectionContents(i.Addresses), generateNetworkSectionContents(networkCfg.DNS, networkCfg.NTP))
+%s`, i.Name, generateLinkSectionContents(deviceAddresses(i.Addresses, i.DisableIPv6), i.Online), generateDHCPSectionContents(i.DHCP, networkCfg.DNS, networkCfg.NTP, i.Addresses), generateNetworkSectionContents(networkCfg.DNS, networkCfg.NTP))
+
+		cfgString += processAddresses(deviceAddresses(i.Addresses, i.DisableIPv6), i.DisableIPv6, networkCfg.DuplicateAddressDetection != "", sendsRouterAdvertisements(i.PrefixDelegation, i.DHCPServer))
+		cfgString += generateStackedDevicesContents(networkCfg, i.Name)
+		cfgString += generateMulticastResolutionContents(i.MulticastDNS, i.LLMNR)
+		cfgString += generateCarrierContents(i.Online)
+		cfgString += generateProxyContents(i.ProxyARP, i.ProxyNDP, i.ProxyNDPAddresses)
+
+		if i.Mode == "direct" {
+			cfgString += fmt.Sprintf("LLDP=%s\nEmitLLDP=%s\n", strconv.FormatBool(i.LLDP), strconv.FormatBool(i.LLDP))
+		}
 
-		cfgString += processAddresses(i.Addresses)
+		cfgString += generateAddressingContents(i.IPv6Token, i.AddressGenerationMode, i.PrefixDelegation, i.DHCPServer)
+
+		if networkCfg.DuplicateAddressDetection != "" {
+			cfgString += generateAddressDetectionContents(deviceAddresses(i.Addresses, i.DisableIPv6))
+		}
 
 		if len(i.Routes) > 0 {
 			cfgString += processRoutes(i.Routes)
 		}
 
+		if len(i.Rules) > 0 {
+			cfgString += processRules(i.Rules)
+		}
+
+		if len(i.Neighbors) > 0 {
+			cfgString += processNeighbors(i.Neighbors)
+		}
+
+		cfgString += generateUplinkIsolationContents(networkCfg, i.Name, deviceAddresses(i.Addresses, i.DisableIPv6), i.Routes)
+
+		// The interface itself is configured when not using a bridge or other device on top of it.
+		if i.Mode == "direct" {
+			cfgString += generateQoSContents(i.QoS)
+
+			if i.SRIOV != nil {
+				cfgString += generateSRIOVContents(*i.SRIOV)
+			}
+
+			ret = append(ret, networkdConfigFile{
+				Name:     fmt.Sprintf("20-%s.network", i.Name),
+				Contents: cfgString,
+			})
+
+			continue
+		}
+
 		ret = append(ret, networkdConfigFile{
 			Name:     fmt.Sprintf("20-%s.network", i.Name),
 			Contents: cfgString,
 		})
 
-		cfgString = fmt.Sprintf(`[Match]
-Name=en%s
+		switch i.Mode {
+		case "macvlan", "ipvlan":
+			cfgString = fmt.Sprintf(`[Match]
+Name=%s
 
 [Network]
-Bridge=%s
+%s=%s
 LLDP=%s
 EmitLLDP=%s
-`, strippedHwaddr, i.Name, strconv.FormatBool(i.LLDP), strconv.FormatBool(i.LLDP))
+`, interfaceDeviceName(i), strings.ToUpper(i.Mode), i.Name, strconv.FormatBool(i.LLDP), strconv.FormatBool(i.LLDP))
+		default:
+			cfgString = fmt.Sprintf(`[Match]
+Name=%s
+
+[Network]
+Bridge=%s
+LLDP=%s
+EmitLLDP=%s
+`, interfaceDeviceName(i), i.Name, strconv.FormatBool(i.LLDP), strconv.FormatBool(i.LLDP))
+
+			cfgString += generateBridgeVLANContents(i.Name, i.VLAN, i.VLANTags, networkCfg.VLANs)
+		}
+
+		cfgString += generateQoSContents(i.QoS)
 
-		cfgString += generateBridgeVLANContents(i.Name, i.VLAN, i.VLANTags, networkCfg.VLANs)
+		if i.SRIOV != nil {
+			cfgString += generateSRIOVContents(*i.SRIOV)
+		}
 
 		ret = append(ret, networkdConfigFile{
-			Name:     fmt.Sprintf("20-en%s.network", strippedHwaddr),
+			Name:     fmt.Sprintf("20-%s.network", interfaceDeviceName(i)),
 			Contents: cfgString,
 		})
 	}
@@ -475,20 +1310,35 @@ Name=%s
 [Link]
 %s
 
-[DHCP]
-ClientIdentifier=mac
-RouteMetric=100
-UseMTU=true
-
+%s
 [Network]
-%s`, b.Name, generateLinkSectionContents(b.Addresses), generateNetworkSectionContents(networkCfg.DNS, networkCfg.NTP))
+%s`, b.Name, generateLinkSectionContents(deviceAddresses(b.Addresses, b.DisableIPv6), b.Online), generateDHCPSectionContents(b.DHCP, networkCfg.DNS, networkCfg.NTP, b.Addresses), generateNetworkSectionContents(networkCfg.DNS, networkCfg.NTP))
+
+		cfgString += processAddresses(deviceAddresses(b.Addresses, b.DisableIPv6), b.DisableIPv6, networkCfg.DuplicateAddressDetection != "", sendsRouterAdvertisements(b.PrefixDelegation, b.DHCPServer))
+		cfgString += generateStackedDevicesContents(networkCfg, b.Name)
+		cfgString += generateMulticastResolutionContents(b.MulticastDNS, b.LLMNR)
+		cfgString += generateCarrierContents(b.Online)
+		cfgString += generateProxyContents(b.ProxyARP, b.ProxyNDP, b.ProxyNDPAddresses)
+		cfgString += generateAddressingContents(b.IPv6Token, b.AddressGenerationMode, b.PrefixDelegation, b.DHCPServer)
 
-		cfgString += processAddresses(b.Addresses)
+		if networkCfg.DuplicateAddressDetection != "" {
+			cfgString += generateAddressDetectionContents(deviceAddresses(b.Addresses, b.DisableIPv6))
+		}
 
 		if len(b.Routes) > 0 {
 			cfgString += processRoutes(b.Routes)
 		}
 
+		if len(b.Rules) > 0 {
+			cfgString += processRules(b.Rules)
+		}
+
+		if len(b.Neighbors) > 0 {
+			cfgString += processNeighbors(b.Neighbors)
+		}
+
+		cfgString += generateUplinkIsolationContents(networkCfg, b.Name, deviceAddresses(b.Addresses, b.DisableIPv6), b.Routes)
+
 		ret = append(ret, networkdConfigFile{
 			Name:     fmt.Sprintf("21-%s.network", b.Name),
 			Contents: cfgString,
@@ -503,6 +1353,7 @@ Bridge=%s
 `, strippedHwaddr, b.Name)
 
 		cfgString += generateBridgeVLANContents(b.Name, b.VLAN, b.VLANTags, networkCfg.VLANs)
+		cfgString += generateQoSContents(b.QoS)
 
 		ret = append(ret, networkdConfigFile{
 			Name:     fmt.Sprintf("21-bn%s.network", strippedHwaddr),
@@ -513,6 +1364,11 @@ Bridge=%s
 		for index, member := range b.Members {
 			memberStrippedHwaddr := strings.ToLower(strings.ReplaceAll(member, ":", ""))
 
+			primaryString := ""
+			if b.PrimaryMember != "" && strings.EqualFold(b.PrimaryMember, member) {
+				primaryString = "PrimarySlave=true\n"
+			}
+
 			ret = append(ret, networkdConfigFile{
 				Name: fmt.Sprintf("21-bn%s-dev%d.network", strippedHwaddr, index),
 				Contents: fmt.Sprintf(`[Match]
@@ -522,14 +1378,18 @@ Name=en%s
 Bond=bn%s
 LLDP=%s
 EmitLLDP=%s
-`, memberStrippedHwaddr, strippedHwaddr, strconv.FormatBool(b.LLDP), strconv.FormatBool(b.LLDP)),
+%s`, memberStrippedHwaddr, strippedHwaddr, strconv.FormatBool(b.LLDP), strconv.FormatBool(b.LLDP), primaryString),
 			})
 		}
 	}
 
 	// Create networks for each VLAN.
 	for _, v := range networkCfg.VLANs {
-		cfgString := fmt.Sprintf(`[Match]
+		// Only vlans directly on top of a bridge have a veth peer to configure.
+		if !isStackedVLANParent(networkCfg, v.Parent) {
+			ret = append(ret, networkdConfigFile{
+				Name: fmt.Sprintf("22-vl%s.network", v.Name),
+				Contents: fmt.Sprintf(`[Match]
 Name=vl%s
 
 [Network]
@@ -539,35 +1399,160 @@ Bridge=%s
 VLAN=%d
 PVID=%d
 EgressUntagged=%d
-`, v.Name, v.Parent, v.ID, v.ID, v.ID)
+`, v.Name, v.Parent, v.ID, v.ID, v.ID),
+			})
+		}
+
+		cfgString := fmt.Sprintf(`[Match]
+Name=%s
+
+[Link]
+%s
+
+%s
+[Network]
+%s`, v.Name, generateLinkSectionContents(deviceAddresses(v.Addresses, v.DisableIPv6), v.Online), generateDHCPSectionContents(v.DHCP, networkCfg.DNS, networkCfg.NTP, v.Addresses), generateNetworkSectionContents(networkCfg.DNS, networkCfg.NTP))
+
+		cfgString += processAddresses(deviceAddresses(v.Addresses, v.DisableIPv6), v.DisableIPv6, networkCfg.DuplicateAddressDetection != "", sendsRouterAdvertisements(v.PrefixDelegation, v.DHCPServer))
+		cfgString += generateStackedDevicesContents(networkCfg, v.Name)
+		cfgString += generateMulticastResolutionContents(v.MulticastDNS, v.LLMNR)
+		cfgString += generateCarrierContents(v.Online)
+		cfgString += generateProxyContents(v.ProxyARP, v.ProxyNDP, v.ProxyNDPAddresses)
+		cfgString += generateAddressingContents(v.IPv6Token, v.AddressGenerationMode, v.PrefixDelegation, v.DHCPServer)
+
+		if networkCfg.DuplicateAddressDetection != "" {
+			cfgString += generateAddressDetectionContents(deviceAddresses(v.Addresses, v.DisableIPv6))
+		}
+
+		if len(v.Routes) > 0 {
+			cfgString += processRoutes(v.Routes)
+		}
+
+		if len(v.Rules) > 0 {
+			cfgString += processRules(v.Rules)
+		}
+
+		if len(v.Neighbors) > 0 {
+			cfgString += processNeighbors(v.Neighbors)
+		}
+
+		cfgString += generateUplinkIsolationContents(networkCfg, v.Name, deviceAddresses(v.Addresses, v.DisableIPv6), v.Routes)
 
 		ret = append(ret, networkdConfigFile{
-			Name:     fmt.Sprintf("22-vl%s.network", v.Name),
+			Name:     fmt.Sprintf("22-%s.network", v.Name),
 			Contents: cfgString,
 		})
+	}
 
-		cfgString = fmt.Sprintf(`[Match]
+	// Create networks for each VXLAN.
+	for _, v := range networkCfg.VXLANs {
+		cfgString := fmt.Sprintf(`[Match]
 Name=%s
 
 [Link]
 %s
 
-[DHCP]
-ClientIdentifier=mac
-RouteMetric=100
-UseMTU=true
-
+%s
 [Network]
-%s`, v.Name, generateLinkSectionContents(v.Addresses), generateNetworkSectionContents(networkCfg.DNS, networkCfg.NTP))
+%s`, v.Name, generateLinkSectionContents(v.Addresses, nil), generateDHCPSectionContents(v.DHCP, networkCfg.DNS, networkCfg.NTP, v.Addresses), generateNetworkSectionContents(networkCfg.DNS, networkCfg.NTP))
 
-		cfgString += processAddresses(v.Addresses)
+		cfgString += processAddresses(v.Addresses, false, false, false)
+		cfgString += generateStackedDevicesContents(networkCfg, v.Name)
 
 		if len(v.Routes) > 0 {
 			cfgString += processRoutes(v.Routes)
 		}
 
 		ret = append(ret, networkdConfigFile{
-			Name:     fmt.Sprintf("22-%s.network", v.Name),
+			Name:     fmt.Sprintf("23-%s.network", v.Name),
+			Contents: cfgString,
+		})
+	}
+
+	// Create networks for each tunnel.
+	for _, t := range networkCfg.Tunnels {
+		// Layer 2 tunnels may be attached to a managed bridge rather than being configured directly.
+		if t.Bridge != "" {
+			ret = append(ret, networkdConfigFile{
+				Name: fmt.Sprintf("24-%s.network", t.Name),
+				Contents: fmt.Sprintf(`[Match]
+Name=%s
+
+[Link]
+RequiredForOnline=no
+
+[Network]
+Bridge=%s
+`, t.Name, t.Bridge),
+			})
+
+			continue
+		}
+
+		cfgString := fmt.Sprintf(`[Match]
+Name=%s
+
+[Link]
+%s
+
+%s
+[Network]
+%s`, t.Name, generateLinkSectionContents(t.Addresses, nil), generateDHCPSectionContents(t.DHCP, networkCfg.DNS, networkCfg.NTP, t.Addresses), generateNetworkSectionContents(networkCfg.DNS, networkCfg.NTP))
+
+		cfgString += processAddresses(t.Addresses, false, false, false)
+
+		if len(t.Routes) > 0 {
+			cfgString += processRoutes(t.Routes)
+		}
+
+		ret = append(ret, networkdConfigFile{
+			Name:     fmt.Sprintf("24-%s.network", t.Name),
+			Contents: cfgString,
+		})
+	}
+
+	// Bring up each VRF.
+	for _, v := range networkCfg.VRFs {
+		ret = append(ret, networkdConfigFile{
+			Name: fmt.Sprintf("25-%s.network", v.Name),
+			Contents: fmt.Sprintf(`[Match]
+Name=%s
+
+[Link]
+RequiredForOnline=no
+
+[Network]
+ConfigureWithoutCarrier=yes
+`, v.Name),
+		})
+	}
+
+	// Configure each dummy device, only required for online if requested.
+	for _, d := range networkCfg.Dummies {
+		linkSection := "RequiredForOnline=no"
+		if d.Online != nil {
+			linkSection = generateLinkSectionContents(d.Addresses, d.Online)
+		}
+
+		cfgString := fmt.Sprintf(`[Match]
+Name=%s
+
+[Link]
+%s
+
+[Network]
+LinkLocalAddressing=no
+ConfigureWithoutCarrier=yes
+`, d.Name, linkSection)
+
+		for _, addr := range d.Addresses {
+			cfgString += fmt.Sprintf("Address=%s\n", addr)
+		}
+
+		cfgString += generateStackedDevicesContents(networkCfg, d.Name)
+
+		ret = append(ret, networkdConfigFile{
+			Name:     fmt.Sprintf("26-%s.network", d.Name),
 			Contents: cfgString,
 		})
 	}
@@ -575,12 +1560,45 @@ UseMTU=true
 	return ret
 }
 
-func processAddresses(addresses []string) string {
-	ret := ""
-	if len(addresses) != 0 {
-		ret += "LinkLocalAddressing=ipv6\n"
-	} else {
-		ret += "LinkLocalAddressing=no\n"
+// deviceAddresses returns the addresses to configure on a device, dropping any IPv6 ones if IPv6 is disabled.
+func deviceAddresses(addresses []string, disableIPv6 bool) []string {
+	if !disableIPv6 {
+		return addresses
+	}
+
+	ret := []string{}
+
+	for _, addr := range addresses {
+		if addr == "dhcp6" || addr == "slaac" || strings.Contains(addr, ":") {
+			continue
+		}
+
+		ret = append(ret, addr)
+	}
+
+	return ret
+}
+
+// processAddresses returns the [Network] section addressing configuration. When detecting duplicate addresses,
+// static IPv4 addresses are instead configured through generateAddressDetectionContents. Sending router
+// advertisements requires an IPv6 link-local address, even if the device has no other address.
+func processAddresses(addresses []string, disableIPv6 bool, detectDuplicates bool, sendRA bool) string {
+	linkLocalAddressing := "no"
+	if (len(addresses) != 0 || sendRA) && !disableIPv6 {
+		linkLocalAddressing = "ipv6"
+	}
+
+	// Without DHCP, always configure an IPv4 link-local address. Otherwise it's only used as a fallback.
+	if slices.Contains(addresses, "ipv4ll") && !slices.Contains(addresses, "dhcp4") {
+		if linkLocalAddressing == "ipv6" {
+			linkLocalAddressing = "yes"
+		} else {
+			linkLocalAddressing = "ipv4"
+		}
+	}
+
+	ret := fmt.Sprintf("LinkLocalAddressing=%s\n", linkLocalAddressing)
+	if len(addresses) == 0 {
 		ret += "ConfigureWithoutCarrier=yes\n"
 	}
 
@@ -595,8 +1613,14 @@ func processAddresses(addresses []string) string {
 			hasDHCP6 = true
 		case "slaac": //nolint:goconst
 			acceptIPv6RA = true
+		case "ipv4ll":
+			// Handled through LinkLocalAddressing or the DHCPv4 fallback.
 
 		default:
+			if detectDuplicates && isStaticIPv4Address(addr) {
+				continue
+			}
+
 			ret += fmt.Sprintf("Address=%s\n", addr)
 		}
 	}
@@ -618,6 +1642,27 @@ func processAddresses(addresses []string) string {
 	return ret
 }
 
+// generateAddressDetectionContents returns [Address] sections enabling IPv4 address conflict detection for
+// the static IPv4 addresses. IPv6 duplicate address detection is always enabled.
+func generateAddressDetectionContents(addresses []string) string {
+	ret := ""
+
+	for _, addr := range addresses {
+		if isStaticIPv4Address(addr) {
+			ret += fmt.Sprintf("\n[Address]\nAddress=%s\nDuplicateAddressDetection=ipv4\n", addr)
+		}
+	}
+
+	return ret
+}
+
+// isStaticIPv4Address returns true if the address is a static IPv4 address.
+func isStaticIPv4Address(addr string) bool {
+	ip, _, err := net.ParseCIDR(addr)
+
+	return err == nil && ip.To4() != nil
+}
+
 func processRoutes(routes []api.SystemNetworkRoute) string {
 	ret := ""
 
@@ -625,6 +1670,8 @@ func processRoutes(routes []api.SystemNetworkRoute) string {
 		ret += "\n[Route]\n"
 
 		switch route.Via {
+		case "":
+			// No gateway, such as for blackhole or unreachable routes.
 		case "dhcp4":
 			ret += "Gateway=_dhcp4\n"
 		case "slaac":
@@ -634,93 +1681,1354 @@ func processRoutes(routes []api.SystemNetworkRoute) string {
 		}
 
 		ret += fmt.Sprintf("Destination=%s\n", route.To)
+
+		if route.Metric != 0 {
+			ret += fmt.Sprintf("Metric=%d\n", route.Metric)
+		}
+
+		if route.Table != "" {
+			ret += fmt.Sprintf("Table=%s\n", route.Table)
+		}
+
+		if route.Scope != "" {
+			ret += fmt.Sprintf("Scope=%s\n", route.Scope)
+		}
+
+		if route.Source != "" {
+			ret += fmt.Sprintf("PreferredSource=%s\n", route.Source)
+		}
+
+		if route.OnLink {
+			ret += "GatewayOnLink=true\n"
+		}
+
+		if route.MTU != 0 {
+			ret += fmt.Sprintf("MTUBytes=%d\n", route.MTU)
+		}
+
+		if route.Type != "" {
+			ret += fmt.Sprintf("Type=%s\n", route.Type)
+		}
 	}
 
 	return ret
 }
 
-func generateNetworkSectionContents(dns *api.SystemNetworkDNS, ntp *api.SystemNetworkNTP) string {
-	ret := ""
+// uplinkTableBase is the first routing table number used to isolate uplinks.
+const uplinkTableBase = 10000
 
-	// If there are search domains or name servers, add those to the config.
-	if dns != nil {
-		if len(dns.SearchDomains) > 0 {
-			ret += fmt.Sprintf("Domains=%s\n", strings.Join(dns.SearchDomains, " "))
-		}
+// getUplinkTables returns the routing table used by each uplink when uplink isolation is enabled.
+// An uplink is an interface, bond or vlan with a default route through a gateway.
+func getUplinkTables(networkCfg api.SystemNetworkConfig) map[string]int {
+	ret := map[string]int{}
 
-		for _, ns := range dns.Nameservers {
-			ret += fmt.Sprintf("DNS=%s\n", ns)
-		}
+	if !networkCfg.IsolateUplinks {
+		return ret
 	}
 
-	// If there are time servers defined, add them to the config.
-	if ntp != nil {
-		for _, ts := range ntp.Timeservers {
-			ret += fmt.Sprintf("NTP=%s\n", ts)
+	addUplink := func(name string, routes []api.SystemNetworkRoute) {
+		for _, route := range routes {
+			if isDefaultGatewayRoute(route) {
+				ret[name] = uplinkTableBase + len(ret)
+
+				return
+			}
 		}
 	}
 
+	for _, i := range networkCfg.Interfaces {
+		addUplink(i.Name, i.Routes)
+	}
+
+	for _, b := range networkCfg.Bonds {
+		addUplink(b.Name, b.Routes)
+	}
+
+	for _, v := range networkCfg.VLANs {
+		addUplink(v.Name, v.Routes)
+	}
+
 	return ret
 }
 
-func generateTimesyncContents(ntp api.SystemNetworkNTP) string {
-	if len(ntp.Timeservers) == 0 {
+// isDefaultGatewayRoute returns true if the route is an IPv4 or IPv6 default route through a gateway.
+func isDefaultGatewayRoute(route api.SystemNetworkRoute) bool {
+	return (route.To == "0.0.0.0/0" || route.To == "::/0") && route.Via != "" && (route.Table == "" || route.Table == "main")
+}
+
+// generateUplinkIsolationContents returns the routes and routing policy rules making sure traffic from the
+// uplink's static addresses leaves through its own default gateway, using a dedicated routing table.
+func generateUplinkIsolationContents(networkCfg api.SystemNetworkConfig, name string, addresses []string, routes []api.SystemNetworkRoute) string {
+	table, ok := getUplinkTables(networkCfg)[name]
+	if !ok {
 		return ""
 	}
 
-	return "[Time]\nFallbackNTP=" + strings.Join(ntp.Timeservers, " ") + "\n"
-}
-
-func generateBridgeVLANContents(bridgeName string, specificVLAN int, additionalVLANTags []int, vlans []api.SystemNetworkVLAN) string {
-	vlanTags := []int{}
+	uplinkRoutes := []api.SystemNetworkRoute{}
 
-	// Add specific VLAN tag, if configured.
-	if specificVLAN != 0 {
-		vlanTags = append(vlanTags, specificVLAN)
+	for _, route := range routes {
+		if isDefaultGatewayRoute(route) {
+			route.Table = strconv.Itoa(table)
+			uplinkRoutes = append(uplinkRoutes, route)
+		}
 	}
 
-	// Add any additional VLAN tags.
-	vlanTags = append(vlanTags, additionalVLANTags...)
+	ret := processRoutes(uplinkRoutes)
 
-	// Grab any relevant tags for this bridge from VLAN definitions.
-	for _, vlan := range vlans {
-		if vlan.Parent == bridgeName {
-			vlanTags = append(vlanTags, vlan.ID)
+	for _, address := range addresses {
+		ip, _, err := net.ParseCIDR(address)
+		if err != nil {
+			// Skip dynamic addresses.
+			continue
+		}
+
+		source := ip.String() + "/32"
+		if ip.To4() == nil {
+			source = ip.String() + "/128"
 		}
+
+		// Directly connected destinations are still reached through the main routing table, only
+		// ignoring its default routes.
+		ret += fmt.Sprintf("\n[RoutingPolicyRule]\nFrom=%s\nTable=main\nSuppressPrefixLength=0\nPriority=%d\n", source, uplinkTableBase-1)
+		ret += fmt.Sprintf("\n[RoutingPolicyRule]\nFrom=%s\nTable=%d\nPriority=%d\n", source, table, uplinkTableBase)
 	}
 
-	// Sort and remove any duplicate tags.
-	slices.Sort(vlanTags)
-	vlanTags = slices.Compact(vlanTags)
+	return ret
+}
 
+func processRules(rules []api.SystemNetworkRule) string {
 	ret := ""
 
-	if len(vlanTags) > 0 {
-		if specificVLAN != 0 {
-			ret += "\n[BridgeVLAN]\n"
-			ret += fmt.Sprintf("PVID=%d\n", specificVLAN)
-			ret += fmt.Sprintf("EgressUntagged=%d\n", specificVLAN)
+	for _, rule := range rules {
+		ret += "\n[RoutingPolicyRule]\n"
+
+		if rule.From != "" {
+			ret += fmt.Sprintf("From=%s\n", rule.From)
 		}
 
-		for _, tag := range vlanTags {
-			ret += "\n[BridgeVLAN]\n"
-			ret += fmt.Sprintf("VLAN=%d\n", tag)
+		if rule.To != "" {
+			ret += fmt.Sprintf("To=%s\n", rule.To)
+		}
+
+		if rule.Table != "" {
+			ret += fmt.Sprintf("Table=%s\n", rule.Table)
+		}
+
+		if rule.Priority != 0 {
+			ret += fmt.Sprintf("Priority=%d\n", rule.Priority)
+		}
+
+		if rule.FwMark != "" {
+			ret += fmt.Sprintf("FirewallMark=%s\n", rule.FwMark)
 		}
 	}
 
 	return ret
 }
 
-func generateLinkSectionContents(addresses []string) string {
-	if len(addresses) == 0 {
-		return "RequiredForOnline=no"
+func processNeighbors(neighbors []api.SystemNetworkNeighbor) string {
+	ret := ""
+
+	for _, neighbor := range neighbors {
+		ret += "\n[Neighbor]\n"
+		ret += fmt.Sprintf("Address=%s\n", neighbor.Address)
+		ret += fmt.Sprintf("LinkLayerAddress=%s\n", neighbor.Hwaddr)
 	}
 
-	expectsIPv4 := false
-	expectsIPv6 := false
-	for _, addr := range addresses {
-		switch addr {
-		case "dhcp4":
+	return ret
+}
+
+// generateStackedDevicesContents returns the [Network] entries needed to attach any devices
+// which are stacked on top of the named parent device, as well as any VRF it belongs to.
+func generateStackedDevicesContents(networkCfg api.SystemNetworkConfig, parent string) string {
+	ret := ""
+
+	for _, v := range networkCfg.VXLANs {
+		if v.Parent == parent {
+			ret += fmt.Sprintf("VXLAN=%s\n", v.Name)
+		}
+	}
+
+	// Stacked vlans are only created on top of other vlans or direct interfaces, bridges use vlan filtering instead.
+	if isStackedVLANParent(networkCfg, parent) {
+		for _, v := range networkCfg.VLANs {
+			if v.Parent == parent {
+				ret += fmt.Sprintf("VLAN=%s\n", v.Name)
+			}
+		}
+	}
+
+	for _, t := range networkCfg.Tunnels {
+		if t.Parent == parent && (t.Kind == "gre" || t.Kind == "gretap") {
+			ret += fmt.Sprintf("Tunnel=%s\n", t.Name)
+		}
+	}
+
+	for _, v := range networkCfg.VRFs {
+		if slices.Contains(v.Members, parent) {
+			ret += fmt.Sprintf("VRF=%s\n", v.Name)
+		}
+	}
+
+	return ret
+}
+
+func generateBondSectionContents(bond api.SystemNetworkBond) string {
+	ret := ""
+
+	if bond.MIIMonitorSec != "" {
+		ret += fmt.Sprintf("MIIMonitorSec=%s\n", bond.MIIMonitorSec)
+	}
+
+	if bond.LACPTransmitRate != "" {
+		ret += fmt.Sprintf("LACPTransmitRate=%s\n", bond.LACPTransmitRate)
+	}
+
+	if bond.TransmitHashPolicy != "" {
+		ret += fmt.Sprintf("TransmitHashPolicy=%s\n", bond.TransmitHashPolicy)
+	}
+
+	if bond.MinLinks != 0 {
+		ret += fmt.Sprintf("MinLinks=%d\n", bond.MinLinks)
+	}
+
+	if bond.ARPIntervalSec != "" {
+		ret += fmt.Sprintf("ARPIntervalSec=%s\n", bond.ARPIntervalSec)
+	}
+
+	if len(bond.ARPIPTargets) > 0 {
+		ret += fmt.Sprintf("ARPIPTargets=%s\n", strings.Join(bond.ARPIPTargets, " "))
+	}
+
+	if bond.UpDelaySec != "" {
+		ret += fmt.Sprintf("UpDelaySec=%s\n", bond.UpDelaySec)
+	}
+
+	if bond.DownDelaySec != "" {
+		ret += fmt.Sprintf("DownDelaySec=%s\n", bond.DownDelaySec)
+	}
+
+	if bond.PrimaryReselect != "" {
+		ret += fmt.Sprintf("PrimaryReselectPolicy=%s\n", bond.PrimaryReselect)
+	}
+
+	if bond.FailOverMACPolicy != "" {
+		ret += fmt.Sprintf("FailOverMACPolicy=%s\n", bond.FailOverMACPolicy)
+	}
+
+	return ret
+}
+
+func generateVXLANSectionContents(vxlan api.SystemNetworkVXLAN) string {
+	ret := fmt.Sprintf("VNI=%d\n", vxlan.VNI)
+
+	if vxlan.Local != "" {
+		ret += fmt.Sprintf("Local=%s\n", vxlan.Local)
+	}
+
+	if vxlan.Remote != "" {
+		ret += fmt.Sprintf("Remote=%s\n", vxlan.Remote)
+	}
+
+	if vxlan.DestinationPort != 0 {
+		ret += fmt.Sprintf("DestinationPort=%d\n", vxlan.DestinationPort)
+	}
+
+	if vxlan.TTL != 0 {
+		ret += fmt.Sprintf("TTL=%d\n", vxlan.TTL)
+	}
+
+	// Without a parent device, the VXLAN must be created on its own.
+	if vxlan.Parent == "" {
+		ret += "Independent=true\n"
+	}
+
+	return ret
+}
+
+func generateTunnelSectionContents(tunnel api.SystemNetworkTunnel) string {
+	ret := ""
+
+	switch tunnel.Kind {
+	case "gre", "gretap":
+		ret += "\n[Tunnel]\n"
+
+		if tunnel.Local != "" {
+			ret += fmt.Sprintf("Local=%s\n", tunnel.Local)
+		}
+
+		ret += fmt.Sprintf("Remote=%s\n", tunnel.Remote)
+
+		if tunnel.Key != "" {
+			ret += fmt.Sprintf("Key=%s\n", tunnel.Key)
+		}
+
+		if tunnel.TTL != 0 {
+			ret += fmt.Sprintf("TTL=%d\n", tunnel.TTL)
+		}
+
+		// Without a parent device, the tunnel must be created on its own.
+		if tunnel.Parent == "" {
+			ret += "Independent=true\n"
+		}
+
+	case "geneve":
+		ret += "\n[GENEVE]\n"
+		ret += fmt.Sprintf("Id=%d\n", tunnel.VNI)
+		ret += fmt.Sprintf("Remote=%s\n", tunnel.Remote)
+
+		if tunnel.DestinationPort != 0 {
+			ret += fmt.Sprintf("DestinationPort=%d\n", tunnel.DestinationPort)
+		}
+
+		if tunnel.TTL != 0 {
+			ret += fmt.Sprintf("TTL=%d\n", tunnel.TTL)
+		}
+	}
+
+	if tunnel.Kind == "wireguard" && tunnel.WireGuard != nil {
+		ret += "\n[WireGuard]\n"
+		ret += fmt.Sprintf("PrivateKeyFile=%s\n", wireGuardKeyPath(tunnel.Name))
+
+		if tunnel.WireGuard.ListenPort != 0 {
+			ret += fmt.Sprintf("ListenPort=%d\n", tunnel.WireGuard.ListenPort)
+		}
+
+		for index, peer := range tunnel.WireGuard.Peers {
+			ret += "\n[WireGuardPeer]\n"
+			ret += fmt.Sprintf("PublicKey=%s\n", peer.PublicKey)
+
+			if peer.PresharedKey != "" {
+				ret += fmt.Sprintf("PresharedKeyFile=%s\n", wireGuardPresharedKeyPath(tunnel.Name, index))
+			}
+
+			if peer.Endpoint != "" {
+				ret += fmt.Sprintf("Endpoint=%s\n", peer.Endpoint)
+			}
+
+			if len(peer.AllowedIPs) > 0 {
+				ret += fmt.Sprintf("AllowedIPs=%s\n", strings.Join(peer.AllowedIPs, ","))
+			}
+
+			if peer.PersistentKeepalive != 0 {
+				ret += fmt.Sprintf("PersistentKeepalive=%d\n", peer.PersistentKeepalive)
+			}
+		}
+	}
+
+	return ret
+}
+
+// wireGuardKeyPath returns the path of the file holding the private key of a WireGuard tunnel.
+func wireGuardKeyPath(name string) string {
+	return filepath.Join(SystemdNetworkConfigPath, name+".key")
+}
+
+// wireGuardPresharedKeyPath returns the path of the file holding the preshared key of a WireGuard peer.
+func wireGuardPresharedKeyPath(name string, peer int) string {
+	return filepath.Join(SystemdNetworkConfigPath, fmt.Sprintf("%s-peer%d.psk", name, peer))
+}
+
+// writeWireGuardKeys writes out the private and preshared keys of all WireGuard tunnels. The
+// keys are only readable by root and the systemd-network group.
+func writeWireGuardKeys(networkCfg api.SystemNetworkConfig) error {
+	keys := map[string]string{}
+
+	for _, t := range networkCfg.Tunnels {
+		if t.Kind != "wireguard" || t.WireGuard == nil {
+			continue
+		}
+
+		keys[wireGuardKeyPath(t.Name)] = t.WireGuard.PrivateKey
+
+		for index, peer := range t.WireGuard.Peers {
+			if peer.PresharedKey != "" {
+				keys[wireGuardPresharedKeyPath(t.Name, index)] = peer.PresharedKey
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	group, err := user.LookupGroup("systemd-network")
+	if err != nil {
+		return err
+	}
+
+	gid, err := strconv.Atoi(group.Gid)
+	if err != nil {
+		return err
+	}
+
+	for path, key := range keys {
+		err := os.WriteFile(path, []byte(key+"\n"), 0o640)
+		if err != nil {
+			return err
+		}
+
+		err = os.Chown(path, 0, gid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writePPPoEConfiguration writes the pppd peer configuration for each PPPoE uplink, as well as
+// the secrets used to authenticate them.
+func writePPPoEConfiguration(networkCfg api.SystemNetworkConfig) error {
+	peersPath := filepath.Join(PPPConfigPath, "peers")
+
+	// Remove any existing configuration, leaving other peers alone.
+	oldCfgs, err := filepath.Glob(pppoePeerFile("*"))
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range oldCfgs {
+		err := os.Remove(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(networkCfg.PPPoE) == 0 {
+		return nil
+	}
+
+	err = os.MkdirAll(peersPath, 0o755)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range networkCfg.PPPoE {
+		err := os.WriteFile(pppoePeerFile(p.Name), []byte(generatePPPoEPeerContents(p)), 0o644)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The same secrets are used for both PAP and CHAP authentication.
+	secrets := generatePPPoESecretsContents(networkCfg.PPPoE)
+
+	for _, name := range []string{"chap-secrets", "pap-secrets"} {
+		err := os.WriteFile(filepath.Join(PPPConfigPath, name), []byte(secrets), 0o600)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restartPPPoE stops any running PPPoE connections, then starts one for each PPPoE uplink.
+func restartPPPoE(ctx context.Context, networkCfg *api.SystemNetworkConfig) error {
+	err := StopUnit(ctx, "incus-osd-pppoe@*.service")
+	if err != nil {
+		return err
+	}
+
+	for _, p := range networkCfg.PPPoE {
+		err := StartUnit(ctx, fmt.Sprintf("incus-osd-pppoe@%s.service", p.Name))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pppoePeerFile returns the path to the pppd peer configuration of a PPPoE uplink.
+func pppoePeerFile(name string) string {
+	return filepath.Join(PPPConfigPath, "peers", "incus-os-"+name)
+}
+
+// pppString returns a quoted pppd string, escaping quotes and backslashes.
+func pppString(value string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(value) + "\""
+}
+
+func generatePPPoEPeerContents(p api.SystemNetworkPPPoE) string {
+	ret := "plugin pppoe.so\n"
+	ret += fmt.Sprintf("nic-%s\n", p.Parent)
+	ret += fmt.Sprintf("ifname %s\n", p.Name)
+	ret += fmt.Sprintf("user %s\n", pppString(p.Username))
+	ret += "noauth\nhide-password\nnoipdefault\ndefaultroute\nusepeerdns\n+ipv6\n"
+	ret += "persist\nmaxfail 0\nholdoff 5\nlcp-echo-interval 20\nlcp-echo-failure 3\n"
+
+	if p.MTU != 0 {
+		ret += fmt.Sprintf("mtu %d\nmru %d\n", p.MTU, p.MTU)
+	}
+
+	return ret
+}
+
+func generatePPPoESecretsContents(pppoe []api.SystemNetworkPPPoE) string {
+	ret := ""
+
+	for _, p := range pppoe {
+		ret += fmt.Sprintf("%s * %s\n", pppString(p.Username), pppString(p.Password))
+	}
+
+	return ret
+}
+
+// wpaSupplicantCertsPath returns the directory holding the 802.1X certificates and keys.
+func wpaSupplicantCertsPath() string {
+	return filepath.Join(WPASupplicantConfigPath, "certs")
+}
+
+// writeWPASupplicantConfiguration writes the wired wpa_supplicant configuration, as well as any
+// certificates and keys, for each interface requiring 802.1X authentication.
+func writeWPASupplicantConfiguration(networkCfg api.SystemNetworkConfig) error {
+	// Remove any existing configuration.
+	oldCfgs, err := filepath.Glob(filepath.Join(WPASupplicantConfigPath, "wpa_supplicant-wired-*.conf"))
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range oldCfgs {
+		err := os.Remove(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.RemoveAll(wpaSupplicantCertsPath())
+	if err != nil {
+		return err
+	}
+
+	for _, i := range networkCfg.Interfaces {
+		if i.IEEE8021X == nil {
+			continue
+		}
+
+		err := os.MkdirAll(wpaSupplicantCertsPath(), 0o700)
+		if err != nil {
+			return err
+		}
+
+		devName := interfaceDeviceName(i)
+
+		files := map[string]string{
+			filepath.Join(wpaSupplicantCertsPath(), devName+"-ca.pem"):     i.IEEE8021X.CACert,
+			filepath.Join(wpaSupplicantCertsPath(), devName+"-client.pem"): i.IEEE8021X.ClientCert,
+			filepath.Join(wpaSupplicantCertsPath(), devName+"-client.key"): i.IEEE8021X.ClientKey,
+		}
+
+		for path, contents := range files {
+			if contents == "" {
+				continue
+			}
+
+			err := os.WriteFile(path, []byte(contents), 0o600)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = os.WriteFile(filepath.Join(WPASupplicantConfigPath, "wpa_supplicant-wired-"+devName+".conf"), []byte(generateWPASupplicantContents(devName, *i.IEEE8021X)), 0o600)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restartWPASupplicant stops any running wired wpa_supplicant instances, then starts one for
+// each interface requiring 802.1X authentication.
+func restartWPASupplicant(ctx context.Context, networkCfg *api.SystemNetworkConfig) error {
+	err := StopUnit(ctx, "wpa_supplicant-wired@*.service")
+	if err != nil {
+		return err
+	}
+
+	for _, i := range networkCfg.Interfaces {
+		if i.IEEE8021X == nil {
+			continue
+		}
+
+		err := StartUnit(ctx, fmt.Sprintf("wpa_supplicant-wired@%s.service", interfaceDeviceName(i)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func generateWPASupplicantContents(devName string, cfg api.SystemNetworkIEEE8021X) string {
+	ret := "ctrl_interface=/run/wpa_supplicant\nap_scan=0\n\nnetwork={\n"
+	ret += "\tkey_mgmt=IEEE8021X\n"
+	ret += "\teapol_flags=0\n"
+	ret += fmt.Sprintf("\teap=%s\n", strings.ToUpper(cfg.EAPMethod))
+
+	if cfg.Identity != "" {
+		ret += fmt.Sprintf("\tidentity=%s\n", wpaSupplicantString(cfg.Identity))
+	}
+
+	if cfg.AnonymousIdentity != "" {
+		ret += fmt.Sprintf("\tanonymous_identity=%s\n", wpaSupplicantString(cfg.AnonymousIdentity))
+	}
+
+	if cfg.Password != "" {
+		ret += fmt.Sprintf("\tpassword=%s\n", wpaSupplicantString(cfg.Password))
+	}
+
+	if cfg.Phase2 != "" {
+		ret += fmt.Sprintf("\tphase2=%s\n", wpaSupplicantString(cfg.Phase2))
+	}
+
+	if cfg.CACert != "" {
+		ret += fmt.Sprintf("\tca_cert=\"%s\"\n", filepath.Join(wpaSupplicantCertsPath(), devName+"-ca.pem"))
+	}
+
+	if cfg.ClientCert != "" {
+		ret += fmt.Sprintf("\tclient_cert=\"%s\"\n", filepath.Join(wpaSupplicantCertsPath(), devName+"-client.pem"))
+	}
+
+	if cfg.ClientKey != "" {
+		ret += fmt.Sprintf("\tprivate_key=\"%s\"\n", filepath.Join(wpaSupplicantCertsPath(), devName+"-client.key"))
+	}
+
+	if cfg.ClientKeyPassword != "" {
+		ret += fmt.Sprintf("\tprivate_key_passwd=%s\n", wpaSupplicantString(cfg.ClientKeyPassword))
+	}
+
+	ret += "}\n"
+
+	return ret
+}
+
+// wpaSupplicantString returns a wpa_supplicant string value. Values which can't be safely quoted, such as those
+// containing quotes or control characters, are hex encoded instead.
+func wpaSupplicantString(value string) string {
+	if strings.ContainsFunc(value, func(r rune) bool { return r == '"' || unicode.IsControl(r) }) {
+		return hex.EncodeToString([]byte(value))
+	}
+
+	return "\"" + value + "\""
+}
+
+// generateCarrierContents returns the [Network] entries controlling how carrier loss is handled.
+func generateCarrierContents(online *api.SystemNetworkOnline) string {
+	if online == nil || online.IgnoreCarrierLoss == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("IgnoreCarrierLoss=%s\n", online.IgnoreCarrierLoss)
+}
+
+// generateProxyContents returns the [Network] entries answering ARP and NDP requests on behalf of other
+// hosts, such as routed instances. Proxy NDP is implied when specific addresses are listed.
+func generateProxyContents(proxyARP bool, proxyNDP bool, proxyNDPAddresses []string) string {
+	ret := ""
+
+	if proxyARP {
+		ret += "IPv4ProxyARP=yes\n"
+	}
+
+	if proxyNDP || len(proxyNDPAddresses) > 0 {
+		ret += "IPv6ProxyNDP=yes\n"
+	}
+
+	for _, addr := range proxyNDPAddresses {
+		ret += fmt.Sprintf("IPv6ProxyNDPAddress=%s\n", addr)
+	}
+
+	return ret
+}
+
+func generateMulticastResolutionContents(mdns *bool, llmnr *bool) string {
+	ret := ""
+
+	if mdns != nil {
+		ret += fmt.Sprintf("MulticastDNS=%s\n", strconv.FormatBool(*mdns))
+	}
+
+	if llmnr != nil {
+		ret += fmt.Sprintf("LLMNR=%s\n", strconv.FormatBool(*llmnr))
+	}
+
+	return ret
+}
+
+// sendsRouterAdvertisements returns true if the device announces itself as an IPv6 router, either for a
+// delegated prefix or alongside its DHCP server.
+func sendsRouterAdvertisements(pd *api.SystemNetworkPrefixDelegation, dhcpServer *api.SystemNetworkDHCPServer) bool {
+	return pd != nil || (dhcpServer != nil && dhcpServer.SendRA)
+}
+
+// generateAddressingContents returns the [Network] entries and additional sections controlling IPv6
+// address generation, prefix delegation and the built-in DHCP server of a device.
+func generateAddressingContents(token string, mode string, pd *api.SystemNetworkPrefixDelegation, dhcpServer *api.SystemNetworkDHCPServer) string {
+	ret := ""
+
+	if mode != "" {
+		ret += fmt.Sprintf("IPv6LinkLocalAddressGenerationMode=%s\n", mode)
+	}
+
+	// Assign a delegated prefix from the uplink and announce it to downstream hosts.
+	if pd != nil {
+		ret += "DHCPPrefixDelegation=yes\n"
+		ret += "IPv6SendRA=yes\n"
+	}
+
+	if dhcpServer != nil {
+		ret += "DHCPServer=yes\n"
+
+		if dhcpServer.SendRA && pd == nil {
+			ret += "IPv6SendRA=yes\n"
+		}
+	}
+
+	// Pick how SLAAC addresses get their interface identifier.
+	if token != "" {
+		ret += fmt.Sprintf("\n[IPv6AcceptRA]\nToken=static:%s\n", token)
+	} else if mode == "stable-privacy" {
+		ret += "\n[IPv6AcceptRA]\nToken=prefixstable\n"
+	}
+
+	if pd != nil {
+		ret += "\n[DHCPPrefixDelegation]\n"
+
+		if pd.Uplink != "" {
+			ret += fmt.Sprintf("UplinkInterface=%s\n", pd.Uplink)
+		}
+
+		if pd.SubnetID != "" {
+			ret += fmt.Sprintf("SubnetId=%s\n", pd.SubnetID)
+		}
+
+		ret += "Announce=yes\n"
+	}
+
+	if dhcpServer != nil {
+		ret += "\n[DHCPServer]\n"
+
+		if dhcpServer.PoolOffset != 0 {
+			ret += fmt.Sprintf("PoolOffset=%d\n", dhcpServer.PoolOffset)
+		}
+
+		if dhcpServer.PoolSize != 0 {
+			ret += fmt.Sprintf("PoolSize=%d\n", dhcpServer.PoolSize)
+		}
+
+		ret += fmt.Sprintf("EmitRouter=%s\n", strconv.FormatBool(dhcpServer.EmitRouter))
+		ret += "EmitDNS=false\n"
+	}
+
+	return ret
+}
+
+func generateDHCPSectionContents(dhcp *api.SystemNetworkDHCP, dns *api.SystemNetworkDNS, ntp *api.SystemNetworkNTP, addresses []string) string {
+	clientIdentifier := "mac"
+	if dhcp != nil && dhcp.ClientIdentifier != "" {
+		clientIdentifier = dhcp.ClientIdentifier
+	}
+
+	routeMetric := 100
+	if dhcp != nil && dhcp.RouteMetric != 0 {
+		routeMetric = dhcp.RouteMetric
+	}
+
+	ret := fmt.Sprintf("[DHCP]\nClientIdentifier=%s\nRouteMetric=%d\nUseMTU=true\n", clientIdentifier, routeMetric)
+
+	if dhcp != nil {
+		ret += generateDHCPOptionsContents(*dhcp)
+	}
+
+	// Accept the DHCP provided hostname, unless overridden for this device.
+	if (dhcp == nil || dhcp.UseHostname == nil) && useDHCPHostname(dns) {
+		ret += "UseHostname=true\n"
+	}
+
+	// Accept the DHCP provided timeservers, unless overridden for this device.
+	if (dhcp == nil || dhcp.UseNTP == nil) && useDHCPTimeservers(ntp) {
+		ret += "UseNTP=true\n"
+	}
+
+	// Fallback to an IPv4 link-local address if no DHCPv4 lease can be obtained.
+	if slices.Contains(addresses, "dhcp4") && slices.Contains(addresses, "ipv4ll") {
+		ret += "\n[DHCPv4]\nIPv4LLFallback=yes\n"
+	}
+
+	return ret
+}
+
+// useDHCPHostname returns whether the hostname provided by DHCP should be used by default.
+func useDHCPHostname(dns *api.SystemNetworkDNS) bool {
+	return dns != nil && dns.Hostname == "" && dns.UseDHCPHostname
+}
+
+// useDHCPTimeservers returns whether the timeservers provided by DHCP should be the only time source.
+func useDHCPTimeservers(ntp *api.SystemNetworkNTP) bool {
+	return ntp != nil && len(ntp.Timeservers) == 0 && ntp.UseDHCP
+}
+
+func generateDHCPOptionsContents(dhcp api.SystemNetworkDHCP) string {
+	ret := ""
+
+	if dhcp.SendHostname != nil {
+		ret += fmt.Sprintf("SendHostname=%s\n", strconv.FormatBool(*dhcp.SendHostname))
+	}
+
+	if dhcp.UseHostname != nil {
+		ret += fmt.Sprintf("UseHostname=%s\n", strconv.FormatBool(*dhcp.UseHostname))
+	}
+
+	if dhcp.VendorClassIdentifier != "" {
+		ret += fmt.Sprintf("VendorClassIdentifier=%s\n", dhcp.VendorClassIdentifier)
+	}
+
+	if len(dhcp.RequestOptions) > 0 {
+		options := []string{}
+		for _, option := range dhcp.RequestOptions {
+			options = append(options, strconv.Itoa(option))
+		}
+
+		ret += fmt.Sprintf("RequestOptions=%s\n", strings.Join(options, " "))
+	}
+
+	if dhcp.UseDNS != nil {
+		ret += fmt.Sprintf("UseDNS=%s\n", strconv.FormatBool(*dhcp.UseDNS))
+	}
+
+	if dhcp.UseNTP != nil {
+		ret += fmt.Sprintf("UseNTP=%s\n", strconv.FormatBool(*dhcp.UseNTP))
+	}
+
+	if dhcp.UseRoutes != nil {
+		ret += fmt.Sprintf("UseRoutes=%s\n", strconv.FormatBool(*dhcp.UseRoutes))
+	}
+
+	return ret
+}
+
+func generateNetworkSectionContents(dns *api.SystemNetworkDNS, ntp *api.SystemNetworkNTP) string {
+	ret := ""
+
+	// If there are search domains or name servers, add those to the config.
+	if dns != nil {
+		if len(dns.SearchDomains) > 0 {
+			ret += fmt.Sprintf("Domains=%s\n", strings.Join(dns.SearchDomains, " "))
+		}
+
+		for _, ns := range dns.Nameservers {
+			// Add the server name used for certificate validation, if any.
+			serverName, ok := dns.ServerNames[ns]
+			if ok {
+				ns += "#" + serverName
+			}
+
+			ret += fmt.Sprintf("DNS=%s\n", ns)
+		}
+	}
+
+	// If there are time servers defined, add them to the config.
+	if ntp != nil {
+		for _, ts := range ntp.Timeservers {
+			ret += fmt.Sprintf("NTP=%s\n", ts)
+		}
+	}
+
+	return ret
+}
+
+func generateResolvedContents(networkCfg api.SystemNetworkConfig) string {
+	dns := api.SystemNetworkDNS{}
+	if networkCfg.DNS != nil {
+		dns = *networkCfg.DNS
+	}
+
+	ret := ""
+
+	// Behind a CLAT, IPv4-only names are resolved through DNS64.
+	if networkCfg.CLAT != nil && len(networkCfg.CLAT.DNS64Servers) > 0 {
+		ret += fmt.Sprintf("DNS=%s\n", strings.Join(networkCfg.CLAT.DNS64Servers, " "))
+	}
+
+	if dns.DNSOverTLS != "" {
+		ret += fmt.Sprintf("DNSOverTLS=%s\n", dns.DNSOverTLS)
+	}
+
+	// The AAAA records synthesized by DNS64 can't be validated, so DNSSEC is disabled behind a CLAT
+	// unless explicitly configured.
+	if dns.DNSSEC != "" {
+		ret += fmt.Sprintf("DNSSEC=%s\n", dns.DNSSEC)
+	} else if networkCfg.CLAT != nil {
+		ret += "DNSSEC=no\n"
+	}
+
+	ret += generateMulticastResolutionContents(dns.MulticastDNS, dns.LLMNR)
+
+	if ret == "" {
+		return ""
+	}
+
+	return "[Resolve]\n" + ret
+}
+
+// useChrony returns true if chrony rather than systemd-timesyncd should be used for time synchronization.
+func useChrony(networkCfg *api.SystemNetworkConfig) bool {
+	return networkCfg.NTP != nil && networkCfg.NTP.Backend == "chrony"
+}
+
+// generateChronyContents returns the chrony configuration, using NTS to authenticate the timeservers if enabled.
+func generateChronyContents(ntp api.SystemNetworkNTP) string {
+	ret := ""
+
+	for _, ts := range ntp.Timeservers {
+		if ntp.NTS {
+			ret += fmt.Sprintf("server %s iburst nts\n", ts)
+		} else {
+			ret += fmt.Sprintf("server %s iburst\n", ts)
+		}
+	}
+
+	ret += "driftfile /var/lib/chrony/chrony.drift\n"
+	ret += "ntsdumpdir /var/lib/chrony\n"
+	ret += "makestep 1 3\n"
+	ret += "rtcsync\n"
+
+	return ret
+}
+
+func generateSysctlContents(networkCfg api.SystemNetworkConfig) string {
+	devices := []string{}
+
+	for _, i := range networkCfg.Interfaces {
+		if i.DisableIPv6 {
+			devices = append(devices, i.Name)
+
+			if i.Mode != "direct" {
+				devices = append(devices, interfaceDeviceName(i))
+			}
+		}
+	}
+
+	for _, b := range networkCfg.Bonds {
+		if b.DisableIPv6 {
+			bondMacAddr := b.Hwaddr
+			if bondMacAddr == "" {
+				bondMacAddr = b.Members[0]
+			}
+
+			devices = append(devices, b.Name, "bn"+strings.ToLower(strings.ReplaceAll(bondMacAddr, ":", "")))
+		}
+	}
+
+	for _, v := range networkCfg.VLANs {
+		if v.DisableIPv6 {
+			devices = append(devices, v.Name)
+
+			if !isStackedVLANParent(networkCfg, v.Parent) {
+				devices = append(devices, "vl"+v.Name)
+			}
+		}
+	}
+
+	ret := ""
+
+	for _, device := range devices {
+		ret += fmt.Sprintf("net.ipv6.conf.%s.disable_ipv6 = 1\n", device)
+	}
+
+	// Allow local services, listening in the default VRF, to be reached through the management VRF.
+	for _, v := range networkCfg.VRFs {
+		if v.Management {
+			ret += "net.ipv4.tcp_l3mdev_accept = 1\nnet.ipv4.udp_l3mdev_accept = 1\n"
+
+			break
+		}
+	}
+
+	return ret
+}
+
+// generateNetworkdContents returns the global systemd-networkd configuration, defining any named routing tables.
+func generateNetworkdContents(networkCfg api.SystemNetworkConfig) string {
+	if len(networkCfg.RoutingTables) == 0 {
+		return ""
+	}
+
+	tables := []string{}
+	for _, name := range slices.Sorted(maps.Keys(networkCfg.RoutingTables)) {
+		tables = append(tables, fmt.Sprintf("%s:%d", name, networkCfg.RoutingTables[name]))
+	}
+
+	return "[Network]\nRouteTable=" + strings.Join(tables, " ") + "\n"
+}
+
+// validateRoutingTables checks the named routing tables and that routes and rules only reference
+// routing tables by number, by a predefined name or by a defined name.
+func validateRoutingTables(networkCfg api.SystemNetworkConfig) error {
+	predefinedTables := []string{"default", "main", "local"}
+
+	for name, table := range networkCfg.RoutingTables {
+		_, err := strconv.Atoi(name)
+		if err == nil || name == "" || slices.Contains(predefinedTables, name) || strings.ContainsAny(name, ": ") {
+			return fmt.Errorf("invalid routing table name %q", name)
+		}
+
+		// The default, main and local routing tables use 253 to 255.
+		if table <= 0 || table >= 253 && table <= 255 || int64(table) > math.MaxUint32 {
+			return fmt.Errorf("invalid number %d for routing table %q", table, name)
+		}
+	}
+
+	// Routing tables used for uplink isolation can't be reused.
+	uplinkTables := getUplinkTables(networkCfg)
+
+	for name, table := range networkCfg.RoutingTables {
+		if slices.Contains(slices.Collect(maps.Values(uplinkTables)), table) {
+			return fmt.Errorf("routing table %q conflicts with the uplink isolation routing tables", name)
+		}
+	}
+
+	for _, v := range networkCfg.VRFs {
+		if slices.Contains(slices.Collect(maps.Values(uplinkTables)), v.Table) {
+			return fmt.Errorf("routing table of vrf %q conflicts with the uplink isolation routing tables", v.Name)
+		}
+	}
+
+	routes := []api.SystemNetworkRoute{}
+	rules := []api.SystemNetworkRule{}
+
+	for _, i := range networkCfg.Interfaces {
+		routes = append(routes, i.Routes...)
+		rules = append(rules, i.Rules...)
+	}
+
+	for _, b := range networkCfg.Bonds {
+		routes = append(routes, b.Routes...)
+		rules = append(rules, b.Rules...)
+	}
+
+	for _, v := range networkCfg.VLANs {
+		routes = append(routes, v.Routes...)
+		rules = append(rules, v.Rules...)
+	}
+
+	for _, v := range networkCfg.VXLANs {
+		routes = append(routes, v.Routes...)
+	}
+
+	for _, t := range networkCfg.Tunnels {
+		routes = append(routes, t.Routes...)
+	}
+
+	tables := []string{}
+	for _, route := range routes {
+		tables = append(tables, route.Table)
+	}
+
+	for _, rule := range rules {
+		tables = append(tables, rule.Table)
+	}
+
+	for _, table := range tables {
+		if table == "" || slices.Contains(predefinedTables, table) {
+			continue
+		}
+
+		_, err := strconv.Atoi(table)
+		if err == nil {
+			continue
+		}
+
+		_, ok := networkCfg.RoutingTables[table]
+		if !ok {
+			return fmt.Errorf("unknown routing table %q", table)
+		}
+	}
+
+	return nil
+}
+
+func generateTimesyncContents(ntp api.SystemNetworkNTP) string {
+	// Don't fall back to the default timeservers when relying on DHCP.
+	if useDHCPTimeservers(&ntp) {
+		return "[Time]\nFallbackNTP=\n"
+	}
+
+	if len(ntp.Timeservers) == 0 {
+		return ""
+	}
+
+	return "[Time]\nFallbackNTP=" + strings.Join(ntp.Timeservers, " ") + "\n"
+}
+
+// isVLAN returns true if the named device is one of the configured vlans.
+func isVLAN(networkCfg api.SystemNetworkConfig, name string) bool {
+	for _, v := range networkCfg.VLANs {
+		if v.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isStackedVLANParent returns true if vlans on top of the named device are regular vlan devices, which
+// is the case for vlans and interfaces without a bridge.
+func isStackedVLANParent(networkCfg api.SystemNetworkConfig, name string) bool {
+	if isVLAN(networkCfg, name) {
+		return true
+	}
+
+	for _, i := range networkCfg.Interfaces {
+		if i.Name == name && i.Mode == "direct" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// interfaceDeviceName returns the name of the network device backing an interface. Unless configured
+// directly or keeping its kernel name, the device is named after its MAC address and the configured
+// name is used for its bridge.
+func interfaceDeviceName(i api.SystemNetworkInterface) string {
+	if i.KernelName != "" {
+		return i.KernelName
+	}
+
+	if i.Mode == "direct" {
+		return i.Name
+	}
+
+	return "en" + strings.ToLower(strings.ReplaceAll(i.Hwaddr, ":", ""))
+}
+
+// generateDescriptionContents returns the Description= line of a .link or .netdev file, if any.
+func generateDescriptionContents(description string) string {
+	if description == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("Description=%s\n", description)
+}
+
+// isValidAlternativeName returns whether a device description can also be used as an alternative name.
+func isValidAlternativeName(name string) bool {
+	if name == "" || name == "." || name == ".." || len(name) > 127 {
+		return false
+	}
+
+	return !strings.ContainsAny(name, "/:%") && !strings.ContainsFunc(name, unicode.IsSpace)
+}
+
+// interfaceMACAddress returns the MAC address an interface presents on the network, which is its
+// permanent address unless overridden.
+func interfaceMACAddress(i api.SystemNetworkInterface) string {
+	if i.OverrideHwaddr != "" {
+		return i.OverrideHwaddr
+	}
+
+	return i.Hwaddr
+}
+
+// generateOverrideMACAddressContents returns the MACAddress= line of a macvlan device if the
+// interface's MAC address is overridden.
+func generateOverrideMACAddressContents(i api.SystemNetworkInterface) string {
+	if i.OverrideHwaddr == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("MACAddress=%s\n", i.OverrideHwaddr)
+}
+
+// generateBridgeVLANProtocolContents switches the bridge to 802.1ad vlan filtering if any
+// vlan on top of it is a QinQ service vlan.
+func generateBridgeVLANProtocolContents(bridgeName string, vlans []api.SystemNetworkVLAN) string {
+	for _, vlan := range vlans {
+		if vlan.Parent == bridgeName && vlan.Protocol == "802.1ad" {
+			return "VLANProtocol=802.1ad\n"
+		}
+	}
+
+	return ""
+}
+
+// generateBridgeSectionContents returns the additional [Bridge] entries of a managed bridge.
+func generateBridgeSectionContents(bridge *api.SystemNetworkBridge) string {
+	if bridge == nil {
+		return ""
+	}
+
+	ret := ""
+
+	if bridge.STP != nil {
+		ret += fmt.Sprintf("STP=%t\n", *bridge.STP)
+	}
+
+	if bridge.ForwardDelaySec != "" {
+		ret += fmt.Sprintf("ForwardDelaySec=%s\n", bridge.ForwardDelaySec)
+	}
+
+	if bridge.HelloTimeSec != "" {
+		ret += fmt.Sprintf("HelloTimeSec=%s\n", bridge.HelloTimeSec)
+	}
+
+	if bridge.MaxAgeSec != "" {
+		ret += fmt.Sprintf("MaxAgeSec=%s\n", bridge.MaxAgeSec)
+	}
+
+	if bridge.Priority != nil {
+		ret += fmt.Sprintf("Priority=%d\n", *bridge.Priority)
+	}
+
+	if bridge.MulticastSnooping != nil {
+		ret += fmt.Sprintf("MulticastSnooping=%t\n", *bridge.MulticastSnooping)
+	}
+
+	if bridge.MulticastQuerier != nil {
+		ret += fmt.Sprintf("MulticastQuerier=%t\n", *bridge.MulticastQuerier)
+	}
+
+	if bridge.MulticastIGMPVersion != 0 {
+		ret += fmt.Sprintf("MulticastIGMPVersion=%d\n", bridge.MulticastIGMPVersion)
+	}
+
+	return ret
+}
+
+func generateBridgeVLANContents(bridgeName string, specificVLAN int, additionalVLANTags []int, vlans []api.SystemNetworkVLAN) string {
+	vlanTags := []int{}
+
+	// Add specific VLAN tag, if configured.
+	if specificVLAN != 0 {
+		vlanTags = append(vlanTags, specificVLAN)
+	}
+
+	// Add any additional VLAN tags.
+	vlanTags = append(vlanTags, additionalVLANTags...)
+
+	// Grab any relevant tags for this bridge from VLAN definitions.
+	for _, vlan := range vlans {
+		if vlan.Parent == bridgeName {
+			vlanTags = append(vlanTags, vlan.ID)
+		}
+	}
+
+	// Sort and remove any duplicate tags.
+	slices.Sort(vlanTags)
+	vlanTags = slices.Compact(vlanTags)
+
+	ret := ""
+
+	if len(vlanTags) > 0 {
+		if specificVLAN != 0 {
+			ret += "\n[BridgeVLAN]\n"
+			ret += fmt.Sprintf("PVID=%d\n", specificVLAN)
+			ret += fmt.Sprintf("EgressUntagged=%d\n", specificVLAN)
+		}
+
+		for _, tag := range vlanTags {
+			ret += "\n[BridgeVLAN]\n"
+			ret += fmt.Sprintf("VLAN=%d\n", tag)
+		}
+	}
+
+	return ret
+}
+
+// generateQoSContents returns the egress queueing discipline configuration of a device.
+func generateQoSContents(qos *api.SystemNetworkQoS) string {
+	if qos == nil {
+		return ""
+	}
+
+	switch qos.Qdisc {
+	case "fq_codel":
+		return "\n[FairQueueingControlledDelay]\nParent=root\n"
+	case "tbf":
+		return fmt.Sprintf("\n[TokenBucketFilter]\nParent=root\nRate=%s\nBurstBytes=64K\nLatencySec=50ms\n", qos.Bandwidth)
+	default:
+		ret := "\n[CAKE]\nParent=root\n"
+		if qos.Bandwidth != "" {
+			ret += fmt.Sprintf("Bandwidth=%s\n", qos.Bandwidth)
+		}
+
+		return ret
+	}
+}
+
+// validateQoS checks the queueing discipline configuration of a device.
+func validateQoS(name string, qos *api.SystemNetworkQoS) error {
+	if qos == nil {
+		return nil
+	}
+
+	switch qos.Qdisc {
+	case "", "cake":
+	case "fq_codel":
+		if qos.Bandwidth != "" {
+			return fmt.Errorf("the fq_codel queueing discipline of %q doesn't support a bandwidth limit", name)
+		}
+	case "tbf":
+		if qos.Bandwidth == "" {
+			return fmt.Errorf("the tbf queueing discipline of %q requires a bandwidth limit", name)
+		}
+	default:
+		return fmt.Errorf("unsupported queueing discipline %q for %q", qos.Qdisc, name)
+	}
+
+	return nil
+}
+
+func generateSRIOVContents(sriov api.SystemNetworkSRIOV) string {
+	ret := ""
+
+	for _, vf := range sriov.VFs {
+		ret += "\n[SR-IOV]\n"
+		ret += fmt.Sprintf("VirtualFunction=%d\n", vf.VF)
+
+		if vf.Hwaddr != "" {
+			ret += fmt.Sprintf("MACAddress=%s\n", vf.Hwaddr)
+		}
+
+		if vf.VLAN != 0 {
+			ret += fmt.Sprintf("VLANId=%d\n", vf.VLAN)
+		}
+
+		if vf.SpoofCheck != nil {
+			ret += fmt.Sprintf("MACSpoofCheck=%s\n", strconv.FormatBool(*vf.SpoofCheck))
+		}
+
+		if vf.Trust != nil {
+			ret += fmt.Sprintf("Trust=%s\n", strconv.FormatBool(*vf.Trust))
+		}
+	}
+
+	return ret
+}
+
+func generateLinkSectionContents(addresses []string, online *api.SystemNetworkOnline) string {
+	if len(addresses) == 0 || (online != nil && online.Ignore) {
+		return "RequiredForOnline=no"
+	}
+
+	required := "yes"
+	if online != nil && online.OperationalState != "" {
+		required = online.OperationalState
+	}
+
+	if online != nil && online.AddressFamily != "" {
+		return fmt.Sprintf("RequiredForOnline=%s\nRequiredFamilyForOnline=%s", required, online.AddressFamily)
+	}
+
+	expectsIPv4 := false
+	expectsIPv6 := false
+	for _, addr := range addresses {
+		switch addr {
+		case "dhcp4", "ipv4ll":
 			expectsIPv4 = true
 		case "dhcp6", "slaac":
 			expectsIPv6 = true
@@ -737,10 +3045,10 @@ func generateLinkSectionContents(addresses []string) string {
 	}
 
 	if expectsIPv4 && expectsIPv6 {
-		return "RequiredForOnline=yes\nRequiredFamilyForOnline=both"
+		return fmt.Sprintf("RequiredForOnline=%s\nRequiredFamilyForOnline=both", required)
 	} else if expectsIPv4 {
-		return "RequiredForOnline=yes\nRequiredFamilyForOnline=ipv4"
+		return fmt.Sprintf("RequiredForOnline=%s\nRequiredFamilyForOnline=ipv4", required)
 	}
 
-	return "RequiredForOnline=yes\nRequiredFamilyForOnline=ipv6"
+	return fmt.Sprintf("RequiredForOnline=%s\nRequiredFamilyForOnline=ipv6", required)
 }
@@ -0,0 +1,314 @@
+package systemd
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/vishvananda/netlink"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// CheckConnectivity verifies that the provided probe target ("host:port") can be reached over TCP.
+func CheckConnectivity(ctx context.Context, target string, timeout time.Duration) error {
+	dialer := net.Dialer{Timeout: timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// CheckProbe runs the provided connectivity probe.
+func CheckProbe(ctx context.Context, probe api.SystemNetworkProbe, timeout time.Duration) error {
+	switch probe.Type {
+	case "icmp":
+		return checkICMP(ctx, probe.Target, timeout)
+	case "tcp":
+		return CheckConnectivity(ctx, probe.Target, timeout)
+	case "https":
+		return checkHTTPS(ctx, probe.Target, timeout)
+	default:
+		return fmt.Errorf("unsupported probe type %q", probe.Type)
+	}
+}
+
+// checkHTTPS performs a GET request against the target URL. Any HTTP response is considered a success.
+func checkHTTPS(ctx context.Context, target string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// checkICMP sends an ICMP echo request to the target and waits for the matching reply.
+func checkICMP(ctx context.Context, target string, timeout time.Duration) error {
+	return sendICMPEcho(ctx, target, 16, false, timeout)
+}
+
+// CheckPathMTU verifies that packets of the provided MTU reach the target without being fragmented.
+func CheckPathMTU(ctx context.Context, target string, mtu int, timeout time.Duration) error {
+	// Account for the IP and ICMP headers.
+	size := mtu - 28
+
+	ip := net.ParseIP(target)
+	if ip != nil && ip.To4() == nil {
+		size = mtu - 48
+	}
+
+	if size < 16 {
+		return fmt.Errorf("invalid MTU %d", mtu)
+	}
+
+	return sendICMPEcho(ctx, target, size, true, timeout)
+}
+
+// sendICMPEcho sends an ICMP echo request with the given payload size to the target and waits for the
+// matching reply. If dontFragment is set, the request may not be fragmented.
+func sendICMPEcho(ctx context.Context, target string, size int, dontFragment bool, timeout time.Duration) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	if len(addrs) == 0 {
+		return fmt.Errorf("failed to resolve %q", target)
+	}
+
+	network := "ip4:icmp"
+	listenAddr := "0.0.0.0"
+	protocol := 1
+	var echoType icmp.Type = ipv4.ICMPTypeEcho
+
+	if addrs[0].IP.To4() == nil {
+		network = "ip6:ipv6-icmp"
+		listenAddr = "::"
+		protocol = 58
+		echoType = ipv6.ICMPTypeEchoRequest
+	}
+
+	conn, err := net.ListenPacket(network, listenAddr)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if dontFragment {
+		err = setDontFragment(conn, addrs[0].IP.To4() == nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	payload := make([]byte, size)
+
+	_, err = rand.Read(payload)
+	if err != nil {
+		return err
+	}
+
+	msg := icmp.Message{
+		Type: echoType,
+		Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: 1, Data: payload},
+	}
+
+	request, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.WriteTo(request, &net.IPAddr{IP: addrs[0].IP, Zone: addrs[0].Zone})
+	if err != nil {
+		return err
+	}
+
+	err = conn.SetReadDeadline(time.Now().Add(timeout))
+	if err != nil {
+		return err
+	}
+
+	reply := make([]byte, 65536)
+
+	for {
+		n, _, err := conn.ReadFrom(reply)
+		if err != nil {
+			return err
+		}
+
+		resp, err := icmp.ParseMessage(protocol, reply[:n])
+		if err != nil {
+			continue
+		}
+
+		echo, ok := resp.Body.(*icmp.Echo)
+		if !ok || resp.Type == echoType {
+			continue
+		}
+
+		if echo.ID == os.Getpid()&0xffff && string(echo.Data) == string(payload) {
+			return nil
+		}
+	}
+}
+
+// setDontFragment disables fragmentation and path MTU discovery on the socket, making oversized packets fail.
+func setDontFragment(conn net.PacketConn, isIPv6 bool) error {
+	ipConn, ok := conn.(*net.IPConn)
+	if !ok {
+		return errors.New("unexpected ICMP connection type")
+	}
+
+	rawConn, err := ipConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var sockErr error
+
+	err = rawConn.Control(func(fd uintptr) {
+		if isIPv6 {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_MTU_DISCOVER, unix.IPV6_PMTUDISC_PROBE)
+			if sockErr == nil {
+				sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_DONTFRAG, 1)
+			}
+		} else {
+			sockErr = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MTU_DISCOVER, unix.IP_PMTUDISC_PROBE)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
+
+// CheckNetworkMTU validates that jumbo frames pass toward the gateways of devices configured with a
+// non-default MTU, as well as toward the ICMP probe targets reached through such devices.
+func CheckNetworkMTU(ctx context.Context, networkCfg *api.SystemNetworkConfig) ([]api.SystemNetworkMTUCheck, error) {
+	if networkCfg == nil {
+		return nil, errors.New("no network configuration provided")
+	}
+
+	ret := []api.SystemNetworkMTUCheck{}
+
+	for _, target := range getMTUCheckTargets(*networkCfg) {
+		ip := net.ParseIP(target)
+		if ip == nil {
+			addrs, err := net.DefaultResolver.LookupIPAddr(ctx, target)
+			if err != nil || len(addrs) == 0 {
+				continue
+			}
+
+			ip = addrs[0].IP
+		}
+
+		// Get the MTU of the device used to reach the target.
+		routes, err := netlink.RouteGet(ip)
+		if err != nil || len(routes) == 0 {
+			continue
+		}
+
+		link, err := netlink.LinkByIndex(routes[0].LinkIndex)
+		if err != nil {
+			continue
+		}
+
+		mtu := link.Attrs().MTU
+		if mtu <= 1500 {
+			continue
+		}
+
+		check := api.SystemNetworkMTUCheck{
+			Target: target,
+			Device: link.Attrs().Name,
+			MTU:    mtu,
+		}
+
+		err = CheckPathMTU(ctx, ip.String(), mtu, 2*time.Second)
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			check.Success = true
+		}
+
+		ret = append(ret, check)
+	}
+
+	return ret, nil
+}
+
+// getMTUCheckTargets returns the gateways of the devices with a non-default MTU and the ICMP probe targets.
+func getMTUCheckTargets(networkCfg api.SystemNetworkConfig) []string {
+	ret := []string{}
+
+	addGateways := func(mtu int, routes []api.SystemNetworkRoute) {
+		if mtu == 0 || mtu == 1500 {
+			return
+		}
+
+		for _, route := range routes {
+			if net.ParseIP(route.Via) != nil && !slices.Contains(ret, route.Via) {
+				ret = append(ret, route.Via)
+			}
+		}
+	}
+
+	for _, i := range networkCfg.Interfaces {
+		addGateways(i.MTU, i.Routes)
+	}
+
+	for _, b := range networkCfg.Bonds {
+		addGateways(b.MTU, b.Routes)
+	}
+
+	for _, v := range networkCfg.VLANs {
+		addGateways(v.MTU, v.Routes)
+	}
+
+	for _, probe := range networkCfg.Probes {
+		if probe.Type == "icmp" && !slices.Contains(ret, probe.Target) {
+			ret = append(ret, probe.Target)
+		}
+	}
+
+	return ret
+}
+
+// validateProbes checks that all configured probes are of a supported type.
+func validateProbes(probes []api.SystemNetworkProbe) error {
+	for _, probe := range probes {
+		if probe.Target == "" {
+			return errors.New("network probes require a target")
+		}
+
+		if probe.Type != "icmp" && probe.Type != "tcp" && probe.Type != "https" {
+			return fmt.Errorf("unsupported probe type %q", probe.Type)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package systemd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// RedactNetworkSecrets returns a copy of the network configuration with its secrets redacted, for
+// returning it through the API.
+func RedactNetworkSecrets(networkCfg *api.SystemNetworkConfig) *api.SystemNetworkConfig {
+	if networkCfg == nil {
+		return nil
+	}
+
+	// Deep copy the configuration, leaving the one in use alone.
+	ret := &api.SystemNetworkConfig{}
+
+	content, err := json.Marshal(networkCfg)
+	if err != nil {
+		return ret
+	}
+
+	err = json.Unmarshal(content, ret)
+	if err != nil {
+		return &api.SystemNetworkConfig{}
+	}
+
+	forEachNetworkSecret(ret, func(_ string, secret *string) {
+		if *secret != "" {
+			*secret = api.RedactedSecret
+		}
+	})
+
+	return ret
+}
+
+// RestoreNetworkSecrets puts back the current secrets into a new network configuration, wherever
+// the secret was left empty or redacted. Secrets are matched by the name of the device they belong
+// to, and by public key for WireGuard peers.
+func RestoreNetworkSecrets(networkCfg *api.SystemNetworkConfig, currentCfg *api.SystemNetworkConfig) {
+	if networkCfg == nil || currentCfg == nil {
+		return
+	}
+
+	current := map[string]string{}
+
+	forEachNetworkSecret(currentCfg, func(key string, secret *string) {
+		current[key] = *secret
+	})
+
+	forEachNetworkSecret(networkCfg, func(key string, secret *string) {
+		if *secret == "" || *secret == api.RedactedSecret {
+			*secret = current[key]
+		}
+	})
+}
+
+// RedactNetworkFiles redacts the secrets of the network configuration from the rendered files.
+func RedactNetworkFiles(files map[string]string, networkCfg *api.SystemNetworkConfig) {
+	if networkCfg == nil {
+		return
+	}
+
+	forEachNetworkSecret(networkCfg, func(_ string, secret *string) {
+		if *secret == "" {
+			return
+		}
+
+		// Secrets may also be rendered hex encoded.
+		for name, content := range files {
+			content = strings.ReplaceAll(content, *secret, api.RedactedSecret)
+			files[name] = strings.ReplaceAll(content, hex.EncodeToString([]byte(*secret)), api.RedactedSecret)
+		}
+	})
+}
+
+// forEachNetworkSecret calls the function on every secret of the network configuration, along with
+// a key identifying it.
+func forEachNetworkSecret(networkCfg *api.SystemNetworkConfig, f func(key string, secret *string)) {
+	if networkCfg.Proxy != nil {
+		f("proxy/password", &networkCfg.Proxy.Password)
+	}
+
+	for i := range networkCfg.Interfaces {
+		auth := networkCfg.Interfaces[i].IEEE8021X
+		if auth == nil {
+			continue
+		}
+
+		prefix := "interfaces/" + networkCfg.Interfaces[i].Name + "/ieee8021x/"
+		f(prefix+"password", &auth.Password)
+		f(prefix+"client_key", &auth.ClientKey)
+		f(prefix+"client_key_password", &auth.ClientKeyPassword)
+	}
+
+	for i := range networkCfg.Tunnels {
+		wireGuard := networkCfg.Tunnels[i].WireGuard
+		if wireGuard == nil {
+			continue
+		}
+
+		prefix := "tunnels/" + networkCfg.Tunnels[i].Name + "/wireguard/"
+		f(prefix+"private_key", &wireGuard.PrivateKey)
+
+		for j := range wireGuard.Peers {
+			f(prefix+"peers/"+wireGuard.Peers[j].PublicKey+"/preshared_key", &wireGuard.Peers[j].PresharedKey)
+		}
+	}
+
+	for i := range networkCfg.PPPoE {
+		f("pppoe/"+networkCfg.PPPoE[i].Name+"/password", &networkCfg.PPPoE[i].Password)
+	}
+}
@@ -0,0 +1,66 @@
+package systemd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestNetworkSecrets(t *testing.T) {
+	t.Parallel()
+
+	current := &api.SystemNetworkConfig{
+		Proxy: &api.SystemNetworkProxy{HTTPProxy: "http://proxy:3128", Username: "user", Password: "proxy-secret"},
+		Interfaces: []api.SystemNetworkInterface{
+			{Name: "uplink", IEEE8021X: &api.SystemNetworkIEEE8021X{Identity: "host", Password: "eap-secret", ClientKey: "key-pem", ClientKeyPassword: "key-secret"}},
+		},
+		Tunnels: []api.SystemNetworkTunnel{
+			{Name: "wg0", Kind: "wireguard", WireGuard: &api.SystemNetworkTunnelWireGuard{
+				PrivateKey: "wg-private",
+				Peers:      []api.SystemNetworkTunnelWireGuardPeer{{PublicKey: "peer1", PresharedKey: "wg-psk"}},
+			}},
+		},
+		PPPoE: []api.SystemNetworkPPPoE{{Name: "ppp0", Parent: "uplink", Username: "isp", Password: "ppp-secret"}},
+	}
+
+	// Secrets are redacted on output, leaving the configuration in use alone.
+	redacted := RedactNetworkSecrets(current)
+
+	content, err := json.Marshal(redacted)
+	require.NoError(t, err)
+
+	for _, secret := range []string{"proxy-secret", "eap-secret", "key-pem", "key-secret", "wg-private", "wg-psk", "ppp-secret"} {
+		require.NotContains(t, string(content), secret)
+	}
+
+	require.Equal(t, api.RedactedSecret, redacted.Tunnels[0].WireGuard.PrivateKey)
+	require.Equal(t, "host", redacted.Interfaces[0].IEEE8021X.Identity)
+	require.Equal(t, "wg-private", current.Tunnels[0].WireGuard.PrivateKey)
+
+	// Echoed back or omitted secrets keep their current value, new ones are used.
+	redacted.PPPoE[0].Password = ""
+	redacted.Proxy.Password = "new-proxy-secret"
+	redacted.Tunnels[0].WireGuard.Peers = append(redacted.Tunnels[0].WireGuard.Peers, api.SystemNetworkTunnelWireGuardPeer{PublicKey: "peer2", PresharedKey: api.RedactedSecret})
+
+	RestoreNetworkSecrets(redacted, current)
+	require.Equal(t, "ppp-secret", redacted.PPPoE[0].Password)
+	require.Equal(t, "new-proxy-secret", redacted.Proxy.Password)
+	require.Equal(t, "eap-secret", redacted.Interfaces[0].IEEE8021X.Password)
+	require.Equal(t, "key-pem", redacted.Interfaces[0].IEEE8021X.ClientKey)
+	require.Equal(t, "wg-private", redacted.Tunnels[0].WireGuard.PrivateKey)
+	require.Equal(t, "wg-psk", redacted.Tunnels[0].WireGuard.Peers[0].PresharedKey)
+	require.Empty(t, redacted.Tunnels[0].WireGuard.Peers[1].PresharedKey)
+
+	require.Nil(t, RedactNetworkSecrets(nil))
+
+	files := map[string]string{"wg0.netdev": "[WireGuard]\nPrivateKey=wg-private\n"}
+	RedactNetworkFiles(files, current)
+	require.Equal(t, "[WireGuard]\nPrivateKey="+api.RedactedSecret+"\n", files["wg0.netdev"])
+
+	files = map[string]string{"wpa.conf": "\tpassword=\"eap-secret\"\n\tprivate_key_passwd=6b65792d736563726574\n"}
+	RedactNetworkFiles(files, current)
+	require.Equal(t, "\tpassword=\""+api.RedactedSecret+"\"\n\tprivate_key_passwd="+api.RedactedSecret+"\n", files["wpa.conf"])
+}
@@ -0,0 +1,370 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+	"github.com/vishvananda/netlink"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// UpdateNetworkState refreshes the runtime state of the configured network devices.
+func UpdateNetworkState(ctx context.Context, network *api.SystemNetwork) error {
+	network.State.Interfaces = map[string]api.SystemNetworkInterfaceState{}
+
+	if network.Config == nil {
+		return nil
+	}
+
+	for _, i := range network.Config.Interfaces {
+		// The device may be missing or not support ethtool queries, in which case no modes are reported.
+		wolModes, err := getWakeOnLANSupport(ctx, interfaceDeviceName(i))
+		if err != nil {
+			wolModes = nil
+		}
+
+		network.State.Interfaces[i.Name] = api.SystemNetworkInterfaceState{
+			WakeOnLANSupported: wolModes,
+		}
+	}
+
+	// Report any static address already in use on the network segment.
+	network.State.DuplicateAddresses = nil
+	if network.Config.DuplicateAddressDetection != "" {
+		network.State.DuplicateAddresses = getDuplicateAddresses(network.Config, true)
+	}
+
+	return nil
+}
+
+// getDeviceDescriptions returns the configured description of network devices, indexed by device name.
+// An interface's description applies to both the interface and the device created on top of it.
+func getDeviceDescriptions(networkCfg *api.SystemNetworkConfig) map[string]string {
+	ret := map[string]string{}
+
+	if networkCfg == nil {
+		return ret
+	}
+
+	for _, i := range networkCfg.Interfaces {
+		if i.Description != "" {
+			ret[i.Name] = i.Description
+			ret[interfaceDeviceName(i)] = i.Description
+		}
+	}
+
+	for _, b := range networkCfg.Bonds {
+		if b.Description != "" {
+			ret[b.Name] = b.Description
+		}
+	}
+
+	for _, v := range networkCfg.VLANs {
+		if v.Description != "" {
+			ret[v.Name] = v.Description
+		}
+	}
+
+	return ret
+}
+
+// getWakeOnLANSupport returns the Wake-on-LAN modes supported by a network device, using the same names as systemd.link.
+func getWakeOnLANSupport(ctx context.Context, name string) ([]string, error) {
+	output, err := subprocess.RunCommandContext(ctx, "ethtool", name)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseWakeOnLANSupport(output), nil
+}
+
+func parseWakeOnLANSupport(ethtoolOutput string) []string {
+	modes := map[rune]string{
+		'p': "phy",
+		'u': "unicast",
+		'm': "multicast",
+		'b': "broadcast",
+		'a': "arp",
+		'g': "magic",
+		's': "secureon",
+	}
+
+	ret := []string{}
+
+	for _, line := range strings.Split(ethtoolOutput, "\n") {
+		flags, ok := strings.CutPrefix(strings.TrimSpace(line), "Supports Wake-on:")
+		if !ok {
+			continue
+		}
+
+		for _, flag := range strings.TrimSpace(flags) {
+			mode, ok := modes[flag]
+			if ok {
+				ret = append(ret, mode)
+			}
+		}
+	}
+
+	return ret
+}
+
+// GetLLDPNeighbors returns the neighbors discovered using LLDP, indexed by local network device.
+func GetLLDPNeighbors(ctx context.Context) (map[string][]api.SystemNetworkLLDPNeighbor, error) {
+	output, err := subprocess.RunCommandContext(ctx, "networkctl", "lldp", "--json=short")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLLDPNeighbors(output)
+}
+
+func parseLLDPNeighbors(networkctlOutput string) (map[string][]api.SystemNetworkLLDPNeighbor, error) {
+	type lldpNeighbor struct {
+		ChassisID         string `json:"ChassisID"`
+		PortID            string `json:"PortID"`
+		PortDescription   string `json:"PortDescription"`
+		SystemName        string `json:"SystemName"`
+		SystemDescription string `json:"SystemDescription"`
+		VLANID            int    `json:"VLANID"`
+	}
+
+	lldp := struct {
+		Neighbors []struct {
+			InterfaceName string         `json:"InterfaceName"`
+			Neighbors     []lldpNeighbor `json:"Neighbors"`
+		} `json:"Neighbors"`
+	}{}
+
+	err := json.Unmarshal([]byte(networkctlOutput), &lldp)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string][]api.SystemNetworkLLDPNeighbor{}
+
+	for _, link := range lldp.Neighbors {
+		for _, n := range link.Neighbors {
+			ret[link.InterfaceName] = append(ret[link.InterfaceName], api.SystemNetworkLLDPNeighbor{
+				ChassisID:         n.ChassisID,
+				PortID:            n.PortID,
+				PortDescription:   n.PortDescription,
+				SystemName:        n.SystemName,
+				SystemDescription: n.SystemDescription,
+				VLAN:              n.VLANID,
+			})
+		}
+	}
+
+	return ret, nil
+}
+
+// GetNetworkDeviceState returns the operational state of all network devices, indexed by name. Devices
+// defined in the network configuration are reported along with their description.
+func GetNetworkDeviceState(ctx context.Context, networkCfg *api.SystemNetworkConfig) (map[string]api.SystemNetworkDeviceState, error) {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return nil, err
+	}
+
+	networkdLinks, err := getNetworkdLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The kernel reports infinite lifetimes as the maximum uint32 value.
+	lifetime := func(seconds int) int {
+		if seconds < 0 || uint32(seconds) == math.MaxUint32 {
+			return -1
+		}
+
+		return seconds
+	}
+
+	descriptions := getDeviceDescriptions(networkCfg)
+
+	ret := map[string]api.SystemNetworkDeviceState{}
+
+	for _, link := range links {
+		attrs := link.Attrs()
+
+		if attrs.Name == "lo" {
+			continue
+		}
+
+		state := api.SystemNetworkDeviceState{
+			Description:      descriptions[attrs.Name],
+			AlternativeNames: attrs.AltNames,
+			Hwaddr:           attrs.HardwareAddr.String(),
+			MTU:              attrs.MTU,
+			Carrier:          attrs.RawFlags&unix.IFF_LOWER_UP != 0,
+		}
+
+		// Physical properties, not all devices report them.
+		state.Duplex = readSysfsNetValue(attrs.Name, "duplex")
+
+		speed, err := strconv.Atoi(readSysfsNetValue(attrs.Name, "speed"))
+		if err == nil && speed > 0 {
+			state.Speed = speed
+		}
+
+		addrs, err := netlink.AddrList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			scope := netlink.Scope(addr.Scope).String()
+			if netlink.Scope(addr.Scope) == netlink.SCOPE_UNIVERSE {
+				scope = "global"
+			}
+
+			state.Addresses = append(state.Addresses, api.SystemNetworkAddressState{
+				Address:           addr.IPNet.String(),
+				Scope:             scope,
+				ValidLifetime:     lifetime(addr.ValidLft),
+				PreferredLifetime: lifetime(addr.PreferedLft),
+			})
+		}
+
+		routes, err := netlink.RouteList(link, netlink.FAMILY_ALL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, route := range routes {
+			to := "default"
+			if route.Dst != nil {
+				to = route.Dst.String()
+			}
+
+			via := ""
+			if route.Gw != nil {
+				via = route.Gw.String()
+			}
+
+			state.Routes = append(state.Routes, api.SystemNetworkRouteState{
+				To:       to,
+				Via:      via,
+				Metric:   route.Priority,
+				Protocol: route.Protocol.String(),
+			})
+		}
+
+		// Get the networkd view of the device, if it's managed.
+		networkdLink, ok := networkdLinks[attrs.Name]
+		if ok {
+			state.OperationalState = networkdLink.OperationalState
+			state.SetupState = networkdLink.SetupState
+			state.OnlineState = networkdLink.OnlineState
+		}
+
+		linkState, err := readNetworkdStateFile(filepath.Join("/run/systemd/netif/links", strconv.Itoa(attrs.Index)))
+		if err == nil {
+			if linkState["DNS"] != "" {
+				state.Nameservers = strings.Fields(linkState["DNS"])
+			}
+
+			lease, err := readNetworkdStateFile(filepath.Join("/run/systemd/netif/leases", strconv.Itoa(attrs.Index)))
+			if err == nil {
+				leaseLifetime, _ := strconv.Atoi(lease["LIFETIME"])
+
+				state.DHCPLease = &api.SystemNetworkDHCPLeaseState{
+					Address:  lease["ADDRESS"],
+					Server:   lease["SERVER_ADDRESS"],
+					Router:   lease["ROUTER"],
+					Lifetime: leaseLifetime,
+				}
+
+				if len(state.Nameservers) == 0 && lease["DNS"] != "" {
+					state.Nameservers = strings.Fields(lease["DNS"])
+				}
+			}
+		}
+
+		ret[attrs.Name] = state
+	}
+
+	return ret, nil
+}
+
+// networkdLink holds the state of a network device as reported by systemd-networkd.
+type networkdLink struct {
+	Index            int    `json:"Index"`
+	Name             string `json:"Name"`
+	OperationalState string `json:"OperationalState"`
+	SetupState       string `json:"SetupState"`
+	OnlineState      string `json:"OnlineState"`
+}
+
+// getNetworkdLinks returns the systemd-networkd state of all network devices, indexed by name.
+func getNetworkdLinks(ctx context.Context) (map[string]networkdLink, error) {
+	output, err := subprocess.RunCommandContext(ctx, "networkctl", "list", "--json=short")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNetworkdLinks(output)
+}
+
+func parseNetworkdLinks(networkctlOutput string) (map[string]networkdLink, error) {
+	links := struct {
+		Interfaces []networkdLink `json:"Interfaces"`
+	}{}
+
+	err := json.Unmarshal([]byte(networkctlOutput), &links)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := map[string]networkdLink{}
+	for _, link := range links.Interfaces {
+		ret[link.Name] = link
+	}
+
+	return ret, nil
+}
+
+// readSysfsNetValue returns a network device attribute from sysfs, or an empty string if it can't be read.
+func readSysfsNetValue(name string, attribute string) string {
+	content, err := os.ReadFile(filepath.Join("/sys/class/net", name, attribute))
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
+
+// readNetworkdStateFile reads one of the KEY=value state files maintained by systemd-networkd.
+func readNetworkdStateFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseNetworkdStateFile(string(content)), nil
+}
+
+func parseNetworkdStateFile(content string) map[string]string {
+	ret := map[string]string{}
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if found {
+			ret[key] = value
+		}
+	}
+
+	return ret
+}
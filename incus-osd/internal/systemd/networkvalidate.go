@@ -0,0 +1,633 @@
+package systemd
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"net"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// NetworkConfigValidationError is returned when a network configuration is inconsistent. It lists every
+// offending field so the problems can be reported back at once.
+type NetworkConfigValidationError struct {
+	Errors []api.SystemNetworkConfigError
+}
+
+// Error returns all validation problems as a single message.
+func (e *NetworkConfigValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fieldErr := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message))
+	}
+
+	return "invalid network configuration: " + strings.Join(msgs, "; ")
+}
+
+// networkConfigValidator accumulates the problems found in a network configuration.
+type networkConfigValidator struct {
+	errors []api.SystemNetworkConfigError
+}
+
+func (v *networkConfigValidator) addError(field string, format string, args ...any) {
+	v.errors = append(v.errors, api.SystemNetworkConfigError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// networkConfigDevice describes a device carrying addresses, along with the path of its definition.
+type networkConfigDevice struct {
+	name      string
+	field     string
+	addresses []string
+	dhcp      *api.SystemNetworkDHCP
+}
+
+// ValidateNetworkConfiguration checks the consistency of a network configuration across its devices: names must be
+// unique, vlan parents must exist, bond members can't be reused, static addresses need a prefix length, subnets
+// can't overlap between devices sharing a routing domain and proxy NDP addresses must be IPv6 addresses. Device
+// options, probes, routing tables, time synchronization and the CLAT are checked as well, so nothing gets applied
+// from an invalid configuration. A *NetworkConfigValidationError is returned on failure.
+func ValidateNetworkConfiguration(networkCfg api.SystemNetworkConfig) error {
+	v := &networkConfigValidator{}
+
+	devices := getNetworkConfigDevices(networkCfg)
+
+	v.validateDeviceNames(networkCfg, devices)
+	v.validateDescriptions(networkCfg, devices)
+	v.validateVLANParents(networkCfg)
+	v.validateBondMembers(networkCfg)
+
+	for _, dev := range devices {
+		v.validateAddresses(dev)
+
+		if dev.dhcp != nil && dev.dhcp.RouteMetric < 0 {
+			v.addError(dev.field+".dhcp.route_metric", "route metric can't be negative")
+		}
+	}
+
+	v.validateSubnetOverlaps(networkCfg, devices)
+
+	for idx, i := range networkCfg.Interfaces {
+		v.validateProxyNDPAddresses(fmt.Sprintf("interfaces[%d]", idx), i.ProxyNDPAddresses, i.DisableIPv6)
+	}
+
+	for idx, b := range networkCfg.Bonds {
+		v.validateProxyNDPAddresses(fmt.Sprintf("bonds[%d]", idx), b.ProxyNDPAddresses, b.DisableIPv6)
+	}
+
+	for idx, vlan := range networkCfg.VLANs {
+		v.validateProxyNDPAddresses(fmt.Sprintf("vlans[%d]", idx), vlan.ProxyNDPAddresses, vlan.DisableIPv6)
+	}
+
+	v.validateDeviceOptions(networkCfg, devices)
+	v.validateTimeSynchronization(networkCfg)
+
+	err := validateProbes(networkCfg.Probes)
+	if err != nil {
+		v.addError("probes", "%s", err)
+	}
+
+	err = validateRoutingTables(networkCfg)
+	if err != nil {
+		v.addError("routing_tables", "%s", err)
+	}
+
+	_, err = getManagementVRF(networkCfg)
+	if err != nil {
+		v.addError("vrfs", "%s", err)
+	}
+
+	err = validateCLAT(networkCfg)
+	if err != nil {
+		v.addError("clat", "%s", err)
+	}
+
+	if len(v.errors) > 0 {
+		return &NetworkConfigValidationError{Errors: v.errors}
+	}
+
+	return nil
+}
+
+// getNetworkConfigDevices returns all named devices defined in the network configuration.
+func getNetworkConfigDevices(networkCfg api.SystemNetworkConfig) []networkConfigDevice {
+	ret := []networkConfigDevice{}
+
+	for idx, i := range networkCfg.Interfaces {
+		ret = append(ret, networkConfigDevice{name: i.Name, field: fmt.Sprintf("interfaces[%d]", idx), addresses: i.Addresses, dhcp: i.DHCP})
+	}
+
+	for idx, b := range networkCfg.Bonds {
+		ret = append(ret, networkConfigDevice{name: b.Name, field: fmt.Sprintf("bonds[%d]", idx), addresses: b.Addresses, dhcp: b.DHCP})
+	}
+
+	for idx, vlan := range networkCfg.VLANs {
+		ret = append(ret, networkConfigDevice{name: vlan.Name, field: fmt.Sprintf("vlans[%d]", idx), addresses: vlan.Addresses, dhcp: vlan.DHCP})
+	}
+
+	for idx, vxlan := range networkCfg.VXLANs {
+		ret = append(ret, networkConfigDevice{name: vxlan.Name, field: fmt.Sprintf("vxlans[%d]", idx), addresses: vxlan.Addresses, dhcp: vxlan.DHCP})
+	}
+
+	for idx, t := range networkCfg.Tunnels {
+		ret = append(ret, networkConfigDevice{name: t.Name, field: fmt.Sprintf("tunnels[%d]", idx), addresses: t.Addresses, dhcp: t.DHCP})
+	}
+
+	for idx, vrf := range networkCfg.VRFs {
+		ret = append(ret, networkConfigDevice{name: vrf.Name, field: fmt.Sprintf("vrfs[%d]", idx)})
+	}
+
+	for idx, p := range networkCfg.PPPoE {
+		ret = append(ret, networkConfigDevice{name: p.Name, field: fmt.Sprintf("pppoe[%d]", idx)})
+	}
+
+	for idx, d := range networkCfg.Dummies {
+		ret = append(ret, networkConfigDevice{name: d.Name, field: fmt.Sprintf("dummies[%d]", idx), addresses: d.Addresses})
+	}
+
+	return ret
+}
+
+// validateDeviceNames checks that every device has a name and that it isn't shared with another device.
+func (v *networkConfigValidator) validateDeviceNames(networkCfg api.SystemNetworkConfig, devices []networkConfigDevice) {
+	seen := map[string]string{}
+
+	for _, dev := range devices {
+		if dev.name == "" {
+			v.addError(dev.field+".name", "name is required")
+
+			continue
+		}
+
+		other, ok := seen[dev.name]
+		if ok {
+			v.addError(dev.field+".name", "name %q is already used by %s", dev.name, other)
+
+			continue
+		}
+
+		seen[dev.name] = dev.field
+	}
+
+	// Interfaces keeping their kernel name can't clash with the names of other devices either.
+	for idx, i := range networkCfg.Interfaces {
+		if i.KernelName == "" || i.KernelName == i.Name {
+			continue
+		}
+
+		other, ok := seen[i.KernelName]
+		if ok {
+			v.addError(fmt.Sprintf("interfaces[%d].kernel_name", idx), "kernel name %q is already used by %s", i.KernelName, other)
+		}
+	}
+}
+
+// validateDescriptions checks that device descriptions fit on a single line, and that those used as
+// alternative names don't clash with other devices.
+func (v *networkConfigValidator) validateDescriptions(networkCfg api.SystemNetworkConfig, devices []networkConfigDevice) {
+	descriptions := map[string]string{}
+
+	for idx, i := range networkCfg.Interfaces {
+		descriptions[fmt.Sprintf("interfaces[%d].description", idx)] = i.Description
+	}
+
+	for idx, b := range networkCfg.Bonds {
+		descriptions[fmt.Sprintf("bonds[%d].description", idx)] = b.Description
+	}
+
+	for idx, vlan := range networkCfg.VLANs {
+		descriptions[fmt.Sprintf("vlans[%d].description", idx)] = vlan.Description
+	}
+
+	for _, field := range slices.Sorted(maps.Keys(descriptions)) {
+		if strings.ContainsFunc(descriptions[field], unicode.IsControl) {
+			v.addError(field, "description can't contain control characters")
+		}
+	}
+
+	altNames := map[string]string{}
+
+	for idx, i := range networkCfg.Interfaces {
+		if !isValidAlternativeName(i.Description) {
+			continue
+		}
+
+		field := fmt.Sprintf("interfaces[%d].description", idx)
+
+		other, ok := altNames[i.Description]
+		if !ok {
+			for _, dev := range devices {
+				if dev.name == i.Description {
+					other, ok = dev.field, true
+
+					break
+				}
+			}
+		}
+
+		if ok {
+			v.addError(field, "description %q is used as an alternative name and clashes with %s", i.Description, other)
+
+			continue
+		}
+
+		altNames[i.Description] = field
+	}
+}
+
+// validateVLANParents checks that every vlan is stacked on top of a device able to carry it.
+func (v *networkConfigValidator) validateVLANParents(networkCfg api.SystemNetworkConfig) {
+	for idx, vlan := range networkCfg.VLANs {
+		field := fmt.Sprintf("vlans[%d].parent", idx)
+
+		if vlan.Parent == "" {
+			v.addError(field, "parent is required")
+
+			continue
+		}
+
+		if vlan.Parent == vlan.Name {
+			v.addError(field, "vlan %q can't be its own parent", vlan.Name)
+
+			continue
+		}
+
+		found := isVLAN(networkCfg, vlan.Parent) || slices.ContainsFunc(networkCfg.Bonds, func(b api.SystemNetworkBond) bool {
+			return b.Name == vlan.Parent
+		})
+
+		for _, i := range networkCfg.Interfaces {
+			if i.Name != vlan.Parent {
+				continue
+			}
+
+			if i.Mode == "macvlan" || i.Mode == "ipvlan" {
+				v.addError(field, "parent %q is a %s interface which can't carry vlans", vlan.Parent, i.Mode)
+			}
+
+			found = true
+
+			break
+		}
+
+		if !found {
+			v.addError(field, "parent %q doesn't exist", vlan.Parent)
+		}
+	}
+}
+
+// validateBondMembers checks that every bond has members and that a physical interface is only used once.
+func (v *networkConfigValidator) validateBondMembers(networkCfg api.SystemNetworkConfig) {
+	seen := map[string]string{}
+
+	for idx, i := range networkCfg.Interfaces {
+		if i.Hwaddr != "" {
+			seen[strings.ToLower(i.Hwaddr)] = fmt.Sprintf("interfaces[%d]", idx)
+		}
+	}
+
+	for idx, b := range networkCfg.Bonds {
+		if len(b.Members) == 0 {
+			v.addError(fmt.Sprintf("bonds[%d].members", idx), "bond %q has no members", b.Name)
+
+			continue
+		}
+
+		for memberIdx, member := range b.Members {
+			field := fmt.Sprintf("bonds[%d].members[%d]", idx, memberIdx)
+
+			other, ok := seen[strings.ToLower(member)]
+			if ok {
+				v.addError(field, "member %q is already used by %s", member, other)
+
+				continue
+			}
+
+			seen[strings.ToLower(member)] = field
+		}
+	}
+}
+
+// validateAddresses checks that static addresses of a device are valid and include a prefix length.
+func (v *networkConfigValidator) validateAddresses(dev networkConfigDevice) {
+	for idx, addr := range dev.addresses {
+		if slices.Contains([]string{"dhcp4", "dhcp6", "slaac", "ipv4ll"}, addr) {
+			continue
+		}
+
+		field := fmt.Sprintf("%s.addresses[%d]", dev.field, idx)
+
+		_, _, err := net.ParseCIDR(addr)
+		if err == nil {
+			continue
+		}
+
+		if net.ParseIP(addr) != nil {
+			v.addError(field, "address %q is missing a prefix length", addr)
+		} else {
+			v.addError(field, "invalid address %q", addr)
+		}
+	}
+}
+
+// validateProxyNDPAddresses checks that the addresses answered for through proxy NDP are IPv6 host addresses.
+func (v *networkConfigValidator) validateProxyNDPAddresses(field string, addresses []string, disableIPv6 bool) {
+	if len(addresses) > 0 && disableIPv6 {
+		v.addError(field+".proxy_ndp_addresses", "proxy NDP requires IPv6")
+
+		return
+	}
+
+	for idx, addr := range addresses {
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.To4() != nil {
+			v.addError(fmt.Sprintf("%s.proxy_ndp_addresses[%d]", field, idx), "invalid IPv6 address %q", addr)
+		}
+	}
+}
+
+// validateSubnetOverlaps checks that static subnets of different devices don't overlap, which would leave the
+// kernel with conflicting routes. Devices in different VRFs have separate routing tables and are compared
+// independently. Host addresses (/32 and /128), such as anycast or service addresses, are allowed anywhere.
+func (v *networkConfigValidator) validateSubnetOverlaps(networkCfg api.SystemNetworkConfig, devices []networkConfigDevice) {
+	type subnet struct {
+		device  string
+		field   string
+		network *net.IPNet
+	}
+
+	getVRF := func(name string) string {
+		for _, vrf := range networkCfg.VRFs {
+			if slices.Contains(vrf.Members, name) {
+				return vrf.Name
+			}
+		}
+
+		return ""
+	}
+
+	subnets := map[string][]subnet{}
+
+	for _, dev := range devices {
+		vrf := getVRF(dev.name)
+
+		for idx, addr := range dev.addresses {
+			_, network, err := net.ParseCIDR(addr)
+			if err != nil {
+				continue
+			}
+
+			ones, bits := network.Mask.Size()
+			if ones == bits {
+				continue
+			}
+
+			field := fmt.Sprintf("%s.addresses[%d]", dev.field, idx)
+
+			for _, other := range subnets[vrf] {
+				if other.device == dev.name {
+					continue
+				}
+
+				if other.network.Contains(network.IP) || network.Contains(other.network.IP) {
+					v.addError(field, "subnet %s overlaps with %s (%s)", network.String(), other.network.String(), other.field)
+
+					break
+				}
+			}
+
+			subnets[vrf] = append(subnets[vrf], subnet{device: dev.name, field: field, network: network})
+		}
+	}
+}
+
+// validateDeviceOptions checks the per-device options: dummy addresses, overridden MAC addresses, queueing
+// disciplines, bond primary members, kernel names of direct interfaces, prefix delegation, DHCP servers and
+// PPPoE credentials.
+func (v *networkConfigValidator) validateDeviceOptions(networkCfg api.SystemNetworkConfig, devices []networkConfigDevice) {
+	// Dummy devices only carry static addresses.
+	for idx, d := range networkCfg.Dummies {
+		for addrIdx, addr := range d.Addresses {
+			if slices.Contains([]string{"dhcp4", "dhcp6", "slaac", "ipv4ll"}, addr) {
+				v.addError(fmt.Sprintf("dummies[%d].addresses[%d]", idx, addrIdx), "dummy device %q only supports static addresses, got %q", d.Name, addr)
+			}
+		}
+	}
+
+	for idx, i := range networkCfg.Interfaces {
+		field := fmt.Sprintf("interfaces[%d]", idx)
+
+		// Overridden MAC addresses must be valid unicast addresses.
+		if i.OverrideHwaddr != "" {
+			hwaddr, err := net.ParseMAC(i.OverrideHwaddr)
+
+			switch {
+			case i.Mode == "ipvlan":
+				v.addError(field+".override_hwaddr", "interface %q can't override its MAC address in ipvlan mode", i.Name)
+			case err != nil || len(hwaddr) != 6:
+				v.addError(field+".override_hwaddr", "invalid override MAC address %q", i.OverrideHwaddr)
+			case hwaddr[0]&0x01 != 0:
+				v.addError(field+".override_hwaddr", "override MAC address %q is a multicast address", i.OverrideHwaddr)
+			}
+		}
+
+		err := validateQoS(i.Name, i.QoS)
+		if err != nil {
+			v.addError(field+".qos", "%s", err)
+		}
+
+		// A direct interface is either renamed to its configured name, or keeps its kernel name.
+		if i.Mode == "direct" && i.KernelName != "" && i.KernelName != i.Name {
+			v.addError(field+".kernel_name", "interface %q in direct mode must be named after its kernel name %q", i.Name, i.KernelName)
+		}
+
+		v.validatePrefixDelegation(field, i.Name, i.DisableIPv6, i.PrefixDelegation, devices)
+		v.validateDHCPServer(field, i.Addresses, i.DHCPServer)
+	}
+
+	for idx, b := range networkCfg.Bonds {
+		field := fmt.Sprintf("bonds[%d]", idx)
+
+		err := validateQoS(b.Name, b.QoS)
+		if err != nil {
+			v.addError(field+".qos", "%s", err)
+		}
+
+		// The primary member of a bond must be one of its members.
+		isMember := slices.ContainsFunc(b.Members, func(member string) bool {
+			return strings.EqualFold(member, b.PrimaryMember)
+		})
+
+		if b.PrimaryMember != "" && !isMember {
+			v.addError(field+".primary_member", "primary member %q isn't one of the bond's members", b.PrimaryMember)
+		}
+
+		v.validatePrefixDelegation(field, b.Name, b.DisableIPv6, b.PrefixDelegation, devices)
+		v.validateDHCPServer(field, b.Addresses, b.DHCPServer)
+	}
+
+	for idx, vlan := range networkCfg.VLANs {
+		field := fmt.Sprintf("vlans[%d]", idx)
+
+		v.validatePrefixDelegation(field, vlan.Name, vlan.DisableIPv6, vlan.PrefixDelegation, devices)
+		v.validateDHCPServer(field, vlan.Addresses, vlan.DHCPServer)
+	}
+
+	// PPPoE credentials are written to the pppd configuration, one per line.
+	for idx, p := range networkCfg.PPPoE {
+		if strings.ContainsFunc(p.Username, unicode.IsControl) {
+			v.addError(fmt.Sprintf("pppoe[%d].username", idx), "username can't contain control characters")
+		}
+
+		if strings.ContainsFunc(p.Password, unicode.IsControl) {
+			v.addError(fmt.Sprintf("pppoe[%d].password", idx), "password can't contain control characters")
+		}
+	}
+
+	if !slices.Contains([]string{"", "warn", "fail"}, networkCfg.DuplicateAddressDetection) {
+		v.addError("duplicate_address_detection", "invalid duplicate address detection mode %q", networkCfg.DuplicateAddressDetection)
+	}
+}
+
+// validatePrefixDelegation checks that a device getting a subnet from a delegated prefix has IPv6 enabled, a valid
+// subnet ID ("auto" or a hexadecimal number) and an uplink which requests the prefix through DHCPv6. Without an
+// explicit uplink (or with ":auto"), networkd picks one among the devices using DHCPv6.
+func (v *networkConfigValidator) validatePrefixDelegation(field string, name string, disableIPv6 bool, pd *api.SystemNetworkPrefixDelegation, devices []networkConfigDevice) {
+	if pd == nil {
+		return
+	}
+
+	field += ".prefix_delegation"
+
+	if disableIPv6 {
+		v.addError(field, "prefix delegation requires IPv6")
+	}
+
+	if pd.SubnetID != "" && pd.SubnetID != "auto" {
+		subnetID, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(pd.SubnetID), "0x"), 16, 64)
+		if err != nil || subnetID > math.MaxInt64 {
+			v.addError(field+".subnet_id", "invalid subnet ID %q", pd.SubnetID)
+		}
+	}
+
+	usesDHCP6 := func(dev networkConfigDevice) bool {
+		return slices.Contains(dev.addresses, "dhcp6")
+	}
+
+	uplink := pd.Uplink
+
+	switch uplink {
+	case "", ":auto":
+		if !slices.ContainsFunc(devices, usesDHCP6) {
+			v.addError(field, "no device uses DHCPv6 to receive a delegated prefix")
+		}
+
+		return
+	case ":self":
+		uplink = name
+	}
+
+	idx := slices.IndexFunc(devices, func(dev networkConfigDevice) bool { return dev.name == uplink })
+	if idx == -1 {
+		v.addError(field+".uplink", "uplink %q doesn't exist", uplink)
+	} else if !usesDHCP6(devices[idx]) {
+		v.addError(field+".uplink", "uplink %q doesn't use DHCPv6", uplink)
+	}
+}
+
+// validateDHCPServer checks that a device running a DHCP server has a static IPv4 address to serve from, and
+// that the address pool fits in its subnet. The pool starts right after the subnet address by default.
+func (v *networkConfigValidator) validateDHCPServer(field string, addresses []string, dhcpServer *api.SystemNetworkDHCPServer) {
+	if dhcpServer == nil {
+		return
+	}
+
+	field += ".dhcp_server"
+
+	var subnet *net.IPNet
+
+	for _, addr := range addresses {
+		ip, network, err := net.ParseCIDR(addr)
+		if err == nil && ip.To4() != nil {
+			subnet = network
+
+			break
+		}
+	}
+
+	if subnet == nil {
+		v.addError(field, "DHCP server requires a static IPv4 address")
+
+		return
+	}
+
+	if dhcpServer.PoolOffset < 0 {
+		v.addError(field+".pool_offset", "pool offset can't be negative")
+	}
+
+	if dhcpServer.PoolSize < 0 {
+		v.addError(field+".pool_size", "pool size can't be negative")
+	}
+
+	if dhcpServer.PoolOffset < 0 || dhcpServer.PoolSize < 0 {
+		return
+	}
+
+	// Leave out the subnet and broadcast addresses.
+	ones, bits := subnet.Mask.Size()
+	hosts := 1<<(bits-ones) - 2
+
+	switch {
+	case hosts < 1:
+		v.addError(field, "subnet %s is too small for a DHCP server", subnet.String())
+	case dhcpServer.PoolOffset > hosts:
+		v.addError(field+".pool_offset", "pool offset %d is outside of subnet %s", dhcpServer.PoolOffset, subnet.String())
+	case dhcpServer.PoolSize > 0 && max(dhcpServer.PoolOffset, 1)+dhcpServer.PoolSize-1 > hosts:
+		v.addError(field+".pool_size", "pool of %d addresses from offset %d doesn't fit in subnet %s", dhcpServer.PoolSize, max(dhcpServer.PoolOffset, 1), subnet.String())
+	}
+}
+
+// validateTimeSynchronization checks that the selected time synchronization backend supports the requested
+// features and is available.
+func (v *networkConfigValidator) validateTimeSynchronization(networkCfg api.SystemNetworkConfig) {
+	if networkCfg.NTP == nil {
+		return
+	}
+
+	// Timeservers are written as a space separated list or one per line.
+	for idx, ts := range networkCfg.NTP.Timeservers {
+		if ts == "" || strings.ContainsFunc(ts, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) {
+			v.addError(fmt.Sprintf("ntp.timeservers[%d]", idx), "invalid timeserver %q", ts)
+		}
+	}
+
+	if !slices.Contains([]string{"", "timesyncd", "chrony"}, networkCfg.NTP.Backend) {
+		v.addError("ntp.backend", "invalid time synchronization backend %q", networkCfg.NTP.Backend)
+
+		return
+	}
+
+	// NTS authentication is only supported by chrony.
+	if networkCfg.NTP.NTS && !useChrony(&networkCfg) {
+		v.addError("ntp.nts", "NTS requires the chrony time synchronization backend")
+	}
+
+	if !useChrony(&networkCfg) {
+		return
+	}
+
+	// Only systemd-timesyncd can be fed the timeservers provided by DHCP, chrony has no fallback.
+	if len(networkCfg.NTP.Timeservers) == 0 {
+		v.addError("ntp.timeservers", "the chrony time synchronization backend requires timeservers")
+	}
+
+	_, err := exec.LookPath("chronyd")
+	if err != nil {
+		v.addError("ntp.backend", "the chrony time synchronization backend isn't available")
+	}
+}
@@ -0,0 +1,158 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+var (
+	networkWatchdogCancel context.CancelFunc
+	networkWatchdogMu     sync.Mutex
+)
+
+// StartNetworkWatchdog watches the uplinks once the current network configuration has been applied. If they
+// all stay offline for the configured watchdog period, the last-known-good configuration, or the provided
+// previous configuration if there's none yet, is restored and a warning is recorded in the network state.
+// Otherwise the current configuration becomes the last-known-good one. Any previously running watchdog is
+// stopped. The caller must hold the state's network lock.
+func StartNetworkWatchdog(s *state.State, previousCfg *api.SystemNetworkConfig) {
+	StopNetworkWatchdog()
+
+	networkCfg := s.System.Network.Config
+
+	if networkCfg == nil || networkCfg.WatchdogTimeout <= 0 {
+		s.System.NetworkLastKnownGood = networkCfg
+
+		return
+	}
+
+	lastKnownGood := s.System.NetworkLastKnownGood
+	if lastKnownGood == nil {
+		lastKnownGood = previousCfg
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	networkWatchdogMu.Lock()
+	networkWatchdogCancel = cancel
+	networkWatchdogMu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		err := waitForUplinks(ctx, networkCfg, time.Duration(networkCfg.WatchdogTimeout)*time.Second)
+
+		s.NetworkLock.Lock()
+		defer s.NetworkLock.Unlock()
+
+		// Don't do anything if the watchdog was stopped or the configuration changed in the meantime.
+		if ctx.Err() != nil || s.System.Network.Config != networkCfg {
+			return
+		}
+
+		if err == nil {
+			s.System.NetworkLastKnownGood = networkCfg
+			_ = s.Save(ctx)
+
+			return
+		}
+
+		if lastKnownGood == nil {
+			slog.Warn("All network uplinks are offline, but there's no known good configuration to revert to")
+
+			return
+		}
+
+		slog.Warn("All network uplinks are offline, restoring the last known good network configuration")
+
+		s.System.Network.Config = lastKnownGood
+		s.System.Network.State.Warning = "Network configuration was reverted as all uplinks stayed offline"
+
+		err = ApplyNetworkConfiguration(ctx, lastKnownGood, 30*time.Second)
+		if err != nil {
+			slog.Error("Failed to restore the last known good network configuration", "err", err)
+		}
+
+		_ = s.Save(ctx)
+	}()
+}
+
+// StopNetworkWatchdog stops the currently running network watchdog, if any, so that it won't act on a
+// configuration which is being replaced.
+func StopNetworkWatchdog() {
+	networkWatchdogMu.Lock()
+	defer networkWatchdogMu.Unlock()
+
+	if networkWatchdogCancel != nil {
+		networkWatchdogCancel()
+		networkWatchdogCancel = nil
+	}
+}
+
+// getUplinkDevices returns the devices providing connectivity beyond the local network segments, being those
+// with a default gateway or a dynamically configured address.
+func getUplinkDevices(networkCfg api.SystemNetworkConfig) []string {
+	ret := []string{}
+
+	addUplink := func(name string, addresses []string, routes []api.SystemNetworkRoute) {
+		if slices.ContainsFunc(routes, isDefaultGatewayRoute) || slices.ContainsFunc(addresses, func(addr string) bool {
+			return addr == "dhcp4" || addr == "dhcp6" || addr == "slaac"
+		}) {
+			ret = append(ret, name)
+		}
+	}
+
+	for _, i := range networkCfg.Interfaces {
+		addUplink(i.Name, i.Addresses, i.Routes)
+	}
+
+	for _, b := range networkCfg.Bonds {
+		addUplink(b.Name, b.Addresses, b.Routes)
+	}
+
+	for _, v := range networkCfg.VLANs {
+		addUplink(v.Name, v.Addresses, v.Routes)
+	}
+
+	for _, p := range networkCfg.PPPoE {
+		ret = append(ret, p.Name)
+	}
+
+	return ret
+}
+
+// waitForUplinks waits up to the provided timeout for at least one of the uplinks to become routable.
+func waitForUplinks(ctx context.Context, networkCfg *api.SystemNetworkConfig, timeout time.Duration) error {
+	uplinks := getUplinkDevices(*networkCfg)
+	if len(uplinks) == 0 {
+		return nil
+	}
+
+	endTime := time.Now().Add(timeout)
+
+	for time.Now().Before(endTime) {
+		links, err := getNetworkdLinks(ctx)
+		if err == nil {
+			for _, name := range uplinks {
+				if links[name].OperationalState == "routable" {
+					return nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	return errors.New("timed out waiting for an uplink to come online")
+}
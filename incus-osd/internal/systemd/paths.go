@@ -10,6 +10,45 @@ var (
 	// SystemdNetworkConfigPath is the location for systemd network config files.
 	SystemdNetworkConfigPath = "/run/systemd/network/"
 
+	// RootPartition is the encrypted root partition.
+	RootPartition = "/dev/disk/by-partlabel/root-x86-64"
+
+	// SwapPartition is the encrypted swap partition.
+	SwapPartition = "/dev/disk/by-partlabel/swap"
+
+	// BootEntriesPath is the location of the boot entries (unified kernel images).
+	BootEntriesPath = "/boot/EFI/Linux"
+
+	// BootReadyFile is created once the system fully started, for the boot entry to be marked as good.
+	BootReadyFile = "/run/incus-os/ready"
+
 	// SystemdTimesyncConfigFile is the configuration file for systemd-timesyncd.
 	SystemdTimesyncConfigFile = "/run/systemd/timesyncd.conf"
+
+	// ChronyConfigFile is the configuration file for chrony.
+	ChronyConfigFile = "/etc/chrony/chrony.conf"
+
+	// SysctlNetworkConfigFile is the sysctl configuration file for network devices.
+	SysctlNetworkConfigFile = "/run/sysctl.d/10-incus-os-network.conf"
+
+	// SysctlResourcesConfigFile is the sysctl configuration file for memory resources.
+	SysctlResourcesConfigFile = "/run/sysctl.d/10-incus-os-resources.conf"
+
+	// ZramGeneratorConfigFile is the configuration file for systemd-zram-generator.
+	ZramGeneratorConfigFile = "/run/systemd/zram-generator.conf"
+
+	// SystemdNetworkdConfigFile is the drop-in configuration file for systemd-networkd.
+	SystemdNetworkdConfigFile = "/run/systemd/networkd.conf.d/10-incus-os.conf"
+
+	// SystemdResolvedConfigFile is the drop-in configuration file for systemd-resolved.
+	SystemdResolvedConfigFile = "/run/systemd/resolved.conf.d/10-incus-os.conf"
+
+	// WPASupplicantConfigPath is the location for wpa_supplicant config files.
+	WPASupplicantConfigPath = "/etc/wpa_supplicant/"
+
+	// CLATConfigFile is the configuration file for clatd.
+	CLATConfigFile = "/etc/clatd.conf"
+
+	// PPPConfigPath is the location for pppd config files.
+	PPPConfigPath = "/etc/ppp/"
 )
@@ -0,0 +1,300 @@
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+var reencryptionLock sync.Mutex
+
+type cryptsetupProgress struct {
+	DeviceBytes string `json:"device_bytes"`
+	DeviceSize  string `json:"device_size"`
+}
+
+// progressWriter parses the JSON progress lines reported by cryptsetup.
+type progressWriter struct {
+	buf      bytes.Buffer
+	progress func(float64)
+}
+
+// Write processes each complete progress line.
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the partial line for the next write.
+			w.buf.Write(line)
+
+			break
+		}
+
+		progress, ok := parseReencryptionProgress(string(line))
+		if ok {
+			w.progress(progress)
+		}
+	}
+
+	return len(p), nil
+}
+
+// StartReencryption re-encrypts a volume with a new volume key, while it remains in use.
+//
+// A temporary passphrase is enrolled to authorize the operation, as cryptsetup otherwise needs to
+// unlock every key slot. All other key slots are then replaced: the TPM-sealed key and the stored
+// recovery keys are enrolled again for the new volume key, as is the Tang binding. Recovery keys
+// which were never stored (such as rotated ones) must be rotated again afterwards.
+//
+// The data is re-encrypted in the background. If interrupted, the operation is resumed on the next
+// boot by ResumeReencryption.
+func StartReencryption(ctx context.Context, s *state.State, volume string) error {
+	partition, ok := EncryptedVolumes[volume]
+	if !ok {
+		return fmt.Errorf("unknown encrypted volume %q", volume)
+	}
+
+	if !reencryptionLock.TryLock() {
+		return errors.New("a re-encryption is already running")
+	}
+
+	err := initReencryption(ctx, s, volume, partition)
+	if err != nil {
+		reencryptionLock.Unlock()
+
+		return err
+	}
+
+	go func() {
+		defer reencryptionLock.Unlock()
+
+		runReencryption(context.Background(), s, partition)
+	}()
+
+	return nil
+}
+
+// ResumeReencryption resumes an interrupted re-encryption.
+func ResumeReencryption(ctx context.Context, s *state.State) {
+	reenc := s.System.Encryption.State.Reencryption
+	if reenc == nil || !reenc.Running {
+		return
+	}
+
+	partition, ok := EncryptedVolumes[reenc.Volume]
+	if !ok {
+		return
+	}
+
+	if !reencryptionLock.TryLock() {
+		return
+	}
+
+	defer reencryptionLock.Unlock()
+
+	slog.Info("Resuming volume re-encryption", "volume", reenc.Volume)
+
+	runReencryption(ctx, s, partition)
+}
+
+// initReencryption enrolls the temporary passphrase, initializes the re-encryption and restores the
+// key slots for the new volume key.
+func initReencryption(ctx context.Context, s *state.State, volume string, partition string) error {
+	// Generate the temporary passphrase.
+	rawKey := make([]byte, 32)
+
+	_, err := rand.Read(rawKey)
+	if err != nil {
+		return err
+	}
+
+	tmpKey := hex.EncodeToString(rawKey)
+
+	tmpKeyFile, err := os.CreateTemp("", "incus-os-reencrypt")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmpKeyFile.Name())
+
+	_, err = tmpKeyFile.WriteString(tmpKey)
+	if err != nil {
+		return err
+	}
+
+	_ = tmpKeyFile.Close()
+
+	// Enroll the temporary passphrase.
+	slotsBefore, err := getVolumeKeySlots(ctx, volume)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = subprocess.RunCommandSplit(ctx, append(os.Environ(), "NEWPASSWORD="+tmpKey), nil, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--password", partition)
+	if err != nil {
+		return err
+	}
+
+	slotsAfter, err := getVolumeKeySlots(ctx, volume)
+	if err != nil {
+		return err
+	}
+
+	tmpSlot := -1
+
+	for _, entry := range slotsAfter {
+		if !slices.Contains(slotsBefore, entry) {
+			tmpSlot = entry.Slot
+		}
+	}
+
+	if tmpSlot == -1 {
+		return errors.New("failed to find the temporary key slot")
+	}
+
+	// Generate the new volume key, only keeping the temporary key slot.
+	_, err = subprocess.RunCommandContext(ctx, "cryptsetup", "reencrypt", "--init-only", "--batch-mode", "--key-slot", strconv.Itoa(tmpSlot), "--key-file", tmpKeyFile.Name(), partition)
+	if err != nil {
+		return err
+	}
+
+	recordEncryptionAudit(s, "reencrypt", volume, tmpSlot, "password")
+
+	s.System.Encryption.State.Reencryption = &api.SystemEncryptionReencryption{
+		Volume:        volume,
+		Running:       true,
+		TemporarySlot: tmpSlot,
+	}
+
+	_ = s.Save(ctx)
+
+	// Enroll the TPM again, so the system can boot unattended even if interrupted.
+	_, err = subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-key-file", tmpKeyFile.Name(), "--tpm2-device", "auto", "--tpm2-pcrs", "7", partition)
+	if err != nil {
+		return err
+	}
+
+	// Enroll the stored recovery keys again.
+	if volume == "root" {
+		for _, key := range s.System.Encryption.Config.RecoveryKeys {
+			_, _, err := subprocess.RunCommandSplit(ctx, append(os.Environ(), "NEWPASSWORD="+key), nil, "systemd-cryptenroll", "--unlock-key-file", tmpKeyFile.Name(), "--password", partition)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// runReencryption re-encrypts the data, then removes the temporary key slot.
+func runReencryption(ctx context.Context, s *state.State, partition string) {
+	reenc := s.System.Encryption.State.Reencryption
+
+	err := func() error {
+		// Unlock through the TPM-sealed key, which allows resuming after a reboot.
+		args := []string{"reencrypt", "--resume-only", "--batch-mode", "--progress-json", "--token-only", "--token-type", "systemd-tpm2"}
+
+		name, err := getActiveName(ctx, partition)
+		if err != nil {
+			return err
+		}
+
+		if name != "" {
+			args = append(args, "--active-name", name)
+		} else {
+			args = append(args, partition)
+		}
+
+		writer := &progressWriter{progress: func(progress float64) { reenc.Progress = progress }}
+
+		err = subprocess.RunCommandWithFds(ctx, nil, writer, "cryptsetup", args...)
+		if err != nil {
+			return err
+		}
+
+		// Remove the temporary key slot.
+		_, err = subprocess.RunCommandContext(ctx, "systemd-cryptenroll", "--unlock-tpm2-device", "auto", "--wipe-slot", strconv.Itoa(reenc.TemporarySlot), partition)
+		if err != nil {
+			return err
+		}
+
+		// Restore the Tang binding.
+		if reenc.Volume == "root" && s.System.Encryption.Config.Tang != nil {
+			err := ApplyTang(ctx, s, s.System.Encryption.Config.Tang)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}()
+
+	reenc.Running = false
+
+	if err != nil {
+		slog.Error("Failed to re-encrypt volume", "volume", reenc.Volume, "err", err.Error())
+		reenc.Error = err.Error()
+	} else {
+		slog.Info("Volume re-encryption completed", "volume", reenc.Volume)
+		reenc.Progress = 100
+	}
+
+	_ = s.Save(ctx)
+}
+
+// getActiveName returns the device mapper name of an unlocked volume.
+func getActiveName(ctx context.Context, partition string) (string, error) {
+	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-r", "-n", "-o", "NAME,TYPE", partition)
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "crypt" {
+			return fields[0], nil
+		}
+	}
+
+	return "", nil
+}
+
+// parseReencryptionProgress parses a cryptsetup JSON progress line into a percentage.
+func parseReencryptionProgress(line string) (float64, bool) {
+	progress := cryptsetupProgress{}
+
+	err := json.Unmarshal([]byte(line), &progress)
+	if err != nil {
+		return 0, false
+	}
+
+	done, err := strconv.ParseFloat(progress.DeviceBytes, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	size, err := strconv.ParseFloat(progress.DeviceSize, 64)
+	if err != nil || size == 0 {
+		return 0, false
+	}
+
+	return done * 100 / size, true
+}
@@ -0,0 +1,33 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReencryptionProgress(t *testing.T) {
+	t.Parallel()
+
+	progress, ok := parseReencryptionProgress(`{"device":"/dev/sda2","device_bytes":"268435456","device_size":"1073741824","speed":"104857600","eta_ms":"7680","time_ms":"2560"}`)
+	require.True(t, ok)
+	require.InDelta(t, 25.0, progress, 0.001)
+
+	_, ok = parseReencryptionProgress("Finished, time 00m10s")
+	require.False(t, ok)
+
+	_, ok = parseReencryptionProgress(`{"device_bytes":"0","device_size":"0"}`)
+	require.False(t, ok)
+}
+
+func TestProgressWriter(t *testing.T) {
+	t.Parallel()
+
+	values := []float64{}
+	writer := &progressWriter{progress: func(progress float64) { values = append(values, progress) }}
+
+	_, _ = writer.Write([]byte(`{"device_bytes":"1","device_size":"4"}` + "\n" + `{"device_bytes":"2",`))
+	_, _ = writer.Write([]byte(`"device_size":"4"}` + "\n"))
+
+	require.Equal(t, []float64{25, 50}, values)
+}
@@ -0,0 +1,150 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/zfs"
+)
+
+// ValidateResources checks that a memory resources configuration is valid.
+func ValidateResources(cfg api.SystemResourcesConfig) error {
+	if cfg.ZramSize < 0 {
+		return errors.New("zram size can't be negative")
+	}
+
+	if cfg.SwapZvolSize < 0 {
+		return errors.New("swap zvol size can't be negative")
+	}
+
+	if cfg.ZramAlgorithm != "" && !slices.Contains([]string{"lzo", "lzo-rle", "lz4", "lz4hc", "zstd", "deflate", "842"}, cfg.ZramAlgorithm) {
+		return fmt.Errorf("unsupported zram compression algorithm %q", cfg.ZramAlgorithm)
+	}
+
+	if cfg.Swappiness != nil && (*cfg.Swappiness < 0 || *cfg.Swappiness > 200) {
+		return errors.New("swappiness must be between 0 and 200")
+	}
+
+	return nil
+}
+
+// ApplyResources configures the zram device, swap zvol and swappiness.
+func ApplyResources(ctx context.Context, cfg api.SystemResourcesConfig) error {
+	err := ValidateResources(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Configure the zram device.
+	zramCfg := generateZramContents(cfg)
+	if zramCfg != "" {
+		err := os.MkdirAll(filepath.Dir(ZramGeneratorConfigFile), 0o755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(ZramGeneratorConfigFile, []byte(zramCfg), 0o644)
+		if err != nil {
+			return err
+		}
+	} else {
+		_ = os.Remove(ZramGeneratorConfigFile)
+	}
+
+	// The zram device can't be resized while in use, so always stop it first.
+	_ = StopUnit(ctx, "systemd-zram-setup@zram0.service")
+
+	err = ReloadDaemon(ctx)
+	if err != nil {
+		return err
+	}
+
+	if zramCfg != "" {
+		err := StartUnit(ctx, "systemd-zram-setup@zram0.service")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Configure the swap zvol.
+	err = zfs.ApplySwapZvol(ctx, cfg.SwapZvolSize)
+	if err != nil {
+		return err
+	}
+
+	// Configure the swappiness.
+	if cfg.Swappiness != nil {
+		err := os.MkdirAll(filepath.Dir(SysctlResourcesConfigFile), 0o755)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(SysctlResourcesConfigFile, []byte(fmt.Sprintf("vm.swappiness = %d\n", *cfg.Swappiness)), 0o644)
+		if err != nil {
+			return err
+		}
+	} else {
+		_ = os.Remove(SysctlResourcesConfigFile)
+	}
+
+	return RestartUnit(ctx, "systemd-sysctl")
+}
+
+// GetSwapDevices returns the active swap devices.
+func GetSwapDevices() ([]api.SystemResourcesSwapDevice, error) {
+	content, err := os.ReadFile("/proc/swaps")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSwaps(string(content)), nil
+}
+
+// parseSwaps parses the content of /proc/swaps, converting sizes to bytes.
+func parseSwaps(content string) []api.SystemResourcesSwapDevice {
+	ret := []api.SystemResourcesSwapDevice{}
+
+	for i, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		fields := strings.Fields(line)
+		if i == 0 || len(fields) != 5 {
+			continue
+		}
+
+		dev := api.SystemResourcesSwapDevice{
+			Device: fields[0],
+			Type:   fields[1],
+		}
+
+		dev.Size, _ = strconv.ParseInt(fields[2], 10, 64)
+		dev.Size *= 1024
+		dev.Used, _ = strconv.ParseInt(fields[3], 10, 64)
+		dev.Used *= 1024
+		dev.Priority, _ = strconv.Atoi(fields[4])
+
+		ret = append(ret, dev)
+	}
+
+	return ret
+}
+
+// generateZramContents generates the systemd-zram-generator configuration.
+func generateZramContents(cfg api.SystemResourcesConfig) string {
+	if cfg.ZramSize == 0 {
+		return ""
+	}
+
+	ret := fmt.Sprintf("[zram0]\nzram-size = %d\nswap-priority = 100\n", cfg.ZramSize)
+
+	if cfg.ZramAlgorithm != "" {
+		ret += "compression-algorithm = " + cfg.ZramAlgorithm + "\n"
+	}
+
+	return ret
+}
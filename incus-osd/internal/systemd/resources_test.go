@@ -0,0 +1,33 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestResourcesConfiguration(t *testing.T) {
+	t.Parallel()
+
+	swappiness := 10
+	cfg := api.SystemResourcesConfig{ZramSize: 4096, ZramAlgorithm: "zstd", SwapZvolSize: 8192, Swappiness: &swappiness}
+	require.NoError(t, ValidateResources(cfg))
+	require.Equal(t, "[zram0]\nzram-size = 4096\nswap-priority = 100\ncompression-algorithm = zstd\n", generateZramContents(cfg))
+	require.Empty(t, generateZramContents(api.SystemResourcesConfig{}))
+
+	swappiness = 201
+	require.Error(t, ValidateResources(cfg))
+	require.Error(t, ValidateResources(api.SystemResourcesConfig{ZramSize: -1}))
+	require.Error(t, ValidateResources(api.SystemResourcesConfig{ZramAlgorithm: "gzip"}))
+
+	swaps := parseSwaps(`Filename				Type		Size		Used		Priority
+/dev/dm-1                               partition	4194300		0		-2
+/dev/zram0                              partition	4194300		1024		100
+`)
+	require.Equal(t, []api.SystemResourcesSwapDevice{
+		{Device: "/dev/dm-1", Type: "partition", Size: 4194300 * 1024, Used: 0, Priority: -2},
+		{Device: "/dev/zram0", Type: "partition", Size: 4194300 * 1024, Used: 1024 * 1024, Priority: 100},
+	}, swaps)
+}
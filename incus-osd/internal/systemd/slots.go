@@ -0,0 +1,163 @@
+package systemd
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+// usrPartitionTypes lists the GPT partition types of the /usr partitions (x86-64 and arm64).
+var usrPartitionTypes = []string{"8484680c-9521-48c6-9c11-b0720656f69e", "b0e01050-ee5f-4390-949a-9101b17104e9"}
+
+type lsblkPartition struct {
+	KName     string `json:"kname"`
+	PKName    string `json:"pkname"`
+	PartN     int    `json:"partn"`
+	PartLabel string `json:"partlabel"`
+	PartType  string `json:"parttype"`
+}
+
+// GetUpdateSlots returns the state of the A/B OS image slots and of the boot entries.
+func GetUpdateSlots(ctx context.Context, runningRelease string) (*api.SystemUpdateSlots, error) {
+	output, err := subprocess.RunCommandContext(ctx, "lsblk", "-J", "-l", "-p", "-o", "KNAME,PKNAME,PARTN,PARTLABEL,PARTTYPE")
+	if err != nil {
+		return nil, err
+	}
+
+	devices := struct {
+		Blockdevices []lsblkPartition `json:"blockdevices"`
+	}{}
+
+	err = json.Unmarshal([]byte(output), &devices)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only consider the partitions of the system drive.
+	rootPartition, err := filepath.EvalSymlinks(RootPartition)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := filepath.Glob(filepath.Join(BootEntriesPath, "IncusOS_*.efi"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, filepath.Base(entry))
+	}
+
+	return updateSlots(devices.Blockdevices, rootPartition, names, runningRelease), nil
+}
+
+// updateSlots builds the state of the A/B OS image slots from the partitions and boot entry names.
+func updateSlots(partitions []lsblkPartition, rootPartition string, names []string, runningRelease string) *api.SystemUpdateSlots {
+	ret := &api.SystemUpdateSlots{
+		Slots:           []api.SystemUpdateSlot{},
+		BootEntries:     []api.SystemUpdateBootEntry{},
+		RollbackTargets: []string{},
+	}
+
+	// Describe the boot entries, newest first.
+	for _, name := range names {
+		ret.BootEntries = append(ret.BootEntries, bootEntryStatus(name))
+	}
+
+	slices.SortFunc(ret.BootEntries, func(a api.SystemUpdateBootEntry, b api.SystemUpdateBootEntry) int {
+		return strings.Compare(b.Version, a.Version)
+	})
+
+	// systemd-boot picks the newest entry, those with no boot attempts left being sorted last.
+	nextBoot := ""
+
+	for _, entry := range ret.BootEntries {
+		if entry.Status != "bad" {
+			nextBoot = entry.Version
+
+			break
+		}
+	}
+
+	if nextBoot == "" && len(ret.BootEntries) > 0 {
+		nextBoot = ret.BootEntries[0].Version
+	}
+
+	// Find the /usr partitions of the system drive, the first one being the "A" slot.
+	disk := ""
+
+	for _, partition := range partitions {
+		if partition.KName == rootPartition {
+			disk = partition.PKName
+		}
+	}
+
+	usrPartitions := []lsblkPartition{}
+
+	for _, partition := range partitions {
+		if partition.PKName == disk && slices.Contains(usrPartitionTypes, strings.ToLower(partition.PartType)) {
+			usrPartitions = append(usrPartitions, partition)
+		}
+	}
+
+	slices.SortFunc(usrPartitions, func(a lsblkPartition, b lsblkPartition) int { return a.PartN - b.PartN })
+
+	for i, partition := range usrPartitions {
+		slot := api.SystemUpdateSlot{
+			Name:      string(rune('A' + i)),
+			Partition: partition.KName,
+		}
+
+		if strings.HasPrefix(partition.PartLabel, "IncusOS_") {
+			slot.Version = strings.TrimPrefix(partition.PartLabel, "IncusOS_")
+			slot.Active = slot.Version == runningRelease
+			slot.NextBoot = slot.Version == nextBoot
+		}
+
+		ret.Slots = append(ret.Slots, slot)
+	}
+
+	// Older releases with both their image and a usable boot entry can be rolled back to.
+	for _, entry := range ret.BootEntries {
+		if entry.Status == "bad" || len(entry.Version) != len(runningRelease) || entry.Version >= runningRelease {
+			continue
+		}
+
+		if slices.ContainsFunc(ret.Slots, func(slot api.SystemUpdateSlot) bool { return slot.Version == entry.Version }) {
+			ret.RollbackTargets = append(ret.RollbackTargets, entry.Version)
+		}
+	}
+
+	return ret
+}
+
+// bootEntryStatus describes a boot entry from its name.
+func bootEntryStatus(name string) api.SystemUpdateBootEntry {
+	entry := api.SystemUpdateBootEntry{
+		Version: bootEntryRelease(name),
+		Status:  "good",
+	}
+
+	triesLeft, triesDone, ok := bootEntryCounters(name)
+	if !ok {
+		return entry
+	}
+
+	entry.TriesLeft = triesLeft
+	entry.TriesDone = triesDone
+
+	if triesLeft == 0 {
+		entry.Status = "bad"
+	} else {
+		entry.Status = "pending"
+	}
+
+	return entry
+}
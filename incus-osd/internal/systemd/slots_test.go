@@ -0,0 +1,50 @@
+package systemd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestBootEntryStatus(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, api.SystemUpdateBootEntry{Version: "202506011200", Status: "good"}, bootEntryStatus("IncusOS_202506011200.efi"))
+	require.Equal(t, api.SystemUpdateBootEntry{Version: "202506021200", Status: "pending", TriesLeft: 2, TriesDone: 1}, bootEntryStatus("IncusOS_202506021200+2-1.efi"))
+	require.Equal(t, api.SystemUpdateBootEntry{Version: "202506021200", Status: "bad", TriesLeft: 0, TriesDone: 3}, bootEntryStatus("IncusOS_202506021200+0-3.efi"))
+}
+
+func TestUpdateSlots(t *testing.T) {
+	t.Parallel()
+
+	partitions := []lsblkPartition{
+		{KName: "/dev/sda", PKName: ""},
+		{KName: "/dev/sda1", PKName: "/dev/sda", PartN: 1, PartLabel: "esp", PartType: "c12a7328-f81f-11d2-ba4b-00a0c93ec93b"},
+		{KName: "/dev/sda6", PKName: "/dev/sda", PartN: 6, PartLabel: "IncusOS_202506021200", PartType: "8484680c-9521-48c6-9c11-b0720656f69e"},
+		{KName: "/dev/sda4", PKName: "/dev/sda", PartN: 4, PartLabel: "IncusOS_202506011200", PartType: "8484680c-9521-48c6-9c11-b0720656f69e"},
+		{KName: "/dev/sda9", PKName: "/dev/sda", PartN: 9, PartLabel: "root-x86-64", PartType: "4f68bce3-e8cd-4db1-96e7-fbcaf984b709"},
+		{KName: "/dev/sdb1", PKName: "/dev/sdb", PartN: 1, PartLabel: "IncusOS_202505011200", PartType: "8484680c-9521-48c6-9c11-b0720656f69e"},
+	}
+
+	// An update pending its first boot.
+	slots := updateSlots(partitions, "/dev/sda9", []string{"IncusOS_202506011200.efi", "IncusOS_202506021200+3.efi"}, "202506011200")
+	require.Equal(t, []api.SystemUpdateSlot{
+		{Name: "A", Partition: "/dev/sda4", Version: "202506011200", Active: true},
+		{Name: "B", Partition: "/dev/sda6", Version: "202506021200", NextBoot: true},
+	}, slots.Slots)
+	require.Empty(t, slots.RollbackTargets)
+
+	// An update which failed to boot.
+	slots = updateSlots(partitions, "/dev/sda9", []string{"IncusOS_202506011200.efi", "IncusOS_202506021200+0-3.efi"}, "202506011200")
+	require.True(t, slots.Slots[0].NextBoot)
+	require.False(t, slots.Slots[1].NextBoot)
+	require.Equal(t, "bad", slots.BootEntries[0].Status)
+
+	// A successful update.
+	slots = updateSlots(partitions, "/dev/sda9", []string{"IncusOS_202506011200.efi", "IncusOS_202506021200.efi"}, "202506021200")
+	require.True(t, slots.Slots[1].Active)
+	require.True(t, slots.Slots[1].NextBoot)
+	require.Equal(t, []string{"202506011200"}, slots.RollbackTargets)
+}
@@ -45,6 +45,32 @@ func StopUnit(ctx context.Context, units ...string) error {
 	return nil
 }
 
+// MaskUnit stops the provided unit(s) and masks them until the next reboot, preventing them from being started.
+func MaskUnit(ctx context.Context, units ...string) error {
+	args := []string{"mask", "--runtime", "--now"}
+	args = append(args, units...)
+
+	_, err := subprocess.RunCommandContext(ctx, "systemctl", args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnmaskUnit instructs systemd to unmask the provided unit(s), masked until the next reboot.
+func UnmaskUnit(ctx context.Context, units ...string) error {
+	args := []string{"unmask", "--runtime"}
+	args = append(args, units...)
+
+	_, err := subprocess.RunCommandContext(ctx, "systemctl", args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // EnableUnit instructs systemd to enable (and optionally start) the provided unit(s).
 func EnableUnit(ctx context.Context, now bool, units ...string) error {
 	args := []string{"enable"}
@@ -72,3 +98,10 @@ func IsFailed(ctx context.Context, unit string) bool {
 
 	return result == "failed\n"
 }
+
+// IsActive returns a boolean indicating if the specified unit is active.
+func IsActive(ctx context.Context, unit string) bool {
+	_, err := subprocess.RunCommandContext(ctx, "systemctl", "is-active", "--quiet", unit)
+
+	return err == nil
+}
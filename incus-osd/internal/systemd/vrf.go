@@ -0,0 +1,79 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+var (
+	managementVRF     atomic.Pointer[string]
+	managementVRFOnce sync.Once
+)
+
+// getManagementVRF returns the name of the management VRF, if any.
+func getManagementVRF(networkCfg api.SystemNetworkConfig) (string, error) {
+	name := ""
+
+	for _, v := range networkCfg.VRFs {
+		if !v.Management {
+			continue
+		}
+
+		if name != "" {
+			return "", errors.New("only one management VRF can be defined")
+		}
+
+		name = v.Name
+	}
+
+	return name, nil
+}
+
+// setManagementVRF places all outgoing HTTP connections, used by the update client and providers,
+// into the provided VRF. An empty name reverts back to the default routing table.
+func setManagementVRF(name string) {
+	managementVRF.Store(&name)
+
+	managementVRFOnce.Do(func() {
+		transport, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			return
+		}
+
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control: func(_ string, _ string, c syscall.RawConn) error {
+				vrf := managementVRF.Load()
+				if vrf == nil || *vrf == "" {
+					return nil
+				}
+
+				var bindErr error
+
+				err := c.Control(func(fd uintptr) {
+					bindErr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, *vrf)
+				})
+				if err != nil {
+					return err
+				}
+
+				return bindErr
+			},
+		}
+
+		transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+	})
+}
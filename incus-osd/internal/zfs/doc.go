@@ -1,2 +1,2 @@
-// Package zfs is used to manage the local ZFS pool.
+// Package zfs is used to manage the ZFS pools and their maintenance.
 package zfs
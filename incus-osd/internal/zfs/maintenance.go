@@ -0,0 +1,94 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// MaintenanceCheckInterval is how often the maintenance scheduler checks whether a task is due.
+var MaintenanceCheckInterval = 15 * time.Minute
+
+// MaintenanceScheduler periodically runs the storage maintenance tasks (scrub and TRIM) according
+// to the configured schedules.
+func MaintenanceScheduler(ctx context.Context, s *state.State) {
+	for {
+		now := time.Now()
+
+		err := checkScrubs(ctx, s, now)
+		if err != nil {
+			slog.Error("Failed to check ZFS pool scrubs", "err", err.Error())
+		}
+
+		err = checkTrims(ctx, s, now)
+		if err != nil {
+			slog.Error("Failed to check storage TRIM", "err", err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(MaintenanceCheckInterval):
+		}
+	}
+}
+
+// validateSchedule checks the interval and the optional "HH:MM" window of a maintenance task.
+func validateSchedule(intervalDays int, windowStart string, windowEnd string) error {
+	if intervalDays < 0 {
+		return errors.New("interval can't be negative")
+	}
+
+	if (windowStart == "") != (windowEnd == "") {
+		return errors.New("both the start and end of the window must be provided")
+	}
+
+	for _, value := range []string{windowStart, windowEnd} {
+		if value == "" {
+			continue
+		}
+
+		_, err := time.Parse("15:04", value)
+		if err != nil {
+			return fmt.Errorf("invalid window time %q", value)
+		}
+	}
+
+	return nil
+}
+
+// maintenanceDue returns whether a maintenance task should be started, based on its interval and window.
+func maintenanceDue(intervalDays int, windowStart string, windowEnd string, lastStart time.Time, now time.Time) bool {
+	if now.Sub(lastStart) < time.Duration(intervalDays)*24*time.Hour {
+		return false
+	}
+
+	if windowStart == "" || windowEnd == "" {
+		return true
+	}
+
+	start, err := time.Parse("15:04", windowStart)
+	if err != nil {
+		return false
+	}
+
+	end, err := time.Parse("15:04", windowEnd)
+	if err != nil {
+		return false
+	}
+
+	current := now.Hour()*60 + now.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	// Handle windows spanning midnight.
+	if startMinutes <= endMinutes {
+		return current >= startMinutes && current < endMinutes
+	}
+
+	return current >= startMinutes || current < endMinutes
+}
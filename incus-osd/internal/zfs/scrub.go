@@ -0,0 +1,89 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// ValidateScrubConfig checks that a scrub schedule is valid.
+func ValidateScrubConfig(cfg api.SystemStorageScrub) error {
+	err := validateSchedule(cfg.IntervalDays, cfg.WindowStart, cfg.WindowEnd)
+	if err != nil {
+		return fmt.Errorf("invalid scrub schedule: %w", err)
+	}
+
+	return nil
+}
+
+// checkScrubs updates the state of running scrubs and starts any scrub that is due.
+func checkScrubs(ctx context.Context, s *state.State, now time.Time) error {
+	cfg := s.System.Storage.Config.Scrub
+
+	pools, err := GetPools(ctx)
+	if err != nil {
+		return err
+	}
+
+	if s.System.Storage.State.Scrubs == nil {
+		s.System.Storage.State.Scrubs = map[string]api.SystemStorageScrubState{}
+	}
+
+	changed := false
+
+	for _, pool := range pools {
+		scrub := s.System.Storage.State.Scrubs[pool.Name]
+		inProgress := strings.HasPrefix(pool.Scan, "scrub in progress")
+
+		// Record the result of a completed scrub.
+		if scrub.Running && !inProgress {
+			scrub.Running = false
+			scrub.Result = pool.Scan
+			s.System.Storage.State.Scrubs[pool.Name] = scrub
+			changed = true
+
+			slog.Info("ZFS pool scrub completed", "pool", pool.Name, "result", pool.Scan)
+		}
+
+		if !cfg.Enabled || inProgress || !scrubDue(cfg, scrub.LastStart, now) {
+			continue
+		}
+
+		slog.Info("Starting scheduled ZFS pool scrub", "pool", pool.Name)
+
+		_, err := subprocess.RunCommandContext(ctx, "zpool", "scrub", pool.Name)
+		if err != nil {
+			return err
+		}
+
+		s.System.Storage.State.Scrubs[pool.Name] = api.SystemStorageScrubState{
+			LastStart: now,
+			Running:   true,
+		}
+
+		changed = true
+	}
+
+	if changed {
+		return s.Save(ctx)
+	}
+
+	return nil
+}
+
+// scrubDue returns whether a scrub should be started, defaulting to a 30 days interval.
+func scrubDue(cfg api.SystemStorageScrub, lastStart time.Time, now time.Time) bool {
+	intervalDays := cfg.IntervalDays
+	if intervalDays == 0 {
+		intervalDays = 30
+	}
+
+	return maintenanceDue(intervalDays, cfg.WindowStart, cfg.WindowEnd, lastStart, now)
+}
@@ -0,0 +1,39 @@
+package zfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestScrubDue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 2, 30, 0, 0, time.Local)
+
+	// Default interval of 30 days.
+	require.True(t, scrubDue(api.SystemStorageScrub{Enabled: true}, time.Time{}, now))
+	require.False(t, scrubDue(api.SystemStorageScrub{Enabled: true}, now.AddDate(0, 0, -29), now))
+	require.True(t, scrubDue(api.SystemStorageScrub{Enabled: true}, now.AddDate(0, 0, -30), now))
+
+	// Custom interval.
+	require.True(t, scrubDue(api.SystemStorageScrub{Enabled: true, IntervalDays: 7}, now.AddDate(0, 0, -7), now))
+
+	// Windows, including one spanning midnight.
+	require.True(t, scrubDue(api.SystemStorageScrub{Enabled: true, WindowStart: "01:00", WindowEnd: "05:00"}, time.Time{}, now))
+	require.False(t, scrubDue(api.SystemStorageScrub{Enabled: true, WindowStart: "03:00", WindowEnd: "05:00"}, time.Time{}, now))
+	require.True(t, scrubDue(api.SystemStorageScrub{Enabled: true, WindowStart: "22:00", WindowEnd: "03:00"}, time.Time{}, now))
+	require.False(t, scrubDue(api.SystemStorageScrub{Enabled: true, WindowStart: "22:00", WindowEnd: "02:00"}, time.Time{}, now))
+}
+
+func TestValidateScrubConfig(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateScrubConfig(api.SystemStorageScrub{Enabled: true, IntervalDays: 14, WindowStart: "22:00", WindowEnd: "04:00"}))
+	require.Error(t, ValidateScrubConfig(api.SystemStorageScrub{IntervalDays: -1}))
+	require.Error(t, ValidateScrubConfig(api.SystemStorageScrub{WindowStart: "22:00"}))
+	require.Error(t, ValidateScrubConfig(api.SystemStorageScrub{WindowStart: "25:00", WindowEnd: "04:00"}))
+}
@@ -0,0 +1,104 @@
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+)
+
+// swapZvol is the name of the zvol used for swap in the "local" ZFS pool.
+var swapZvol = LocalPoolName + "/swap"
+
+// SwapZvolDevice is the block device of the swap zvol.
+var SwapZvolDevice = "/dev/zvol/" + swapZvol
+
+// ApplySwapZvol creates, resizes or removes the swap zvol, sized in MiB. As the zvol lives in the
+// "local" ZFS pool, swapped out memory is encrypted at rest.
+func ApplySwapZvol(ctx context.Context, size int) error {
+	// Get the current size of the zvol, if any.
+	currentSize := int64(0)
+
+	output, err := subprocess.RunCommandContext(ctx, "zfs", "get", "-H", "-p", "-o", "value", "volsize", swapZvol)
+	if err == nil {
+		currentSize, err = strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+		if err != nil {
+			return err
+		}
+	} else if !strings.Contains(err.Error(), "dataset does not exist") {
+		return err
+	}
+
+	wantedSize := int64(size) * 1024 * 1024
+
+	if currentSize == wantedSize {
+		// Make sure the swap is active.
+		if wantedSize > 0 {
+			return swapOn(ctx)
+		}
+
+		return nil
+	}
+
+	// Disable and remove or resize the existing zvol.
+	if currentSize > 0 {
+		err := swapOff(ctx)
+		if err != nil {
+			return err
+		}
+
+		if wantedSize == 0 {
+			_, err := subprocess.RunCommandContext(ctx, "zfs", "destroy", swapZvol)
+
+			return err
+		}
+
+		_, err = subprocess.RunCommandContext(ctx, "zfs", "set", fmt.Sprintf("volsize=%d", wantedSize), swapZvol)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Use settings recommended for swap on ZFS.
+		_, err := subprocess.RunCommandContext(ctx, "zfs", "create", "-V", fmt.Sprintf("%dM", size), "-b", strconv.Itoa(os.Getpagesize()),
+			"-o", "compression=zle", "-o", "logbias=throughput", "-o", "sync=always",
+			"-o", "primarycache=metadata", "-o", "secondarycache=none", swapZvol)
+		if err != nil {
+			return err
+		}
+
+		_, err = subprocess.RunCommandContext(ctx, "udevadm", "settle")
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "mkswap", SwapZvolDevice)
+	if err != nil {
+		return err
+	}
+
+	return swapOn(ctx)
+}
+
+// swapOn enables the swap zvol if not already active.
+func swapOn(ctx context.Context) error {
+	_, err := subprocess.RunCommandContext(ctx, "swapon", SwapZvolDevice)
+	if err != nil && !strings.Contains(err.Error(), "Device or resource busy") {
+		return err
+	}
+
+	return nil
+}
+
+// swapOff disables the swap zvol if active.
+func swapOff(ctx context.Context) error {
+	_, err := subprocess.RunCommandContext(ctx, "swapoff", SwapZvolDevice)
+	if err != nil && !strings.Contains(err.Error(), "Invalid argument") {
+		return fmt.Errorf("failed to disable the swap zvol: %w", err)
+	}
+
+	return nil
+}
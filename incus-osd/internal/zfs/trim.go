@@ -0,0 +1,130 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+	"github.com/lxc/incus-os/incus-osd/internal/state"
+)
+
+// trimFilesystems is the state key used for the TRIM of the non-ZFS filesystems.
+const trimFilesystems = "filesystems"
+
+var trimRateRegex = regexp.MustCompile(`^[0-9]+[KMGT]?$`)
+
+// ValidateTrimConfig checks that a TRIM schedule is valid.
+func ValidateTrimConfig(cfg api.SystemStorageTrim) error {
+	err := validateSchedule(cfg.IntervalDays, cfg.WindowStart, cfg.WindowEnd)
+	if err != nil {
+		return fmt.Errorf("invalid TRIM schedule: %w", err)
+	}
+
+	if cfg.Rate != "" && !trimRateRegex.MatchString(cfg.Rate) {
+		return fmt.Errorf("invalid TRIM rate %q", cfg.Rate)
+	}
+
+	return nil
+}
+
+// checkTrims starts a TRIM of the ZFS pools and other filesystems when due.
+func checkTrims(ctx context.Context, s *state.State, now time.Time) error {
+	cfg := s.System.Storage.Config.Trim
+	if !cfg.Enabled {
+		return nil
+	}
+
+	pools, err := GetPools(ctx)
+	if err != nil {
+		return err
+	}
+
+	if s.System.Storage.State.Trims == nil {
+		s.System.Storage.State.Trims = map[string]api.SystemStorageTrimState{}
+	}
+
+	changed := false
+
+	for _, pool := range pools {
+		if !trimDue(cfg, s.System.Storage.State.Trims[pool.Name].LastStart, now) {
+			continue
+		}
+
+		slog.Info("Starting scheduled ZFS pool TRIM", "pool", pool.Name)
+
+		args := []string{"trim"}
+		if cfg.Rate != "" {
+			args = append(args, "-r", cfg.Rate)
+		}
+
+		args = append(args, pool.Name)
+
+		// Record failures, such as pools without any TRIM capable device, so they aren't retried until the next interval.
+		result := "started"
+
+		_, err := subprocess.RunCommandContext(ctx, "zpool", args...)
+		if err != nil {
+			result = err.Error()
+
+			if !strings.Contains(err.Error(), "currently trimming") {
+				slog.Warn("Failed to TRIM ZFS pool", "pool", pool.Name, "err", err.Error())
+			}
+		}
+
+		s.System.Storage.State.Trims[pool.Name] = api.SystemStorageTrimState{
+			LastStart: now,
+			Result:    result,
+		}
+
+		changed = true
+	}
+
+	// Trim the remaining filesystems, such as the EFI system partition.
+	if trimDue(cfg, s.System.Storage.State.Trims[trimFilesystems].LastStart, now) {
+		slog.Info("Starting scheduled filesystems TRIM")
+
+		output, err := subprocess.RunCommandContext(ctx, "fstrim", "--all", "--verbose", "--quiet-unsupported")
+		result := strings.TrimSpace(output)
+
+		if err != nil {
+			slog.Warn("Failed to TRIM filesystems", "err", err.Error())
+
+			var runErr subprocess.RunError
+			if errors.As(err, &runErr) {
+				result = strings.TrimSpace(runErr.StdErr().String())
+			} else {
+				result = err.Error()
+			}
+		}
+
+		s.System.Storage.State.Trims[trimFilesystems] = api.SystemStorageTrimState{
+			LastStart: now,
+			Result:    result,
+		}
+
+		changed = true
+	}
+
+	if changed {
+		return s.Save(ctx)
+	}
+
+	return nil
+}
+
+// trimDue returns whether a TRIM should be started, defaulting to a 7 days interval.
+func trimDue(cfg api.SystemStorageTrim, lastStart time.Time, now time.Time) bool {
+	intervalDays := cfg.IntervalDays
+	if intervalDays == 0 {
+		intervalDays = 7
+	}
+
+	return maintenanceDue(intervalDays, cfg.WindowStart, cfg.WindowEnd, lastStart, now)
+}
@@ -0,0 +1,33 @@
+package zfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/incus-os/incus-osd/api"
+)
+
+func TestTrimDue(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 2, 30, 0, 0, time.Local)
+
+	// Default interval of 7 days.
+	require.True(t, trimDue(api.SystemStorageTrim{Enabled: true}, time.Time{}, now))
+	require.False(t, trimDue(api.SystemStorageTrim{Enabled: true}, now.AddDate(0, 0, -6), now))
+	require.True(t, trimDue(api.SystemStorageTrim{Enabled: true}, now.AddDate(0, 0, -7), now))
+
+	// Window.
+	require.False(t, trimDue(api.SystemStorageTrim{Enabled: true, WindowStart: "03:00", WindowEnd: "05:00"}, time.Time{}, now))
+}
+
+func TestValidateTrimConfig(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, ValidateTrimConfig(api.SystemStorageTrim{Enabled: true, IntervalDays: 1, Rate: "100M"}))
+	require.NoError(t, ValidateTrimConfig(api.SystemStorageTrim{Rate: "1048576"}))
+	require.Error(t, ValidateTrimConfig(api.SystemStorageTrim{Rate: "fast"}))
+	require.Error(t, ValidateTrimConfig(api.SystemStorageTrim{WindowEnd: "04:00"}))
+}
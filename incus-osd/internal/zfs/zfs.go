@@ -2,19 +2,36 @@ package zfs
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/lxc/incus/v6/shared/subprocess"
+
+	"github.com/lxc/incus-os/incus-osd/api"
 )
 
-var zfsLocalKeyfile = "/var/lib/incus-os/zpool.local.key"
+// LocalPoolName is the name of the ZFS pool created on the install disk.
+const LocalPoolName = "local"
+
+var zfsKeyPath = "/var/lib/incus-os"
+
+var zfsLocalKeyfile = poolKeyfile(LocalPoolName)
+
+var poolNameRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_.-]*$`)
 
 // ImportOrCreateLocalPool imports and loads the encryption key for the "local" ZFS pool if the it
 // exists, otherwise will create an encrypted ZFS pool "local" in the partition labeled "local-data".
-func ImportOrCreateLocalPool(ctx context.Context) error {
+// If a topology is provided, the pool is created with that topology across the partition and the
+// additional devices.
+func ImportOrCreateLocalPool(ctx context.Context, topology string, devices []string) error {
 	// Check if the "local" ZFS pool exists.
 	_, err := subprocess.RunCommandContext(ctx, "zpool", "import", "local")
 	if err == nil || strings.Contains(err.Error(), "cannot import 'local': a pool with that name already exists") {
@@ -29,34 +46,493 @@ func ImportOrCreateLocalPool(ctx context.Context) error {
 		// Need to create the "local" ZFS pool.
 
 		// Create a random encryption key file.
-		{
-			devUrandom, err := os.OpenFile("/dev/urandom", os.O_RDONLY, 0o0600)
-			if err != nil {
-				return err
+		err := createKeyfile(zfsLocalKeyfile)
+		if err != nil {
+			return err
+		}
+
+		// Create the ZFS pool.
+		args := []string{"create", "-o", "ashift=12", "-O", "mountpoint=none", "-O", "encryption=aes-256-gcm", "-O", "keyformat=raw", "-O", "keylocation=file://" + zfsLocalKeyfile}
+
+		if topology != "" {
+			// The additional devices are usually larger than the partition, so force the creation.
+			args = append(args, "-f", "local", topology, "/dev/disk/by-partlabel/local-data")
+			args = append(args, devices...)
+		} else {
+			args = append(args, "local", "/dev/disk/by-partlabel/local-data")
+		}
+
+		_, err = subprocess.RunCommandContext(ctx, "zpool", args...)
+
+		return err
+	}
+
+	return err
+}
+
+// ExpandLocalPool expands the "local" ZFS pool to the full size of its "local-data" partition, if it
+// was grown. Returns whether the pool was expanded.
+func ExpandLocalPool(ctx context.Context) (bool, error) {
+	output, err := subprocess.RunCommandContext(ctx, "zpool", "list", "-H", "-p", "-o", "expandsize", LocalPoolName)
+	if err != nil {
+		return false, err
+	}
+
+	// The expandable size is reported as "-" when there is none.
+	expandSize, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil || expandSize == 0 {
+		return false, nil //nolint:nilerr
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "zpool", "online", "-e", LocalPoolName, "/dev/disk/by-partlabel/local-data")
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ImportPools imports and loads the encryption key of any additional ZFS pool previously created or
+// imported, as tracked by their encryption key files.
+func ImportPools(ctx context.Context) error {
+	keyfiles, err := filepath.Glob(filepath.Join(zfsKeyPath, "zpool.*.key"))
+	if err != nil {
+		return err
+	}
+
+	for _, keyfile := range keyfiles {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(keyfile), "zpool."), ".key")
+		if name == LocalPoolName {
+			continue
+		}
+
+		err := importPool(ctx, name, keyfile)
+		if err != nil {
+			return fmt.Errorf("failed to import ZFS pool %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// GetPools returns the ZFS pools currently imported.
+func GetPools(ctx context.Context) ([]api.SystemStoragePool, error) {
+	output, err := subprocess.RunCommandContext(ctx, "zpool", "list", "-H", "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []api.SystemStoragePool{}
+
+	for _, name := range strings.Fields(output) {
+		pool, err := GetPool(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, *pool)
+	}
+
+	return ret, nil
+}
+
+// GetPool returns the layout, health and usage of an imported ZFS pool.
+func GetPool(ctx context.Context, name string) (*api.SystemStoragePool, error) {
+	output, err := subprocess.RunCommandContext(ctx, "zpool", "status", "-P", "-L", name)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := parsePoolStatus(output)
+	pool.Name = name
+
+	// Get the pool usage.
+	output, err = subprocess.RunCommandContext(ctx, "zpool", "list", "-H", "-p", "-o", "size,allocated,free,capacity,fragmentation", name)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(output)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("unexpected zpool list output %q", output)
+	}
+
+	// Values not reported by ZFS, such as fragmentation, are shown as "-" and left as zero.
+	pool.Size, _ = strconv.ParseInt(fields[0], 10, 64)
+	pool.Allocated, _ = strconv.ParseInt(fields[1], 10, 64)
+	pool.Free, _ = strconv.ParseInt(fields[2], 10, 64)
+	pool.Capacity, _ = strconv.Atoi(fields[3])
+	pool.Fragmentation, _ = strconv.Atoi(fields[4])
+
+	// Get the datasets usage.
+	output, err = subprocess.RunCommandContext(ctx, "zfs", "list", "-H", "-p", "-r", "-t", "filesystem,volume", "-o", "name,used,available,referenced", name)
+	if err != nil {
+		return nil, err
+	}
+
+	pool.Datasets = parseDatasets(output)
+
+	return &pool, nil
+}
+
+// GetPoolEncryptionKey returns the base64 encoded encryption key of a ZFS pool.
+func GetPoolEncryptionKey(name string) (string, error) {
+	key, err := os.ReadFile(poolKeyfile(name))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// CreatePool creates a new encrypted ZFS pool on the provided devices, using either a "mirror",
+// "raidz1", "raidz2" or "raidz3" topology, or striping the data across all devices if none is provided.
+func CreatePool(ctx context.Context, name string, topology string, devices []string) error {
+	err := validatePoolName(name)
+	if err != nil {
+		return err
+	}
+
+	if len(devices) == 0 {
+		return errors.New("at least one device is required")
+	}
+
+	// Minimum number of devices required by each topology.
+	minDevices := map[string]int{
+		"":       1,
+		"mirror": 2,
+		"raidz1": 3,
+		"raidz2": 4,
+		"raidz3": 5,
+	}
+
+	numDevices, ok := minDevices[topology]
+	if !ok {
+		return fmt.Errorf("unsupported pool topology %q", topology)
+	}
+
+	if len(devices) < numDevices {
+		return fmt.Errorf("pool topology %q requires at least %d devices", topology, numDevices)
+	}
+
+	// Never touch the key of an existing pool.
+	err = checkPoolAbsent(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	// Create the key aside, only moving it into place once the pool exists.
+	keyfile := poolKeyfile(name)
+	tmpKeyfile := keyfile + ".tmp"
+
+	err = createKeyfile(tmpKeyfile)
+	if err != nil {
+		_ = os.Remove(tmpKeyfile)
+
+		return err
+	}
+
+	args := []string{"create", "-o", "ashift=12", "-O", "mountpoint=none", "-O", "encryption=aes-256-gcm", "-O", "keyformat=raw", "-O", "keylocation=file://" + tmpKeyfile, name}
+	if topology != "" {
+		args = append(args, topology)
+	}
+
+	args = append(args, devices...)
+
+	_, err = subprocess.RunCommandContext(ctx, "zpool", args...)
+	if err != nil {
+		_ = os.Remove(tmpKeyfile)
+
+		return err
+	}
+
+	err = os.Rename(tmpKeyfile, keyfile)
+	if err != nil {
+		return err
+	}
+
+	// Record where the key is stored on this system.
+	_, err = subprocess.RunCommandContext(ctx, "zfs", "set", "keylocation=file://"+keyfile, name)
+
+	return err
+}
+
+// ImportPool imports an existing encrypted ZFS pool. The base64 encoded encryption key is required
+// unless the pool was previously created or imported on this system.
+func ImportPool(ctx context.Context, name string, encryptionKey string) error {
+	err := validatePoolName(name)
+	if err != nil {
+		return err
+	}
+
+	keyfile := poolKeyfile(name)
+
+	if encryptionKey != "" {
+		key, err := base64.StdEncoding.DecodeString(encryptionKey)
+		if err != nil {
+			return fmt.Errorf("invalid encryption key: %w", err)
+		}
+
+		if len(key) != 32 {
+			return errors.New("encryption key must be 32 bytes long")
+		}
+
+		// Never touch the key of an existing pool.
+		err = checkPoolAbsent(ctx, name)
+		if err != nil {
+			return err
+		}
+
+		// Write the key aside, only moving it into place once the pool is imported.
+		tmpKeyfile := keyfile + ".tmp"
+
+		err = os.WriteFile(tmpKeyfile, key, 0o600)
+		if err != nil {
+			return err
+		}
+
+		err = importPool(ctx, name, tmpKeyfile)
+		if err != nil {
+			_ = os.Remove(tmpKeyfile)
+
+			return err
+		}
+
+		err = os.Rename(tmpKeyfile, keyfile)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err := os.Stat(keyfile)
+		if err != nil {
+			return fmt.Errorf("no encryption key available for ZFS pool %q", name)
+		}
+
+		err = importPool(ctx, name, keyfile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record where the key is stored on this system.
+	_, err = subprocess.RunCommandContext(ctx, "zfs", "set", "keylocation=file://"+keyfile, name)
+
+	return err
+}
+
+// DestroyPool destroys a ZFS pool and its encryption key. The "local" ZFS pool can't be destroyed.
+func DestroyPool(ctx context.Context, name string) error {
+	err := validatePoolName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "zpool", "destroy", name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(poolKeyfile(name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// importPool imports a ZFS pool if not already imported, then loads its encryption key from the
+// provided file.
+func importPool(ctx context.Context, name string, keyfile string) error {
+	_, err := subprocess.RunCommandContext(ctx, "zpool", "import", name)
+	if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("cannot import '%s': a pool with that name already exists", name)) {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "zfs", "load-key", "-L", "file://"+keyfile, name)
+	if err != nil && !strings.Contains(err.Error(), fmt.Sprintf("Key already loaded for '%s'.", name)) {
+		return err
+	}
+
+	return nil
+}
+
+// validatePoolName checks that a name can be used for an additional ZFS pool.
+func validatePoolName(name string) error {
+	if name == LocalPoolName {
+		return fmt.Errorf("the %q ZFS pool is managed by the system", LocalPoolName)
+	}
+
+	if !poolNameRegex.MatchString(name) || slices.Contains([]string{"mirror", "raidz", "draid", "spare", "log"}, name) {
+		return fmt.Errorf("invalid ZFS pool name %q", name)
+	}
+
+	return nil
+}
+
+// checkPoolAbsent checks that neither a ZFS pool with the name is imported nor its key file exists.
+func checkPoolAbsent(ctx context.Context, name string) error {
+	_, err := os.Stat(poolKeyfile(name))
+	if err == nil {
+		return fmt.Errorf("an encryption key already exists for ZFS pool %q", name)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	_, err = subprocess.RunCommandContext(ctx, "zpool", "list", "-H", "-o", "name", name)
+	if err == nil {
+		return fmt.Errorf("ZFS pool %q already exists", name)
+	}
+
+	return nil
+}
+
+// poolKeyfile returns the path of the encryption key file of a ZFS pool.
+func poolKeyfile(name string) string {
+	return filepath.Join(zfsKeyPath, fmt.Sprintf("zpool.%s.key", name))
+}
+
+// createKeyfile creates a file holding a random 32 bytes encryption key.
+func createKeyfile(path string) error {
+	devUrandom, err := os.OpenFile("/dev/urandom", os.O_RDONLY, 0o0600)
+	if err != nil {
+		return err
+	}
+	defer devUrandom.Close()
+
+	keyfile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o0600)
+	if err != nil {
+		return err
+	}
+	defer keyfile.Close()
+
+	count, err := io.CopyN(keyfile, devUrandom, 32)
+	if err != nil {
+		return err
+	}
+
+	if count != 32 {
+		return errors.New("failed to read 32 bytes from /dev/urandom")
+	}
+
+	return nil
+}
+
+// parseDatasets extracts the datasets usage from the "zfs list -H -p" output.
+func parseDatasets(zfsOutput string) []api.SystemStoragePoolDataset {
+	ret := []api.SystemStoragePoolDataset{}
+
+	for _, line := range strings.Split(strings.TrimSpace(zfsOutput), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+
+		dataset := api.SystemStoragePoolDataset{
+			Name: fields[0],
+		}
+
+		dataset.Used, _ = strconv.ParseInt(fields[1], 10, 64)
+		dataset.Available, _ = strconv.ParseInt(fields[2], 10, 64)
+		dataset.Referenced, _ = strconv.ParseInt(fields[3], 10, 64)
+
+		ret = append(ret, dataset)
+	}
+
+	return ret
+}
+
+// parsePoolStatus extracts the health, topology and devices of a pool from the "zpool status -P -L" output.
+func parsePoolStatus(zpoolOutput string) api.SystemStoragePool {
+	ret := api.SystemStoragePool{}
+
+	section := ""
+	inConfig := false
+	seenPool := false
+	header := map[string][]string{}
+
+	for _, line := range strings.Split(zpoolOutput, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if !inConfig {
+			key, value, found := strings.Cut(trimmed, ":")
+			if found && !strings.HasPrefix(line, "\t") && !strings.Contains(key, " ") {
+				// New "key: value" section.
+				section = key
+				inConfig = section == "config"
+
+				if strings.TrimSpace(value) != "" {
+					header[section] = append(header[section], strings.TrimSpace(value))
+				}
+			} else if trimmed != "" && section != "" && section != "config" {
+				// Continuation of a multi-line section.
+				header[section] = append(header[section], trimmed)
 			}
-			defer devUrandom.Close()
 
-			keyfile, err := os.OpenFile(zfsLocalKeyfile, os.O_CREATE|os.O_WRONLY, 0o0600)
-			if err != nil {
-				return err
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+
+		// Skip the column headers.
+		if len(fields) == 0 || (fields[0] == "NAME" && !seenPool) {
+			if seenPool {
+				inConfig = false
+				section = ""
 			}
-			defer keyfile.Close()
 
-			count, err := io.CopyN(keyfile, devUrandom, 32)
-			if err != nil {
-				return err
+			continue
+		}
+
+		// Skip auxiliary vdev sections.
+		if slices.Contains([]string{"logs", "cache", "spares", "special", "dedup"}, fields[0]) {
+			section = "aux"
+
+			continue
+		}
+
+		if section == "aux" {
+			continue
+		}
+
+		// The first entry is the pool itself.
+		if !seenPool {
+			seenPool = true
+
+			if len(fields) > 1 {
+				ret.State = fields[1]
 			}
 
-			if count != 32 {
-				return errors.New("failed to read 32 bytes from /dev/urandom")
+			continue
+		}
+
+		if strings.HasPrefix(fields[0], "/") {
+			ret.Devices = append(ret.Devices, fields[0])
+
+			if len(fields) > 1 {
+				if ret.DeviceStates == nil {
+					ret.DeviceStates = map[string]string{}
+				}
+
+				ret.DeviceStates[fields[0]] = fields[1]
 			}
+
+			continue
 		}
 
-		// Create the ZFS pool.
-		_, err = subprocess.RunCommandContext(ctx, "zpool", "create", "-o", "ashift=12", "-O", "mountpoint=none", "-O", "encryption=aes-256-gcm", "-O", "keyformat=raw", "-O", "keylocation=file://"+zfsLocalKeyfile, "local", "/dev/disk/by-partlabel/local-data")
+		// Vdev names are suffixed with their index, such as "mirror-0" or "raidz2-1".
+		topology, _, _ := strings.Cut(fields[0], "-")
+		if ret.Topology == "" {
+			ret.Topology = topology
+		}
+	}
 
-		return err
+	if ret.Topology == "" && len(ret.Devices) > 0 {
+		ret.Topology = "stripe"
 	}
 
-	return err
+	ret.Status = strings.Join(header["status"], " ")
+	ret.Scan = strings.Join(header["scan"], ", ")
+	ret.Errors = strings.Join(header["errors"], " ")
+
+	return ret
 }
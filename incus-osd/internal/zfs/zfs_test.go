@@ -0,0 +1,88 @@
+package zfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var zpoolStatusMirror = `  pool: data
+ state: ONLINE
+config:
+
+	NAME                                     STATE     READ WRITE CKSUM
+	data                                     ONLINE       0     0     0
+	  mirror-0                               ONLINE       0     0     0
+	    /dev/disk/by-id/nvme-disk1           ONLINE       0     0     0
+	    /dev/disk/by-id/nvme-disk2           ONLINE       0     0     0
+	cache
+	  /dev/disk/by-id/nvme-disk3             ONLINE       0     0     0
+
+errors: No known data errors
+`
+
+var zpoolStatusStripe = `  pool: data
+ state: DEGRADED
+status: One or more devices are faulted in response to persistent errors.
+	Sufficient replicas exist for the pool to continue functioning in a
+	degraded state.
+action: Replace the faulted device, or use 'zpool clear' to mark the device
+	repaired.
+  scan: resilver in progress since Sun Jul  2 10:00:00 2023
+	1.23G scanned at 100M/s, 500M issued at 50M/s, 10G total
+	480M resilvered, 5.00% done, 00:03:00 to go
+config:
+
+	NAME                       STATE     READ WRITE CKSUM
+	data                       DEGRADED     0     0     0
+	  /dev/disk/by-id/disk1    ONLINE       0     0     0
+	  /dev/disk/by-id/disk2    FAULTED      0     0     0
+
+errors: 2 data errors, use '-v' for a list
+`
+
+var zfsListOutput = `data	1048576	2097152	98304
+data/incus	950272	2097152	950272
+`
+
+func TestParsePoolStatus(t *testing.T) {
+	t.Parallel()
+
+	pool := parsePoolStatus(zpoolStatusMirror)
+	require.Equal(t, "ONLINE", pool.State)
+	require.Equal(t, "mirror", pool.Topology)
+	require.Equal(t, []string{"/dev/disk/by-id/nvme-disk1", "/dev/disk/by-id/nvme-disk2"}, pool.Devices)
+	require.Empty(t, pool.Status)
+	require.Empty(t, pool.Scan)
+	require.Equal(t, "No known data errors", pool.Errors)
+
+	pool = parsePoolStatus(zpoolStatusStripe)
+	require.Equal(t, "DEGRADED", pool.State)
+	require.Equal(t, "stripe", pool.Topology)
+	require.Equal(t, []string{"/dev/disk/by-id/disk1", "/dev/disk/by-id/disk2"}, pool.Devices)
+	require.Equal(t, map[string]string{"/dev/disk/by-id/disk1": "ONLINE", "/dev/disk/by-id/disk2": "FAULTED"}, pool.DeviceStates)
+	require.Equal(t, "One or more devices are faulted in response to persistent errors. Sufficient replicas exist for the pool to continue functioning in a degraded state.", pool.Status)
+	require.Equal(t, "resilver in progress since Sun Jul  2 10:00:00 2023, 1.23G scanned at 100M/s, 500M issued at 50M/s, 10G total, 480M resilvered, 5.00% done, 00:03:00 to go", pool.Scan)
+	require.Equal(t, "2 data errors, use '-v' for a list", pool.Errors)
+}
+
+func TestParseDatasets(t *testing.T) {
+	t.Parallel()
+
+	datasets := parseDatasets(zfsListOutput)
+	require.Len(t, datasets, 2)
+	require.Equal(t, "data/incus", datasets[1].Name)
+	require.Equal(t, int64(950272), datasets[1].Used)
+	require.Equal(t, int64(2097152), datasets[1].Available)
+	require.Equal(t, int64(950272), datasets[1].Referenced)
+}
+
+func TestValidatePoolName(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, validatePoolName("data"))
+	require.Error(t, validatePoolName("local"))
+	require.Error(t, validatePoolName("mirror"))
+	require.Error(t, validatePoolName("1data"))
+	require.Error(t, validatePoolName("data/foo"))
+}
@@ -0,0 +1,8 @@
+#!/bin/sh -eux
+
+# Copy the chrony binaries, the service and configuration are provided by incus-osd.
+mkdir -p "${DESTDIR}/usr/sbin/" "${DESTDIR}/usr/bin/"
+cp /buildroot/usr/sbin/chronyd "${DESTDIR}/usr/sbin/"
+cp /buildroot/usr/bin/chronyc "${DESTDIR}/usr/bin/"
+
+exit 0